@@ -29,6 +29,11 @@ import (
 // @name Authorization
 // @description Type "Bearer" followed by a space and the token. Example: "Bearer your_token"
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key of a machine client, it must have the scope of the route. Example: "exk_3fa9c1d2_9b1f..."
+
 // @externalDocs.description  OpenAPI
 // @externalDocs.url          https://swagger.io/resources/open-api/
 func main() {
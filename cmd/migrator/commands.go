@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+var (
+	errUsage   = errors.New("invalid command or arguments")
+	errAborted = errors.New("the command is not confirmed, nothing has been changed")
+)
+
+// migrationName is the allowed name of a new migration, the spaces are replaced with underscores before the check.
+var migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// migrations is the part of *migrate.Migrate used by the commands.
+type migrations interface {
+	Up() error
+	Down() error
+	Steps(n int) error
+	Migrate(version uint) error
+	Force(version int) error
+	Version() (version uint, dirty bool, err error)
+}
+
+// migrator runs the commands against the database, confirm asks the user before a destructive command.
+type migrator struct {
+	m       migrations
+	out     io.Writer
+	confirm func(question string) bool
+}
+
+// run runs the command with its arguments. Rolling back, going to a lower version and forcing a version
+// are destructive and must be confirmed, otherwise errAborted is returned.
+// If the database is already at the requested version, it is reported and nil is returned.
+func (c *migrator) run(command string, args []string) error {
+	var err error
+
+	switch command {
+	case "up":
+		var steps int
+		if steps, err = optionalSteps(args); err != nil {
+			return err
+		}
+
+		if steps == 0 {
+			err = c.m.Up()
+		} else {
+			err = c.m.Steps(steps)
+		}
+
+	case "down":
+		var steps int
+		if steps, err = optionalSteps(args); err != nil {
+			return err
+		}
+
+		question := "Roll back ALL applied migrations, the data of their tables will be lost"
+		if steps != 0 {
+			question = fmt.Sprintf("Roll back %d applied migration(s), the data of their tables will be lost", steps)
+		}
+		if !c.confirm(question) {
+			return errAborted
+		}
+
+		if steps == 0 {
+			err = c.m.Down()
+		} else {
+			err = c.m.Steps(-steps)
+		}
+
+	case "goto":
+		if len(args) != 1 {
+			return errUsage
+		}
+
+		version, parseErr := strconv.ParseUint(args[0], 10, 64)
+		if parseErr != nil {
+			return errUsage
+		}
+
+		current, _, versionErr := c.m.Version()
+		if versionErr != nil && !errors.Is(versionErr, migrate.ErrNilVersion) {
+			return versionErr
+		}
+
+		if uint(version) < current && !c.confirm(fmt.Sprintf("Migrate down from version %d to %d, the data of the rolled back tables will be lost", current, version)) {
+			return errAborted
+		}
+
+		err = c.m.Migrate(uint(version))
+
+	case "force":
+		if len(args) != 1 {
+			return errUsage
+		}
+
+		// -1 means that no migration is applied
+		version, parseErr := strconv.Atoi(args[0])
+		if parseErr != nil || version < -1 {
+			return errUsage
+		}
+
+		if !c.confirm(fmt.Sprintf("Set the version to %d without running any migration", version)) {
+			return errAborted
+		}
+
+		err = c.m.Force(version)
+
+	case "status":
+		return c.status()
+
+	default:
+		return errUsage
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Fprintln(c.out, "no migrations to apply")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.out, "migrations have been successfully applied")
+	return c.status()
+}
+
+// status prints the current version and whether the last migration failed.
+func (c *migrator) status() error {
+	version, dirty, err := c.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Fprintln(c.out, "version: none, no migrations have been applied")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "version: %d, dirty: %t\n", version, dirty)
+	if dirty {
+		fmt.Fprintf(c.out, "the migration %d has failed, fix the database by hand and run \"force VERSION\" with the last correct version\n", version)
+	}
+	return nil
+}
+
+// optionalSteps parses the optional number of the migrations, 0 means all of them.
+func optionalSteps(args []string) (int, error) {
+	switch len(args) {
+	case 0:
+		return 0, nil
+	case 1:
+		steps, err := strconv.Atoi(args[0])
+		if err != nil || steps <= 0 {
+			return 0, errUsage
+		}
+		return steps, nil
+	default:
+		return 0, errUsage
+	}
+}
+
+// createMigration creates the empty up and down files of a new migration in dir, the version is the UTC time
+// of now, so it is always after the existing migrations. The files are never overwritten.
+func createMigration(dir, name string, now time.Time) ([]string, error) {
+	name = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", "_"))
+	if !migrationName.MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q, only letters, digits and underscores are allowed", name)
+	}
+
+	prefix := filepath.Join(dir, now.UTC().Format("20060102150405")+"_"+name)
+	files := []string{prefix + ".up.sql", prefix + ".down.sql"}
+
+	for _, path := range files {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := file.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
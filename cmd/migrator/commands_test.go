@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// fakeMigrations records the calls, the database is at version.
+type fakeMigrations struct {
+	version uint
+	calls   []string
+}
+
+func (f *fakeMigrations) Up() error {
+	f.calls = append(f.calls, "up")
+	return nil
+}
+
+func (f *fakeMigrations) Down() error {
+	f.calls = append(f.calls, "down")
+	return nil
+}
+
+func (f *fakeMigrations) Steps(n int) error {
+	f.calls = append(f.calls, fmt.Sprintf("steps %d", n))
+	return nil
+}
+
+func (f *fakeMigrations) Migrate(version uint) error {
+	f.calls = append(f.calls, fmt.Sprintf("migrate %d", version))
+	if version == f.version {
+		return migrate.ErrNoChange
+	}
+	return nil
+}
+
+func (f *fakeMigrations) Force(version int) error {
+	f.calls = append(f.calls, fmt.Sprintf("force %d", version))
+	return nil
+}
+
+func (f *fakeMigrations) Version() (uint, bool, error) {
+	if f.version == 0 {
+		return 0, false, migrate.ErrNilVersion
+	}
+	return f.version, false, nil
+}
+
+func TestMigratorRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		args    []string
+		confirm bool
+		calls   []string
+		asked   bool
+		err     error
+	}{
+		{name: "up", command: "up", calls: []string{"up"}},
+		{name: "up N", command: "up", args: []string{"2"}, calls: []string{"steps 2"}},
+		{name: "up with an invalid N", command: "up", args: []string{"-2"}, err: errUsage},
+		{name: "down confirmed", command: "down", confirm: true, asked: true, calls: []string{"down"}},
+		{name: "down N confirmed", command: "down", args: []string{"1"}, confirm: true, asked: true, calls: []string{"steps -1"}},
+		{name: "down not confirmed", command: "down", asked: true, err: errAborted},
+		{name: "goto a higher version", command: "goto", args: []string{"20"}, calls: []string{"migrate 20"}},
+		{name: "goto the same version", command: "goto", args: []string{"16"}, calls: []string{"migrate 16"}},
+		{name: "goto a lower version confirmed", command: "goto", args: []string{"12"}, confirm: true, asked: true, calls: []string{"migrate 12"}},
+		{name: "goto a lower version not confirmed", command: "goto", args: []string{"12"}, asked: true, err: errAborted},
+		{name: "goto without a version", command: "goto", err: errUsage},
+		{name: "force confirmed", command: "force", args: []string{"15"}, confirm: true, asked: true, calls: []string{"force 15"}},
+		{name: "force no version", command: "force", args: []string{"-1"}, confirm: true, asked: true, calls: []string{"force -1"}},
+		{name: "force not confirmed", command: "force", args: []string{"15"}, asked: true, err: errAborted},
+		{name: "status", command: "status"},
+		{name: "unknown command", command: "drop", err: errUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMigrations{version: 16}
+			var asked bool
+			m := &migrator{
+				m:   fake,
+				out: io.Discard,
+				confirm: func(question string) bool {
+					asked = true
+					return tt.confirm
+				},
+			}
+
+			err := m.run(tt.command, tt.args)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if asked != tt.asked {
+				t.Errorf("asked for a confirmation = %t, want %t", asked, tt.asked)
+			}
+			if !reflect.DeepEqual(fake.calls, tt.calls) {
+				t.Errorf("calls = %v, want %v", fake.calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 19, 12, 30, 5, 0, time.UTC)
+
+	files, err := createMigration(dir, "Add Invoices", now)
+	if err != nil {
+		t.Fatalf("createMigration() error = %v", err)
+	}
+
+	want := []string{
+		filepath.Join(dir, "20261019123005_add_invoices.up.sql"),
+		filepath.Join(dir, "20261019123005_add_invoices.down.sql"),
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("files = %v, want %v", files, want)
+	}
+	for _, file := range files {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("file is not created: %v", err)
+		}
+	}
+
+	if _, err := createMigration(dir, "add invoices", now); err == nil {
+		t.Errorf("existing migration is overwritten")
+	}
+	if _, err := createMigration(dir, "../escape", now); err == nil {
+		t.Errorf("invalid name is accepted")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+const usage = `usage: migrator [flags] [command] [arguments]
+
+commands:
+  up [N]          apply all or N pending migrations, it is the default command
+  down [N]        roll back all or N applied migrations
+  goto VERSION    migrate up or down to the version
+  status          show the current version and whether it is dirty
+  force VERSION   set the version without running the migrations, it clears the dirty state
+  create NAME     create the timestamped up and down files of a new migration
+
+the flags go before the command:
+`
+
+// main is the entry point of the migration script.
+// It reads command-line flags for the database connection path, or the config file of the server to take it from,
+// and the path to the migration files, then runs the command, "up" if none is given.
+// The destructive commands ask for a confirmation unless the -yes flag is set.
+// If the command fails, the program exits with code 1.
+func main() {
+	os.Exit(run())
+}
+
+// run parses the flags, runs the command and returns the exit code.
+func run() int {
+	var pathDB string
+	var configPath string
+	var fileMigrationPath string
+	var yes bool
+
+	flag.StringVar(&pathDB, "storage-path", "", "database connection path, STORAGE_PATH of the config is used without it")
+	flag.StringVar(&configPath, "config", "", "path to config file of the server")
+	flag.StringVar(&fileMigrationPath, "migrations-path", "./migrations", "path to migration files")
+	flag.BoolVar(&yes, "yes", false, "do not ask to confirm the destructive commands")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	command := "up"
+	args := flag.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	// a new migration only needs the directory
+	if command == "create" {
+		if len(args) != 1 {
+			flag.Usage()
+			return 1
+		}
+
+		files, err := createMigration(fileMigrationPath, args[0], time.Now())
+		if err != nil {
+			log.Println("failed to create the migration:", err)
+			return 1
+		}
+
+		for _, file := range files {
+			log.Println("created", file)
+		}
+		return 0
+	}
+
+	if pathDB == "" && configPath != "" {
+		storage, err := config.LoadStorage(configPath)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		pathDB = storage.StoragePath
+	}
+
+	if pathDB == "" {
+		log.Println("the database connection path is not specified, set -storage-path or -config")
+		return 1
+	}
+
+	migrateDb, err := migrate.New("file://"+fileMigrationPath, pathDB)
+	if err != nil {
+		log.Println("failed to initialize the migrations:", err)
+		return 1
+	}
+	defer migrateDb.Close()
+
+	m := &migrator{
+		m:       migrateDb,
+		out:     os.Stdout,
+		confirm: promptConfirm(yes, bufio.NewReader(os.Stdin)),
+	}
+
+	if err := m.run(command, args); err != nil {
+		if err == errUsage {
+			flag.Usage()
+		} else {
+			log.Println(err)
+		}
+		return 1
+	}
+
+	return 0
+}
+
+// promptConfirm returns the confirmation of the destructive commands, it asks on the terminal
+// and only "y" or "yes" is accepted. With yes every command is confirmed without asking.
+func promptConfirm(yes bool, in *bufio.Reader) func(question string) bool {
+	return func(question string) bool {
+		if yes {
+			return true
+		}
+
+		fmt.Printf("%s? [y/N] ", question)
+		answer, _ := in.ReadString('\n')
+
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes"
+	}
+}
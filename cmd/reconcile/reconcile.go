@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages/postgres"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/logs"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/retry"
+)
+
+const (
+	exitDiscrepancies = 1
+	exitFailure       = 2
+)
+
+// main is the entry point of the reconciliation script.
+// It recomputes the balances of all accounts from the persisted history of the operations and prints the mismatches
+// per user and currency, per account, the negative balances and the orphaned accounts.
+// If discrepancies are found, the program exits with code 1, if the reconciliation fails, with code 2.
+func main() {
+	os.Exit(run())
+}
+
+// run reads command-line flags for the database connection path, the optional path of the JSON report and the timeout,
+// runs the reconciliation and returns the exit code.
+func run() int {
+	var pathDB string
+	var reportPath string
+	var timeout time.Duration
+
+	flag.StringVar(&pathDB, "storage-path", "", "database connection path")
+	flag.StringVar(&reportPath, "report", "", "path to the JSON report, it is not written without it")
+	flag.DurationVar(&timeout, "timeout", 5*time.Minute, "maximum duration of the reconciliation")
+	flag.Parse()
+
+	if pathDB == "" {
+		log.Println("the database connection path is not specified")
+		return exitFailure
+	}
+
+	db, err := postgres.New(context.Background(), pathDB, logs.NewDiscardLogger(), retry.Policy{})
+	if err != nil {
+		log.Println(err)
+		return exitFailure
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	report, err := db.Reconcile(ctx)
+	if err != nil {
+		log.Println("failed to reconcile the balances:", err)
+		return exitFailure
+	}
+
+	printReport(os.Stdout, report)
+
+	if reportPath != "" {
+		if err := writeReport(reportPath, report); err != nil {
+			log.Println("failed to write the report:", err)
+			return exitFailure
+		}
+		log.Println("the report has been written to", reportPath)
+	}
+
+	if report.Discrepancies() > 0 {
+		return exitDiscrepancies
+	}
+
+	return 0
+}
+
+// printReport prints the discrepancies of the report, one per line, and the summary.
+func printReport(out io.Writer, report *models.ReconcileReport) {
+	for _, mismatch := range report.Mismatches {
+		fmt.Fprintf(out, "MISMATCH user %d %s: balance %.2f, history %.2f, difference %.2f\n",
+			mismatch.UserID, mismatch.Currency, mismatch.Balance, mismatch.Expected, mismatch.Difference)
+	}
+
+	for _, mismatch := range report.AccountMismatches {
+		fmt.Fprintf(out, "ACCOUNT MISMATCH user %d account %d %s: balance %.2f, history %.2f, difference %.2f\n",
+			mismatch.UserID, mismatch.AccountID, mismatch.Currency, mismatch.Balance, mismatch.Expected, mismatch.Difference)
+	}
+
+	for _, account := range report.NegativeBalances {
+		fmt.Fprintf(out, "NEGATIVE user %d account %d %s: balance %.2f\n",
+			account.UserID, account.AccountID, account.Currency, account.Balance)
+	}
+
+	for _, account := range report.OrphanedAccounts {
+		fmt.Fprintf(out, "ORPHANED account %d %s: balance %.2f, held %.2f, %s\n",
+			account.AccountID, account.Currency, account.Balance, account.Held, account.Reason)
+	}
+
+	fmt.Fprintf(out, "checked %d accounts, found %d discrepancies\n", report.Accounts, report.Discrepancies())
+}
+
+// writeReport writes the report as indented JSON to the file, the file is replaced if it exists.
+func writeReport(path string, report *models.ReconcileReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -1,78 +1,170 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 
 	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
 	"github.com/EvansTrein/RESTful_exchangerServer/internal/server"
 	servAuth "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	servBalances "github.com/EvansTrein/RESTful_exchangerServer/internal/services/balances"
+	servHolds "github.com/EvansTrein/RESTful_exchangerServer/internal/services/holds"
+	servOrders "github.com/EvansTrein/RESTful_exchangerServer/internal/services/orders"
+	servOutbox "github.com/EvansTrein/RESTful_exchangerServer/internal/services/outbox"
+	servRates "github.com/EvansTrein/RESTful_exchangerServer/internal/services/rates"
+	servScheduler "github.com/EvansTrein/RESTful_exchangerServer/internal/services/scheduler"
 	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	servWebhooks "github.com/EvansTrein/RESTful_exchangerServer/internal/services/webhooks"
 	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages/postgres"
 	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages/redis"
+	"github.com/EvansTrein/RESTful_exchangerServer/migrations"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/encryption"
 	grpcclient "github.com/EvansTrein/RESTful_exchangerServer/pkg/gRPCclient"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/jwtkeys"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/mailer"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/retry"
 )
 
 type App struct {
-	server   *server.HttpServer
-	log      *slog.Logger
-	conf     *config.Config
-	auth     *servAuth.Auth
-	wallet   *servWallet.Wallet
-	db       *postgres.PostgresDB
-	cacheDB  *redis.RedisDB
-	servGRPC *grpcclient.ServerGRPC
+	server    *server.HttpServer
+	log       *slog.Logger
+	conf      *config.Config
+	auth      *servAuth.Auth
+	wallet    *servWallet.Wallet
+	orders    *servOrders.Orders
+	holds     *servHolds.Holds
+	scheduler *servScheduler.Scheduler
+	webhooks  *servWebhooks.Webhooks
+	outbox    *servOutbox.Outbox
+	rates     *servRates.Rates
+	balances  *servBalances.Balances
+	db        *postgres.PostgresDB
+	cacheDB   *redis.RedisDB
+	servGRPC  *grpcclient.ServerGRPC
 }
 
 // New initializes and returns a new instance of the App struct.
-// It sets up the HTTP server, database connections (Postgres and Redis), gRPC client, and services (Auth and Wallet).
-// If any initialization step fails, the function panics.
+// It sets up the HTTP server, database connections (Postgres and Redis, the pending migrations are applied if AUTO_MIGRATE is set), gRPC client, and services (Auth, Wallet, Orders, Holds, Scheduler, Webhooks, Outbox, Rates and Balances).
+// Postgres, Redis and the gRPC server are waited for until STARTUP_DEADLINE, with STARTUP_DEGRADED Redis and the gRPC server
+// may stay unavailable and are reconnected in the background. If any initialization step fails, the function panics.
 // The function logs the creation process and returns the fully initialized App instance.
 func New(conf *config.Config, log *slog.Logger) *App {
 	log.Debug("application: creation is started")
 
-	httpServer := server.New(log, &conf.HTTPServer)
+	httpServer, err := server.New(log, &conf.HTTPServer)
+	if err != nil {
+		panic(err)
+	}
+
+	// the dependencies may start after the server, every connection is retried until the deadline
+	wait := retry.Policy{
+		Deadline:     conf.Startup.Deadline,
+		InitialDelay: conf.Startup.InitialBackoff,
+		MaxDelay:     conf.Startup.MaxBackoff,
+	}
+
+	db, err := postgres.New(context.Background(), conf.StoragePath, log, wait)
+	if err != nil {
+		panic(err)
+	}
+
+	if conf.AutoMigrate {
+		ctx, cancel := context.WithTimeout(context.Background(), conf.MigrateTimeout)
+		err := db.Migrate(ctx, migrations.FS)
+		cancel()
+		if err != nil {
+			panic(fmt.Errorf("failed to migrate the database: %w", err))
+		}
+	}
+
+	redis, err := redis.New(context.Background(), log, conf.Redis.Address, conf.Redis.Port, conf.Redis.Password, conf.Redis.TTLKeys, wait, conf.Startup.Degraded)
+	if err != nil {
+		panic(err)
+	}
+
+	clientGRPC, err := grpcclient.New(context.Background(), log, conf.Services.AddressGRPC, conf.Services.PortGRPC, wait, conf.Startup.Degraded)
+	if err != nil {
+		panic(err)
+	}
+
+	mail, err := newMailer(log, &conf.Mailer)
+	if err != nil {
+		panic(err)
+	}
 
-	db, err := postgres.New(conf.StoragePath, log)
+	cipher, err := encryption.New(conf.TwoFactor.EncryptionKey)
 	if err != nil {
 		panic(err)
 	}
 
-	redis, err := redis.New(log, conf.Redis.Address, conf.Redis.Port, conf.Redis.Password, conf.Redis.TTLKeys)
+	keys, err := jwtkeys.Load(conf.JWT.Keys, conf.JWT.ActiveKey, conf.SecretKey)
 	if err != nil {
 		panic(err)
 	}
 
-	clientGRPC, err := grpcclient.New(log, conf.Services.AddressGRPC, conf.Services.PortGRPC)
+	auth := servAuth.New(log, db, mail, keys, &conf.JWT, &conf.Verification, &conf.PasswordReset, &conf.TwoFactor, cipher)
+	wallet := servWallet.New(log, clientGRPC, db, redis, &conf.Fees, auth, &conf.TwoFactor)
+	orders := servOrders.New(log, db, clientGRPC, wallet, conf.Orders.MatchInterval)
+	holds := servHolds.New(log, db, auth, &conf.Holds, &conf.TwoFactor)
+	scheduler, err := servScheduler.New(log, db, wallet, &conf.Scheduler)
 	if err != nil {
 		panic(err)
 	}
 
-	auth := servAuth.New(log, db, conf.SecretKey)
-	wallet := servWallet.New(log, clientGRPC, db, redis)
+	webhooks := servWebhooks.New(log, db, &conf.Webhooks)
 
-	httpServer.InitRouters(&conf.HTTPServer, auth, wallet)
+	outbox, err := servOutbox.New(log, db, &conf.Outbox)
+	if err != nil {
+		panic(err)
+	}
+
+	rates := servRates.New(log, clientGRPC, &conf.Streaming)
+
+	// the balance notifications are driven by the committed wallet events of the outbox
+	balances := servBalances.New(log, db, redis, &conf.Streaming)
+	for _, event := range []string{servOutbox.EventDeposited, servOutbox.EventWithdrawn, servOutbox.EventExchanged} {
+		outbox.Subscribe(event, balances.Notify)
+	}
+
+	httpServer.InitRouters(&conf.HTTPServer, auth, wallet, orders, holds, scheduler, webhooks, rates, balances, &conf.Streaming, redis, &conf.PasswordReset, &conf.TwoFactor)
 
 	app := &App{
-		server:   httpServer,
-		log:      log,
-		conf:     conf,
-		auth:     auth,
-		wallet:   wallet,
-		db:       db,
-		cacheDB:  redis,
-		servGRPC: clientGRPC,
+		server:    httpServer,
+		log:       log,
+		conf:      conf,
+		auth:      auth,
+		wallet:    wallet,
+		orders:    orders,
+		holds:     holds,
+		scheduler: scheduler,
+		webhooks:  webhooks,
+		outbox:    outbox,
+		rates:     rates,
+		balances:  balances,
+		db:        db,
+		cacheDB:   redis,
+		servGRPC:  clientGRPC,
 	}
 
 	log.Info("application: successfully created")
 	return app
 }
 
-// MustStart starts the application, including the HTTP server.
+// MustStart starts the application, including the matcher of the limit orders, the expirer of the holds, the workers of the scheduled operations, the webhook delivery worker, the outbox relay, the fetcher of the rate streams, the listener of the balance notifications and the HTTP server.
 // If the server fails to start, the function panics.
 // The function logs the start process and the port on which the server is running.
 func (a *App) MustStart() {
 	a.log.Debug("application: started")
 
+	a.orders.StartMatcher()
+	a.holds.StartExpirer()
+	a.scheduler.StartWorkers()
+	a.webhooks.StartWorker()
+	a.outbox.StartRelay()
+	a.rates.StartFetcher()
+	a.balances.StartListener()
+
 	a.log.Info("application: successfully started", "port", a.conf.HTTPServer.Port)
 	if err := a.server.Start(); err != nil {
 		panic(err)
@@ -80,17 +172,53 @@ func (a *App) MustStart() {
 }
 
 // Stop gracefully shuts down the application, stopping the HTTP server, gRPC server, Redis, and database connections.
-// It also stops the Auth and Wallet services.
+// It also stops the Auth, Wallet, Orders, Holds, Scheduler, Webhooks, Outbox, Rates and Balances services, the background workers are stopped right after the HTTP server.
 // If any step fails, the function logs the error and returns it.
 // The function logs the successful shutdown process and cleans up the App instance.
 func (a *App) Stop() error {
 	a.log.Debug("application: stop started")
 
+	// the live streams are closed first, otherwise the HTTP server would wait for them until the shutdown timeout
+	if err := a.rates.Stop(); err != nil {
+		a.log.Error("failed to stop the Rates service")
+		return err
+	}
+
+	if err := a.balances.Stop(); err != nil {
+		a.log.Error("failed to stop the Balances service")
+		return err
+	}
+
 	if err := a.server.Stop(); err != nil {
 		a.log.Error("failed to stop HTTP server")
 		return err
 	}
 
+	if err := a.orders.Stop(); err != nil {
+		a.log.Error("failed to stop the Orders service")
+		return err
+	}
+
+	if err := a.holds.Stop(); err != nil {
+		a.log.Error("failed to stop the Holds service")
+		return err
+	}
+
+	if err := a.scheduler.Stop(); err != nil {
+		a.log.Error("failed to stop the Scheduler service")
+		return err
+	}
+
+	if err := a.webhooks.Stop(); err != nil {
+		a.log.Error("failed to stop the Webhooks service")
+		return err
+	}
+
+	if err := a.outbox.Stop(); err != nil {
+		a.log.Error("failed to stop the Outbox service")
+		return err
+	}
+
 	if err := a.servGRPC.Close(); err != nil {
 		a.log.Error("failed to stop gRPC server")
 		return err
@@ -118,6 +246,13 @@ func (a *App) Stop() error {
 
 	a.auth = nil
 	a.wallet = nil
+	a.orders = nil
+	a.holds = nil
+	a.scheduler = nil
+	a.webhooks = nil
+	a.outbox = nil
+	a.rates = nil
+	a.balances = nil
 	a.db = nil
 	a.cacheDB = nil
 	a.servGRPC = nil
@@ -126,3 +261,18 @@ func (a *App) Stop() error {
 	a.log.Info("application: stop successful")
 	return nil
 }
+
+// newMailer creates the mailer of the configured driver.
+// If the driver is unknown or the mail file can not be opened, it returns an error.
+func newMailer(log *slog.Logger, conf *config.Mailer) (mailer.Mailer, error) {
+	switch conf.Driver {
+	case mailer.DriverSMTP:
+		return mailer.NewSMTP(conf.Host, conf.Port, conf.Username, conf.Password, conf.From), nil
+	case mailer.DriverFile:
+		return mailer.NewFile(conf.From, conf.FilePath)
+	case mailer.DriverLog:
+		return mailer.NewLog(log, conf.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer driver %q", conf.Driver)
+	}
+}
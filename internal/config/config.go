@@ -2,6 +2,7 @@ package config
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"time"
@@ -12,10 +13,26 @@ import (
 type Config struct {
 	Env         string `env:"ENV" env-required:"true"`
 	StoragePath string `env:"STORAGE_PATH" env-required:"true"`
-	SecretKey   string `env:"SECRET_KEY" env-required:"true"`
-	HTTPServer  `env-prefix:"HTTP_"`
-	Services    `env-prefix:"SERVICES_"`
-	Redis       `env-prefix:"REDIS_"`
+	SecretKey   string `env:"SECRET_KEY"`
+	// AutoMigrate applies the pending embedded migrations at startup, MigrateTimeout includes the wait for the other replicas
+	AutoMigrate    bool          `env:"AUTO_MIGRATE" env-default:"false"`
+	MigrateTimeout time.Duration `env:"MIGRATE_TIMEOUT" env-default:"5m"`
+	HTTPServer     `env-prefix:"HTTP_"`
+	Startup        `env-prefix:"STARTUP_"`
+	Services       `env-prefix:"SERVICES_"`
+	Redis          `env-prefix:"REDIS_"`
+	Fees           `env-prefix:"FEES_"`
+	Orders         `env-prefix:"ORDERS_"`
+	Holds          `env-prefix:"HOLDS_"`
+	Scheduler      `env-prefix:"SCHEDULER_"`
+	Webhooks       `env-prefix:"WEBHOOKS_"`
+	Outbox         `env-prefix:"OUTBOX_"`
+	Streaming      `env-prefix:"STREAM_"`
+	Verification   `env-prefix:"VERIFICATION_"`
+	Mailer         `env-prefix:"MAILER_"`
+	PasswordReset  `env-prefix:"PASSWORD_RESET_"`
+	TwoFactor      `env-prefix:"TWO_FACTOR_"`
+	JWT            `env-prefix:"JWT_"`
 }
 
 type HTTPServer struct {
@@ -25,6 +42,18 @@ type HTTPServer struct {
 	ReadTimeout       time.Duration `env:"READ_TIMEOUT"`
 	WriteTimeout      time.Duration `env:"WRITE_TIMEOUT"`
 	IdleTimeout       time.Duration `env:"IDLE_TIMEOUT"`
+	// TrustedProxies are the IPs or CIDRs of the proxies whose forwarding headers set the client IP, none by default
+	TrustedProxies []string `env:"TRUSTED_PROXIES"`
+}
+
+// Startup configures the waiting for Postgres, Redis and the gRPC server at startup. Every connection is retried,
+// the delay starts at InitialBackoff and doubles up to MaxBackoff, until Deadline. With Degraded the server starts
+// without Redis or the gRPC server if they are still unavailable, they are reconnected in the background every MaxBackoff.
+type Startup struct {
+	Deadline       time.Duration `env:"DEADLINE" env-default:"30s"`
+	InitialBackoff time.Duration `env:"INITIAL_BACKOFF" env-default:"200ms"`
+	MaxBackoff     time.Duration `env:"MAX_BACKOFF" env-default:"5s"`
+	Degraded       bool          `env:"DEGRADED" env-default:"false"`
 }
 
 type Services struct {
@@ -39,6 +68,75 @@ type Redis struct {
 	TTLKeys  time.Duration `env:"TTL_KEYS"`
 }
 
+// Fees describes how the exchange fee is calculated.
+// SpreadPercent is applied to every pair unless PairSpreads has an override for "FROM/TO".
+// SegmentMultipliers scale the spread for a user segment, MinFees sets the minimum fee per target currency.
+type Fees struct {
+	SpreadPercent      float32            `env:"SPREAD_PERCENT" env-default:"0"`
+	MinFee             float32            `env:"MIN_FEE" env-default:"0"`
+	MinFees            map[string]float32 `env:"MIN_FEES"`
+	PairSpreads        map[string]float32 `env:"PAIR_SPREADS"`
+	SegmentMultipliers map[string]float32 `env:"SEGMENT_MULTIPLIERS"`
+}
+
+// Orders configures the matcher of the limit orders, MatchInterval is the time between two matching rounds.
+type Orders struct {
+	MatchInterval time.Duration `env:"MATCH_INTERVAL" env-default:"10s"`
+}
+
+// Holds configures the holds of the funds. A hold without an expiry time expires after DefaultTTL,
+// a hold can not be kept longer than MaxTTL. Every ExpireInterval the expired holds are released.
+type Holds struct {
+	DefaultTTL     time.Duration `env:"DEFAULT_TTL" env-default:"168h"`
+	MaxTTL         time.Duration `env:"MAX_TTL" env-default:"720h"`
+	ExpireInterval time.Duration `env:"EXPIRE_INTERVAL" env-default:"1m"`
+}
+
+// Scheduler configures the workers of the scheduled operations.
+// Every PollInterval due schedules are claimed in batches of BatchSize for Lease and run by Workers goroutines,
+// a run must finish within RunTimeout, the Lease must be longer than ceil(BatchSize/Workers) runs and their records. A failed run is retried MaxRetries times, the delay starts at RetryDelay and doubles.
+type Scheduler struct {
+	PollInterval time.Duration `env:"POLL_INTERVAL" env-default:"15s"`
+	Workers      int           `env:"WORKERS" env-default:"4"`
+	BatchSize    int           `env:"BATCH_SIZE" env-default:"20"`
+	Lease        time.Duration `env:"LEASE" env-default:"10m"`
+	RunTimeout   time.Duration `env:"RUN_TIMEOUT" env-default:"30s"`
+	MaxRetries   int           `env:"MAX_RETRIES" env-default:"3"`
+	RetryDelay   time.Duration `env:"RETRY_DELAY" env-default:"1m"`
+}
+
+// Webhooks configures the delivery worker of the webhooks.
+// Every PollInterval pending deliveries are claimed in batches of BatchSize for Lease and sent by Workers goroutines,
+// a request must finish within Timeout. A failed delivery is retried with a doubling delay starting at RetryDelay
+// (at most MaxRetryDelay) until MaxAttempts attempts have been made.
+// The endpoints on the loopback, private and link-local networks are refused unless AllowPrivate is set (development only).
+type Webhooks struct {
+	PollInterval  time.Duration `env:"POLL_INTERVAL" env-default:"5s"`
+	Workers       int           `env:"WORKERS" env-default:"4"`
+	BatchSize     int           `env:"BATCH_SIZE" env-default:"50"`
+	Lease         time.Duration `env:"LEASE" env-default:"1m"`
+	Timeout       time.Duration `env:"TIMEOUT" env-default:"10s"`
+	MaxAttempts   int           `env:"MAX_ATTEMPTS" env-default:"8"`
+	RetryDelay    time.Duration `env:"RETRY_DELAY" env-default:"30s"`
+	MaxRetryDelay time.Duration `env:"MAX_RETRY_DELAY" env-default:"6h"`
+	AllowPrivate  bool          `env:"ALLOW_PRIVATE" env-default:"false"`
+}
+
+// Outbox configures the relay of the domain events.
+// Every PollInterval up to BatchSize unpublished events are published to the Sinks:
+// "bus" (in-process subscribers), "stdout" and "file" (JSON lines appended to FilePath).
+// A failed event is retried with a doubling delay starting at RetryDelay (at most MaxRetryDelay),
+// after MaxAttempts attempts it is dead-lettered.
+type Outbox struct {
+	PollInterval  time.Duration `env:"POLL_INTERVAL" env-default:"1s"`
+	BatchSize     int           `env:"BATCH_SIZE" env-default:"100"`
+	Sinks         []string      `env:"SINKS" env-default:"bus"`
+	FilePath      string        `env:"FILE_PATH" env-default:"outbox.jsonl"`
+	MaxAttempts   int           `env:"MAX_ATTEMPTS" env-default:"10"`
+	RetryDelay    time.Duration `env:"RETRY_DELAY" env-default:"1s"`
+	MaxRetryDelay time.Duration `env:"MAX_RETRY_DELAY" env-default:"10m"`
+}
+
 // MustLoad loads the configuration from a file specified via a command-line flag.
 // If the configuration file does not exist or an error occurs while reading it, the program terminates with a fatal error.
 // Upon successful loading of the configuration, the function returns a pointer to the Config struct.
@@ -60,3 +158,105 @@ func MustLoad() *Config {
 	log.Println("configuration file successfully loaded")
 	return &cfg
 }
+
+// Storage is the part of the configuration used by the command-line tools that only work with the database.
+type Storage struct {
+	StoragePath string `env:"STORAGE_PATH" env-required:"true"`
+}
+
+// LoadStorage reads only the database connection path from the config file of the server,
+// so that the tools do not need the rest of the settings. The environment variables override the file, as for the server.
+func LoadStorage(filePath string) (*Storage, error) {
+	var cfg Storage
+
+	if _, err := os.Stat(filePath); err != nil {
+		return nil, fmt.Errorf("env file is not available: %w", err)
+	}
+
+	if err := cleanenv.ReadConfig(filePath, &cfg); err != nil {
+		return nil, fmt.Errorf("cannot read config: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// Streaming configures the live streams of the exchange rates and the balances.
+// The subscribed pairs are requested from the rate service every FetchInterval, the clients receive a heartbeat
+// every HeartbeatInterval. A client whose queue of ClientBuffer updates is full is disconnected.
+type Streaming struct {
+	FetchInterval     time.Duration `env:"FETCH_INTERVAL" env-default:"1s"`
+	HeartbeatInterval time.Duration `env:"HEARTBEAT_INTERVAL" env-default:"15s"`
+	WriteTimeout      time.Duration `env:"WRITE_TIMEOUT" env-default:"10s"`
+	ClientBuffer      int           `env:"CLIENT_BUFFER" env-default:"16"`
+	MaxPairs          int           `env:"MAX_PAIRS" env-default:"20"`
+}
+
+// Verification configures the confirmation of the email addresses.
+// If Required is false, the new users are verified at once, it is meant for the local environment.
+// A token is valid for TokenTTL, a new one can be requested once per ResendInterval.
+// LinkURL is the page of the client that confirms the email, the token is appended to it.
+type Verification struct {
+	Required       bool          `env:"REQUIRED" env-default:"true"`
+	TokenTTL       time.Duration `env:"TOKEN_TTL" env-default:"24h"`
+	ResendInterval time.Duration `env:"RESEND_INTERVAL" env-default:"1m"`
+	LinkURL        string        `env:"LINK_URL"`
+}
+
+// PasswordReset configures the reset of the forgotten passwords.
+// A token is valid for TokenTTL, a new one is sent to the same user once per ResendInterval.
+// LinkURL is the page of the client that sets the new password, the token is appended to it.
+// Each client IP can call every password endpoint RequestLimit times per RequestWindow.
+type PasswordReset struct {
+	TokenTTL       time.Duration `env:"TOKEN_TTL" env-default:"1h"`
+	ResendInterval time.Duration `env:"RESEND_INTERVAL" env-default:"1m"`
+	LinkURL        string        `env:"LINK_URL"`
+	RequestLimit   int           `env:"REQUEST_LIMIT" env-default:"5"`
+	RequestWindow  time.Duration `env:"REQUEST_WINDOW" env-default:"15m"`
+}
+
+// TwoFactor configures the two-factor authentication with the TOTP codes.
+// EncryptionKey is the hex encoded AES-256 key the secrets are encrypted with in the database,
+// Issuer is the name of the account in the authenticator app. After the password the client has ChallengeTTL
+// to send the code, a user gets RecoveryCodes one-time recovery codes. The code endpoints accept RequestLimit
+// requests per RequestWindow from one IP. With 2FA enabled, a withdrawal of at least WithdrawThresholds[currency]
+// and the deletion of the user need a code, the currencies without a threshold need no code.
+// After MaxFailures wrong codes in a row the codes of the user are not checked for Lockout.
+type TwoFactor struct {
+	EncryptionKey      string             `env:"ENCRYPTION_KEY" env-required:"true"`
+	Issuer             string             `env:"ISSUER" env-default:"Exchanger"`
+	ChallengeTTL       time.Duration      `env:"CHALLENGE_TTL" env-default:"5m"`
+	RecoveryCodes      int                `env:"RECOVERY_CODES" env-default:"10"`
+	WithdrawThresholds map[string]float32 `env:"WITHDRAW_THRESHOLDS"`
+	RequestLimit       int                `env:"REQUEST_LIMIT" env-default:"10"`
+	RequestWindow      time.Duration      `env:"REQUEST_WINDOW" env-default:"15m"`
+	MaxFailures        int                `env:"MAX_FAILURES" env-default:"5"`
+	Lockout            time.Duration      `env:"LOCKOUT" env-default:"15m"`
+}
+
+// JWT configures the keys of the access tokens.
+// Keys are the PEM files of the keys by their kid, an RSA key signs with RS256 and an Ed25519 key with EdDSA.
+// The tokens are signed with ActiveKey, the other keys only verify the tokens they have signed, so a key is rotated
+// without logging anybody out: add the new key, make it active and remove the old one after TTL. A public key only verifies.
+// Without Keys the tokens are signed with HS256 and SECRET_KEY, then other services can not verify them.
+// The tokens carry the Issuer and the Audience and are accepted only with them,
+// the expiration, the issue and the not before times are checked with ClockSkew of allowed difference between the clocks.
+type JWT struct {
+	Keys      map[string]string `env:"KEYS"`
+	ActiveKey string            `env:"ACTIVE_KEY"`
+	TTL       time.Duration     `env:"TTL" env-default:"1h"`
+	Issuer    string            `env:"ISSUER" env-default:"exchanger"`
+	Audience  string            `env:"AUDIENCE" env-default:"exchanger-api"`
+	ClockSkew time.Duration     `env:"CLOCK_SKEW" env-default:"30s"`
+}
+
+// Mailer configures the delivery of the emails.
+// Driver is "smtp" (the server at Host:Port), "file" (the messages are appended to FilePath) or "log".
+type Mailer struct {
+	Driver   string `env:"DRIVER" env-default:"log"`
+	From     string `env:"FROM" env-default:"no-reply@exchanger.local"`
+	Host     string `env:"HOST"`
+	Port     string `env:"PORT" env-default:"587"`
+	Username string `env:"USERNAME"`
+	Password string `env:"PASSWORD"`
+	FilePath string `env:"FILE_PATH" env-default:"mail.log"`
+}
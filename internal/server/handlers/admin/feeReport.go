@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type feeReportServ interface {
+	FeeReport(ctx context.Context, req models.FeeReportRequest) (*models.FeeReportResponse, error)
+}
+
+// FeeReport is a Gin handler function that returns the exchange fee revenue per currency for a period.
+// It binds the query parameters "from" and "to" (YYYY-MM-DD) and calls the service to build the report.
+// If the parameters are invalid or the period is empty, it returns a 400 Bad Request.
+// If the request times out, it returns a 504 Gateway Timeout.
+// On success, it returns a 200 OK response with the report.
+//
+// @Summary Fee revenue report
+// @Description Exchange fee revenue per currency for the period [from, to), admin only
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param from query string true "Beginning of the period (inclusive)" example(2025-01-01)
+// @Param to query string true "End of the period (exclusive)" example(2025-02-01)
+// @Success 200 {object} models.FeeReportResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 403 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /admin/fees [get]
+func FeeReport(log *slog.Logger, serv feeReportServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler FeeReport: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("fee revenue report request received")
+
+		var req models.FeeReportRequest
+		if err := ctx.ShouldBindQuery(&req); err != nil {
+			log.Warn("fail BindQuery", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		log.Debug("request data has been successfully validated", "data", req)
+
+		result, err := serv.FeeReport(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case services.ErrInvalidPeriod:
+				log.Warn("failed to generate the report", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "the beginning of the period must be earlier than its end",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to generate the report", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to generate the report", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to generate the report",
+				})
+				return
+			}
+		}
+
+		log.Info("fee revenue report successfully sent")
+		ctx.JSON(200, result)
+	}
+}
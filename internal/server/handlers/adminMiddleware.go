@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware is a Gin middleware function that allows the request only for administrators.
+// It must be used after LoggingMiddleware, which puts the role from the token into the context.
+// If the role is missing or is not admin, it returns a 403 Forbidden response.
+func AdminMiddleware(log *slog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "AdminMiddleware"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request for an admin resource is received")
+
+		role, exists := ctx.Get("role")
+		if !exists || role != services.RoleAdmin {
+			log.Warn("access to an admin resource is denied", "role", role)
+			ctx.JSON(403, models.HandlerResponse{
+				Status:  http.StatusForbidden,
+				Error:   "admin role is required",
+				Message: "access denied",
+			})
+			ctx.Abort()
+			return
+		}
+
+		log.Debug("admin role successfully verified")
+		ctx.Next()
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type apiKeysServ interface {
+	APIKeys(ctx context.Context, userId uint) (*models.APIKeysResponse, error)
+}
+
+// APIKeys is a Gin handler function that returns the API keys of the authenticated user, the revoked ones included.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the request times out, it returns a 504 Gateway Timeout.
+// On success, it returns a 200 OK response with the keys, the keys themselves are not returned.
+//
+// @Summary API keys
+// @Description Get the API keys of the user with their prefixes, scopes and the last use time
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIKeysResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /api-keys [get]
+func APIKeys(log *slog.Logger, serv apiKeysServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler APIKeys: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		log.Debug("user id was successfully obtained from the context", "userID", userIdUint)
+
+		result, err := serv.APIKeys(ctx.Request.Context(), userIdUint)
+		if err != nil {
+			switch err {
+			case context.DeadlineExceeded:
+				log.Error("failed to get the API keys", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to get the API keys", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to get the API keys",
+				})
+				return
+			}
+		}
+
+		log.Info("API keys successfully received")
+		ctx.JSON(200, result)
+	}
+}
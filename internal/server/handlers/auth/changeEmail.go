@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type changeEmailServ interface {
+	ChangeEmail(ctx context.Context, req *models.ChangeEmailRequest) error
+}
+
+// ChangeEmail is a Gin handler function that starts the change of the email of the authenticated user.
+// The current password must be passed, a verification token is sent to the new email,
+// the email is changed when the token is confirmed through POST /verify-email.
+// If the data is invalid or the email is the current one, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the current password is wrong, it returns a 403 Forbidden.
+// If the user is not found, it returns a 404 Not Found.
+// If the email belongs to another user, it returns a 409 Conflict.
+// If a verification token was sent recently, it returns a 429 Too Many Requests.
+// If the request times out, it returns a 504 Gateway Timeout.
+// On success, it returns a 200 OK response.
+//
+// @Summary Change the email
+// @Description Send a verification token to the new email, the email is changed after it is verified
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body models.ChangeEmailRequest true "New email and the current password"
+// @Success 200 {object} models.HandlerResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 403 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 409 {object} models.HandlerResponse
+// @Failure 429 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /me/email [post]
+func ChangeEmail(log *slog.Logger, serv changeEmailServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler ChangeEmail: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.ChangeEmailRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		err := serv.ChangeEmail(ctx.Request.Context(), &req)
+		if err != nil {
+			switch err {
+			case services.ErrSameEmail:
+				log.Warn("failed to change the email", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "new email is the current email",
+				})
+				return
+			case services.ErrInvalidPassword:
+				log.Warn("failed to change the email", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "current password is incorrect",
+				})
+				return
+			case services.ErrUserNotFound:
+				log.Warn("failed to change the email", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "user not found",
+				})
+				return
+			case services.ErrEmailAlreadyExists:
+				log.Warn("failed to change the email", "error", err)
+				ctx.JSON(409, models.HandlerResponse{
+					Status:  http.StatusConflict,
+					Error:   err.Error(),
+					Message: "email is already used by another user",
+				})
+				return
+			case services.ErrTooManyRequests:
+				log.Warn("failed to change the email", "error", err)
+				ctx.JSON(429, models.HandlerResponse{
+					Status:  http.StatusTooManyRequests,
+					Error:   err.Error(),
+					Message: "verification email was sent recently, try again later",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to change the email", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to change the email", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to change the email",
+				})
+				return
+			}
+		}
+
+		log.Info("verification token sent to the new email")
+		ctx.JSON(200, models.HandlerResponse{Status: http.StatusOK, Message: "verification token sent to the new email"})
+	}
+}
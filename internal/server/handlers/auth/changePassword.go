@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type changePasswordServ interface {
+	ChangePassword(ctx context.Context, req *models.ChangePasswordRequest) (*models.LoginResponse, error)
+}
+
+// ChangePassword is a Gin handler function that sets a new password of the authenticated user.
+// The current password must be passed, all sessions of the user are ended and a new token is returned.
+// If the data is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the current password is wrong, it returns a 403 Forbidden.
+// If the user is not found, it returns a 404 Not Found.
+// If the request times out, it returns a 504 Gateway Timeout.
+// On success, it returns a 200 OK response with the new token.
+//
+// @Summary Change the password
+// @Description Set a new password, the tokens issued before are revoked and a new one is returned
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body models.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 403 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /me/password [post]
+func ChangePassword(log *slog.Logger, serv changePasswordServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler ChangePassword: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.ChangePasswordRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.ChangePassword(ctx.Request.Context(), &req)
+		if err != nil {
+			switch err {
+			case services.ErrInvalidPassword:
+				log.Warn("failed to change the password", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "current password is incorrect",
+				})
+				return
+			case services.ErrUserNotFound:
+				log.Warn("failed to change the password", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "user not found",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to change the password", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to change the password", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to change the password",
+				})
+				return
+			}
+		}
+
+		log.Info("password successfully changed")
+		ctx.JSON(200, result)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type confirmTwoFactorServ interface {
+	ConfirmTwoFactor(ctx context.Context, req *models.TwoFactorCodeRequest) (*models.TwoFactorConfirmResponse, error)
+}
+
+// ConfirmTwoFactor is a Gin handler function that enables the two-factor authentication of the authenticated user
+// with the first code from the authenticator app.
+// If the data is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the code is wrong, it returns a 403 Forbidden.
+// If the enrollment has not been started, it returns a 404 Not Found.
+// If the two-factor authentication is already enabled, it returns a 409 Conflict.
+// If too many wrong two-factor codes were sent in a row, it returns a 429 Too Many Requests.
+// If the request times out, it returns a 504 Gateway Timeout.
+// On success, it returns a 200 OK response with the recovery codes, they are shown only once.
+//
+// @Summary Confirm the two-factor enrollment
+// @Description Enable the two-factor authentication with the first code, the response has the one-time recovery codes
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body models.TwoFactorCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} models.TwoFactorConfirmResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 403 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 409 {object} models.HandlerResponse
+// @Failure 429 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /2fa/confirm [post]
+func ConfirmTwoFactor(log *slog.Logger, serv confirmTwoFactorServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler ConfirmTwoFactor: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.TwoFactorCodeRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.ConfirmTwoFactor(ctx.Request.Context(), &req)
+		if err != nil {
+			switch err {
+			case services.ErrInvalidTwoFactor:
+				log.Warn("failed to confirm the two-factor authentication", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "two-factor code is invalid or already used",
+				})
+				return
+			case services.ErrTwoFactorLocked:
+				log.Warn("failed to confirm the two-factor authentication", "error", err)
+				ctx.JSON(429, models.HandlerResponse{
+					Status:  http.StatusTooManyRequests,
+					Error:   err.Error(),
+					Message: "too many invalid two-factor codes, try again later",
+				})
+				return
+			case services.ErrTwoFactorDisabled:
+				log.Warn("failed to confirm the two-factor authentication", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "start the enrollment first",
+				})
+				return
+			case services.ErrTwoFactorEnabled:
+				log.Warn("failed to confirm the two-factor authentication", "error", err)
+				ctx.JSON(409, models.HandlerResponse{
+					Status:  http.StatusConflict,
+					Error:   err.Error(),
+					Message: "two-factor authentication is already enabled",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to confirm the two-factor authentication", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to confirm the two-factor authentication", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to confirm the two-factor authentication",
+				})
+				return
+			}
+		}
+
+		log.Info("two-factor authentication enabled")
+		ctx.JSON(200, result)
+	}
+}
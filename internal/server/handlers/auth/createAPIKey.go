@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type createAPIKeyServ interface {
+	CreateAPIKey(ctx context.Context, req models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error)
+}
+
+// CreateAPIKey is a Gin handler function that creates an API key of the authenticated user for the machine clients.
+// It binds the incoming JSON request to a struct, validates the data, and calls the service to create the key.
+// If the data is invalid or the expiration time is in the past, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If a user without the admin role requests the admin scope, it returns a 403 Forbidden.
+// If the user is not found, it returns a 404 Not Found.
+// On success, it returns a 201 Created response with the key, it is shown only once.
+//
+// @Summary Create an API key
+// @Description Create an API key with the scopes (balance:read, wallet:write, exchange, orders, schedules, webhooks, admin). The key is passed in the X-API-Key header instead of the Bearer token and is returned only in this response, the admin scope is allowed only for the admins
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body models.CreateAPIKeyRequest true "API key request"
+// @Success 201 {object} models.CreateAPIKeyResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 403 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /api-keys [post]
+func CreateAPIKey(log *slog.Logger, serv createAPIKeyServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler CreateAPIKey: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.CreateAPIKeyRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.CreateAPIKey(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case services.ErrInvalidExpiry:
+				log.Warn("failed to create the API key", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "invalid expiration time",
+				})
+				return
+			case services.ErrScopeNotAllowed:
+				log.Warn("failed to create the API key", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "the admin scope requires the admin role",
+				})
+				return
+			case services.ErrUserNotFound:
+				log.Warn("failed to create the API key", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "user not found",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to create the API key", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to create the API key", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to create the API key",
+				})
+				return
+			}
+		}
+
+		log.Info("API key successfully created")
+		ctx.JSON(201, result)
+	}
+}
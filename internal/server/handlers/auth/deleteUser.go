@@ -6,30 +6,43 @@ import (
 	"net/http"
 
 	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
 	"github.com/EvansTrein/RESTful_exchangerServer/models"
 	"github.com/gin-gonic/gin"
 )
 
 type deleteServ interface {
-	DeleteUser(ctx context.Context, userId uint) error
+	DeleteUser(ctx context.Context, req models.DeleteUserRequest) (*models.DeleteUserResponse, error)
 }
 
 // Delete is a Gin handler function that handles the deletion of a user.
 // It retrieves the user ID from the context, validates it, and calls the service to delete the user.
+// The user is soft-deleted and anonymized. The body is optional, it names the target of the remaining funds.
+// If the data is invalid or the sweep target user is not found, it returns a 400 Bad Request.
 // If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the user has enabled the two-factor authentication and the code in the X-2FA-Code header is missing or wrong,
+// it returns a 403 Forbidden, the same if the funds are swept and the email of the user is not verified.
 // If the user is not found, it returns a 404 Not Found.
+// If the accounts still have funds and no sweep target is named, it returns a 409 Conflict.
+// If too many wrong two-factor codes were sent in a row, it returns a 429 Too Many Requests.
 // If the request times out, it returns a 504 Gateway Timeout.
-// On successful deletion, it returns a 200 OK response.
+// On successful deletion, it returns a 200 OK response with the swept balances.
 //
 // @Summary Delete
-// @Description user delete
+// @Description Delete the user, the remaining funds are withdrawn or moved to another user, the history is kept anonymized
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {object} models.HandlerResponse
+// @Param X-2FA-Code header string false "Two-factor code or recovery code, required if 2FA is enabled"
+// @Param body body models.DeleteUserRequest false "Target of the remaining funds"
+// @Success 200 {object} models.DeleteUserResponse
+// @Failure 400 {object} models.HandlerResponse
 // @Failure 401 {object} models.HandlerResponse
+// @Failure 403 {object} models.HandlerResponse
 // @Failure 404 {object} models.HandlerResponse
+// @Failure 409 {object} models.HandlerResponse
+// @Failure 429 {object} models.HandlerResponse
 // @Failure 504 {object} models.HandlerResponse
 // @Failure 500 {object} models.HandlerResponse
 // @Router /delete [delete]
@@ -43,6 +56,15 @@ func Delete(log *slog.Logger, serv deleteServ) gin.HandlerFunc {
 		)
 		log.Debug("user removal request")
 
+		var req models.DeleteUserRequest
+		if ctx.Request.ContentLength != 0 {
+			if err := ctx.ShouldBindJSON(&req); err != nil {
+				log.Warn("fail BindJSON", "error", err)
+				ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+				return
+			}
+		}
+
 		// get user id from context
 		userID, exists := ctx.Get("userID")
 		if !exists {
@@ -64,8 +86,61 @@ func Delete(log *slog.Logger, serv deleteServ) gin.HandlerFunc {
 			return
 		}
 
-		if err := serv.DeleteUser(ctx.Request.Context(), userIdUint); err != nil {
+		req.UserID = userIdUint
+		req.TwoFactorCode = ctx.GetHeader("X-2FA-Code")
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.DeleteUser(ctx.Request.Context(), req)
+		if err != nil {
 			switch err {
+			case services.ErrTwoFactorRequired:
+				log.Warn("failed to delete user", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "pass the two-factor code in the X-2FA-Code header",
+				})
+				return
+			case services.ErrInvalidTwoFactor:
+				log.Warn("failed to delete user", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "two-factor code is invalid or already used",
+				})
+				return
+			case services.ErrTwoFactorLocked:
+				log.Warn("failed to delete user", "error", err)
+				ctx.JSON(429, models.HandlerResponse{
+					Status:  http.StatusTooManyRequests,
+					Error:   err.Error(),
+					Message: "too many invalid two-factor codes, try again later",
+				})
+				return
+			case servWallet.ErrEmailNotVerified:
+				log.Warn("failed to delete user", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "verify the email before the funds are swept",
+				})
+				return
+			case services.ErrSweepTarget:
+				log.Warn("failed to delete user", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "sweep target user is not found",
+				})
+				return
+			case services.ErrFundsRemain:
+				log.Warn("failed to delete user", "error", err)
+				ctx.JSON(409, models.HandlerResponse{
+					Status:  http.StatusConflict,
+					Error:   err.Error(),
+					Message: "withdraw the funds or name a sweep target in sweep_to",
+				})
+				return
 			case services.ErrUserNotFound:
 				log.Error("request to delete a non-existent user was received", "error", err, "user id", userIdUint)
 				ctx.JSON(404, models.HandlerResponse{
@@ -94,9 +169,6 @@ func Delete(log *slog.Logger, serv deleteServ) gin.HandlerFunc {
 		}
 
 		log.Info("user successfully deleted")
-		ctx.JSON(200, models.HandlerResponse{
-			Status:  http.StatusOK,
-			Message: "user successfully deleted",
-		})
+		ctx.JSON(200, result)
 	}
 }
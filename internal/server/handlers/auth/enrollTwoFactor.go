@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type enrollTwoFactorServ interface {
+	EnrollTwoFactor(ctx context.Context, userId uint) (*models.TwoFactorEnrollResponse, error)
+}
+
+// EnrollTwoFactor is a Gin handler function that starts the two-factor enrollment of the authenticated user.
+// It returns a new secret and its provisioning URI, the two-factor authentication is enabled after the first code
+// is confirmed at /2fa/confirm.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the user is not found, it returns a 404 Not Found.
+// If the two-factor authentication is already enabled, it returns a 409 Conflict.
+// If the request times out, it returns a 504 Gateway Timeout.
+// On success, it returns a 200 OK response with the secret.
+//
+// @Summary Start the two-factor enrollment
+// @Description Generate a TOTP secret, add it to the authenticator app by the provisioning URI (as a QR code)
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.TwoFactorEnrollResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 409 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /2fa/enroll [post]
+func EnrollTwoFactor(log *slog.Logger, serv enrollTwoFactorServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler EnrollTwoFactor: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		log.Debug("user id was successfully obtained from the context", "userID", userIdUint)
+
+		result, err := serv.EnrollTwoFactor(ctx.Request.Context(), userIdUint)
+		if err != nil {
+			switch err {
+			case services.ErrUserNotFound:
+				log.Warn("failed to start the enrollment", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "user not found",
+				})
+				return
+			case services.ErrTwoFactorEnabled:
+				log.Warn("failed to start the enrollment", "error", err)
+				ctx.JSON(409, models.HandlerResponse{
+					Status:  http.StatusConflict,
+					Error:   err.Error(),
+					Message: "two-factor authentication is already enabled",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to start the enrollment", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to start the enrollment", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to start the enrollment",
+				})
+				return
+			}
+		}
+
+		log.Info("two-factor enrollment started")
+		ctx.JSON(200, result)
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type exportMeServ interface {
+	ExportUser(ctx context.Context, userId uint) (*models.UserExport, error)
+}
+
+// ExportMe is a Gin handler function that returns all data of the authenticated user as a JSON file.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the user is not found, it returns a 404 Not Found.
+// If the request times out, it returns a 504 Gateway Timeout.
+// On success, it returns a 200 OK response with the profile, the balances, the history of the operations,
+// the orders, the schedules, the webhooks and the API keys, the response is downloaded as a file.
+//
+// @Summary Export the data
+// @Description Download all data of the user: the profile, the balances, the operations, the orders, the schedules, the webhooks and the API keys
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.UserExport
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /me/export [get]
+func ExportMe(log *slog.Logger, serv exportMeServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler ExportMe: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		log.Debug("user id was successfully obtained from the context", "userID", userIdUint)
+
+		result, err := serv.ExportUser(ctx.Request.Context(), userIdUint)
+		if err != nil {
+			switch err {
+			case services.ErrUserNotFound:
+				log.Warn("failed to export the user data", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "user not found",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to export the user data", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to export the user data", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to export the user data",
+				})
+				return
+			}
+		}
+
+		log.Info("user data successfully exported")
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%d.json"`, userIdUint))
+		ctx.JSON(200, result)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type forgotPasswordServ interface {
+	ForgotPassword(ctx context.Context, req models.ForgotPasswordRequest) error
+}
+
+// ForgotPassword is a Gin handler function that sends a password reset token to the email of a user.
+// It binds the email from the JSON body and calls the service, the response is the same whether the email
+// is registered or not, so that the endpoint can not be used to find the registered emails.
+// If the data is invalid, it returns a 400 Bad Request.
+// If the request times out, it returns a 504 Gateway Timeout.
+// On success, it returns a 200 OK response.
+//
+// @Summary Forgotten password
+// @Description Send a password reset token to the email, if a user with this email exists
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.ForgotPasswordRequest true "Email of the user"
+// @Success 200 {object} models.HandlerResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 429 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /password/forgot [post]
+func ForgotPassword(log *slog.Logger, serv forgotPasswordServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler ForgotPassword: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.ForgotPasswordRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		err := serv.ForgotPassword(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case context.DeadlineExceeded:
+				log.Error("failed to send the reset token", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to send the reset token", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to send the reset token",
+				})
+				return
+			}
+		}
+
+		log.Info("password reset requested")
+		ctx.JSON(200, models.HandlerResponse{Status: http.StatusOK, Message: "if the email is registered, a reset token has been sent to it"})
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type jwksServ interface {
+	JWKS() models.JWKS
+}
+
+// JWKS is a Gin handler function that publishes the public keys of the access tokens as a JSON Web Key Set.
+// Other services verify the tokens with the key named by the kid header of the token.
+// The set is cached by the clients for a few minutes, a new key must be published before it becomes active.
+// It always returns a 200 OK response, the set is empty if the tokens are signed with HS256.
+// The route is outside the API base path, so it is not in the Swagger documentation.
+func JWKS(log *slog.Logger, serv jwksServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler JWKS: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		jwks := serv.JWKS()
+
+		log.Info("public keys successfully sent", "count", len(jwks.Keys))
+		ctx.Header("Cache-Control", "public, max-age=300")
+		ctx.JSON(200, jwks)
+	}
+}
@@ -20,10 +20,11 @@ type loginServ interface {
 // It calls the service to authenticate the user and returns the appropriate response.
 // If the login data is invalid or the user is not found, it returns a 400 Bad Request or 404 Not Found, respectively.
 // If the request times out, it returns a 504 Gateway Timeout.
-// On successful login, it returns a 200 OK response with the login result.
+// On successful login, it returns a 200 OK response with the login result, if the user has enabled the two-factor
+// authentication, the result has a challenge instead of the token, it is exchanged for the token at /login/2fa.
 //
 // @Summary Login
-// @Description user login
+// @Description user login, with the two-factor authentication the response has two_factor_required and a challenge for /login/2fa
 // @Tags auth
 // @Accept json
 // @Produce json
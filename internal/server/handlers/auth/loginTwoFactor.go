@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type loginTwoFactorServ interface {
+	LoginTwoFactor(ctx context.Context, req models.LoginTwoFactorRequest) (*models.LoginResponse, error)
+}
+
+// LoginTwoFactor is a Gin handler function that handles the second step of the login with the two-factor authentication.
+// It binds the challenge from the first step and the code (a TOTP code or a recovery code) and calls the service.
+// If the data is invalid, or the challenge is invalid or expired, it returns a 400 Bad Request.
+// If the code is wrong or already used, it returns a 403 Forbidden.
+// If too many wrong two-factor codes were sent in a row, it returns a 429 Too Many Requests.
+// If the request times out, it returns a 504 Gateway Timeout.
+// On success, it returns a 200 OK response with the token.
+//
+// @Summary Login with the two-factor code
+// @Description Exchange the challenge from /login and the code from the authenticator app (or a recovery code) for the token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.LoginTwoFactorRequest true "Challenge and code"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 403 {object} models.HandlerResponse
+// @Failure 429 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /login/2fa [post]
+func LoginTwoFactor(log *slog.Logger, serv loginTwoFactorServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler LoginTwoFactor: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.LoginTwoFactorRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		result, err := serv.LoginTwoFactor(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case services.ErrInvalidChallenge:
+				log.Warn("failed to log in", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "challenge is invalid or expired, log in again",
+				})
+				return
+			case services.ErrInvalidTwoFactor:
+				log.Warn("failed to log in", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "two-factor code is invalid or already used",
+				})
+				return
+			case services.ErrTwoFactorLocked:
+				log.Warn("failed to log in", "error", err)
+				ctx.JSON(429, models.HandlerResponse{
+					Status:  http.StatusTooManyRequests,
+					Error:   err.Error(),
+					Message: "too many invalid two-factor codes, try again later",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to log in", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to log in", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to log in",
+				})
+				return
+			}
+		}
+
+		log.Info("authorization successful")
+		ctx.JSON(200, result)
+	}
+}
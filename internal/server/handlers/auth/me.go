@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type meServ interface {
+	Profile(ctx context.Context, userId uint) (*models.ProfileResponse, error)
+}
+
+// Me is a Gin handler function that returns the profile of the authenticated user.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the user is not found, it returns a 404 Not Found.
+// If the request times out, it returns a 504 Gateway Timeout.
+// On success, it returns a 200 OK response with the profile.
+//
+// @Summary Profile
+// @Description Get the profile of the user
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.ProfileResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /me [get]
+func Me(log *slog.Logger, serv meServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler Me: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		log.Debug("user id was successfully obtained from the context", "userID", userIdUint)
+
+		result, err := serv.Profile(ctx.Request.Context(), userIdUint)
+		if err != nil {
+			switch err {
+			case services.ErrUserNotFound:
+				log.Warn("failed to get the profile", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "user not found",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to get the profile", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to get the profile", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to get the profile",
+				})
+				return
+			}
+		}
+
+		log.Info("profile successfully sent")
+		ctx.JSON(200, result)
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type resendVerificationServ interface {
+	ResendVerification(ctx context.Context, req models.ResendVerificationRequest) error
+}
+
+// ResendVerification is a Gin handler function that sends a new verification token to the email of the authenticated user.
+// The tokens sent before stop working.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the user is not found, it returns a 404 Not Found.
+// If the email is already verified, it returns a 409 Conflict.
+// If the previous token was sent recently, it returns a 429 Too Many Requests.
+// If the request times out, it returns a 504 Gateway Timeout.
+// On success, it returns a 200 OK response.
+//
+// @Summary Resend the verification email
+// @Description Send a new verification token to the email of the user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 409 {object} models.HandlerResponse
+// @Failure 429 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /verify-email/resend [post]
+func ResendVerification(log *slog.Logger, serv resendVerificationServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler ResendVerification: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.ResendVerificationRequest
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		err := serv.ResendVerification(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case services.ErrUserNotFound:
+				log.Warn("failed to resend the verification", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "user not found",
+				})
+				return
+			case services.ErrAlreadyVerified:
+				log.Warn("failed to resend the verification", "error", err)
+				ctx.JSON(409, models.HandlerResponse{
+					Status:  http.StatusConflict,
+					Error:   err.Error(),
+					Message: "email is already verified",
+				})
+				return
+			case services.ErrTooManyRequests:
+				log.Warn("failed to resend the verification", "error", err)
+				ctx.JSON(429, models.HandlerResponse{
+					Status:  http.StatusTooManyRequests,
+					Error:   err.Error(),
+					Message: "verification email was sent recently, try again later",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to resend the verification", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to resend the verification", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to resend the verification",
+				})
+				return
+			}
+		}
+
+		log.Info("verification email successfully sent")
+		ctx.JSON(200, models.HandlerResponse{Status: http.StatusOK, Message: "verification email sent"})
+	}
+}
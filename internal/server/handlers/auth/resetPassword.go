@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type resetPasswordServ interface {
+	ResetPassword(ctx context.Context, req models.ResetPasswordRequest) error
+}
+
+// ResetPassword is a Gin handler function that sets a new password of a user with the token from the reset email.
+// It binds the token and the new password from the JSON body and calls the service, the token can be used only once.
+// After the reset all access tokens of the user are revoked, the user has to log in again.
+// If the data is invalid, or the token is unknown, expired or already used, it returns a 400 Bad Request.
+// If the request times out, it returns a 504 Gateway Timeout.
+// On success, it returns a 200 OK response.
+//
+// @Summary Reset the password
+// @Description Set a new password with the token sent to the email, all sessions of the user are ended
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.ResetPasswordRequest true "Reset token and the new password"
+// @Success 200 {object} models.HandlerResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 429 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /password/reset [post]
+func ResetPassword(log *slog.Logger, serv resetPasswordServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler ResetPassword: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.ResetPasswordRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		err := serv.ResetPassword(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case services.ErrInvalidToken:
+				log.Warn("failed to reset the password", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "token is invalid, expired or already used",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to reset the password", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to reset the password", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to reset the password",
+				})
+				return
+			}
+		}
+
+		log.Info("password successfully reset")
+		ctx.JSON(200, models.HandlerResponse{Status: http.StatusOK, Message: "password successfully reset, log in again"})
+	}
+}
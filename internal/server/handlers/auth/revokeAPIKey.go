@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type revokeAPIKeyServ interface {
+	RevokeAPIKey(ctx context.Context, req models.APIKeyRequest) error
+}
+
+// RevokeAPIKey is a Gin handler function that revokes an API key of the authenticated user.
+// It binds the key ID from the path and calls the service to revoke the key.
+// If the key ID is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the key is not found or already revoked, it returns a 404 Not Found.
+// On success, it returns a 200 OK response.
+//
+// @Summary Revoke an API key
+// @Description Revoke an API key of the user, the requests with it are rejected at once
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} models.HandlerResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /api-keys/{id} [delete]
+func RevokeAPIKey(log *slog.Logger, serv revokeAPIKeyServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler RevokeAPIKey: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.APIKeyRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			log.Warn("fail BindUri", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid API key id"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		err := serv.RevokeAPIKey(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case services.ErrAPIKeyNotFound:
+				log.Warn("failed to revoke the API key", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "API key does not exist or is already revoked",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to revoke the API key", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to revoke the API key", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to revoke the API key",
+				})
+				return
+			}
+		}
+
+		log.Info("API key successfully revoked")
+		ctx.JSON(200, models.HandlerResponse{Status: http.StatusOK, Message: "API key successfully revoked"})
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type verifyEmailServ interface {
+	VerifyEmail(ctx context.Context, req models.VerifyEmailRequest) error
+}
+
+// VerifyEmail is a Gin handler function that confirms the email of a user with the token from the verification email.
+// It binds the token from the JSON body and calls the service to verify the email, the token can be used only once.
+// If the data is invalid, or the token is unknown, expired or already used, it returns a 400 Bad Request.
+// If the email has been taken by another user in the meantime, it returns a 409 Conflict.
+// If the request times out, it returns a 504 Gateway Timeout.
+// On success, it returns a 200 OK response.
+//
+// @Summary Verify the email
+// @Description Confirm the email of the user with the token sent to it
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} models.HandlerResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 409 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /verify-email [post]
+func VerifyEmail(log *slog.Logger, serv verifyEmailServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler VerifyEmail: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.VerifyEmailRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		err := serv.VerifyEmail(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case services.ErrInvalidToken:
+				log.Warn("failed to verify the email", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "token is invalid, expired or already used",
+				})
+				return
+			case services.ErrEmailAlreadyExists:
+				log.Warn("failed to verify the email", "error", err)
+				ctx.JSON(409, models.HandlerResponse{
+					Status:  http.StatusConflict,
+					Error:   err.Error(),
+					Message: "email is already used by another user",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to verify the email", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to verify the email", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to verify the email",
+				})
+				return
+			}
+		}
+
+		log.Info("email successfully verified")
+		ctx.JSON(200, models.HandlerResponse{Status: http.StatusOK, Message: "email successfully verified"})
+	}
+}
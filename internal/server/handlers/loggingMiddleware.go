@@ -1,26 +1,32 @@
 package handlers
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 
+	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
 	"github.com/EvansTrein/RESTful_exchangerServer/models"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 )
 
 type checkToken interface {
-	ParseToken(tokenString string) (*jwt.Token, error)
-	TokenPayloadExtraction(token *jwt.Token) (*models.PayloadToken, error)
+	ParseToken(tokenString string) (*models.PayloadToken, error)
+	CheckSession(ctx context.Context, payload *models.PayloadToken) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.PayloadToken, error)
 }
 
 // LoggingMiddleware is a Gin middleware function that logs incoming requests and validates JWT tokens.
 // It checks for the presence and format of the "Authorization" header.
 // If the header is missing or invalid, it returns a 401 Unauthorized response.
-// It parses and validates the JWT token, extracts the token payload, and sets the user ID in the context.
+// It parses and validates the JWT token and its claims, an invalid or expired token gets a 401 Unauthorized,
+// checks that the token has not been revoked by a password reset (401 Unauthorized), and sets the user ID in the context.
 // If any step fails, it logs the error and returns an appropriate HTTP response.
-func LoggingMiddleware(log *slog.Logger, ch checkToken) gin.HandlerFunc {
+// Without the "Authorization" header, the API key of the "X-API-Key" header is accepted instead, but only
+// on the routes that pass the scopes, the key must have all of them (see apiKeyAuthorization).
+func LoggingMiddleware(log *slog.Logger, ch checkToken, scopes ...string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		op := "LoggingMiddleware"
 		log = log.With(
@@ -31,6 +37,11 @@ func LoggingMiddleware(log *slog.Logger, ch checkToken) gin.HandlerFunc {
 		log.Debug("request for a protected resource is received")
 
 		authHeader := ctx.GetHeader("Authorization")
+		if authHeader == "" && ctx.GetHeader("X-API-Key") != "" {
+			apiKeyAuthorization(ctx, log, ch, ctx.GetHeader("X-API-Key"), scopes)
+			return
+		}
+
 		if authHeader == "" {
 			log.Warn("authorization header is not passed")
 			ctx.JSON(401, models.HandlerResponse{
@@ -60,9 +71,49 @@ func LoggingMiddleware(log *slog.Logger, ch checkToken) gin.HandlerFunc {
 
 		log.Debug("token successfully passed the prefix check", "token string", tokenStr)
 
-		token, err := ch.ParseToken(tokenStr)
+		tokenPayload, err := ch.ParseToken(tokenStr)
 		if err != nil {
-			log.Error("failed to check the token")
+			switch err {
+			case services.ErrTokenExpired:
+				log.Warn("token expired")
+				ctx.JSON(401, models.HandlerResponse{
+					Status:  http.StatusUnauthorized,
+					Error:   err.Error(),
+					Message: "log in again",
+				})
+			case services.ErrTokenMalformed, services.ErrTokenSignature, services.ErrTokenAlgorithm, services.ErrTokenNotYetValid, services.ErrTokenClaims:
+				log.Warn("invalid token", "error", err)
+				ctx.JSON(401, models.HandlerResponse{
+					Status:  http.StatusUnauthorized,
+					Error:   err.Error(),
+					Message: "unauthorized user",
+				})
+			default:
+				log.Error("failed to check the token", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to check the token",
+				})
+			}
+			ctx.Abort()
+			return
+		}
+
+		log.Debug("jwt token has been successfully validated, token is valid")
+
+		if err := ch.CheckSession(ctx.Request.Context(), tokenPayload); err != nil {
+			if err == services.ErrSessionRevoked {
+				log.Warn("token is revoked", "userID", tokenPayload.UserID)
+				ctx.JSON(401, models.HandlerResponse{
+					Status:  http.StatusUnauthorized,
+					Error:   err.Error(),
+					Message: "log in again",
+				})
+				ctx.Abort()
+				return
+			}
+			log.Error("failed to check the session", "error", err)
 			ctx.JSON(500, models.HandlerResponse{
 				Status:  http.StatusInternalServerError,
 				Error:   err.Error(),
@@ -72,37 +123,70 @@ func LoggingMiddleware(log *slog.Logger, ch checkToken) gin.HandlerFunc {
 			return
 		}
 
-		log.Debug("jwt token was successfully retrieved from the passed string", "token", token)
+		log.Debug("token payload successfully received, authorization passed successfully", "tokenPayload", tokenPayload)
+
+		// save the user id from the token in the context, we will need it later to form a request to other resources 
+		ctx.Set("userID", tokenPayload.UserID)
+		ctx.Set("role", tokenPayload.Role)
+		ctx.Next()
+	}
+}
 
-		if !token.Valid {
-			log.Warn("token expired")
+// apiKeyAuthorization authorizes the request with the API key.
+// If the route does not accept the API keys or the key lacks one of the scopes, it returns a 403 Forbidden.
+// If the key is unknown, revoked or expired, it returns a 401 Unauthorized.
+// On success, the user ID, the role and the scopes of the key are set in the context.
+func apiKeyAuthorization(ctx *gin.Context, log *slog.Logger, ch checkToken, key string, scopes []string) {
+	if len(scopes) == 0 {
+		log.Warn("API key passed to a route without scopes")
+		ctx.JSON(403, models.HandlerResponse{
+			Status:  http.StatusForbidden,
+			Error:   "API keys are not accepted by this route",
+			Message: "log in with the password",
+		})
+		ctx.Abort()
+		return
+	}
+
+	payload, err := ch.AuthenticateAPIKey(ctx.Request.Context(), key)
+	if err != nil {
+		if err == services.ErrInvalidAPIKey {
+			log.Warn("invalid API key", "error", err)
 			ctx.JSON(401, models.HandlerResponse{
 				Status:  http.StatusUnauthorized,
-				Error:   "token expired",
-				Message: "log in again",
+				Error:   err.Error(),
+				Message: "unauthorized user",
 			})
 			ctx.Abort()
 			return
 		}
+		log.Error("failed to check the API key", "error", err)
+		ctx.JSON(500, models.HandlerResponse{
+			Status:  http.StatusInternalServerError,
+			Error:   err.Error(),
+			Message: "failed to check the API key",
+		})
+		ctx.Abort()
+		return
+	}
 
-		log.Debug("jwt token has been successfully validated, token is valid")
-
-		tokenPayload, err := ch.TokenPayloadExtraction(token)
-		if err != nil {
-			log.Warn("failed to parse token claims")
-			ctx.JSON(500, models.HandlerResponse{
-				Status:  http.StatusInternalServerError,
-				Error:   err.Error(),
-				Message: "failed to parse token claims",
+	for _, scope := range scopes {
+		if !slices.Contains(payload.Scopes, scope) {
+			log.Warn("API key does not have the scope", "userID", payload.UserID, "scope", scope)
+			ctx.JSON(403, models.HandlerResponse{
+				Status:  http.StatusForbidden,
+				Error:   "API key does not have the scope " + scope,
+				Message: "insufficient scope",
 			})
 			ctx.Abort()
 			return
 		}
+	}
 
-		log.Debug("token payload successfully received, authorization passed successfully", "tokenPayload", tokenPayload)
+	log.Debug("API key authorization passed successfully", "userID", payload.UserID, "scopes", payload.Scopes)
 
-		// save the user id from the token in the context, we will need it later to form a request to other resources 
-		ctx.Set("userID", tokenPayload.UserID)
-		ctx.Next()
-	}
+	ctx.Set("userID", payload.UserID)
+	ctx.Set("role", payload.Role)
+	ctx.Set("scopes", payload.Scopes)
+	ctx.Next()
 }
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	servOrders "github.com/EvansTrein/RESTful_exchangerServer/internal/services/orders"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type cancelOrderServ interface {
+	CancelOrder(ctx context.Context, req models.OrderRequest) (*models.OrderResponse, error)
+}
+
+// CancelOrder is a Gin handler function that cancels an open limit order of the authenticated user.
+// It binds the order ID from the path and calls the service to cancel the order, the reserved amount is returned to the account.
+// If the order ID is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the order is not found, it returns a 404 Not Found.
+// If the order is not open anymore, it returns a 409 Conflict.
+// On success, it returns a 200 OK response with the cancelled order.
+//
+// @Summary Cancel a limit order
+// @Description Cancel an open limit order of the authenticated user and release the reserved amount
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Order ID"
+// @Success 200 {object} models.OrderResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 409 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /orders/{id} [delete]
+func CancelOrder(log *slog.Logger, serv cancelOrderServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler CancelOrder: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.OrderRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			log.Warn("fail BindUri", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid order id"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.CancelOrder(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case servOrders.ErrOrderNotFound:
+				log.Warn("failed to cancel the order", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "order does not exist",
+				})
+				return
+			case servOrders.ErrOrderNotOpen:
+				log.Warn("failed to cancel the order", "error", err)
+				ctx.JSON(409, models.HandlerResponse{
+					Status:  http.StatusConflict,
+					Error:   err.Error(),
+					Message: "order is already filled, cancelled or expired",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to send data", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to cancel the order", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to cancel the order",
+				})
+				return
+			}
+		}
+
+		log.Info("order successfully cancelled")
+		ctx.JSON(200, result)
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	servOrders "github.com/EvansTrein/RESTful_exchangerServer/internal/services/orders"
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type createOrderServ interface {
+	CreateOrder(ctx context.Context, req models.CreateOrderRequest) (*models.OrderResponse, error)
+}
+
+// CreateOrder is a Gin handler function that creates a limit order for the authenticated user.
+// It binds the incoming JSON request to a struct, validates the data, and calls the service to create the order.
+// If the data is invalid, the currencies are the same or the expiry time has passed, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the account or currency is not found, it returns a 404 Not Found.
+// If there are insufficient funds for the reservation, it returns a 402 Payment Required.
+// If the exchange limit would be exceeded, it returns a 403 Forbidden with the remaining allowance,
+// a 403 Forbidden is also returned if the email of the user is not verified.
+// On success, it returns a 201 Created response with the order.
+//
+// @Summary Create a limit order
+// @Description Create an order that exchanges the amount when the applied rate (after the fee) reaches the target rate. The amount is reserved until the order is filled, cancelled or expired
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param body body models.CreateOrderRequest true "Order request"
+// @Success 201 {object} models.OrderResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 402 {object} models.HandlerResponse
+// @Failure 403 {object} models.LimitExceededResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /orders [post]
+func CreateOrder(log *slog.Logger, serv createOrderServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler CreateOrder: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.CreateOrderRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		log.Debug("request data has been successfully validated", "data", req)
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.CreateOrder(ctx.Request.Context(), req)
+		if err != nil {
+			var limitErr *servWallet.LimitExceededError
+			if errors.As(err, &limitErr) {
+				log.Warn("failed to create the order", "error", err)
+				ctx.JSON(403, models.LimitExceededResponse{
+					Status:    http.StatusForbidden,
+					Error:     limitErr.Error(),
+					Message:   "transaction limit exceeded",
+					Operation: limitErr.Operation,
+					Currency:  limitErr.Currency,
+					Period:    limitErr.Period,
+					Limit:     limitErr.Limit,
+					Remaining: limitErr.Remaining,
+				})
+				return
+			}
+
+			switch err {
+			case servOrders.ErrSameCurrency, servOrders.ErrInvalidExpiry:
+				log.Warn("failed to create the order", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "invalid data",
+				})
+				return
+			case servWallet.ErrEmailNotVerified:
+				log.Warn("failed to create the order", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "verify the email to continue",
+				})
+				return
+			case servWallet.ErrInsufficientFunds:
+				log.Warn("failed to create the order", "error", err)
+				ctx.JSON(402, models.HandlerResponse{
+					Status:  http.StatusPaymentRequired,
+					Error:   err.Error(),
+					Message: "insufficient funds",
+				})
+				return
+			case servWallet.ErrAccountNotFound:
+				log.Warn("failed to create the order", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "no account in this currency",
+				})
+				return
+			case servWallet.ErrCurrencyNotFound:
+				log.Warn("failed to create the order", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "currency is not supported",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to send data", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to create the order", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to create the order",
+				})
+				return
+			}
+		}
+
+		log.Info("order successfully created")
+		ctx.JSON(201, result)
+	}
+}
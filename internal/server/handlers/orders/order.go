@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	servOrders "github.com/EvansTrein/RESTful_exchangerServer/internal/services/orders"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type orderServ interface {
+	UserOrder(ctx context.Context, req models.OrderRequest) (*models.OrderResponse, error)
+}
+
+// Order is a Gin handler function that returns a limit order of the authenticated user together with its audit trail.
+// It binds the order ID from the path and calls the service to fetch the order.
+// If the order ID is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the order is not found, it returns a 404 Not Found.
+// On success, it returns a 200 OK response with the order and its events.
+//
+// @Summary Get a limit order
+// @Description Get a limit order of the authenticated user with the audit trail of its events
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Order ID"
+// @Success 200 {object} models.OrderResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /orders/{id} [get]
+func Order(log *slog.Logger, serv orderServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler Order: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.OrderRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			log.Warn("fail BindUri", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid order id"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.UserOrder(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case servOrders.ErrOrderNotFound:
+				log.Warn("failed to send data", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "order does not exist",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to send data", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to send data", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to send data",
+				})
+				return
+			}
+		}
+
+		log.Info("data successfully sent")
+		ctx.JSON(200, result)
+	}
+}
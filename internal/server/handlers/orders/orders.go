@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type ordersServ interface {
+	UserOrders(ctx context.Context, req models.OrdersRequest) (*models.OrdersResponse, error)
+}
+
+// Orders is a Gin handler function that returns the limit orders of the authenticated user.
+// It binds the optional status filter from the query and calls the service to fetch the orders.
+// If the status is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// On success, it returns a 200 OK response with the orders.
+//
+// @Summary Get limit orders
+// @Description Get the limit orders of the authenticated user, newest first
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param status query string false "Order status" Enums(open, filled, cancelled, expired)
+// @Success 200 {object} models.OrdersResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /orders [get]
+func Orders(log *slog.Logger, serv ordersServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler Orders: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.OrdersRequest
+		if err := ctx.ShouldBindQuery(&req); err != nil {
+			log.Warn("fail BindQuery", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.UserOrders(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case context.DeadlineExceeded:
+				log.Error("failed to send data", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to send data", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to send data",
+				})
+				return
+			}
+		}
+
+		log.Info("data successfully sent")
+		ctx.JSON(200, result)
+	}
+}
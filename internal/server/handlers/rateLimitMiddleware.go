@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// maxKeyBody is the largest body read to find the target account, the larger bodies are limited by the client IP only
+const maxKeyBody = 64 << 10
+
+type rateLimiter interface {
+	Allow(key string, limit int, window time.Duration) (bool, time.Duration, error)
+}
+
+// AccountKey returns the target account of the request (an email, a token, a user), the requests are limited
+// per account in addition to the client IP. An empty key is not counted.
+type AccountKey func(ctx *gin.Context) string
+
+// BodyField returns the AccountKey of the string field of the JSON body, the body is read and restored for the handler.
+func BodyField(name string) AccountKey {
+	return func(ctx *gin.Context) string {
+		if ctx.Request.Body == nil {
+			return ""
+		}
+
+		data, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxKeyBody+1))
+		ctx.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), ctx.Request.Body))
+		if err != nil || len(data) > maxKeyBody {
+			return ""
+		}
+
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			return ""
+		}
+
+		value, _ := body[name].(string)
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+}
+
+// UserAccount is the AccountKey of the authenticated user, it must run after LoggingMiddleware.
+func UserAccount(ctx *gin.Context) string {
+	userID, ok := ctx.Get("userID")
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprint(userID)
+}
+
+// RateLimitMiddleware is a Gin middleware function that limits the number of requests to the route.
+// Every client IP can make limit requests per window, and with the account keys every target account can receive
+// limit requests per window from all IPs together, so changing the IP does not give a new allowance.
+// The next requests get a 429 Too Many Requests with the Retry-After header.
+// The client IP is taken from the forwarding headers only behind the trusted proxies of the router.
+// If the limiter is not available, the requests are counted in the memory of the process until it is back,
+// so an outage neither lifts the limits nor locks the users out. The counts of the fallback are per instance.
+func RateLimitMiddleware(log *slog.Logger, limiter rateLimiter, limit int, window time.Duration, accounts ...AccountKey) gin.HandlerFunc {
+	fallback := ratelimit.NewLocal()
+
+	return func(ctx *gin.Context) {
+		op := "RateLimitMiddleware"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+
+		keys := []string{ctx.FullPath() + ":" + ctx.ClientIP()}
+		for _, account := range accounts {
+			if key := account(ctx); key != "" {
+				// the emails and the tokens are not stored in the limiter as they are
+				hash := sha256.Sum256([]byte(key))
+				keys = append(keys, ctx.FullPath()+":account:"+hex.EncodeToString(hash[:]))
+			}
+		}
+
+		var denied bool
+		var retryAfter time.Duration
+		for _, key := range keys {
+			allowed, wait, err := limiter.Allow(key, limit, window)
+			if err != nil {
+				log.Error("failed to check the rate limit, the request is counted in the process", "error", err)
+				allowed, wait, _ = fallback.Allow(key, limit, window)
+			}
+
+			if !allowed {
+				denied = true
+				retryAfter = max(retryAfter, wait)
+			}
+		}
+
+		if denied {
+			log.Warn("rate limit exceeded", "client", ctx.ClientIP())
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			ctx.JSON(429, models.HandlerResponse{
+				Status:  http.StatusTooManyRequests,
+				Error:   "rate limit exceeded",
+				Message: "too many requests, try again later",
+			})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
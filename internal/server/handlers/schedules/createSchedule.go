@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	servScheduler "github.com/EvansTrein/RESTful_exchangerServer/internal/services/scheduler"
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type createScheduleServ interface {
+	CreateSchedule(ctx context.Context, req models.CreateScheduleRequest) (*models.ScheduleResponse, error)
+}
+
+// CreateSchedule is a Gin handler function that creates a scheduled deposit or exchange for the authenticated user.
+// It binds the incoming JSON request to a struct, validates the data, and calls the service to create the schedule.
+// If the data, the cron expression, the interval or the period is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the currency is not supported, it returns a 404 Not Found.
+// On success, it returns a 201 Created response with the schedule and its first run.
+//
+// @Summary Create a schedule
+// @Description Create a deposit or an exchange that runs by a cron expression (UTC, e.g. "0 9 * * MON" or "@daily") or at a fixed interval (e.g. "168h") between the start and the optional end date. Exactly one of cron and interval must be specified
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param body body models.CreateScheduleRequest true "Schedule request"
+// @Success 201 {object} models.ScheduleResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /schedules [post]
+func CreateSchedule(log *slog.Logger, serv createScheduleServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler CreateSchedule: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.CreateScheduleRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		log.Debug("request data has been successfully validated", "data", req)
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.CreateSchedule(ctx.Request.Context(), req)
+		if errors.Is(err, servScheduler.ErrInvalidCron) {
+			log.Warn("failed to create the schedule", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid cron expression"})
+			return
+		}
+		if err != nil {
+			switch err {
+			case servScheduler.ErrScheduleTiming, servScheduler.ErrInvalidInterval, servScheduler.ErrMissingToCurrency,
+				servScheduler.ErrSameCurrency, servScheduler.ErrInvalidPeriod, servScheduler.ErrNoOccurrences:
+				log.Warn("failed to create the schedule", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "invalid data",
+				})
+				return
+			case servWallet.ErrCurrencyNotFound:
+				log.Warn("failed to create the schedule", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "currency is not supported",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to create the schedule", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to create the schedule", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to create the schedule",
+				})
+				return
+			}
+		}
+
+		log.Info("schedule successfully created")
+		ctx.JSON(201, result)
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	servScheduler "github.com/EvansTrein/RESTful_exchangerServer/internal/services/scheduler"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type deleteScheduleServ interface {
+	DeleteSchedule(ctx context.Context, req models.ScheduleRequest) error
+}
+
+// DeleteSchedule is a Gin handler function that deletes a schedule of the authenticated user together with the history of its runs.
+// It binds the schedule ID from the path and calls the service to delete the schedule.
+// If the schedule ID is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the schedule is not found, it returns a 404 Not Found.
+// On success, it returns a 200 OK response.
+//
+// @Summary Delete a schedule
+// @Description Delete a schedule of the authenticated user together with the history of its runs
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} models.HandlerResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /schedules/{id} [delete]
+func DeleteSchedule(log *slog.Logger, serv deleteScheduleServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler DeleteSchedule: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.ScheduleRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			log.Warn("fail BindUri", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid schedule id"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		err := serv.DeleteSchedule(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case servScheduler.ErrScheduleNotFound:
+				log.Warn("failed to delete the schedule", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "schedule does not exist",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to delete the schedule", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to delete the schedule", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to delete the schedule",
+				})
+				return
+			}
+		}
+
+		log.Info("schedule successfully deleted")
+		ctx.JSON(200, models.HandlerResponse{Status: http.StatusOK, Message: "schedule successfully deleted"})
+	}
+}
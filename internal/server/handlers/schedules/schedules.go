@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type schedulesServ interface {
+	Schedules(ctx context.Context, req models.SchedulesRequest) (*models.SchedulesResponse, error)
+}
+
+// Schedules is a Gin handler function that returns the schedules of the authenticated user.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// On success, it returns a 200 OK response with the schedules.
+//
+// @Summary Get schedules
+// @Description Get the scheduled deposits and exchanges of the authenticated user, newest first
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Success 200 {object} models.SchedulesResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /schedules [get]
+func Schedules(log *slog.Logger, serv schedulesServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler Schedules: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.SchedulesRequest
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.Schedules(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case context.DeadlineExceeded:
+				log.Error("failed to send data", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to send data", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to send data",
+				})
+				return
+			}
+		}
+
+		log.Info("data successfully sent")
+		ctx.JSON(200, result)
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	servScheduler "github.com/EvansTrein/RESTful_exchangerServer/internal/services/scheduler"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type updateScheduleServ interface {
+	UpdateSchedule(ctx context.Context, req models.UpdateScheduleRequest) (*models.ScheduleResponse, error)
+}
+
+// UpdateSchedule is a Gin handler function that pauses or resumes a schedule of the authenticated user,
+// or changes its amount or end date.
+// It binds the schedule ID from the path and the changes from the JSON body and calls the service to update the schedule.
+// If the data or the end date is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the schedule is not found, it returns a 404 Not Found.
+// If the schedule is already finished, it returns a 409 Conflict.
+// On success, it returns a 200 OK response with the updated schedule.
+//
+// @Summary Update a schedule
+// @Description Pause or resume a schedule of the authenticated user, change its amount or end date. A resumed schedule continues from the next occurrence
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Schedule ID"
+// @Param body body models.UpdateScheduleRequest true "Schedule changes"
+// @Success 200 {object} models.ScheduleResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 409 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /schedules/{id} [patch]
+func UpdateSchedule(log *slog.Logger, serv updateScheduleServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler UpdateSchedule: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.UpdateScheduleRequest
+		var uri models.ScheduleRequest
+		if err := ctx.ShouldBindUri(&uri); err != nil {
+			log.Warn("fail BindUri", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid schedule id"})
+			return
+		}
+
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		req.ScheduleID = uri.ScheduleID
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.UpdateSchedule(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case servScheduler.ErrScheduleNotFound:
+				log.Warn("failed to update the schedule", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "schedule does not exist",
+				})
+				return
+			case servScheduler.ErrInvalidPeriod:
+				log.Warn("failed to update the schedule", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "invalid data",
+				})
+				return
+			case servScheduler.ErrScheduleFinished:
+				log.Warn("failed to update the schedule", "error", err)
+				ctx.JSON(409, models.HandlerResponse{
+					Status:  http.StatusConflict,
+					Error:   err.Error(),
+					Message: "schedule is already finished",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to update the schedule", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to update the schedule", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to update the schedule",
+				})
+				return
+			}
+		}
+
+		log.Info("schedule successfully updated")
+		ctx.JSON(200, result)
+	}
+}
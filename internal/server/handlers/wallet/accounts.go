@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type accountsServ interface {
+	Accounts(ctx context.Context, req models.AccountsRequest) (*models.AccountsResponse, error)
+}
+
+// Accounts is a Gin handler function that returns all accounts (pockets) of the authenticated user with their balances.
+// Every currency has a default account, the named accounts follow it.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// On success, it returns a 200 OK response with the accounts.
+//
+// @Summary List the accounts
+// @Description Get all accounts of the user, every currency has a default account and may have named accounts (pockets)
+// @Tags wallet
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Success 200 {object} models.AccountsResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /accounts [get]
+func Accounts(log *slog.Logger, serv accountsServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler Accounts: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.AccountsRequest
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.Accounts(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case context.DeadlineExceeded:
+				log.Error("failed to get the accounts", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to get the accounts", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to get the accounts",
+				})
+				return
+			}
+		}
+
+		log.Info("accounts successfully sent")
+		ctx.JSON(200, result)
+	}
+}
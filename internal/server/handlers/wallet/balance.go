@@ -5,7 +5,9 @@ import (
 	"net/http"
 
 	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
 	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	grpcclient "github.com/EvansTrein/RESTful_exchangerServer/pkg/gRPCclient"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/net/context"
 )
@@ -16,21 +18,29 @@ type balanceServ interface {
 
 // Balance is a Gin handler function that retrieves the balance of all accounts for the authenticated user.
 // It gets the user ID from the context, validates it, and calls the service to fetch the balance.
+// With the "valuation" query parameter, every account is also valued in that currency and the total is returned,
+// without it the display currency from the profile of the user is used.
+// If the query is invalid, it returns a 400 Bad Request.
 // If the user ID is missing or invalid, it returns a 500 Internal Server Error.
-// If the user is not found, it returns a 404 Not Found.
+// If the user or the valuation currency is not found, it returns a 404 Not Found.
+// If the gRPC server is unavailable, it returns a 503 Service Unavailable.
 // If the request times out, it returns a 504 Gateway Timeout.
 // On success, it returns a 200 OK response with the balance data.
 // 
 // @Summary Get user balance
-// @Description Get the balance of all accounts
+// @Description Get the balance of all accounts, optionally valued in the currency given in "valuation" or the display currency of the user"
 // @Tags wallet
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param valuation query string false "Currency in which the accounts are valued" example(USD)
 // @Success 200 {object} models.BalanceResponse
+// @Failure 400 {object} models.HandlerResponse
 // @Failure 401 {object} models.HandlerResponse
 // @Failure 404 {object} models.HandlerResponse
 // @Failure 500 {object} models.HandlerResponse
+// @Failure 503 {object} models.HandlerResponse
 // @Failure 504 {object} models.HandlerResponse
 // @Router /balance [get]
 func Balance(log *slog.Logger, serv balanceServ) gin.HandlerFunc {
@@ -65,6 +75,12 @@ func Balance(log *slog.Logger, serv balanceServ) gin.HandlerFunc {
 		}
 
 		var req models.BalanceRequest
+		if err := ctx.ShouldBindQuery(&req); err != nil {
+			log.Warn("fail BindQuery", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
 		req.UserID = userIdUint
 		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
 
@@ -79,6 +95,30 @@ func Balance(log *slog.Logger, serv balanceServ) gin.HandlerFunc {
 					Message: "the balance of a non-existent user was requested",
 				})
 				return
+			case servWallet.ErrCurrencyNotFound:
+				log.Warn("failed to send data", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "valuation currency is not supported",
+				})
+				return
+			case grpcclient.ErrServerUnavailable:
+				log.Warn("failed to send data", "error", err)
+				ctx.JSON(503, models.HandlerResponse{
+					Status:  http.StatusServiceUnavailable,
+					Error:   err.Error(),
+					Message: "failed to value the accounts",
+				})
+				return
+			case grpcclient.ErrServerTimeOut:
+				log.Error("failed to send data", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "response timeout expired on the GRPC server side",
+				})
+				return
 			case context.DeadlineExceeded:
 				log.Error("failed to send data", "error", err)
 				ctx.JSON(504, models.HandlerResponse{
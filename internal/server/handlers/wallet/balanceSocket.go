@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	servBalances "github.com/EvansTrein/RESTful_exchangerServer/internal/services/balances"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+type balanceSocketServ interface {
+	Subscribe(ctx context.Context, userId uint) (*servBalances.Subscriber, error)
+	Unsubscribe(sub *servBalances.Subscriber)
+}
+
+// BalanceSocket is a Gin handler function that pushes the balance changes of the authenticated user over a WebSocket.
+// It subscribes the user before the connection is upgraded, the current balance is the first message.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the server is shutting down, it returns a 503 Service Unavailable.
+// After the upgrade every committed deposit, withdrawal or exchange of the user, made on any instance of the server,
+// is pushed with the new balance. The connection is kept alive with ping frames,
+// a client that does not answer them or does not keep up with the notifications is disconnected.
+//
+// @Summary Balance notifications (WebSocket)
+// @Description Upgrade to a WebSocket that pushes the new balance and the operation after every balance change of the user.
+// @Description The first message has the type "balance" and the current balance.
+// @Tags wallet
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Success 101 {object} models.BalanceNotification
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 503 {object} models.HandlerResponse
+// @Router /balance/ws [get]
+func BalanceSocket(log *slog.Logger, serv balanceSocketServ, conf *config.Streaming) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler BalanceSocket: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		log.Debug("user id was successfully obtained from the context", "userID", userIdUint)
+
+		sub, err := serv.Subscribe(ctx.Request.Context(), userIdUint)
+		if err != nil {
+			switch err {
+			case servBalances.ErrStreamClosed:
+				log.Warn("failed to subscribe", "error", err)
+				ctx.JSON(503, models.HandlerResponse{
+					Status:  http.StatusServiceUnavailable,
+					Error:   err.Error(),
+					Message: "balance notifications are not available",
+				})
+				return
+			default:
+				log.Error("failed to subscribe", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to get the balance",
+				})
+				return
+			}
+		}
+		defer serv.Unsubscribe(sub)
+
+		// the upgrader writes the error response itself
+		conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+		if err != nil {
+			log.Warn("failed to upgrade the connection", "error", err)
+			return
+		}
+		defer conn.Close()
+
+		log.Info("balance socket opened")
+
+		// the client must answer the pings, otherwise the read fails after two heartbeats
+		pongWait := 2 * conf.HeartbeatInterval
+		conn.SetReadLimit(maxSocketMessage)
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(pongWait))
+		})
+
+		// the client is not expected to send anything, the reader only handles the pongs and the close
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+						log.Warn("failed to read the message", "error", err)
+					}
+					return
+				}
+			}
+		}()
+
+		write := func(msg any) error {
+			conn.SetWriteDeadline(time.Now().Add(conf.WriteTimeout))
+			return conn.WriteJSON(msg)
+		}
+
+		heartbeat := time.NewTicker(conf.HeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-done:
+				log.Info("balance socket closed by the client")
+				return
+			case <-heartbeat.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(conf.WriteTimeout)); err != nil {
+					log.Warn("failed to send the ping", "error", err)
+					return
+				}
+			case notification, ok := <-sub.Updates():
+				if !ok {
+					code := websocket.CloseGoingAway
+					if sub.Err() == servBalances.ErrSlowConsumer {
+						code = websocket.CloseTryAgainLater
+					}
+
+					conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, sub.Err().Error()), time.Now().Add(conf.WriteTimeout))
+
+					log.Warn("balance socket closed by the server", "reason", sub.Err())
+					return
+				}
+
+				if err := write(notification); err != nil {
+					log.Warn("failed to send the notification", "error", err)
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type closeAccountServ interface {
+	CloseAccount(ctx context.Context, req models.AccountRequest) error
+}
+
+// CloseAccount is a Gin handler function that closes an empty named account (pocket) of the authenticated user.
+// It binds the account ID from the path and calls the service to close the account.
+// If the account ID is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the account is not found, it returns a 404 Not Found.
+// If the account is the default account of its currency or still has funds, it returns a 409 Conflict.
+// On success, it returns a 200 OK response.
+//
+// @Summary Close an account
+// @Description Close an empty named account of the user, the default accounts can not be closed, the history of the account is kept
+// @Tags wallet
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Account ID"
+// @Success 200 {object} models.HandlerResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 409 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /accounts/{id} [delete]
+func CloseAccount(log *slog.Logger, serv closeAccountServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler CloseAccount: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.AccountRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			log.Warn("fail BindUri", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid account id"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		err := serv.CloseAccount(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case servWallet.ErrAccountNotFound:
+				log.Warn("failed to close the account", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "account does not exist",
+				})
+				return
+			case servWallet.ErrDefaultAccount:
+				log.Warn("failed to close the account", "error", err)
+				ctx.JSON(409, models.HandlerResponse{
+					Status:  http.StatusConflict,
+					Error:   err.Error(),
+					Message: "default account of a currency can not be closed",
+				})
+				return
+			case servWallet.ErrAccountNotEmpty:
+				log.Warn("failed to close the account", "error", err)
+				ctx.JSON(409, models.HandlerResponse{
+					Status:  http.StatusConflict,
+					Error:   err.Error(),
+					Message: "transfer the funds to another account first",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to close the account", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to close the account", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to close the account",
+				})
+				return
+			}
+		}
+
+		log.Info("account successfully closed")
+		ctx.JSON(200, models.HandlerResponse{Status: http.StatusOK, Message: "account successfully closed"})
+	}
+}
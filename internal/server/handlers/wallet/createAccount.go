@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type createAccountServ interface {
+	CreateAccount(ctx context.Context, req models.CreateAccountRequest) (*models.AccountResponse, error)
+}
+
+// CreateAccount is a Gin handler function that opens a named account (pocket) of the authenticated user.
+// It binds the incoming JSON request to a struct, validates the data, and calls the service to create the account.
+// If the data is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the currency is not found, it returns a 404 Not Found.
+// If the user already has an account with this name in the currency, it returns a 409 Conflict.
+// On success, it returns a 201 Created response with the account.
+//
+// @Summary Create an account
+// @Description Open a named account (pocket) in the currency, for example a "travel" EUR account next to the default one
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param body body models.CreateAccountRequest true "Account request"
+// @Success 201 {object} models.AccountResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 409 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /accounts [post]
+func CreateAccount(log *slog.Logger, serv createAccountServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler CreateAccount: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.CreateAccountRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.CreateAccount(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case servWallet.ErrCurrencyNotFound:
+				log.Warn("failed to create the account", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "currency is not supported",
+				})
+				return
+			case servWallet.ErrAccountNameTaken:
+				log.Warn("failed to create the account", "error", err)
+				ctx.JSON(409, models.HandlerResponse{
+					Status:  http.StatusConflict,
+					Error:   err.Error(),
+					Message: "choose another name of the account",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to create the account", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to create the account", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to create the account",
+				})
+				return
+			}
+		}
+
+		log.Info("account successfully created")
+		ctx.JSON(201, result)
+	}
+}
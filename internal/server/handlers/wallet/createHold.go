@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	servAuth "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	servHolds "github.com/EvansTrein/RESTful_exchangerServer/internal/services/holds"
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type createHoldServ interface {
+	CreateHold(ctx context.Context, req models.CreateHoldRequest) (*models.HoldResponse, error)
+}
+
+// CreateHold is a Gin handler function that holds funds of an account of the authenticated user for a merchant.
+// It binds the incoming JSON request to a struct, validates the data, and calls the service to create the hold.
+// If the data is invalid or the expiry time is not allowed, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the available balance is not enough, it returns a 402 Payment Required.
+// If the withdrawal limit would be exceeded, it returns a 403 Forbidden with the remaining allowance,
+// a 403 Forbidden is also returned if the email of the user is not verified, or if the hold is above
+// the two-factor threshold and the code in the X-2FA-Code header is missing or wrong.
+// If the account is not found, it returns a 404 Not Found.
+// If too many wrong two-factor codes were sent in a row, it returns a 429 Too Many Requests.
+// On success, it returns a 201 Created response with the hold.
+//
+// @Summary Hold funds
+// @Description Reserve an amount of an account until it is captured, released or expired, the held amount stays in the balance but is not available. account_id chooses a named account, without it the default account of the currency is used
+// @Tags holds
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param body body models.CreateHoldRequest true "Hold request"
+// @Param X-2FA-Code header string false "Two-factor code or recovery code, required above the withdrawal threshold if 2FA is enabled"
+// @Success 201 {object} models.HoldResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 402 {object} models.HandlerResponse
+// @Failure 403 {object} models.LimitExceededResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 429 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /wallet/holds [post]
+func CreateHold(log *slog.Logger, serv createHoldServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler CreateHold: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.CreateHoldRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		req.TwoFactorCode = ctx.GetHeader("X-2FA-Code")
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.CreateHold(ctx.Request.Context(), req)
+		if err != nil {
+			var limitErr *servWallet.LimitExceededError
+			if errors.As(err, &limitErr) {
+				log.Warn("failed to create the hold", "error", err)
+				ctx.JSON(403, limitExceededResponse(limitErr))
+				return
+			}
+
+			switch err {
+			case servHolds.ErrInvalidExpiry:
+				log.Warn("failed to create the hold", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "invalid data",
+				})
+				return
+			case servWallet.ErrEmailNotVerified:
+				log.Warn("failed to create the hold", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "verify the email to continue",
+				})
+				return
+			case servAuth.ErrTwoFactorRequired:
+				log.Warn("failed to create the hold", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "pass the two-factor code in the X-2FA-Code header",
+				})
+				return
+			case servAuth.ErrInvalidTwoFactor:
+				log.Warn("failed to create the hold", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "two-factor code is invalid or already used",
+				})
+				return
+			case servAuth.ErrTwoFactorLocked:
+				log.Warn("failed to create the hold", "error", err)
+				ctx.JSON(429, models.HandlerResponse{
+					Status:  http.StatusTooManyRequests,
+					Error:   err.Error(),
+					Message: "too many invalid two-factor codes, try again later",
+				})
+				return
+			case servWallet.ErrInsufficientFunds:
+				log.Warn("failed to create the hold", "error", err)
+				ctx.JSON(402, models.HandlerResponse{
+					Status:  http.StatusPaymentRequired,
+					Error:   err.Error(),
+					Message: "insufficient funds",
+				})
+				return
+			case servWallet.ErrAccountNotFound:
+				log.Warn("failed to create the hold", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "no account in the specified currency",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to create the hold", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to create the hold", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to create the hold",
+				})
+				return
+			}
+		}
+
+		log.Info("hold successfully created")
+		ctx.JSON(201, result)
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"context"
 	"log/slog"
 	"net/http"
@@ -19,19 +20,22 @@ type depositServ interface {
 // If the data is invalid, it returns a 400 Bad Request.
 // If the user ID is missing or invalid, it returns a 500 Internal Server Error.
 // If the currency or account is not found, it returns a 404 Not Found.
+// If a transaction limit would be exceeded, it returns a 403 Forbidden with the remaining allowance.
 // If the request times out, it returns a 504 Gateway Timeout.
 // On success, it returns a 200 OK response with the deposit result.
 //
 // @Summary Deposit funds into an account
-// @Description Deposit funds into a user's account for a specific currency
+// @Description Deposit funds into a user's account for a specific currency, account_id chooses a named account, without it the default account of the currency is used
 // @Tags wallet
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param body body models.AccountOperationRequest true "Deposit request"
 // @Success 200 {object} models.AccountOperationResponse
 // @Failure 400 {object} models.HandlerResponse
 // @Failure 401 {object} models.HandlerResponse
+// @Failure 403 {object} models.LimitExceededResponse
 // @Failure 404 {object} models.HandlerResponse
 // @Failure 500 {object} models.HandlerResponse
 // @Failure 504 {object} models.HandlerResponse
@@ -81,6 +85,13 @@ func Deposit(log *slog.Logger, serv depositServ) gin.HandlerFunc {
 
 		result, err := serv.Deposit(ctx.Request.Context(), &req)
 		if err != nil {
+			var limitErr *services.LimitExceededError
+			if errors.As(err, &limitErr) {
+				log.Warn("failed to deposit", "error", err)
+				ctx.JSON(403, limitExceededResponse(limitErr))
+				return
+			}
+
 			switch err {
 			case services.ErrCurrencyNotFound:
 				log.Warn("failed to deposit", "error", err)
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 
@@ -18,24 +19,28 @@ type exchangeServ interface {
 
 // Exchange is a Gin handler function that handles currency exchange for the authenticated user.
 // It binds the incoming JSON request to a struct, validates the data, and calls the service to perform the exchange.
-// If the data is invalid or the currencies are the same, it returns a 400 Bad Request.
+// If the data is invalid, the currencies are the same or the amount does not cover the fee, it returns a 400 Bad Request.
 // If the user ID is missing or invalid, it returns a 500 Internal Server Error.
 // If the user, account, or currency is not found, it returns a 404 Not Found.
 // If there are insufficient funds, it returns a 402 Payment Required.
+// If a transaction limit would be exceeded, it returns a 403 Forbidden with the remaining allowance,
+// a 403 Forbidden is also returned if the email of the user is not verified.
 // If the request times out or the gRPC server is unavailable, it returns a 504 Gateway Timeout or 503 Service Unavailable.
 // On success, it returns a 200 OK response with the exchange result.
 //
 // @Summary Exchange currency
-// @Description Exchange one currency to another for the authenticated user
+// @Description Exchange one currency to another for the authenticated user, the fee is deducted from the received amount. from_account_id and to_account_id choose named accounts, without them the default accounts are used
 // @Tags wallet
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param body body models.ExchangeRequest true "Exchange request"
 // @Success 200 {object} models.ExchangeResponse
 // @Failure 400 {object} models.HandlerResponse
 // @Failure 401 {object} models.HandlerResponse
 // @Failure 402 {object} models.HandlerResponse
+// @Failure 403 {object} models.LimitExceededResponse
 // @Failure 404 {object} models.HandlerResponse
 // @Failure 500 {object} models.HandlerResponse
 // @Failure 503 {object} models.HandlerResponse
@@ -96,7 +101,22 @@ func Exchange(log *slog.Logger, serv exchangeServ) gin.HandlerFunc {
 
 		result, err := serv.Exchange(ctx.Request.Context(), req)
 		if err != nil {
+			var limitErr *servWallet.LimitExceededError
+			if errors.As(err, &limitErr) {
+				log.Warn("failed to exchanged", "error", err)
+				ctx.JSON(403, limitExceededResponse(limitErr))
+				return
+			}
+
 			switch err {
+			case servWallet.ErrEmailNotVerified:
+				log.Warn("failed to exchanged", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "verify the email to continue",
+				})
+				return
 			case servWallet.ErrInsufficientFunds:
 				log.Warn("failed to exchanged", "error", err)
 				ctx.JSON(402, models.HandlerResponse{
@@ -105,6 +125,14 @@ func Exchange(log *slog.Logger, serv exchangeServ) gin.HandlerFunc {
 					Message: "insufficient funds",
 				})
 				return
+			case servWallet.ErrAmountBelowFee:
+				log.Warn("failed to exchanged", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "amount is too small to cover the exchange fee",
+				})
+				return
 			case servAuth.ErrUserNotFound:
 				log.Error("request was received for a non-existent user", "error", err, "user id", userIdUint)
 				ctx.JSON(404, models.HandlerResponse{
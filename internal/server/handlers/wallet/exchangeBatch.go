@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	servAuth "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	grpcclient "github.com/EvansTrein/RESTful_exchangerServer/pkg/gRPCclient"
+	"github.com/gin-gonic/gin"
+)
+
+type exchangeBatchServ interface {
+	ExchangeBatch(ctx context.Context, req models.ExchangeBatchRequest) (*models.ExchangeBatchResponse, error)
+}
+
+// ExchangeBatch is a Gin handler function that executes several currency exchanges atomically for the authenticated user.
+// It binds the incoming JSON request, which must contain either a list of legs or a target currency for consolidation.
+// If the data is invalid, a leg has the same currencies or a leg does not cover the fee, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the user, account, or currency is not found, it returns a 404 Not Found.
+// If there are insufficient funds, it returns a 402 Payment Required.
+// If a transaction limit would be exceeded, it returns a 403 Forbidden with the remaining allowance,
+// a 403 Forbidden is also returned if the email of the user is not verified.
+// If the request times out or the gRPC server is unavailable, it returns a 504 Gateway Timeout or 503 Service Unavailable.
+// On success, it returns a 200 OK response with the result of every leg, otherwise no leg is applied.
+//
+// @Summary Exchange currency in several legs
+// @Description Execute all legs in one transaction (all or nothing), or convert every non-zero account into consolidate_to
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param body body models.ExchangeBatchRequest true "Batch exchange request"
+// @Success 200 {object} models.ExchangeBatchResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 402 {object} models.HandlerResponse
+// @Failure 403 {object} models.LimitExceededResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 503 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /exchange/batch [post]
+func ExchangeBatch(log *slog.Logger, serv exchangeBatchServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler ExchangeBatch: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.ExchangeBatchRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		if (len(req.Legs) == 0) == (req.ConsolidateTo == "") {
+			log.Warn("neither or both legs and consolidate_to are specified")
+			ctx.JSON(400, models.HandlerResponse{
+				Status:  http.StatusBadRequest,
+				Error:   "either legs or consolidate_to must be specified",
+				Message: "invalid data",
+			})
+			return
+		}
+
+		log.Debug("request data has been successfully validated", "data", req)
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.ExchangeBatch(ctx.Request.Context(), req)
+		if err != nil {
+			var limitErr *servWallet.LimitExceededError
+			if errors.As(err, &limitErr) {
+				log.Warn("failed to exchanged", "error", err)
+				ctx.JSON(403, limitExceededResponse(limitErr))
+				return
+			}
+
+			switch err {
+			case servWallet.ErrSameCurrency, servWallet.ErrEmptyBatch, servWallet.ErrAmountBelowFee:
+				log.Warn("failed to exchanged", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "invalid data",
+				})
+				return
+			case servWallet.ErrEmailNotVerified:
+				log.Warn("failed to exchanged", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "verify the email to continue",
+				})
+				return
+			case servWallet.ErrInsufficientFunds:
+				log.Warn("failed to exchanged", "error", err)
+				ctx.JSON(402, models.HandlerResponse{
+					Status:  http.StatusPaymentRequired,
+					Error:   err.Error(),
+					Message: "insufficient funds",
+				})
+				return
+			case servAuth.ErrUserNotFound:
+				log.Error("request was received for a non-existent user", "error", err, "user id", userIdUint)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "user does not exist",
+				})
+				return
+			case servWallet.ErrAccountNotFound:
+				log.Warn("failed to exchanged", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "no account in this currency",
+				})
+				return
+			case servWallet.ErrCurrencyNotFound:
+				log.Warn("failed to exchanged", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "currency is not supported",
+				})
+				return
+			case grpcclient.ErrServerUnavailable:
+				log.Warn("failed to exchanged", "error", err)
+				ctx.JSON(503, models.HandlerResponse{
+					Status:  http.StatusServiceUnavailable,
+					Error:   err.Error(),
+					Message: "failed to exchanged",
+				})
+				return
+			case grpcclient.ErrServerTimeOut:
+				log.Error("failed to exchanged", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "response timeout expired on the GRPC server side",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to exchanged", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to exchanged", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to exchanged",
+				})
+				return
+			}
+		}
+
+		log.Info("batch currency exchange successfully")
+		ctx.JSON(200, result)
+	}
+}
@@ -25,6 +25,7 @@ type exchangeRatesServ interface {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Success 200 {object} models.ExchangeRatesResponse
 // @Failure 401 {object} models.HandlerResponse
 // @Failure 500 {object} models.HandlerResponse
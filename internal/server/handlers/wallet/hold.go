@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	servHolds "github.com/EvansTrein/RESTful_exchangerServer/internal/services/holds"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type holdServ interface {
+	UserHold(ctx context.Context, req models.HoldRequest) (*models.HoldResponse, error)
+}
+
+// Hold is a Gin handler function that returns a hold of the authenticated user.
+// It binds the hold ID from the path and calls the service to fetch the hold.
+// If the hold ID is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the hold is not found, it returns a 404 Not Found.
+// On success, it returns a 200 OK response with the hold.
+//
+// @Summary Get a hold
+// @Description Get a hold of the authenticated user
+// @Tags holds
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Hold ID"
+// @Success 200 {object} models.HoldResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /wallet/holds/{id} [get]
+func Hold(log *slog.Logger, serv holdServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler Hold: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.HoldRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			log.Warn("fail BindUri", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid hold id"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.UserHold(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case servHolds.ErrHoldNotFound:
+				log.Warn("failed to get the hold", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "hold not found",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to get the hold", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to get the hold", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to get the hold",
+				})
+				return
+			}
+		}
+
+		log.Info("hold successfully received")
+		ctx.JSON(200, result)
+	}
+}
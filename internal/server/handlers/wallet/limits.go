@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	servAuth "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type limitsServ interface {
+	Limits(ctx context.Context, req models.LimitsRequest) (*models.LimitsResponse, error)
+}
+
+// Limits is a Gin handler function that returns the transaction limits of the authenticated user and their current usage.
+// It gets the user ID from the context, validates it, and calls the service to fetch the limits.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the user is not found, it returns a 404 Not Found.
+// If the request times out, it returns a 504 Gateway Timeout.
+// On success, it returns a 200 OK response with the limits.
+//
+// @Summary Get transaction limits
+// @Description Get the daily and monthly limits for deposits, withdrawals and exchanges and how much of them is used
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Success 200 {object} models.LimitsResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /limits [get]
+func Limits(log *slog.Logger, serv limitsServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler Limits: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request for transaction limits")
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		var req models.LimitsRequest
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.Limits(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case servAuth.ErrUserNotFound:
+				log.Error("user not found", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "the limits of a non-existent user was requested",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to send data", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to send data", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to send data",
+				})
+				return
+			}
+		}
+
+		log.Info("data successfully sent")
+		ctx.JSON(200, result)
+	}
+}
+
+// limitExceededResponse builds the 403 response body for an operation rejected by a transaction limit.
+func limitExceededResponse(err *servWallet.LimitExceededError) models.LimitExceededResponse {
+	return models.LimitExceededResponse{
+		Status:    http.StatusForbidden,
+		Error:     err.Error(),
+		Message:   "transaction limit exceeded",
+		Operation: err.Operation,
+		Currency:  err.Currency,
+		Period:    err.Period,
+		Limit:     err.Limit,
+		Remaining: err.Remaining,
+	}
+}
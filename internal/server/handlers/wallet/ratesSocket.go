@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	servRates "github.com/EvansTrein/RESTful_exchangerServer/internal/services/rates"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// maxSocketMessage limits the size of a message from the client, only the subscription changes are expected.
+const maxSocketMessage = 4096
+
+var errUnknownMessage = errors.New("unknown message type, expected subscribe or unsubscribe")
+
+type ratesSocketServ interface {
+	Subscribe(pairs []string) (*servRates.Subscription, error)
+	AddPairs(sub *servRates.Subscription, pairs []string) error
+	RemovePairs(sub *servRates.Subscription, pairs []string) error
+	Unsubscribe(sub *servRates.Subscription)
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// RatesSocket is a Gin handler function that streams the exchange rates of the subscribed pairs over a WebSocket.
+// It binds the initial pairs from the query and subscribes to them before the connection is upgraded.
+// If the pairs are missing or invalid, it returns a 400 Bad Request.
+// If the server is shutting down, it returns a 503 Service Unavailable.
+// After the upgrade the server sends "rates" messages, the client changes the subscription with "subscribe"
+// and "unsubscribe" messages. The connection is kept alive with ping frames, a client that does not answer
+// them or does not keep up with the updates is disconnected.
+//
+// @Summary Stream exchange rates (WebSocket)
+// @Description Upgrade to a WebSocket that streams the changed exchange rates of the subscribed currency pairs.
+// @Description Messages are models.RatesStreamMessage, the client sends {"type":"subscribe","pairs":["USD/CNY"]} or "unsubscribe" to change the pairs.
+// @Tags wallet
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param pairs query string true "Comma separated currency pairs" example(USD/EUR,EUR/RUB)
+// @Success 101 {object} models.RatesStreamMessage
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 503 {object} models.HandlerResponse
+// @Router /exchange/rates/ws [get]
+func RatesSocket(log *slog.Logger, serv ratesSocketServ, conf *config.Streaming) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler RatesSocket: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.RatesStreamRequest
+		if err := ctx.ShouldBindQuery(&req); err != nil {
+			log.Warn("fail BindQuery", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid request data"})
+			return
+		}
+
+		sub, err := serv.Subscribe(strings.Split(req.Pairs, ","))
+		if err != nil {
+			switch err {
+			case servRates.ErrInvalidPair, servRates.ErrTooManyPairs:
+				log.Warn("failed to subscribe", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "invalid currency pairs",
+				})
+				return
+			default:
+				log.Warn("failed to subscribe", "error", err)
+				ctx.JSON(503, models.HandlerResponse{
+					Status:  http.StatusServiceUnavailable,
+					Error:   err.Error(),
+					Message: "rate streaming is not available",
+				})
+				return
+			}
+		}
+		defer serv.Unsubscribe(sub)
+
+		// the upgrader writes the error response itself
+		conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+		if err != nil {
+			log.Warn("failed to upgrade the connection", "error", err)
+			return
+		}
+		defer conn.Close()
+
+		log.Info("rate socket opened", "pairs", req.Pairs)
+
+		// the client must answer the pings, otherwise the read fails after two heartbeats
+		pongWait := 2 * conf.HeartbeatInterval
+		conn.SetReadLimit(maxSocketMessage)
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(pongWait))
+		})
+
+		// only this goroutine writes to the connection, the reader passes its replies through the channel.
+		// The gin context is reused after the handler returns, the reader keeps only the request context.
+		reqCtx := ctx.Request.Context()
+		replies := make(chan models.RatesStreamMessage, 1)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				var msg models.RatesStreamMessage
+				if err := conn.ReadJSON(&msg); err != nil {
+					if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+						log.Warn("failed to read the message", "error", err)
+					}
+					return
+				}
+
+				var err error
+				switch msg.Type {
+				case servRates.MessageSubscribe:
+					err = serv.AddPairs(sub, msg.Pairs)
+				case servRates.MessageUnsubscribe:
+					err = serv.RemovePairs(sub, msg.Pairs)
+				default:
+					err = errUnknownMessage
+				}
+
+				reply := models.RatesStreamMessage{Type: msg.Type, Pairs: msg.Pairs}
+				if err != nil {
+					reply = models.RatesStreamMessage{Type: servRates.MessageError, Pairs: msg.Pairs, Error: err.Error()}
+				}
+
+				select {
+				case replies <- reply:
+				case <-reqCtx.Done():
+					return
+				}
+			}
+		}()
+
+		write := func(msg any) error {
+			conn.SetWriteDeadline(time.Now().Add(conf.WriteTimeout))
+			return conn.WriteJSON(msg)
+		}
+
+		heartbeat := time.NewTicker(conf.HeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-done:
+				log.Info("rate socket closed by the client")
+				return
+			case <-heartbeat.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(conf.WriteTimeout)); err != nil {
+					log.Warn("failed to send the ping", "error", err)
+					return
+				}
+			case reply := <-replies:
+				if err := write(reply); err != nil {
+					log.Warn("failed to send the reply", "error", err)
+					return
+				}
+			case updates, ok := <-sub.Updates():
+				if !ok {
+					code := websocket.CloseGoingAway
+					if sub.Err() == servRates.ErrSlowConsumer {
+						code = websocket.CloseTryAgainLater
+					}
+
+					write(models.RatesStreamMessage{Type: servRates.MessageError, Error: sub.Err().Error()})
+					conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, sub.Err().Error()), time.Now().Add(conf.WriteTimeout))
+
+					log.Warn("rate socket closed by the server", "reason", sub.Err())
+					return
+				}
+
+				if err := write(models.RatesStreamMessage{Type: servRates.MessageRates, Rates: updates}); err != nil {
+					log.Warn("failed to send the rates", "error", err)
+					return
+				}
+			}
+		}
+	}
+}
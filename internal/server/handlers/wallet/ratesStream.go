@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	servRates "github.com/EvansTrein/RESTful_exchangerServer/internal/services/rates"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type ratesStreamServ interface {
+	Subscribe(pairs []string) (*servRates.Subscription, error)
+	Unsubscribe(sub *servRates.Subscription)
+}
+
+// RatesStream is a Gin handler function that streams the exchange rates of the subscribed pairs as Server-Sent Events.
+// It binds the pairs from the query and subscribes to them, the known rates are sent at once, then only the changed ones.
+// If the pairs are missing or invalid, it returns a 400 Bad Request.
+// If the server is shutting down, it returns a 503 Service Unavailable.
+// On success, it keeps the response open and sends "rates" events, a comment line is sent as a heartbeat.
+// If the client does not keep up with the updates, an "error" event is sent and the stream is closed.
+//
+// @Summary Stream exchange rates (SSE)
+// @Description Stream the changed exchange rates of the subscribed currency pairs as Server-Sent Events.
+// @Description Every "rates" event carries a JSON array of models.RateUpdate.
+// @Tags wallet
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param pairs query string true "Comma separated currency pairs" example(USD/EUR,EUR/RUB)
+// @Success 200 {array} models.RateUpdate
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 503 {object} models.HandlerResponse
+// @Router /exchange/rates/stream [get]
+func RatesStream(log *slog.Logger, serv ratesStreamServ, conf *config.Streaming) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler RatesStream: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.RatesStreamRequest
+		if err := ctx.ShouldBindQuery(&req); err != nil {
+			log.Warn("fail BindQuery", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid request data"})
+			return
+		}
+
+		sub, err := serv.Subscribe(strings.Split(req.Pairs, ","))
+		if err != nil {
+			switch err {
+			case servRates.ErrInvalidPair, servRates.ErrTooManyPairs:
+				log.Warn("failed to subscribe", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "invalid currency pairs",
+				})
+				return
+			default:
+				log.Warn("failed to subscribe", "error", err)
+				ctx.JSON(503, models.HandlerResponse{
+					Status:  http.StatusServiceUnavailable,
+					Error:   err.Error(),
+					Message: "rate streaming is not available",
+				})
+				return
+			}
+		}
+		defer serv.Unsubscribe(sub)
+
+		// the stream outlives the read and write timeouts of the server, the write deadline is moved before every event
+		rc := http.NewResponseController(ctx.Writer)
+		if err := rc.SetReadDeadline(time.Time{}); err != nil {
+			log.Warn("failed to reset the read deadline", "error", err)
+		}
+
+		write := func(frame string) error {
+			if err := rc.SetWriteDeadline(time.Now().Add(conf.WriteTimeout)); err != nil {
+				log.Debug("failed to set the write deadline", "error", err)
+			}
+			if _, err := ctx.Writer.WriteString(frame); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+
+		ctx.Header("Content-Type", "text/event-stream")
+		ctx.Header("Cache-Control", "no-cache")
+		ctx.Header("Connection", "keep-alive")
+		ctx.Header("X-Accel-Buffering", "no")
+		ctx.Status(http.StatusOK)
+
+		if err := write(": connected\n\n"); err != nil {
+			log.Warn("failed to start the stream", "error", err)
+			return
+		}
+
+		log.Info("rate stream started", "pairs", req.Pairs)
+
+		heartbeat := time.NewTicker(conf.HeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-ctx.Request.Context().Done():
+				log.Info("rate stream closed by the client")
+				return
+			case <-heartbeat.C:
+				if err := write(": heartbeat\n\n"); err != nil {
+					log.Warn("failed to send the heartbeat", "error", err)
+					return
+				}
+			case updates, ok := <-sub.Updates():
+				if !ok {
+					data, _ := json.Marshal(models.HandlerResponse{
+						Status:  http.StatusServiceUnavailable,
+						Error:   sub.Err().Error(),
+						Message: "rate stream is closed, reconnect to continue",
+					})
+					if err := write(fmt.Sprintf("event: %s\ndata: %s\n\n", servRates.MessageError, data)); err != nil {
+						log.Debug("failed to send the error event", "error", err)
+					}
+					log.Warn("rate stream closed by the server", "reason", sub.Err())
+					return
+				}
+
+				data, err := json.Marshal(updates)
+				if err != nil {
+					log.Error("failed to encode the rates", "error", err)
+					return
+				}
+
+				if err := write(fmt.Sprintf("event: %s\ndata: %s\n\n", servRates.MessageRates, data)); err != nil {
+					log.Warn("failed to send the rates", "error", err)
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	servHolds "github.com/EvansTrein/RESTful_exchangerServer/internal/services/holds"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type releaseHoldServ interface {
+	ReleaseHold(ctx context.Context, req models.HoldRequest) (*models.HoldResponse, error)
+}
+
+// ReleaseHold is a Gin handler function that releases an open hold of the authenticated user, its amount becomes available again.
+// If the hold ID is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the hold is not found, it returns a 404 Not Found, if it is no longer open, it returns a 409 Conflict.
+// On success, it returns a 200 OK response with the released hold.
+//
+// @Summary Release a hold
+// @Description Release an open hold, its amount becomes available again
+// @Tags holds
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Hold ID"
+// @Success 200 {object} models.HoldResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 409 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /wallet/holds/{id}/release [post]
+func ReleaseHold(log *slog.Logger, serv releaseHoldServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler ReleaseHold: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.HoldRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			log.Warn("fail BindUri", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid hold id"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.ReleaseHold(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case servHolds.ErrHoldNotFound:
+				log.Warn("failed to release the hold", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "hold not found",
+				})
+				return
+			case servHolds.ErrHoldNotOpen:
+				log.Warn("failed to release the hold", "error", err)
+				ctx.JSON(409, models.HandlerResponse{
+					Status:  http.StatusConflict,
+					Error:   err.Error(),
+					Message: "hold is already closed",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to release the hold", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to release the hold", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to release the hold",
+				})
+				return
+			}
+		}
+
+		log.Info("hold successfully released")
+		ctx.JSON(200, result)
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type statementServ interface {
+	Statement(ctx context.Context, req models.StatementRequest, out io.Writer) error
+}
+
+// Statement is a Gin handler function that streams the statement of the operations of the authenticated user for a period.
+// It binds the query parameters to a struct, validates the data, and calls the service to write the statement,
+// the statement is downloaded as a CSV or JSON file.
+// If the data is invalid or the period is empty, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the request times out before the statement is started, it returns a 504 Gateway Timeout,
+// an error after the start can only be logged and the response is cut.
+// On success, it returns a 200 OK response with the statement.
+//
+// @Summary Statement of the operations
+// @Description Download the statement of the operations from "from" (inclusive) to "to" (exclusive), optionally of one currency. Every operation has the balances of its account before and after it, the rate of an exchange and the running balance of the currency. The statement is built from the persisted history, so it is reproducible
+// @Tags wallet
+// @Produce json
+// @Produce text/csv
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param from query string true "Start of the period (YYYY-MM-DD)"
+// @Param to query string true "End of the period, exclusive (YYYY-MM-DD)"
+// @Param currency query string false "Currency of the statement, all currencies without it"
+// @Param format query string false "Format of the statement" Enums(csv, json) default(json)
+// @Success 200 {object} models.Statement
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /statements [get]
+func Statement(log *slog.Logger, serv statementServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler Statement: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.StatementRequest
+		if err := ctx.ShouldBindQuery(&req); err != nil {
+			log.Warn("fail BindQuery", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		contentType := "application/json; charset=utf-8"
+		if req.Format == servWallet.StatementCSV {
+			contentType = "text/csv; charset=utf-8"
+		}
+
+		// the headers are sent with the first written bytes, until then an error can still be returned as JSON
+		ctx.Header("Content-Type", contentType)
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%s-%s.%s"`,
+			req.From.Format("2006-01-02"), req.To.Format("2006-01-02"), req.Format))
+		ctx.Status(http.StatusOK)
+
+		err := serv.Statement(ctx.Request.Context(), req, ctx.Writer)
+		if err != nil {
+			if ctx.Writer.Written() {
+				log.Error("statement is cut, failed to write the rest", "error", err)
+				return
+			}
+			ctx.Writer.Header().Del("Content-Type")
+			ctx.Writer.Header().Del("Content-Disposition")
+
+			switch err {
+			case servWallet.ErrInvalidPeriod:
+				log.Warn("failed to create the statement", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "invalid data",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to create the statement", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to create the statement", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to create the statement",
+				})
+				return
+			}
+		}
+
+		log.Info("statement successfully sent")
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type transferServ interface {
+	Transfer(ctx context.Context, req models.TransferRequest) (*models.TransferResponse, error)
+}
+
+// Transfer is a Gin handler function that moves funds between two accounts of the authenticated user in the same currency.
+// It binds the incoming JSON request to a struct, validates the data, and calls the service to make the transfer.
+// If the data is invalid, the accounts are the same or their currencies differ, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the balance is not enough, it returns a 402 Payment Required.
+// If an account is not found, it returns a 404 Not Found.
+// On success, it returns a 200 OK response with both accounts.
+//
+// @Summary Transfer between accounts
+// @Description Move funds between two own accounts in the same currency, the transaction limits are not applied
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param body body models.TransferRequest true "Transfer request"
+// @Success 200 {object} models.TransferResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 402 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /accounts/transfer [post]
+func Transfer(log *slog.Logger, serv transferServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler Transfer: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.TransferRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.Transfer(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case servWallet.ErrSameAccount, servWallet.ErrCurrencyMismatch:
+				log.Warn("failed to transfer", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "choose two accounts in the same currency",
+				})
+				return
+			case servWallet.ErrInsufficientFunds:
+				log.Warn("failed to transfer", "error", err)
+				ctx.JSON(402, models.HandlerResponse{
+					Status:  http.StatusPaymentRequired,
+					Error:   err.Error(),
+					Message: "insufficient funds on the account",
+				})
+				return
+			case servWallet.ErrAccountNotFound:
+				log.Warn("failed to transfer", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "account does not exist",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to transfer", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to transfer", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to transfer",
+				})
+				return
+			}
+		}
+
+		log.Info("successfully transferred")
+		ctx.JSON(200, result)
+	}
+}
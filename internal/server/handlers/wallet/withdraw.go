@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"context"
 	"log/slog"
 	"net/http"
 
+	servAuth "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
 	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
 	"github.com/EvansTrein/RESTful_exchangerServer/models"
 	"github.com/gin-gonic/gin"
@@ -19,21 +21,29 @@ type withdrawServ interface {
 // If the data is invalid, it returns a 400 Bad Request.
 // If the user ID is missing or invalid, it returns a 500 Internal Server Error.
 // If there are insufficient funds or the currency/account is not found, it returns a 402 Payment Required or 404 Not Found.
+// If a transaction limit would be exceeded, it returns a 403 Forbidden with the remaining allowance,
+// a 403 Forbidden is also returned if the email of the user is not verified, or if the withdrawal is above
+// the two-factor threshold and the code in the X-2FA-Code header is missing or wrong.
+// If too many wrong two-factor codes were sent in a row, it returns a 429 Too Many Requests.
 // If the request times out, it returns a 504 Gateway Timeout.
 // On success, it returns a 200 OK response with the withdrawal result.
 //
 // @Summary Withdraw funds from an account
-// @Description Withdraw funds from a user's account for a specific currency
+// @Description Withdraw funds from a user's account for a specific currency, account_id chooses a named account, without it the default account of the currency is used
 // @Tags wallet
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param body body models.AccountOperationRequest true "Withdraw request"
+// @Param X-2FA-Code header string false "Two-factor code or recovery code, required above the threshold if 2FA is enabled"
 // @Success 200 {object} models.AccountOperationResponse
 // @Failure 400 {object} models.HandlerResponse
 // @Failure 401 {object} models.HandlerResponse
 // @Failure 402 {object} models.HandlerResponse
+// @Failure 403 {object} models.LimitExceededResponse
 // @Failure 404 {object} models.HandlerResponse
+// @Failure 429 {object} models.HandlerResponse
 // @Failure 500 {object} models.HandlerResponse
 // @Failure 504 {object} models.HandlerResponse
 // @Router /wallet/withdraw [post]
@@ -78,11 +88,51 @@ func Withdraw(log *slog.Logger, serv withdrawServ) gin.HandlerFunc {
 		}
 
 		req.UserID = userIdUint
+		req.TwoFactorCode = ctx.GetHeader("X-2FA-Code")
 		log.Debug("user id was successfully obtained from the context and added to the request")
 
 		result, err := serv.Withdraw(ctx.Request.Context(), &req)
 		if err != nil {
+			var limitErr *services.LimitExceededError
+			if errors.As(err, &limitErr) {
+				log.Warn("failed to withdraw", "error", err)
+				ctx.JSON(403, limitExceededResponse(limitErr))
+				return
+			}
+
 			switch err {
+			case services.ErrEmailNotVerified:
+				log.Warn("failed to withdraw", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "verify the email to continue",
+				})
+				return
+			case servAuth.ErrTwoFactorRequired:
+				log.Warn("failed to withdraw", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "pass the two-factor code in the X-2FA-Code header",
+				})
+				return
+			case servAuth.ErrInvalidTwoFactor:
+				log.Warn("failed to withdraw", "error", err)
+				ctx.JSON(403, models.HandlerResponse{
+					Status:  http.StatusForbidden,
+					Error:   err.Error(),
+					Message: "two-factor code is invalid or already used",
+				})
+				return
+			case servAuth.ErrTwoFactorLocked:
+				log.Warn("failed to withdraw", "error", err)
+				ctx.JSON(429, models.HandlerResponse{
+					Status:  http.StatusTooManyRequests,
+					Error:   err.Error(),
+					Message: "too many invalid two-factor codes, try again later",
+				})
+				return
 			case services.ErrInsufficientFunds:
 				log.Warn("failed to withdraw", "error", err)
 				ctx.JSON(402, models.HandlerResponse{
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	servWebhooks "github.com/EvansTrein/RESTful_exchangerServer/internal/services/webhooks"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type createWebhookServ interface {
+	CreateWebhook(ctx context.Context, req models.CreateWebhookRequest) (*models.WebhookResponse, error)
+}
+
+// CreateWebhook is a Gin handler function that registers a webhook endpoint of the authenticated user.
+// It binds the incoming JSON request to a struct, validates the data, and calls the service to create the webhook.
+// If the data or the url is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// On success, it returns a 201 Created response with the webhook and its signing secret.
+//
+// @Summary Create a webhook
+// @Description Register an endpoint that receives the chosen wallet events as signed JSON POST requests. Every request has the X-Webhook-Signature header "t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>">" made with the secret, which is returned only in this response
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param body body models.CreateWebhookRequest true "Webhook request"
+// @Success 201 {object} models.WebhookResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /webhooks [post]
+func CreateWebhook(log *slog.Logger, serv createWebhookServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler CreateWebhook: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.CreateWebhookRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("fail BindJSON", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid data"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.CreateWebhook(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case servWebhooks.ErrInvalidURL, servWebhooks.ErrForbiddenAddress:
+				log.Warn("failed to create the webhook", "error", err)
+				ctx.JSON(400, models.HandlerResponse{
+					Status:  http.StatusBadRequest,
+					Error:   err.Error(),
+					Message: "invalid data",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to create the webhook", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to create the webhook", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to create the webhook",
+				})
+				return
+			}
+		}
+
+		log.Info("webhook successfully created")
+		ctx.JSON(201, result)
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	servWebhooks "github.com/EvansTrein/RESTful_exchangerServer/internal/services/webhooks"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type deleteWebhookServ interface {
+	DeleteWebhook(ctx context.Context, req models.WebhookRequest) error
+}
+
+// DeleteWebhook is a Gin handler function that deletes a webhook endpoint of the authenticated user together with its delivery log.
+// It binds the webhook ID from the path and calls the service to delete the webhook.
+// If the webhook ID is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the webhook is not found, it returns a 404 Not Found.
+// On success, it returns a 200 OK response.
+//
+// @Summary Delete a webhook
+// @Description Delete a webhook endpoint of the authenticated user together with its delivery log
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.HandlerResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /webhooks/{id} [delete]
+func DeleteWebhook(log *slog.Logger, serv deleteWebhookServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler DeleteWebhook: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.WebhookRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			log.Warn("fail BindUri", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid webhook id"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		err := serv.DeleteWebhook(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case servWebhooks.ErrWebhookNotFound:
+				log.Warn("failed to delete the webhook", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "webhook does not exist",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to delete the webhook", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to delete the webhook", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to delete the webhook",
+				})
+				return
+			}
+		}
+
+		log.Info("webhook successfully deleted")
+		ctx.JSON(200, models.HandlerResponse{Status: http.StatusOK, Message: "webhook successfully deleted"})
+	}
+}
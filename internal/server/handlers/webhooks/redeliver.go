@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	servWebhooks "github.com/EvansTrein/RESTful_exchangerServer/internal/services/webhooks"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/gin-gonic/gin"
+)
+
+type redeliverServ interface {
+	Redeliver(ctx context.Context, req models.RedeliverRequest) (*models.WebhookDeliveryResponse, error)
+}
+
+// Redeliver is a Gin handler function that sends a webhook delivery of the authenticated user again.
+// It binds the webhook and delivery IDs from the path and calls the service to schedule the redelivery.
+// If an ID is invalid, it returns a 400 Bad Request.
+// If the user ID is missing or invalid, it returns a 500 Internal Server Error.
+// If the delivery is not found or is being sent right now, it returns a 404 Not Found.
+// On success, it returns a 202 Accepted response with the pending delivery.
+//
+// @Summary Redeliver a webhook
+// @Description Make a delivery pending again with a new set of attempts, it is sent within the poll interval of the delivery worker
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Webhook ID"
+// @Param delivery_id path int true "Delivery ID"
+// @Success 202 {object} models.WebhookDeliveryResponse
+// @Failure 400 {object} models.HandlerResponse
+// @Failure 401 {object} models.HandlerResponse
+// @Failure 404 {object} models.HandlerResponse
+// @Failure 500 {object} models.HandlerResponse
+// @Failure 504 {object} models.HandlerResponse
+// @Router /webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func Redeliver(log *slog.Logger, serv redeliverServ) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := "Handler Redeliver: call"
+		log := log.With(
+			slog.String("operation", op),
+			slog.String("apiPath", ctx.FullPath()),
+			slog.String("HTTP Method", ctx.Request.Method),
+		)
+		log.Debug("request received")
+
+		var req models.RedeliverRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			log.Warn("fail BindUri", "error", err)
+			ctx.JSON(400, models.HandlerResponse{Status: http.StatusBadRequest, Error: err.Error(), Message: "invalid webhook or delivery id"})
+			return
+		}
+
+		// get user id from context
+		userID, exists := ctx.Get("userID")
+		if !exists {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "userID not found in context",
+				Message: "failed to retrieve user id from context",
+			})
+			return
+		}
+
+		userIdUint, ok := userID.(uint)
+		if !ok {
+			ctx.JSON(500, models.HandlerResponse{
+				Status:  http.StatusInternalServerError,
+				Error:   "invalid userID type in context",
+				Message: "failed to convert user id to the required data type",
+			})
+			return
+		}
+
+		req.UserID = userIdUint
+		log.Debug("user id was successfully obtained from the context and added to the request", "userID", userIdUint)
+
+		result, err := serv.Redeliver(ctx.Request.Context(), req)
+		if err != nil {
+			switch err {
+			case servWebhooks.ErrDeliveryNotFound:
+				log.Warn("failed to schedule the redelivery", "error", err)
+				ctx.JSON(404, models.HandlerResponse{
+					Status:  http.StatusNotFound,
+					Error:   err.Error(),
+					Message: "delivery does not exist or is being sent",
+				})
+				return
+			case context.DeadlineExceeded:
+				log.Error("failed to schedule the redelivery", "error", err)
+				ctx.JSON(504, models.HandlerResponse{
+					Status:  http.StatusGatewayTimeout,
+					Error:   err.Error(),
+					Message: "the waiting time for a response from the internal service has expired",
+				})
+				return
+			default:
+				log.Error("failed to schedule the redelivery", "error", err)
+				ctx.JSON(500, models.HandlerResponse{
+					Status:  http.StatusInternalServerError,
+					Error:   err.Error(),
+					Message: "failed to schedule the redelivery",
+				})
+				return
+			}
+		}
+
+		log.Info("redelivery successfully scheduled")
+		ctx.JSON(202, result)
+	}
+}
@@ -5,10 +5,21 @@ import (
 
 	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
 	handler "github.com/EvansTrein/RESTful_exchangerServer/internal/server/handlers"
+	handlerAdmin "github.com/EvansTrein/RESTful_exchangerServer/internal/server/handlers/admin"
 	handlerAuth "github.com/EvansTrein/RESTful_exchangerServer/internal/server/handlers/auth"
+	handlerOrders "github.com/EvansTrein/RESTful_exchangerServer/internal/server/handlers/orders"
+	handlerSchedules "github.com/EvansTrein/RESTful_exchangerServer/internal/server/handlers/schedules"
 	handlerWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/server/handlers/wallet"
+	handlerWebhooks "github.com/EvansTrein/RESTful_exchangerServer/internal/server/handlers/webhooks"
 	servAuth "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	servBalances "github.com/EvansTrein/RESTful_exchangerServer/internal/services/balances"
+	servHolds "github.com/EvansTrein/RESTful_exchangerServer/internal/services/holds"
+	servOrders "github.com/EvansTrein/RESTful_exchangerServer/internal/services/orders"
+	servRates "github.com/EvansTrein/RESTful_exchangerServer/internal/services/rates"
+	servScheduler "github.com/EvansTrein/RESTful_exchangerServer/internal/services/scheduler"
 	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	servWebhooks "github.com/EvansTrein/RESTful_exchangerServer/internal/services/webhooks"
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages/redis"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -21,26 +32,91 @@ const (
 )
 
 // InitRouters initializes the HTTP routes for the application.
-// It sets up routes for authentication (register, login, delete) and wallet operations (balance, deposit, withdraw, exchange rates, and exchange).
-// Middleware for timeout and logging is applied to the routes.
-// Additionally, it sets up the Swagger documentation route for API exploration.
-func (s *HttpServer) InitRouters(conf *config.HTTPServer, auth *servAuth.Auth, wallet *servWallet.Wallet) {
+// It sets up routes for authentication (register, login, email verification, password reset, profile, two-factor, API keys, delete), wallet operations (balance, deposit, withdraw, statements, exchange rates, and exchange),
+// live rate streams and balance notifications, holds of the funds, limit orders, scheduled operations, webhooks and admin reports.
+// Middleware for timeout and logging is applied to the routes, the streams are long-lived, so they only pass the authorization.
+// The routes that machine clients may call pass the scope, with it LoggingMiddleware also accepts the API keys that have the scope,
+// the routes of the account itself (profile, password, two-factor, API keys, deletion) accept only the user tokens.
+// The password reset and the two-factor code routes are rate limited per client IP and per target account
+// (the email, the reset token, the login challenge or the user), the counters are kept in Redis.
+// Additionally, it sets up the JWKS route with the public keys of the access tokens and the Swagger documentation route for API exploration.
+func (s *HttpServer) InitRouters(conf *config.HTTPServer, auth *servAuth.Auth, wallet *servWallet.Wallet, orders *servOrders.Orders, holds *servHolds.Holds, scheduler *servScheduler.Scheduler, webhooks *servWebhooks.Webhooks, rates *servRates.Rates, balances *servBalances.Balances, streamConf *config.Streaming, limiter *redis.RedisDB, resetConf *config.PasswordReset, twoFactorConf *config.TwoFactor) {
 	authRouters := s.router.Group(fmt.Sprintf("/api/%s", apiVersion))
 	walletRouters := s.router.Group(fmt.Sprintf("/api/%s", apiVersion))
+	streamRouters := s.router.Group(fmt.Sprintf("/api/%s", apiVersion))
+	adminRouters := s.router.Group(fmt.Sprintf("/api/%s/admin", apiVersion))
 
 	authRouters.Use(handler.TimeoutMiddleware(s.log, conf))
 	authRouters.POST("/register", handlerAuth.Register(s.log, auth))
 	authRouters.POST("/login", handlerAuth.Login(s.log, auth))
+	authRouters.POST("/verify-email", handlerAuth.VerifyEmail(s.log, auth))
+	authRouters.POST("/verify-email/resend", handler.LoggingMiddleware(s.log, auth), handlerAuth.ResendVerification(s.log, auth))
+	authRouters.POST("/password/forgot", handler.RateLimitMiddleware(s.log, limiter, resetConf.RequestLimit, resetConf.RequestWindow, handler.BodyField("email")), handlerAuth.ForgotPassword(s.log, auth))
+	authRouters.POST("/password/reset", handler.RateLimitMiddleware(s.log, limiter, resetConf.RequestLimit, resetConf.RequestWindow, handler.BodyField("token")), handlerAuth.ResetPassword(s.log, auth))
+	authRouters.POST("/login/2fa", handler.RateLimitMiddleware(s.log, limiter, twoFactorConf.RequestLimit, twoFactorConf.RequestWindow, handler.BodyField("challenge")), handlerAuth.LoginTwoFactor(s.log, auth))
+	authRouters.POST("/2fa/enroll", handler.LoggingMiddleware(s.log, auth), handlerAuth.EnrollTwoFactor(s.log, auth))
+	authRouters.POST("/2fa/confirm", handler.LoggingMiddleware(s.log, auth), handler.RateLimitMiddleware(s.log, limiter, twoFactorConf.RequestLimit, twoFactorConf.RequestWindow, handler.UserAccount), handlerAuth.ConfirmTwoFactor(s.log, auth))
+	authRouters.POST("/2fa/disable", handler.LoggingMiddleware(s.log, auth), handler.RateLimitMiddleware(s.log, limiter, twoFactorConf.RequestLimit, twoFactorConf.RequestWindow, handler.UserAccount), handlerAuth.DisableTwoFactor(s.log, auth))
+	authRouters.GET("/me", handler.LoggingMiddleware(s.log, auth), handlerAuth.Me(s.log, auth))
+	authRouters.PATCH("/me", handler.LoggingMiddleware(s.log, auth), handlerAuth.UpdateMe(s.log, auth))
+	authRouters.GET("/me/export", handler.LoggingMiddleware(s.log, auth), handlerAuth.ExportMe(s.log, auth))
+	authRouters.POST("/me/password", handler.LoggingMiddleware(s.log, auth), handlerAuth.ChangePassword(s.log, auth))
+	authRouters.POST("/me/email", handler.LoggingMiddleware(s.log, auth), handlerAuth.ChangeEmail(s.log, auth))
+	authRouters.POST("/api-keys", handler.LoggingMiddleware(s.log, auth), handlerAuth.CreateAPIKey(s.log, auth))
+	authRouters.GET("/api-keys", handler.LoggingMiddleware(s.log, auth), handlerAuth.APIKeys(s.log, auth))
+	authRouters.DELETE("/api-keys/:id", handler.LoggingMiddleware(s.log, auth), handlerAuth.RevokeAPIKey(s.log, auth))
 	authRouters.DELETE("/delete", handler.LoggingMiddleware(s.log, auth), handlerAuth.Delete(s.log, auth))
 
 	walletRouters.Use(handler.TimeoutMiddleware(s.log, conf))
-	walletRouters.Use(handler.LoggingMiddleware(s.log, auth))
-	walletRouters.GET("/balance", handlerWallet.Balance(s.log, wallet))
-	walletRouters.POST("/wallet/deposit", handlerWallet.Deposit(s.log, wallet))
-	walletRouters.POST("/wallet/withdraw", handlerWallet.Withdraw(s.log, wallet))
+	walletRouters.GET("/balance", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeBalanceRead), handlerWallet.Balance(s.log, wallet))
+	walletRouters.POST("/wallet/deposit", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeWalletWrite), handlerWallet.Deposit(s.log, wallet))
+	walletRouters.POST("/wallet/withdraw", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeWalletWrite), handlerWallet.Withdraw(s.log, wallet))
+	walletRouters.GET("/limits", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeBalanceRead), handlerWallet.Limits(s.log, wallet))
+	walletRouters.GET("/statements", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeBalanceRead), handlerWallet.Statement(s.log, wallet))
+	walletRouters.GET("/accounts", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeBalanceRead), handlerWallet.Accounts(s.log, wallet))
+	walletRouters.POST("/accounts", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeWalletWrite), handlerWallet.CreateAccount(s.log, wallet))
+	walletRouters.DELETE("/accounts/:id", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeWalletWrite), handlerWallet.CloseAccount(s.log, wallet))
+	walletRouters.POST("/accounts/transfer", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeWalletWrite), handlerWallet.Transfer(s.log, wallet))
 
-	walletRouters.GET("/exchange/rates", handlerWallet.ExchangeRates(s.log, wallet))
-	walletRouters.POST("/exchange", handlerWallet.Exchange(s.log, wallet))
+	walletRouters.POST("/wallet/holds", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeWalletWrite), handlerWallet.CreateHold(s.log, holds))
+	walletRouters.GET("/wallet/holds", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeBalanceRead), handlerWallet.Holds(s.log, holds))
+	walletRouters.GET("/wallet/holds/:id", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeBalanceRead), handlerWallet.Hold(s.log, holds))
+	walletRouters.POST("/wallet/holds/:id/capture", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeWalletWrite), handlerWallet.CaptureHold(s.log, holds))
+	walletRouters.POST("/wallet/holds/:id/release", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeWalletWrite), handlerWallet.ReleaseHold(s.log, holds))
+
+	walletRouters.GET("/exchange/rates", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeBalanceRead), handlerWallet.ExchangeRates(s.log, wallet))
+	walletRouters.POST("/exchange", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeExchange), handlerWallet.Exchange(s.log, wallet))
+	walletRouters.POST("/exchange/batch", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeExchange), handlerWallet.ExchangeBatch(s.log, wallet))
+
+	streamRouters.Use(handler.LoggingMiddleware(s.log, auth, servAuth.ScopeBalanceRead))
+	streamRouters.GET("/exchange/rates/stream", handlerWallet.RatesStream(s.log, rates, streamConf))
+	streamRouters.GET("/exchange/rates/ws", handlerWallet.RatesSocket(s.log, rates, streamConf))
+	streamRouters.GET("/balance/ws", handlerWallet.BalanceSocket(s.log, balances, streamConf))
+
+	walletRouters.POST("/orders", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeOrders), handlerOrders.CreateOrder(s.log, orders))
+	walletRouters.GET("/orders", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeOrders), handlerOrders.Orders(s.log, orders))
+	walletRouters.GET("/orders/:id", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeOrders), handlerOrders.Order(s.log, orders))
+	walletRouters.DELETE("/orders/:id", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeOrders), handlerOrders.CancelOrder(s.log, orders))
+
+	walletRouters.POST("/schedules", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeSchedules), handlerSchedules.CreateSchedule(s.log, scheduler))
+	walletRouters.GET("/schedules", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeSchedules), handlerSchedules.Schedules(s.log, scheduler))
+	walletRouters.GET("/schedules/:id", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeSchedules), handlerSchedules.Schedule(s.log, scheduler))
+	walletRouters.PATCH("/schedules/:id", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeSchedules), handlerSchedules.UpdateSchedule(s.log, scheduler))
+	walletRouters.DELETE("/schedules/:id", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeSchedules), handlerSchedules.DeleteSchedule(s.log, scheduler))
+
+	walletRouters.POST("/webhooks", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeWebhooks), handlerWebhooks.CreateWebhook(s.log, webhooks))
+	walletRouters.GET("/webhooks", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeWebhooks), handlerWebhooks.Webhooks(s.log, webhooks))
+	walletRouters.DELETE("/webhooks/:id", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeWebhooks), handlerWebhooks.DeleteWebhook(s.log, webhooks))
+	walletRouters.GET("/webhooks/:id/deliveries", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeWebhooks), handlerWebhooks.Deliveries(s.log, webhooks))
+	walletRouters.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", handler.LoggingMiddleware(s.log, auth, servAuth.ScopeWebhooks), handlerWebhooks.Redeliver(s.log, webhooks))
+
+	adminRouters.Use(handler.TimeoutMiddleware(s.log, conf))
+	adminRouters.Use(handler.LoggingMiddleware(s.log, auth, servAuth.ScopeAdmin))
+	adminRouters.Use(handler.AdminMiddleware(s.log))
+	adminRouters.GET("/fees", handlerAdmin.FeeReport(s.log, wallet))
+
+	// the public keys of the access tokens are published at the well-known path of the host, outside the API version
+	s.router.GET("/.well-known/jwks.json", handlerAuth.JWKS(s.log, auth))
 
 	// Swagger documentation route - http://localhost:8000/swagger/index.html
 	s.router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
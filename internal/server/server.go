@@ -21,14 +21,22 @@ type HttpServer struct {
 
 // New creates and returns a new instance of the HttpServer.
 // It initializes the Gin router and sets up the server configuration.
-func New(log *slog.Logger, conf *config.HTTPServer) *HttpServer {
+// The client IP is taken from the X-Forwarded-For and X-Real-IP headers only if the request comes from one of
+// the trusted proxies, without them it is always the address of the connection.
+// If a trusted proxy is not a valid IP or CIDR, it returns an error.
+func New(log *slog.Logger, conf *config.HTTPServer) (*HttpServer, error) {
 	router := gin.Default()
 
+	if err := router.SetTrustedProxies(conf.TrustedProxies); err != nil {
+		log.Error("invalid trusted proxies", "error", err)
+		return nil, err
+	}
+
 	return &HttpServer{
 		router: router,
 		conf:   conf,
 		log:    log,
-	}
+	}, nil
 }
 
 // Start starts the HTTP server and listens for incoming requests.
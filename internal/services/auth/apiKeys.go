@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// The scopes of the API keys, every route that accepts the keys requires one of them.
+const (
+	ScopeBalanceRead = "balance:read"
+	ScopeWalletWrite = "wallet:write"
+	ScopeExchange    = "exchange"
+	ScopeOrders      = "orders"
+	ScopeSchedules   = "schedules"
+	ScopeWebhooks    = "webhooks"
+	ScopeAdmin       = "admin"
+)
+
+// apiKeyPrefix marks the API keys, so that a leaked key is easy to recognise.
+const apiKeyPrefix = "exk_"
+
+// CreateAPIKey creates a new API key of the user with the scopes, the key is returned only once,
+// the database keeps its hash and the visible prefix.
+// The admin scope is allowed only for the admins. If the expiration time is in the past, it returns ErrInvalidExpiry.
+func (a *Auth) CreateAPIKey(ctx context.Context, req models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	op := "service Auth: API key creation"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("CreateAPIKey func call", slog.Any("requets data", req))
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		log.Warn("expiration time is in the past", "expires at", req.ExpiresAt)
+		return nil, ErrInvalidExpiry
+	}
+
+	user, err := a.db.UserByID(ctx, req.UserID)
+	if err != nil {
+		log.Warn("failed to find the user in the database", "error", err)
+		return nil, err
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if scope == ScopeAdmin && user.Role != RoleAdmin {
+			log.Warn("admin scope requested by a user", "user id", req.UserID)
+			return nil, ErrScopeNotAllowed
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	key, prefix, err := newAPIKey()
+	if err != nil {
+		log.Error("failed to generate the API key", "error", err)
+		return nil, err
+	}
+
+	apiKey := &models.APIKey{
+		UserID:    req.UserID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hashToken(key),
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	if err := a.db.CreateAPIKey(ctx, apiKey); err != nil {
+		log.Error("failed to save the API key in the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("API key successfully created", "key id", apiKey.ID, "prefix", prefix)
+	return &models.CreateAPIKeyResponse{
+		Message: "API key successfully created, store the key, it is not shown again",
+		Key:     key,
+		APIKey:  *apiKey,
+	}, nil
+}
+
+// APIKeys returns the API keys of the user including the revoked ones, the keys themselves are not returned.
+func (a *Auth) APIKeys(ctx context.Context, userId uint) (*models.APIKeysResponse, error) {
+	op := "service Auth: list of API keys"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("APIKeys func call", slog.Any("user id", userId))
+
+	keys, err := a.db.UserAPIKeys(ctx, userId)
+	if err != nil {
+		log.Error("failed to get the API keys from the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("API keys successfully received", "count", len(keys))
+	return &models.APIKeysResponse{Message: "API keys successfully received", APIKeys: keys}, nil
+}
+
+// RevokeAPIKey revokes the API key of the user, the requests with it are rejected at once.
+func (a *Auth) RevokeAPIKey(ctx context.Context, req models.APIKeyRequest) error {
+	op := "service Auth: API key revocation"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("RevokeAPIKey func call", slog.Any("requets data", req))
+
+	if err := a.db.RevokeAPIKey(ctx, req.UserID, req.KeyID); err != nil {
+		log.Warn("failed to revoke the API key", "error", err)
+		return err
+	}
+
+	log.Info("API key successfully revoked")
+	return nil
+}
+
+// AuthenticateAPIKey returns the payload of the request made with the API key, the scopes of the key are set in it.
+// If the key is unknown, revoked or expired, it returns ErrInvalidAPIKey.
+func (a *Auth) AuthenticateAPIKey(ctx context.Context, key string) (*models.PayloadToken, error) {
+	op := "service Auth: API key authentication"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("AuthenticateAPIKey func call")
+
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		log.Warn("invalid API key format")
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := a.db.UseAPIKey(ctx, hashToken(key))
+	if err != nil {
+		log.Warn("failed to use the API key", "error", err)
+		return nil, err
+	}
+
+	log.Debug("API key successfully authenticated", "key id", apiKey.ID, "user id", apiKey.UserID)
+	return &models.PayloadToken{UserID: apiKey.UserID, Role: apiKey.Role, Scopes: apiKey.Scopes}, nil
+}
+
+// newAPIKey generates a random API key and its visible prefix, the key is "exk_<prefix>_<secret>".
+func newAPIKey() (string, string, error) {
+	b := make([]byte, 36)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	prefix := apiKeyPrefix + hex.EncodeToString(b[:4])
+	return prefix + "_" + hex.EncodeToString(b[4:]), prefix, nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+func (f *fakeStore) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	key.ID = uint(len(f.apiKeys) + 1)
+	key.CreatedAt = time.Now()
+	copied := *key
+	f.apiKeys = append(f.apiKeys, &copied)
+	return nil
+}
+
+func (f *fakeStore) RevokeAPIKey(ctx context.Context, userId, keyId uint) error {
+	for _, key := range f.apiKeys {
+		if key.ID == keyId && key.UserID == userId && key.RevokedAt == nil {
+			now := time.Now()
+			key.RevokedAt = &now
+			return nil
+		}
+	}
+	return ErrAPIKeyNotFound
+}
+
+func (f *fakeStore) UseAPIKey(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	for _, key := range f.apiKeys {
+		if key.KeyHash == keyHash && key.RevokedAt == nil && (key.ExpiresAt == nil || key.ExpiresAt.After(time.Now())) {
+			copied := *key
+			copied.Role = RoleUser
+			return &copied, nil
+		}
+	}
+	return nil, ErrInvalidAPIKey
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		req     models.CreateAPIKeyRequest
+		wantErr error
+	}{
+		{"valid", models.CreateAPIKeyRequest{UserID: 1, Name: "script", Scopes: []string{ScopeBalanceRead}}, nil},
+		{"with expiration", models.CreateAPIKeyRequest{UserID: 1, Name: "script", Scopes: []string{ScopeExchange}, ExpiresAt: &future}, nil},
+		{"expired", models.CreateAPIKeyRequest{UserID: 1, Name: "script", Scopes: []string{ScopeExchange}, ExpiresAt: &past}, ErrInvalidExpiry},
+		{"admin scope of a user", models.CreateAPIKeyRequest{UserID: 1, Name: "script", Scopes: []string{ScopeBalanceRead, ScopeAdmin}}, ErrScopeNotAllowed},
+		{"unknown user", models.CreateAPIKeyRequest{UserID: 9, Name: "script", Scopes: []string{ScopeBalanceRead}}, ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, store, _ := newProfileTest(t)
+
+			_, err := a.CreateAPIKey(context.Background(), tt.req)
+			if err != tt.wantErr {
+				t.Fatalf("CreateAPIKey() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && len(store.apiKeys) != 0 {
+				t.Fatal("the key is saved after an error")
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	a, store, _ := newProfileTest(t)
+	ctx := context.Background()
+
+	created, err := a.CreateAPIKey(ctx, models.CreateAPIKeyRequest{UserID: 1, Name: "script", Scopes: []string{ScopeBalanceRead, ScopeExchange, ScopeBalanceRead}})
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+
+	if !strings.HasPrefix(created.Key, created.APIKey.Prefix+"_") {
+		t.Fatalf("key %q does not start with the prefix %q", created.Key, created.APIKey.Prefix)
+	}
+	if store.apiKeys[0].KeyHash == created.Key || strings.Contains(store.apiKeys[0].KeyHash, created.Key) {
+		t.Fatal("the key is stored unhashed")
+	}
+	if len(created.APIKey.Scopes) != 2 {
+		t.Fatalf("scopes = %v, the duplicates are not removed", created.APIKey.Scopes)
+	}
+
+	payload, err := a.AuthenticateAPIKey(ctx, created.Key)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey() error = %v", err)
+	}
+	if payload.UserID != 1 || len(payload.Scopes) != 2 {
+		t.Fatalf("payload = %+v, want user 1 with two scopes", payload)
+	}
+
+	for _, key := range []string{"", "exk_unknown", created.Key[4:], created.Key + "0"} {
+		if _, err := a.AuthenticateAPIKey(ctx, key); err != ErrInvalidAPIKey {
+			t.Fatalf("AuthenticateAPIKey(%q) error = %v, want %v", key, err, ErrInvalidAPIKey)
+		}
+	}
+
+	// a key can be revoked only by its user and only once
+	if err := a.RevokeAPIKey(ctx, models.APIKeyRequest{UserID: 2, KeyID: created.APIKey.ID}); err != ErrAPIKeyNotFound {
+		t.Fatalf("RevokeAPIKey() of another user error = %v, want %v", err, ErrAPIKeyNotFound)
+	}
+	if err := a.RevokeAPIKey(ctx, models.APIKeyRequest{UserID: 1, KeyID: created.APIKey.ID}); err != nil {
+		t.Fatalf("RevokeAPIKey() error = %v", err)
+	}
+	if err := a.RevokeAPIKey(ctx, models.APIKeyRequest{UserID: 1, KeyID: created.APIKey.ID}); err != ErrAPIKeyNotFound {
+		t.Fatalf("RevokeAPIKey() twice error = %v, want %v", err, ErrAPIKeyNotFound)
+	}
+
+	if _, err := a.AuthenticateAPIKey(ctx, created.Key); err != ErrInvalidAPIKey {
+		t.Fatalf("AuthenticateAPIKey() of a revoked key error = %v, want %v", err, ErrInvalidAPIKey)
+	}
+}
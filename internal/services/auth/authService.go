@@ -5,36 +5,92 @@ import (
 	"errors"
 	"log/slog"
 
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
 	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages"
 	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/encryption"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/jwtkeys"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/mailer"
 	"github.com/EvansTrein/RESTful_exchangerServer/pkg/utils"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+
+	// the targets of the remaining funds of a deleted user
+	SweepWithdraw = "withdraw"
+	SweepUser     = "user"
+
+	// the operations of the history that move the funds of a deleted user to another user
+	OperationSweepOut = "sweep_out"
+	OperationSweepIn  = "sweep_in"
+)
+
 var (
 	ErrUserNotFound       = errors.New("user not found")
 	ErrEmailAlreadyExists = errors.New("email already exists")
 	ErrInvalidLoginData   = errors.New("invalid email or password")
+	ErrInvalidToken       = errors.New("token is invalid, expired or already used")
+	ErrAlreadyVerified    = errors.New("email is already verified")
+	ErrTooManyRequests    = errors.New("too many requests, try again later")
+	ErrSessionRevoked     = errors.New("session is revoked")
+	ErrInvalidPassword    = errors.New("current password is incorrect")
+	ErrSameEmail          = errors.New("new email is the current email")
+	ErrTwoFactorRequired  = errors.New("two-factor code is required")
+	ErrInvalidTwoFactor   = errors.New("two-factor code is invalid or already used")
+	ErrTwoFactorEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorDisabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorLocked    = errors.New("too many invalid two-factor codes, try again later")
+	ErrInvalidChallenge   = errors.New("login challenge is invalid or expired")
+	ErrAPIKeyNotFound     = errors.New("API key not found")
+	ErrInvalidAPIKey      = errors.New("API key is invalid, expired or revoked")
+	ErrScopeNotAllowed    = errors.New("scope is not allowed for the user")
+	ErrInvalidExpiry      = errors.New("expiration time must be in the future")
+	ErrTokenMalformed     = errors.New("token is malformed")
+	ErrTokenSignature     = errors.New("token signature is invalid")
+	ErrTokenAlgorithm     = errors.New("token signing algorithm is not accepted")
+	ErrTokenExpired       = errors.New("token is expired")
+	ErrTokenNotYetValid   = errors.New("token is not valid yet")
+	ErrTokenClaims        = errors.New("token claims are invalid")
+	ErrFundsRemain        = errors.New("accounts still have funds, name a sweep target")
+	ErrSweepTarget        = errors.New("sweep target user is not found or invalid")
 )
 
 // Auth is a service that handles user authentication and registration.
-// It provides methods for user registration, login, and deletion.
-// The service interacts with the database to store and retrieve user information.
+// It provides methods for user registration, email verification, login, profile management, password reset,
+// two-factor authentication, API keys and deletion. The service interacts with the database to store and retrieve user information
+// and sends the emails through the mailer, the two-factor secrets are encrypted with the cipher.
 type Auth struct {
-	log       *slog.Logger
-	db        storages.StoreAuth
-	secretKey string
+	log          *slog.Logger
+	db           storages.StoreAuth
+	mailer       mailer.Mailer
+	keys         *jwtkeys.Keyring
+	tokens       *config.JWT
+	verification *config.Verification
+	reset        *config.PasswordReset
+	twoFactor    *config.TwoFactor
+	cipher       *encryption.Cipher
 }
 
 // New creates a new instance of the Auth service.
-// It initializes the service with a logger, database storage, and a secret key for token generation.
-func New(log *slog.Logger, db storages.StoreAuth, secretKey string) *Auth {
+// It initializes the service with a logger, database storage, mailer, the keyring and the configuration of the access tokens,
+// the configuration of the email verification, the password reset and the two-factor authentication,
+// and the cipher of the two-factor secrets.
+func New(log *slog.Logger, db storages.StoreAuth, mail mailer.Mailer, keys *jwtkeys.Keyring, tokens *config.JWT, verification *config.Verification, reset *config.PasswordReset, twoFactor *config.TwoFactor, cipher *encryption.Cipher) *Auth {
 	log.Debug("Auth service: started creating")
 
 	log.Info("Auth service: successfully created")
 	return &Auth{
-		log:       log,
-		db:        db,
-		secretKey: secretKey,
+		log:          log,
+		db:           db,
+		mailer:       mail,
+		keys:         keys,
+		tokens:       tokens,
+		verification: verification,
+		reset:        reset,
+		twoFactor:    twoFactor,
+		cipher:       cipher,
 	}
 }
 
@@ -44,6 +100,7 @@ func (a *Auth) Stop() error {
 	a.log.Debug("service Auth: stop started")
 
 	a.db = nil
+	a.mailer = nil
 
 	a.log.Info("service Auth: stop successful")
 	return nil
@@ -52,6 +109,8 @@ func (a *Auth) Stop() error {
 
 // Register handles user registration.
 // It hashes the user's password, stores the user in the database, and returns a response with the user ID.
+// If the verification is required, a verification token is sent to the email, a failed email does not fail
+// the registration, the user can request a new one.
 // If the email already exists, it returns an error.
 func (a *Auth) Register(ctx context.Context, req models.RegisterRequest) (*models.RegisterResponse, error) {
 	op := "service Auth: user registration"
@@ -65,6 +124,7 @@ func (a *Auth) Register(ctx context.Context, req models.RegisterRequest) (*model
 	}
 
 	req.HashPassword = hash
+	req.Verified = !a.verification.Required
 
 	id, err := a.db.CreateUser(ctx, req)
 	if err != nil {
@@ -72,6 +132,12 @@ func (a *Auth) Register(ctx context.Context, req models.RegisterRequest) (*model
 		return nil, err
 	}
 
+	if !req.Verified {
+		if err := a.sendVerification(ctx, id, req.Email); err != nil {
+			log.Error("failed to send the verification email", "user id", id, "error", err)
+		}
+	}
+
 	log.Info("user successfully created")
 	return &models.RegisterResponse{Message: "user successfully created", UserID: id}, nil
 }
@@ -79,6 +145,8 @@ func (a *Auth) Register(ctx context.Context, req models.RegisterRequest) (*model
 
 // Login handles user authentication.
 // It verifies the user's credentials, generates a JWT token, and returns it in the response.
+// If the user has enabled the two-factor authentication, a challenge is returned instead of the token,
+// the token is issued by LoginTwoFactor with the challenge and the code.
 // If the user is not found or the password is incorrect, it returns an error.
 func (a *Auth) Login(ctx context.Context, req models.LoginRequest) (*models.LoginResponse, error) {
 	op := "service Auth: user login"
@@ -100,9 +168,26 @@ func (a *Auth) Login(ctx context.Context, req models.LoginRequest) (*models.Logi
 
 	log.Debug("password has been successfully verified")
 
+	twoFactor, err := a.db.TwoFactor(ctx, user.ID)
+	if err != nil && err != ErrTwoFactorDisabled {
+		log.Error("failed to get the two-factor authentication", "error", err)
+		return nil, err
+	}
+
+	if twoFactor != nil && twoFactor.Enabled {
+		challenge, err := a.generateChallenge(user.ID, user.TokenVersion)
+		if err != nil {
+			log.Error("failed to generate the login challenge", "error", err)
+			return nil, err
+		}
+
+		log.Info("password verified, the two-factor code is required", "user id", user.ID)
+		return &models.LoginResponse{TwoFactorRequired: true, Challenge: challenge}, nil
+	}
+
 	var tokenForUser models.LoginResponse
 
-	token, err := a.GenerateToken(user.ID)
+	token, err := a.GenerateToken(user.ID, user.Role, user.TokenVersion)
 	if err != nil {
 		log.Error("failed to generate token")
 		return nil, err
@@ -117,18 +202,32 @@ func (a *Auth) Login(ctx context.Context, req models.LoginRequest) (*models.Logi
 }
 
 // DeleteUser handles user deletion.
-// It removes the user from the database based on the provided user ID.
+// The user is soft-deleted and anonymized, the history of the operations is kept.
+// If the user has enabled the two-factor authentication, the code must be passed (see VerifyStepUp).
+// If the accounts still have funds, the request must name a sweep target, otherwise it returns ErrFundsRemain.
+// If the sweep target is another user without an email, it returns ErrSweepTarget.
 // If the user is not found, it returns an error.
-func (a *Auth) DeleteUser(ctx context.Context, userId uint) error {
+func (a *Auth) DeleteUser(ctx context.Context, req models.DeleteUserRequest) (*models.DeleteUserResponse, error) {
 	op := "service Auth: delete user"
 	log := a.log.With(slog.String("operation", op))
-	log.Debug("DeleteUser func call", slog.Any("user id", userId))
+	log.Debug("DeleteUser func call", slog.Any("requets data", req))
+
+	if err := a.VerifyStepUp(ctx, req.UserID, req.TwoFactorCode); err != nil {
+		log.Warn("step-up verification failed", "error", err)
+		return nil, err
+	}
 
-	if err := a.db.DeleteUser(ctx, userId); err != nil {
+	if req.SweepTo == SweepUser && req.SweepEmail == "" {
+		log.Warn("sweep target user is not specified")
+		return nil, ErrSweepTarget
+	}
+
+	swept, err := a.db.DeleteUser(ctx, &req)
+	if err != nil {
 		log.Error("failed to delete the user from the database", "error", err)
-		return err
+		return nil, err
 	}
 
 	log.Info("user successfully deleted")
-	return nil
+	return &models.DeleteUserResponse{Message: "user successfully deleted", Swept: swept}, nil
 }
\ No newline at end of file
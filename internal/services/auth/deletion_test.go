@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+func (f *fakeStore) DeleteUser(ctx context.Context, req *models.DeleteUserRequest) (map[string]float32, error) {
+	user, err := f.UserByID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	swept := f.balances[req.UserID]
+	if len(swept) > 0 {
+		switch req.SweepTo {
+		case "":
+			return nil, ErrFundsRemain
+		case SweepUser:
+			target, ok := f.users[req.SweepEmail]
+			if !ok || target.ID == req.UserID {
+				return nil, ErrSweepTarget
+			}
+			if f.balances[target.ID] == nil {
+				f.balances[target.ID] = make(map[string]float32)
+			}
+			for currency, balance := range swept {
+				f.balances[target.ID][currency] += balance
+			}
+		}
+	}
+
+	delete(f.balances, req.UserID)
+	delete(f.users, user.Email)
+	return swept, nil
+}
+
+func (f *fakeStore) ExportUser(ctx context.Context, userId uint) (*models.UserExport, error) {
+	export := &models.UserExport{}
+	for currency, balance := range f.balances[userId] {
+		export.Accounts = append(export.Accounts, models.Account{Currency: currency, Balance: balance, Default: true})
+	}
+	return export, nil
+}
+
+func TestDeleteUser(t *testing.T) {
+	a, store, _ := newProfileTest(t)
+	store.balances = map[uint]map[string]float32{1: {"USD": 100, "EUR": 5}}
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		req  models.DeleteUserRequest
+		want error
+	}{
+		{"funds remain", models.DeleteUserRequest{UserID: 1}, ErrFundsRemain},
+		{"sweep to a user without an email", models.DeleteUserRequest{UserID: 1, SweepTo: SweepUser}, ErrSweepTarget},
+		{"sweep to an unknown user", models.DeleteUserRequest{UserID: 1, SweepTo: SweepUser, SweepEmail: "nobody@example.com"}, ErrSweepTarget},
+		{"sweep to itself", models.DeleteUserRequest{UserID: 1, SweepTo: SweepUser, SweepEmail: "john@example.com"}, ErrSweepTarget},
+		{"sweep to another user", models.DeleteUserRequest{UserID: 1, SweepTo: SweepUser, SweepEmail: "jane@example.com"}, nil},
+		{"already deleted", models.DeleteUserRequest{UserID: 1, SweepTo: SweepWithdraw}, ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := a.DeleteUser(ctx, tt.req); err != tt.want {
+				t.Errorf("DeleteUser() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if got := store.balances[2]["USD"]; got != 100 {
+		t.Errorf("swept USD balance = %v, want 100", got)
+	}
+	if got := store.balances[2]["EUR"]; got != 5 {
+		t.Errorf("swept EUR balance = %v, want 5", got)
+	}
+}
+
+func TestExportUser(t *testing.T) {
+	a, store, _ := newProfileTest(t)
+	store.balances = map[uint]map[string]float32{2: {"USD": 10}}
+
+	export, err := a.ExportUser(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("ExportUser() error = %v", err)
+	}
+	if export.Profile.Email != "jane@example.com" || len(export.Accounts) != 1 || export.Accounts[0].Balance != 10 {
+		t.Errorf("ExportUser() = %+v, want the profile and the balances of jane", export)
+	}
+	if export.ExportedAt.IsZero() {
+		t.Error("ExportUser() did not set the export time")
+	}
+
+	if _, err := a.ExportUser(context.Background(), 3); err != ErrUserNotFound {
+		t.Errorf("ExportUser() of an unknown user error = %v, want %v", err, ErrUserNotFound)
+	}
+}
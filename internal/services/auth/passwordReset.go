@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/mailer"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/utils"
+)
+
+// ForgotPassword sends a password reset token to the email, if a user with this email exists.
+// The response does not tell whether the email is registered: an unknown email, a token requested
+// less than the resend interval ago and a failed email are only logged.
+func (a *Auth) ForgotPassword(ctx context.Context, req models.ForgotPasswordRequest) error {
+	op := "service Auth: forgotten password"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("ForgotPassword func call", slog.Any("requets data", req))
+
+	user, err := a.db.SearchUser(ctx, models.LoginRequest{Email: req.Email})
+	if err != nil {
+		if err == ErrUserNotFound {
+			log.Warn("password reset for an unknown email", "email", req.Email)
+			return nil
+		}
+		log.Error("failed to find the user in the database", "error", err)
+		return err
+	}
+
+	token, hash, err := newToken()
+	if err != nil {
+		log.Error("failed to generate the token", "error", err)
+		return err
+	}
+
+	reset := &models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(a.reset.TokenTTL),
+	}
+
+	if err := a.db.CreatePasswordReset(ctx, reset, a.reset.ResendInterval); err != nil {
+		if err == ErrTooManyRequests {
+			log.Warn("reset token was sent recently", "user id", user.ID)
+			return nil
+		}
+		log.Error("failed to save the reset token", "error", err)
+		return err
+	}
+
+	body := fmt.Sprintf("Set a new password with the token:\n\n%s\n\nThe token is valid for %s. If you did not request it, ignore this email.", token, a.reset.TokenTTL)
+	if a.reset.LinkURL != "" {
+		body = fmt.Sprintf("Set a new password by following the link:\n\n%s%s\n\nThe link is valid for %s. If you did not request it, ignore this email.", a.reset.LinkURL, token, a.reset.TokenTTL)
+	}
+
+	if err := a.mailer.Send(ctx, mailer.Message{To: user.Email, Subject: "Reset your password", Body: body}); err != nil {
+		log.Error("failed to send the reset email", "user id", user.ID, "error", err)
+		return nil
+	}
+
+	log.Info("reset email successfully sent", "user id", user.ID)
+	return nil
+}
+
+// ResetPassword sets a new password of the user with the token from the reset email.
+// The token can be used only once and only before it expires, all access tokens issued before the reset are revoked.
+// If the token is unknown, expired or already used, it returns ErrInvalidToken.
+func (a *Auth) ResetPassword(ctx context.Context, req models.ResetPasswordRequest) error {
+	op := "service Auth: password reset"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("ResetPassword func call")
+
+	hash, err := utils.Hashing(req.Password)
+	if err != nil {
+		log.Error("password hashing failed", "error", err)
+		return err
+	}
+
+	userId, err := a.db.ResetPassword(ctx, hashToken(req.Token), hash)
+	if err != nil {
+		log.Warn("failed to reset the password", "error", err)
+		return err
+	}
+
+	log.Info("password successfully reset", "user id", userId)
+	return nil
+}
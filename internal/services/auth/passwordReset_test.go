@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/utils"
+)
+
+func (f *fakeStore) SearchUser(ctx context.Context, req models.LoginRequest) (*models.User, error) {
+	user, ok := f.users[req.Email]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (f *fakeStore) CreatePasswordReset(ctx context.Context, reset *models.PasswordReset, resendInterval time.Duration) error {
+	f.resets[reset.TokenHash] = reset.UserID
+	return nil
+}
+
+func (f *fakeStore) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (uint, error) {
+	userId, ok := f.resets[tokenHash]
+	if !ok {
+		return 0, ErrInvalidToken
+	}
+	delete(f.resets, tokenHash)
+
+	for _, user := range f.users {
+		if user.ID == userId {
+			user.HashPassword = passwordHash
+			user.TokenVersion++
+		}
+	}
+	return userId, nil
+}
+
+func (f *fakeStore) TokenVersion(ctx context.Context, userId uint) (int, error) {
+	for _, user := range f.users {
+		if user.ID == userId {
+			return user.TokenVersion, nil
+		}
+	}
+	return 0, ErrUserNotFound
+}
+
+func TestPasswordReset(t *testing.T) {
+	hash, err := utils.Hashing("123456")
+	if err != nil {
+		t.Fatalf("Hashing() error = %v", err)
+	}
+
+	store := &fakeStore{
+		users:  map[string]*models.User{"john@example.com": {ID: 1, Email: "john@example.com", HashPassword: hash, Role: RoleUser}},
+		resets: make(map[string]uint),
+	}
+	mail := &fakeMailer{}
+	reset := &config.PasswordReset{TokenTTL: time.Hour, LinkURL: "https://wallet.example.com/reset?token="}
+	a := newTestAuth(t, store, mail, &config.Verification{}, reset)
+	ctx := context.Background()
+
+	// the session issued before the reset
+	login, err := a.Login(ctx, models.LoginRequest{Email: "john@example.com", Password: "123456"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	oldSession := session(t, a, login.Token)
+	if err := a.CheckSession(ctx, oldSession); err != nil {
+		t.Fatalf("CheckSession() before the reset error = %v", err)
+	}
+
+	// an unknown email gets the same answer and no email
+	if err := a.ForgotPassword(ctx, models.ForgotPasswordRequest{Email: "nobody@example.com"}); err != nil {
+		t.Fatalf("ForgotPassword() for an unknown email error = %v", err)
+	}
+	if len(mail.sent) != 0 {
+		t.Fatalf("sent %d emails for an unknown email", len(mail.sent))
+	}
+
+	if err := a.ForgotPassword(ctx, models.ForgotPasswordRequest{Email: "john@example.com"}); err != nil {
+		t.Fatalf("ForgotPassword() error = %v", err)
+	}
+	if len(mail.sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(mail.sent))
+	}
+
+	body := mail.sent[0].Body
+	start := strings.Index(body, reset.LinkURL)
+	if start < 0 {
+		t.Fatalf("email %+v does not contain the link", mail.sent[0])
+	}
+	token := body[start+len(reset.LinkURL) : start+len(reset.LinkURL)+64]
+
+	if _, ok := store.resets[token]; ok {
+		t.Fatal("the token is stored instead of its hash")
+	}
+
+	if err := a.ResetPassword(ctx, models.ResetPasswordRequest{Token: token, Password: "654321"}); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"token is used twice", a.ResetPassword(ctx, models.ResetPasswordRequest{Token: token, Password: "000000"}), ErrInvalidToken},
+		{"session before the reset", a.CheckSession(ctx, oldSession), ErrSessionRevoked},
+		{"old password", loginErr(a, "123456"), ErrInvalidLoginData},
+		{"new password", loginErr(a, "654321"), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err != tt.want {
+				t.Errorf("error = %v, want %v", tt.err, tt.want)
+			}
+		})
+	}
+
+	// the session issued after the reset is valid
+	login, err = a.Login(ctx, models.LoginRequest{Email: "john@example.com", Password: "654321"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if err := a.CheckSession(ctx, session(t, a, login.Token)); err != nil {
+		t.Errorf("CheckSession() after the reset error = %v", err)
+	}
+}
+
+func session(t *testing.T, a *Auth, tokenString string) *models.PayloadToken {
+	payload, err := a.ParseToken(tokenString)
+	if err != nil {
+		t.Fatalf("ParseToken() error = %v", err)
+	}
+	return payload
+}
+
+func loginErr(a *Auth, password string) error {
+	_, err := a.Login(context.Background(), models.LoginRequest{Email: "john@example.com", Password: password})
+	return err
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/utils"
+)
+
+// Profile returns the profile of the user.
+// If the user is not found, it returns ErrUserNotFound.
+func (a *Auth) Profile(ctx context.Context, userId uint) (*models.ProfileResponse, error) {
+	op := "service Auth: getting the profile"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("Profile func call", slog.Any("user id", userId))
+
+	user, err := a.db.UserByID(ctx, userId)
+	if err != nil {
+		log.Warn("failed to find the user in the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("profile successfully received")
+	return profile(user), nil
+}
+
+// UpdateProfile changes the name and the display currency of the user, only the passed fields are changed.
+// If the user is not found, it returns ErrUserNotFound, if the currency is unknown, the storage returns ErrCurrencyNotFound.
+func (a *Auth) UpdateProfile(ctx context.Context, req *models.UpdateProfileRequest) (*models.ProfileResponse, error) {
+	op := "service Auth: updating the profile"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("UpdateProfile func call", slog.Any("requets data", req))
+
+	user, err := a.db.UpdateProfile(ctx, req)
+	if err != nil {
+		log.Warn("failed to update the profile", "error", err)
+		return nil, err
+	}
+
+	log.Info("profile successfully updated")
+	return profile(user), nil
+}
+
+// ChangePassword sets a new password of the user, the current password must be passed.
+// All access tokens issued before the change are revoked, a new token is returned, so that the client stays logged in.
+// If the current password is wrong, it returns ErrInvalidPassword.
+func (a *Auth) ChangePassword(ctx context.Context, req *models.ChangePasswordRequest) (*models.LoginResponse, error) {
+	op := "service Auth: changing the password"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("ChangePassword func call", slog.Any("user id", req.UserID))
+
+	user, err := a.db.UserByID(ctx, req.UserID)
+	if err != nil {
+		log.Warn("failed to find the user in the database", "error", err)
+		return nil, err
+	}
+
+	if !utils.CheckHashing(req.CurrentPassword, user.HashPassword) {
+		log.Warn("incorrect current password", "user id", req.UserID)
+		return nil, ErrInvalidPassword
+	}
+
+	hash, err := utils.Hashing(req.NewPassword)
+	if err != nil {
+		log.Error("password hashing failed", "error", err)
+		return nil, err
+	}
+
+	version, err := a.db.ChangePassword(ctx, req.UserID, hash)
+	if err != nil {
+		log.Error("failed to change the password", "error", err)
+		return nil, err
+	}
+
+	token, err := a.GenerateToken(user.ID, user.Role, version)
+	if err != nil {
+		log.Error("failed to generate token", "error", err)
+		return nil, err
+	}
+
+	log.Info("password successfully changed", "user id", req.UserID)
+	return &models.LoginResponse{Token: token}, nil
+}
+
+// ChangeEmail sends a verification token to the new email of the user, the current password must be passed.
+// The email of the user is changed only when the token is used, until then the current email stays.
+// If the current password is wrong, it returns ErrInvalidPassword. If the email is the current one, it returns ErrSameEmail,
+// if it belongs to another user, it returns ErrEmailAlreadyExists.
+// If a verification token was sent less than the resend interval ago, it returns ErrTooManyRequests.
+func (a *Auth) ChangeEmail(ctx context.Context, req *models.ChangeEmailRequest) error {
+	op := "service Auth: changing the email"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("ChangeEmail func call", "user id", req.UserID, "email", req.Email)
+
+	user, err := a.db.UserByID(ctx, req.UserID)
+	if err != nil {
+		log.Warn("failed to find the user in the database", "error", err)
+		return err
+	}
+
+	if !utils.CheckHashing(req.Password, user.HashPassword) {
+		log.Warn("incorrect current password", "user id", req.UserID)
+		return ErrInvalidPassword
+	}
+
+	if req.Email == user.Email {
+		log.Warn("new email is the current email", "user id", req.UserID)
+		return ErrSameEmail
+	}
+
+	// the email is checked again when the token is used, another user could take it in the meantime
+	if _, err := a.db.SearchUser(ctx, models.LoginRequest{Email: req.Email}); err != ErrUserNotFound {
+		if err == nil {
+			log.Warn("email belongs to another user", "email", req.Email)
+			return ErrEmailAlreadyExists
+		}
+		log.Error("failed to check the email", "error", err)
+		return err
+	}
+
+	if err := a.sendVerification(ctx, user.ID, req.Email); err != nil {
+		log.Warn("failed to send the verification email", "error", err)
+		return err
+	}
+
+	log.Info("verification email sent to the new email", "user id", req.UserID)
+	return nil
+}
+
+// ExportUser returns all data of the user as one archive: the profile, the balances, the history of the operations,
+// the orders, the schedules, the webhooks and the API keys.
+// If the user is not found, it returns ErrUserNotFound.
+func (a *Auth) ExportUser(ctx context.Context, userId uint) (*models.UserExport, error) {
+	op := "service Auth: exporting the user data"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("ExportUser func call", slog.Any("user id", userId))
+
+	user, err := a.db.UserByID(ctx, userId)
+	if err != nil {
+		log.Warn("failed to find the user in the database", "error", err)
+		return nil, err
+	}
+
+	export, err := a.db.ExportUser(ctx, userId)
+	if err != nil {
+		log.Error("failed to export the user data", "error", err)
+		return nil, err
+	}
+
+	export.Profile = *profile(user)
+	export.ExportedAt = time.Now().UTC()
+
+	log.Info("user data successfully exported")
+	return export, nil
+}
+
+// profile converts the user to the profile, the password hash and the token version are not returned.
+func profile(user *models.User) *models.ProfileResponse {
+	return &models.ProfileResponse{
+		ID:              user.ID,
+		Name:            user.Name,
+		Email:           user.Email,
+		EmailVerified:   user.EmailVerified,
+		DisplayCurrency: user.DisplayCurrency,
+		Role:            user.Role,
+		Segment:         user.Segment,
+	}
+}
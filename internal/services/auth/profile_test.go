@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/utils"
+)
+
+func (f *fakeStore) UserByID(ctx context.Context, userId uint) (*models.User, error) {
+	for _, user := range f.users {
+		if user.ID == userId {
+			return user, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (f *fakeStore) ChangePassword(ctx context.Context, userId uint, passwordHash string) (int, error) {
+	user, err := f.UserByID(ctx, userId)
+	if err != nil {
+		return 0, err
+	}
+	user.HashPassword = passwordHash
+	user.TokenVersion++
+	return user.TokenVersion, nil
+}
+
+func newProfileTest(t *testing.T) (*Auth, *fakeStore, *fakeMailer) {
+	hash, err := utils.Hashing("123456")
+	if err != nil {
+		t.Fatalf("Hashing() error = %v", err)
+	}
+
+	store := &fakeStore{
+		users: map[string]*models.User{
+			"john@example.com": {ID: 1, Email: "john@example.com", HashPassword: hash, Role: RoleUser, EmailVerified: true},
+			"jane@example.com": {ID: 2, Email: "jane@example.com", HashPassword: hash, Role: RoleUser, EmailVerified: true},
+		},
+		verified: make(map[string]uint),
+	}
+	mail := &fakeMailer{}
+	verification := &config.Verification{Required: true, TokenTTL: time.Hour}
+	a := newTestAuth(t, store, mail, verification, &config.PasswordReset{})
+	return a, store, mail
+}
+
+func TestChangePassword(t *testing.T) {
+	a, _, _ := newProfileTest(t)
+	ctx := context.Background()
+
+	login, err := a.Login(ctx, models.LoginRequest{Email: "john@example.com", Password: "123456"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	oldSession := session(t, a, login.Token)
+
+	if _, err := a.ChangePassword(ctx, &models.ChangePasswordRequest{UserID: 1, CurrentPassword: "wrong", NewPassword: "654321"}); err != ErrInvalidPassword {
+		t.Fatalf("ChangePassword() with a wrong password error = %v, want %v", err, ErrInvalidPassword)
+	}
+	if err := a.CheckSession(ctx, oldSession); err != nil {
+		t.Fatalf("a failed change revoked the session: %v", err)
+	}
+
+	resp, err := a.ChangePassword(ctx, &models.ChangePasswordRequest{UserID: 1, CurrentPassword: "123456", NewPassword: "654321"})
+	if err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+
+	if err := a.CheckSession(ctx, oldSession); err != ErrSessionRevoked {
+		t.Errorf("CheckSession() of the old token error = %v, want %v", err, ErrSessionRevoked)
+	}
+	if err := a.CheckSession(ctx, session(t, a, resp.Token)); err != nil {
+		t.Errorf("CheckSession() of the new token error = %v", err)
+	}
+	if err := loginErr(a, "654321"); err != nil {
+		t.Errorf("Login() with the new password error = %v", err)
+	}
+}
+
+func TestChangeEmail(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		want     error
+	}{
+		{"wrong password", "john.new@example.com", "wrong", ErrInvalidPassword},
+		{"same email", "john@example.com", "123456", ErrSameEmail},
+		{"email of another user", "jane@example.com", "123456", ErrEmailAlreadyExists},
+		{"new email", "john.new@example.com", "123456", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, store, mail := newProfileTest(t)
+
+			err := a.ChangeEmail(context.Background(), &models.ChangeEmailRequest{UserID: 1, Email: tt.email, Password: tt.password})
+			if err != tt.want {
+				t.Fatalf("ChangeEmail() error = %v, want %v", err, tt.want)
+			}
+
+			if tt.want != nil {
+				if len(mail.sent) != 0 || len(store.verifications) != 0 {
+					t.Errorf("verification sent after a failed change")
+				}
+				return
+			}
+
+			// the token goes to the new email, the current email stays until it is confirmed
+			if len(mail.sent) != 1 || mail.sent[0].To != tt.email {
+				t.Fatalf("sent %+v, want one email to %s", mail.sent, tt.email)
+			}
+			if store.verifications[0].Email != tt.email || store.verifications[0].UserID != 1 {
+				t.Errorf("verification = %+v, want the new email of the user 1", store.verifications[0])
+			}
+			if store.users["john@example.com"].Email != "john@example.com" {
+				t.Errorf("email changed before the verification")
+			}
+		})
+	}
+}
@@ -1,63 +1,183 @@
 package services
 
 import (
-	"fmt"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/jwtkeys"
 	"github.com/golang-jwt/jwt"
 )
 
+// Claims are the claims of the tokens issued by the service, the subject is the ID of the user.
+// The tokens of the first login step with the two-factor authentication have the Purpose, they are not access tokens.
+type Claims struct {
+	Role         string `json:"role,omitempty"`
+	TokenVersion int    `json:"tokenVersion"`
+	Purpose      string `json:"purpose,omitempty"`
+	jwt.StandardClaims
+}
+
 // GenerateToken generates a JWT token for the given user ID.
-// The token includes the user ID and an expiration time.
-// It is signed using the service's secret key.
-func (a *Auth) GenerateToken(id uint) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userID": id,
-		"exp":    time.Now().Add(time.Minute * 60).Unix(),
-	})
-
-	signedToken, err := token.SignedString([]byte(a.secretKey))
+// The token includes the user ID as the subject, the user role, the token version of the user, the issuer, the audience,
+// the issue time and an expiration time, every token gets its own ID.
+// It is signed with the active key of the keyring, its kid is set in the header.
+func (a *Auth) GenerateToken(id uint, role string, tokenVersion int) (string, error) {
+	return a.signToken(id, Claims{Role: role, TokenVersion: tokenVersion}, a.tokens.TTL)
+}
+
+// ParseToken parses and validates a JWT access token and returns its payload.
+// The signature is verified with the key of the keyring named by the kid header, the algorithm must be the one of the key.
+// The claims are validated with the allowed clock skew, see validateClaims.
+// Every failure has its own error: ErrTokenMalformed, ErrTokenSignature, ErrTokenAlgorithm, ErrTokenExpired,
+// ErrTokenNotYetValid or ErrTokenClaims, the login challenges are rejected with ErrTokenClaims.
+func (a *Auth) ParseToken(tokenString string) (*models.PayloadToken, error) {
+	claims, userId, err := a.parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != "" || claims.Role == "" {
+		return nil, ErrTokenClaims
+	}
+
+	return &models.PayloadToken{UserID: userId, Role: claims.Role, TokenVersion: claims.TokenVersion}, nil
+}
+
+// JWKS returns the public keys that verify the access tokens, so that other services can verify them.
+func (a *Auth) JWKS() models.JWKS {
+	return a.keys.JWKS()
+}
+
+// CheckSession verifies that the token has not been revoked, the token version must be the current version of the user.
+// If the version is outdated or the user no longer exists, it returns ErrSessionRevoked.
+func (a *Auth) CheckSession(ctx context.Context, payload *models.PayloadToken) error {
+	version, err := a.db.TokenVersion(ctx, payload.UserID)
+	if err != nil {
+		if err == ErrUserNotFound {
+			return ErrSessionRevoked
+		}
+		return err
+	}
+
+	if version != payload.TokenVersion {
+		return ErrSessionRevoked
+	}
+
+	return nil
+}
+
+// challengePurpose marks the tokens of the first login step, they are not accepted as access tokens
+const challengePurpose = "login_2fa"
+
+// generateChallenge generates a short-lived token of the first login step of a user with the two-factor authentication.
+// It has the purpose claim, so it can not be used as an access token.
+func (a *Auth) generateChallenge(id uint, tokenVersion int) (string, error) {
+	return a.signToken(id, Claims{TokenVersion: tokenVersion, Purpose: challengePurpose}, a.twoFactor.ChallengeTTL)
+}
+
+// parseChallenge validates the challenge token and returns the user ID and the token version it was issued for.
+func (a *Auth) parseChallenge(tokenString string) (uint, int, error) {
+	claims, userId, err := a.parseClaims(tokenString)
 	if err != nil {
+		return 0, 0, err
+	}
+
+	if claims.Purpose != challengePurpose {
+		return 0, 0, ErrTokenClaims
+	}
+
+	return userId, claims.TokenVersion, nil
+}
+
+// signToken sets the registered claims of the token of the user valid for ttl and signs it.
+func (a *Auth) signToken(id uint, claims Claims, ttl time.Duration) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
 		return "", err
 	}
 
-	return signedToken, nil
+	now := time.Now()
+	claims.StandardClaims = jwt.StandardClaims{
+		Id:        hex.EncodeToString(jti),
+		Issuer:    a.tokens.Issuer,
+		Audience:  a.tokens.Audience,
+		Subject:   strconv.FormatUint(uint64(id), 10),
+		IssuedAt:  now.Unix(),
+		NotBefore: now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+
+	return a.keys.Sign(claims)
 }
 
-// ParseToken parses and validates a JWT token.
-// It checks the token's signing method and verifies its signature using the service's secret key.
-// If the token is expired, it still returns the token for further processing.
-func (a *Auth) ParseToken(tokenString string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("token unexpected signing method: %v", token.Header["alg"])
+// parseClaims verifies the signature of the token and validates its claims, it returns the claims and the user ID.
+// The errors of the jwt package are mapped to the errors of the service.
+func (a *Auth) parseClaims(tokenString string) (*Claims, uint, error) {
+	var claims Claims
+
+	// the registered claims are validated by validateClaims with the clock skew
+	parser := jwt.Parser{SkipClaimsValidation: true}
+	if _, err := parser.ParseWithClaims(tokenString, &claims, a.keys.Keyfunc); err != nil {
+		var validationErr *jwt.ValidationError
+		if !errors.As(err, &validationErr) {
+			return nil, 0, ErrTokenMalformed
 		}
-		return []byte(a.secretKey), nil
-	})
 
-	// skip, if the token has already expired, we need to return the token and work with it elsewhere.
-	// but if the error is different, we return this error
-	if err != nil && err.Error() != "Token is expired" {
-		return nil, err
+		switch {
+		case validationErr.Errors&jwt.ValidationErrorMalformed != 0:
+			return nil, 0, ErrTokenMalformed
+		case validationErr.Inner == jwtkeys.ErrUnknownKey:
+			return nil, 0, ErrTokenSignature
+		case validationErr.Errors&jwt.ValidationErrorUnverifiable != 0:
+			// an unexpected algorithm of the key or an algorithm the jwt package does not know
+			return nil, 0, ErrTokenAlgorithm
+		case validationErr.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+			return nil, 0, ErrTokenSignature
+		default:
+			return nil, 0, ErrTokenMalformed
+		}
+	}
+
+	userId, err := a.validateClaims(&claims, time.Now())
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return token, nil
+	return &claims, userId, nil
 }
 
-// TokenPayloadExtraction extracts the user ID from the JWT token's claims.
-// It returns a PayloadToken struct containing the user ID.
-// If the claims are invalid or the user ID is missing, it returns an error.
-func (a *Auth) TokenPayloadExtraction(token *jwt.Token) (*models.PayloadToken, error) {
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, fmt.Errorf("failed to parse token claims")
+// validateClaims validates the registered claims of the token at the time now, it returns the user ID of the subject.
+// The issuer and the audience must be the configured ones, the token ID, the issue and the expiration time are required.
+// The times are compared with the allowed clock skew of the configuration.
+func (a *Auth) validateClaims(claims *Claims, now time.Time) (uint, error) {
+	skew := int64(a.tokens.ClockSkew / time.Second)
+	unix := now.Unix()
+
+	userId, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil || userId == 0 {
+		return 0, ErrTokenClaims
+	}
+
+	if claims.Issuer != a.tokens.Issuer || claims.Audience != a.tokens.Audience || claims.Id == "" {
+		return 0, ErrTokenClaims
+	}
+
+	if claims.ExpiresAt == 0 || claims.IssuedAt == 0 {
+		return 0, ErrTokenClaims
+	}
+
+	if unix > claims.ExpiresAt+skew {
+		return 0, ErrTokenExpired
 	}
 
-	userId, ok := claims["userID"].(float64) 
-	if !ok {
-		return nil, fmt.Errorf("failed to extract userID from token")
+	if claims.IssuedAt > unix+skew || claims.NotBefore > unix+skew {
+		return 0, ErrTokenNotYetValid
 	}
 
-	return &models.PayloadToken{UserID: uint(userId)}, nil
+	return uint(userId), nil
 }
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func TestParseToken(t *testing.T) {
+	a, _, _ := newProfileTest(t)
+
+	valid, err := a.GenerateToken(1, RoleUser, 0)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	// sign builds a token of the user 1 with changed claims
+	sign := func(change func(c *Claims)) string {
+		now := time.Now()
+		claims := Claims{Role: RoleUser, StandardClaims: jwt.StandardClaims{
+			Id:        "jti",
+			Issuer:    a.tokens.Issuer,
+			Audience:  a.tokens.Audience,
+			Subject:   "1",
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(time.Hour).Unix(),
+		}}
+		change(&claims)
+
+		token, err := a.keys.Sign(claims)
+		if err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+		return token
+	}
+
+	signWith := func(method jwt.SigningMethod, key interface{}) string {
+		token, err := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "1", "role": RoleUser}).SignedString(key)
+		if err != nil {
+			t.Fatalf("SignedString() error = %v", err)
+		}
+		return token
+	}
+
+	parts := strings.Split(valid, ".")
+	other := sign(func(c *Claims) { c.Role = RoleAdmin })
+	otherParts := strings.Split(other, ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"valid", valid, nil},
+		{"expired within the skew", sign(func(c *Claims) { c.ExpiresAt = time.Now().Add(-10 * time.Second).Unix() }), nil},
+		{"issued within the skew", sign(func(c *Claims) { c.IssuedAt = time.Now().Add(10 * time.Second).Unix() }), nil},
+		{"garbage", "not.a.token", ErrTokenMalformed},
+		{"empty", "", ErrTokenMalformed},
+		{"tampered payload", parts[0] + "." + otherParts[1] + "." + parts[2], ErrTokenSignature},
+		{"tampered signature", parts[0] + "." + parts[1] + "." + otherParts[2], ErrTokenSignature},
+		{"wrong algorithm", signWith(jwt.SigningMethodHS512, []byte("secret")), ErrTokenAlgorithm},
+		{"none algorithm", signWith(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType), ErrTokenAlgorithm},
+		{"expired", sign(func(c *Claims) { c.ExpiresAt = time.Now().Add(-time.Minute).Unix() }), ErrTokenExpired},
+		{"not before in the future", sign(func(c *Claims) { c.NotBefore = time.Now().Add(time.Minute).Unix() }), ErrTokenNotYetValid},
+		{"issued in the future", sign(func(c *Claims) { c.IssuedAt = time.Now().Add(time.Minute).Unix() }), ErrTokenNotYetValid},
+		{"wrong issuer", sign(func(c *Claims) { c.Issuer = "someone" }), ErrTokenClaims},
+		{"wrong audience", sign(func(c *Claims) { c.Audience = "other-api" }), ErrTokenClaims},
+		{"without token id", sign(func(c *Claims) { c.Id = "" }), ErrTokenClaims},
+		{"without expiration", sign(func(c *Claims) { c.ExpiresAt = 0 }), ErrTokenClaims},
+		{"without subject", sign(func(c *Claims) { c.Subject = "" }), ErrTokenClaims},
+		{"invalid subject", sign(func(c *Claims) { c.Subject = "john" }), ErrTokenClaims},
+		{"without role", sign(func(c *Claims) { c.Role = "" }), ErrTokenClaims},
+		{"login challenge", sign(func(c *Claims) { c.Purpose = challengePurpose }), ErrTokenClaims},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := a.ParseToken(tt.token)
+			if err != tt.wantErr {
+				t.Fatalf("ParseToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (payload.UserID != 1 || payload.Role != RoleUser) {
+				t.Fatalf("ParseToken() = %+v, want the user 1", payload)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/totp"
+)
+
+// codeSkew is the number of time steps before and after the current one whose codes are accepted
+const codeSkew = 1
+
+// recoveryEncoding encodes the recovery codes, the lowercase letters and digits are easy to type
+var recoveryEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// EnrollTwoFactor generates a new TOTP secret of the user and returns it with the provisioning URI for the authenticator app.
+// The secret is stored encrypted and is enabled only after the first code is confirmed with ConfirmTwoFactor.
+// If the two-factor authentication is already enabled, it returns ErrTwoFactorEnabled.
+func (a *Auth) EnrollTwoFactor(ctx context.Context, userId uint) (*models.TwoFactorEnrollResponse, error) {
+	op := "service Auth: two-factor enrollment"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("EnrollTwoFactor func call", slog.Any("user id", userId))
+
+	user, err := a.db.UserByID(ctx, userId)
+	if err != nil {
+		log.Warn("failed to find the user in the database", "error", err)
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Error("failed to generate the secret", "error", err)
+		return nil, err
+	}
+
+	encrypted, err := a.cipher.Encrypt([]byte(secret))
+	if err != nil {
+		log.Error("failed to encrypt the secret", "error", err)
+		return nil, err
+	}
+
+	if err := a.db.SaveTwoFactorSecret(ctx, userId, encrypted); err != nil {
+		log.Warn("failed to save the secret", "error", err)
+		return nil, err
+	}
+
+	log.Info("two-factor enrollment started", "user id", userId)
+	return &models.TwoFactorEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(a.twoFactor.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables the two-factor authentication with the first code from the authenticator app
+// and returns the recovery codes, they are shown only once.
+// If there is no enrollment, it returns ErrTwoFactorDisabled, if it is already enabled, it returns ErrTwoFactorEnabled.
+// If the code is wrong, it returns ErrInvalidTwoFactor, after too many wrong codes it returns ErrTwoFactorLocked.
+func (a *Auth) ConfirmTwoFactor(ctx context.Context, req *models.TwoFactorCodeRequest) (*models.TwoFactorConfirmResponse, error) {
+	op := "service Auth: two-factor confirmation"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("ConfirmTwoFactor func call", slog.Any("user id", req.UserID))
+
+	twoFactor, err := a.db.TwoFactor(ctx, req.UserID)
+	if err != nil {
+		log.Warn("failed to get the two-factor authentication", "error", err)
+		return nil, err
+	}
+
+	if twoFactor.Enabled {
+		log.Warn("two-factor authentication is already enabled", "user id", req.UserID)
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := a.cipher.Decrypt(twoFactor.Secret)
+	if err != nil {
+		log.Error("failed to decrypt the secret", "error", err)
+		return nil, err
+	}
+
+	if err := a.db.CountTwoFactorAttempt(ctx, req.UserID, a.twoFactor.MaxFailures, a.twoFactor.Lockout); err != nil {
+		log.Warn("failed to count the two-factor attempt", "error", err)
+		return nil, err
+	}
+
+	step, ok := totp.Validate(string(secret), req.Code, time.Now(), codeSkew)
+	if !ok {
+		log.Warn("invalid two-factor code", "user id", req.UserID)
+		return nil, ErrInvalidTwoFactor
+	}
+
+	codes, hashes, err := newRecoveryCodes(a.twoFactor.RecoveryCodes)
+	if err != nil {
+		log.Error("failed to generate the recovery codes", "error", err)
+		return nil, err
+	}
+
+	if err := a.db.EnableTwoFactor(ctx, req.UserID, step, hashes); err != nil {
+		log.Warn("failed to enable the two-factor authentication", "error", err)
+		return nil, err
+	}
+
+	if err := a.db.ResetTwoFactorFailures(ctx, req.UserID); err != nil {
+		log.Warn("failed to reset the failed two-factor attempts", "error", err)
+		return nil, err
+	}
+
+	log.Info("two-factor authentication enabled", "user id", req.UserID)
+	return &models.TwoFactorConfirmResponse{Message: "two-factor authentication enabled", RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns the two-factor authentication off, a code or a recovery code must be passed.
+// If it is not enabled, it returns ErrTwoFactorDisabled, if the code is wrong, it returns ErrInvalidTwoFactor,
+// after too many wrong codes it returns ErrTwoFactorLocked.
+func (a *Auth) DisableTwoFactor(ctx context.Context, req *models.TwoFactorCodeRequest) error {
+	op := "service Auth: disabling the two-factor authentication"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("DisableTwoFactor func call", slog.Any("user id", req.UserID))
+
+	twoFactor, err := a.db.TwoFactor(ctx, req.UserID)
+	if err != nil {
+		log.Warn("failed to get the two-factor authentication", "error", err)
+		return err
+	}
+
+	if !twoFactor.Enabled {
+		log.Warn("two-factor authentication is not enabled", "user id", req.UserID)
+		return ErrTwoFactorDisabled
+	}
+
+	if err := a.checkCode(ctx, twoFactor, req.Code); err != nil {
+		log.Warn("failed to check the two-factor code", "error", err)
+		return err
+	}
+
+	if err := a.db.DisableTwoFactor(ctx, req.UserID); err != nil {
+		log.Warn("failed to disable the two-factor authentication", "error", err)
+		return err
+	}
+
+	log.Info("two-factor authentication disabled", "user id", req.UserID)
+	return nil
+}
+
+// VerifyStepUp checks the code of a sensitive operation of the user, it passes if the user has not enabled
+// the two-factor authentication. If the code is missing, it returns ErrTwoFactorRequired,
+// if it is wrong or already used, it returns ErrInvalidTwoFactor, after too many wrong codes it returns ErrTwoFactorLocked.
+func (a *Auth) VerifyStepUp(ctx context.Context, userId uint, code string) error {
+	op := "service Auth: step-up verification"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("VerifyStepUp func call", slog.Any("user id", userId))
+
+	twoFactor, err := a.db.TwoFactor(ctx, userId)
+	if err != nil {
+		if err == ErrTwoFactorDisabled {
+			return nil
+		}
+		log.Error("failed to get the two-factor authentication", "error", err)
+		return err
+	}
+
+	if !twoFactor.Enabled {
+		return nil
+	}
+
+	if code == "" {
+		log.Warn("two-factor code is required", "user id", userId)
+		return ErrTwoFactorRequired
+	}
+
+	if err := a.checkCode(ctx, twoFactor, code); err != nil {
+		log.Warn("failed to check the two-factor code", "error", err)
+		return err
+	}
+
+	log.Info("step-up verification passed", "user id", userId)
+	return nil
+}
+
+// LoginTwoFactor is the second step of the login of a user with the two-factor authentication.
+// It checks the challenge from the first step and the code, then issues the token.
+// If the challenge is invalid, expired or the password has been changed since it was issued, it returns ErrInvalidChallenge.
+// If the code is wrong or already used, it returns ErrInvalidTwoFactor, after too many wrong codes it returns ErrTwoFactorLocked.
+func (a *Auth) LoginTwoFactor(ctx context.Context, req models.LoginTwoFactorRequest) (*models.LoginResponse, error) {
+	op := "service Auth: two-factor login"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("LoginTwoFactor func call")
+
+	userId, tokenVersion, err := a.parseChallenge(req.Challenge)
+	if err != nil {
+		log.Warn("invalid login challenge", "error", err)
+		return nil, ErrInvalidChallenge
+	}
+
+	user, err := a.db.UserByID(ctx, userId)
+	if err != nil {
+		if err == ErrUserNotFound {
+			log.Warn("user of the challenge no longer exists", "user id", userId)
+			return nil, ErrInvalidChallenge
+		}
+		log.Error("failed to find the user in the database", "error", err)
+		return nil, err
+	}
+
+	if user.TokenVersion != tokenVersion {
+		log.Warn("challenge was issued before the password change", "user id", userId)
+		return nil, ErrInvalidChallenge
+	}
+
+	if err := a.VerifyStepUp(ctx, userId, req.Code); err != nil {
+		log.Warn("failed to check the two-factor code", "error", err)
+		return nil, err
+	}
+
+	token, err := a.GenerateToken(user.ID, user.Role, user.TokenVersion)
+	if err != nil {
+		log.Error("failed to generate token", "error", err)
+		return nil, err
+	}
+
+	log.Info("two-factor login successful", "user id", userId)
+	return &models.LoginResponse{Token: token}, nil
+}
+
+// checkCode accepts a TOTP code of a not yet used time step or an unused recovery code.
+// Every attempt is counted before the code is checked and the count is reset by an accepted code,
+// after MaxFailures attempts in a row the codes of the user are locked for Lockout with ErrTwoFactorLocked,
+// so the few codes valid at once can not be guessed from many sessions or IPs.
+func (a *Auth) checkCode(ctx context.Context, twoFactor *models.TwoFactor, code string) error {
+	if err := a.db.CountTwoFactorAttempt(ctx, twoFactor.UserID, a.twoFactor.MaxFailures, a.twoFactor.Lockout); err != nil {
+		return err
+	}
+
+	if err := a.useCode(ctx, twoFactor, code); err != nil {
+		return err
+	}
+
+	return a.db.ResetTwoFactorFailures(ctx, twoFactor.UserID)
+}
+
+// useCode accepts a TOTP code or a recovery code of the user, every code is accepted once.
+func (a *Auth) useCode(ctx context.Context, twoFactor *models.TwoFactor, code string) error {
+	if len(code) == totp.Digits {
+		secret, err := a.cipher.Decrypt(twoFactor.Secret)
+		if err != nil {
+			return err
+		}
+
+		step, ok := totp.Validate(string(secret), code, time.Now(), codeSkew)
+		if !ok || step <= twoFactor.LastUsedStep {
+			return ErrInvalidTwoFactor
+		}
+
+		return a.db.UseTwoFactorStep(ctx, twoFactor.UserID, step)
+	}
+
+	return a.db.UseRecoveryCode(ctx, twoFactor.UserID, hashToken(normalizeRecoveryCode(code)))
+}
+
+// newRecoveryCodes generates n recovery codes in the form "xxxxx-xxxxx" and their SHA-256 hashes for the database.
+func newRecoveryCodes(n int) ([]string, []string, error) {
+	codes := make([]string, 0, n)
+	hashes := make([]string, 0, n)
+
+	for range n {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := recoveryEncoding.EncodeToString(b)[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode removes the separators and the spaces the user may type with the code.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/totp"
+)
+
+func (f *fakeStore) TwoFactor(ctx context.Context, userId uint) (*models.TwoFactor, error) {
+	twoFactor, ok := f.twoFactor[userId]
+	if !ok {
+		return nil, ErrTwoFactorDisabled
+	}
+	copied := *twoFactor
+	return &copied, nil
+}
+
+func (f *fakeStore) SaveTwoFactorSecret(ctx context.Context, userId uint, secret string) error {
+	if twoFactor, ok := f.twoFactor[userId]; ok && twoFactor.Enabled {
+		return ErrTwoFactorEnabled
+	}
+	f.twoFactor[userId] = &models.TwoFactor{UserID: userId, Secret: secret}
+	return nil
+}
+
+func (f *fakeStore) EnableTwoFactor(ctx context.Context, userId uint, step int64, recoveryCodeHashes []string) error {
+	f.twoFactor[userId].Enabled = true
+	f.twoFactor[userId].LastUsedStep = step
+	for _, hash := range recoveryCodeHashes {
+		f.recovery[hash] = true
+	}
+	return nil
+}
+
+func (f *fakeStore) UseTwoFactorStep(ctx context.Context, userId uint, step int64) error {
+	if f.twoFactor[userId].LastUsedStep >= step {
+		return ErrInvalidTwoFactor
+	}
+	f.twoFactor[userId].LastUsedStep = step
+	return nil
+}
+
+func (f *fakeStore) UseRecoveryCode(ctx context.Context, userId uint, codeHash string) error {
+	if !f.recovery[codeHash] {
+		return ErrInvalidTwoFactor
+	}
+	delete(f.recovery, codeHash)
+	return nil
+}
+
+func (f *fakeStore) CountTwoFactorAttempt(ctx context.Context, userId uint, maxFailures int, lockout time.Duration) error {
+	if f.failures[userId] >= maxFailures {
+		return ErrTwoFactorLocked
+	}
+	f.failures[userId]++
+	return nil
+}
+
+func (f *fakeStore) ResetTwoFactorFailures(ctx context.Context, userId uint) error {
+	delete(f.failures, userId)
+	return nil
+}
+
+func (f *fakeStore) DisableTwoFactor(ctx context.Context, userId uint) error {
+	delete(f.twoFactor, userId)
+	return nil
+}
+
+func TestTwoFactor(t *testing.T) {
+	a, store, _ := newProfileTest(t)
+	store.twoFactor = make(map[uint]*models.TwoFactor)
+	store.recovery = make(map[string]bool)
+	store.failures = make(map[uint]int)
+	ctx := context.Background()
+
+	// without 2FA the step-up passes and the login returns the token at once
+	if err := a.VerifyStepUp(ctx, 1, ""); err != nil {
+		t.Fatalf("VerifyStepUp() without 2FA error = %v", err)
+	}
+
+	enroll, err := a.EnrollTwoFactor(ctx, 1)
+	if err != nil {
+		t.Fatalf("EnrollTwoFactor() error = %v", err)
+	}
+	if store.twoFactor[1].Secret == enroll.Secret {
+		t.Fatal("the secret is stored unencrypted")
+	}
+
+	// the secret is not enabled until it is confirmed
+	if err := a.VerifyStepUp(ctx, 1, ""); err != nil {
+		t.Fatalf("VerifyStepUp() before the confirmation error = %v", err)
+	}
+
+	if _, err := a.ConfirmTwoFactor(ctx, &models.TwoFactorCodeRequest{UserID: 1, Code: "000000"}); err != ErrInvalidTwoFactor {
+		t.Fatalf("ConfirmTwoFactor() with a wrong code error = %v, want %v", err, ErrInvalidTwoFactor)
+	}
+
+	now := time.Now()
+	code, err := totp.Code(enroll.Secret, totp.Step(now))
+	if err != nil {
+		t.Fatalf("Code() error = %v", err)
+	}
+
+	confirm, err := a.ConfirmTwoFactor(ctx, &models.TwoFactorCodeRequest{UserID: 1, Code: code})
+	if err != nil {
+		t.Fatalf("ConfirmTwoFactor() error = %v", err)
+	}
+	if len(confirm.RecoveryCodes) != 3 {
+		t.Fatalf("got %d recovery codes, want 3", len(confirm.RecoveryCodes))
+	}
+
+	login, err := a.Login(ctx, models.LoginRequest{Email: "john@example.com", Password: "123456"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if login.Token != "" || !login.TwoFactorRequired || login.Challenge == "" {
+		t.Fatalf("Login() = %+v, want a challenge without the token", login)
+	}
+
+	// the challenge is not an access token
+	if _, err := a.ParseToken(login.Challenge); err != ErrTokenClaims {
+		t.Fatalf("ParseToken() of the challenge error = %v, want %v", err, ErrTokenClaims)
+	}
+
+	// the code of the next step, the confirmed one can not be used again
+	next, err := totp.Code(enroll.Secret, totp.Step(now)+1)
+	if err != nil {
+		t.Fatalf("Code() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{"login with the confirmed code", func() error {
+			_, err := a.LoginTwoFactor(ctx, models.LoginTwoFactorRequest{Challenge: login.Challenge, Code: code})
+			return err
+		}, ErrInvalidTwoFactor},
+		{"login with a forged challenge", func() error {
+			_, err := a.LoginTwoFactor(ctx, models.LoginTwoFactorRequest{Challenge: login.Challenge + "x", Code: next})
+			return err
+		}, ErrInvalidChallenge},
+		{"login with the next code", func() error {
+			_, err := a.LoginTwoFactor(ctx, models.LoginTwoFactorRequest{Challenge: login.Challenge, Code: next})
+			return err
+		}, nil},
+		{"step-up without a code", func() error { return a.VerifyStepUp(ctx, 1, "") }, ErrTwoFactorRequired},
+		{"step-up with a used code", func() error { return a.VerifyStepUp(ctx, 1, next) }, ErrInvalidTwoFactor},
+		{"step-up with a recovery code", func() error { return a.VerifyStepUp(ctx, 1, " "+confirm.RecoveryCodes[0]+" ") }, nil},
+		{"recovery code used twice", func() error { return a.VerifyStepUp(ctx, 1, confirm.RecoveryCodes[0]) }, ErrInvalidTwoFactor},
+		{"deletion without a code", func() error {
+			_, err := a.DeleteUser(ctx, models.DeleteUserRequest{UserID: 1})
+			return err
+		}, ErrTwoFactorRequired},
+		{"disable with a recovery code", func() error {
+			return a.DisableTwoFactor(ctx, &models.TwoFactorCodeRequest{UserID: 1, Code: confirm.RecoveryCodes[1]})
+		}, nil},
+		{"step-up after disabling", func() error { return a.VerifyStepUp(ctx, 1, "") }, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); err != tt.want {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTwoFactorLockout(t *testing.T) {
+	a, store, _ := newProfileTest(t)
+	store.twoFactor = make(map[uint]*models.TwoFactor)
+	store.recovery = make(map[string]bool)
+	store.failures = make(map[uint]int)
+	ctx := context.Background()
+
+	enroll, err := a.EnrollTwoFactor(ctx, 1)
+	if err != nil {
+		t.Fatalf("EnrollTwoFactor() error = %v", err)
+	}
+
+	now := time.Now()
+	code, err := totp.Code(enroll.Secret, totp.Step(now)-1)
+	if err != nil {
+		t.Fatalf("Code() error = %v", err)
+	}
+	if _, err := a.ConfirmTwoFactor(ctx, &models.TwoFactorCodeRequest{UserID: 1, Code: code}); err != nil {
+		t.Fatalf("ConfirmTwoFactor() error = %v", err)
+	}
+
+	valid := func(step int64) string {
+		code, err := totp.Code(enroll.Secret, totp.Step(now)+step)
+		if err != nil {
+			t.Fatalf("Code() error = %v", err)
+		}
+		return code
+	}
+
+	// an accepted code starts the count again, the wrong codes in a row lock the valid ones too
+	tests := []struct {
+		name string
+		code string
+		want error
+	}{
+		{"first wrong code", "000000", ErrInvalidTwoFactor},
+		{"wrong recovery code", "aaaaa-aaaaa", ErrInvalidTwoFactor},
+		{"valid code resets the count", valid(0), nil},
+		{"wrong code after the reset", "000000", ErrInvalidTwoFactor},
+		{"second wrong code", "111111", ErrInvalidTwoFactor},
+		{"third wrong code", "222222", ErrInvalidTwoFactor},
+		{"valid code while locked", valid(1), ErrTwoFactorLocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := a.VerifyStepUp(ctx, 1, tt.code); err != tt.want {
+				t.Errorf("VerifyStepUp() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := a.DisableTwoFactor(ctx, &models.TwoFactorCodeRequest{UserID: 1, Code: valid(1)}); err != ErrTwoFactorLocked {
+		t.Errorf("DisableTwoFactor() while locked error = %v, want %v", err, ErrTwoFactorLocked)
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/mailer"
+)
+
+// VerifyEmail confirms the email of the user with the token from the verification email.
+// The token can be used only once and only before it expires.
+// If the token is unknown, expired or already used, it returns ErrInvalidToken.
+func (a *Auth) VerifyEmail(ctx context.Context, req models.VerifyEmailRequest) error {
+	op := "service Auth: email verification"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("VerifyEmail func call")
+
+	userId, err := a.db.VerifyEmail(ctx, hashToken(req.Token))
+	if err != nil {
+		log.Warn("failed to verify the email", "error", err)
+		return err
+	}
+
+	log.Info("email successfully verified", "user id", userId)
+	return nil
+}
+
+// ResendVerification sends a new verification token to the email of the user, the previous tokens stop working.
+// If the email is already verified, it returns ErrAlreadyVerified.
+// If the previous token was sent less than the resend interval ago, it returns ErrTooManyRequests.
+func (a *Auth) ResendVerification(ctx context.Context, req models.ResendVerificationRequest) error {
+	op := "service Auth: resending the verification"
+	log := a.log.With(slog.String("operation", op))
+	log.Debug("ResendVerification func call", slog.Any("requets data", req))
+
+	user, err := a.db.UserByID(ctx, req.UserID)
+	if err != nil {
+		log.Warn("failed to find the user in the database", "error", err)
+		return err
+	}
+
+	if user.EmailVerified {
+		log.Warn("email is already verified", "user id", req.UserID)
+		return ErrAlreadyVerified
+	}
+
+	if err := a.sendVerification(ctx, user.ID, user.Email); err != nil {
+		log.Warn("failed to send the verification email", "error", err)
+		return err
+	}
+
+	log.Info("verification email successfully sent", "user id", req.UserID)
+	return nil
+}
+
+// sendVerification saves a new verification token of the email and sends the token to it.
+func (a *Auth) sendVerification(ctx context.Context, userId uint, email string) error {
+	token, hash, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	verification := &models.EmailVerification{
+		UserID:    userId,
+		Email:     email,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(a.verification.TokenTTL),
+	}
+
+	if err := a.db.CreateEmailVerification(ctx, verification, a.verification.ResendInterval); err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Confirm your email with the token:\n\n%s\n\nThe token is valid for %s.", token, a.verification.TokenTTL)
+	if a.verification.LinkURL != "" {
+		body = fmt.Sprintf("Confirm your email by following the link:\n\n%s%s\n\nThe link is valid for %s.", a.verification.LinkURL, token, a.verification.TokenTTL)
+	}
+
+	return a.mailer.Send(ctx, mailer.Message{To: email, Subject: "Confirm your email", Body: body})
+}
+
+// newToken generates a random token for the user and its SHA-256 hash for the database.
+func newToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := hex.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+// hashToken returns the hex SHA-256 hash of the token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/encryption"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/jwtkeys"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/mailer"
+)
+
+type fakeStore struct {
+	storages.StoreAuth
+	registered    models.RegisterRequest
+	verifications []models.EmailVerification
+	verified      map[string]uint
+	users         map[string]*models.User
+	resets        map[string]uint
+	twoFactor     map[uint]*models.TwoFactor
+	recovery      map[string]bool
+	failures      map[uint]int
+	apiKeys       []*models.APIKey
+	balances      map[uint]map[string]float32
+}
+
+func (f *fakeStore) CreateUser(ctx context.Context, req models.RegisterRequest) (uint, error) {
+	f.registered = req
+	return 1, nil
+}
+
+func (f *fakeStore) CreateEmailVerification(ctx context.Context, verification *models.EmailVerification, resendInterval time.Duration) error {
+	f.verifications = append(f.verifications, *verification)
+	return nil
+}
+
+func (f *fakeStore) VerifyEmail(ctx context.Context, tokenHash string) (uint, error) {
+	for _, v := range f.verifications {
+		if v.TokenHash == tokenHash {
+			if _, used := f.verified[tokenHash]; used {
+				return 0, ErrInvalidToken
+			}
+			f.verified[tokenHash] = v.UserID
+			return v.UserID, nil
+		}
+	}
+	return 0, ErrInvalidToken
+}
+
+// newTestAuth creates the service with the fakes, the two-factor secrets are encrypted with a test key.
+func newTestAuth(t *testing.T, store *fakeStore, mail *fakeMailer, verification *config.Verification, reset *config.PasswordReset) *Auth {
+	cipher, err := encryption.New(strings.Repeat("ab", encryption.KeySize))
+	if err != nil {
+		t.Fatalf("encryption.New() error = %v", err)
+	}
+
+	keys, err := jwtkeys.New(nil, "", "secret")
+	if err != nil {
+		t.Fatalf("jwtkeys.New() error = %v", err)
+	}
+
+	twoFactor := &config.TwoFactor{Issuer: "Exchanger", ChallengeTTL: time.Minute, RecoveryCodes: 3, MaxFailures: 3, Lockout: time.Minute}
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, mail, keys, &config.JWT{TTL: time.Hour, Issuer: "exchanger", Audience: "exchanger-api", ClockSkew: 30 * time.Second}, verification, reset, twoFactor, cipher)
+}
+
+type fakeMailer struct {
+	sent []mailer.Message
+}
+
+func (f *fakeMailer) Send(ctx context.Context, msg mailer.Message) error {
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+func TestRegisterVerification(t *testing.T) {
+	tests := []struct {
+		name         string
+		required     bool
+		wantVerified bool
+		wantMails    int
+	}{
+		{"verification required", true, false, 1},
+		{"verification disabled", false, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{verified: make(map[string]uint)}
+			mail := &fakeMailer{}
+			conf := &config.Verification{Required: tt.required, TokenTTL: time.Hour, LinkURL: "https://wallet.example.com/verify?token="}
+			a := newTestAuth(t, store, mail, conf, &config.PasswordReset{})
+
+			if _, err := a.Register(context.Background(), models.RegisterRequest{Email: "john@example.com", Name: "john", HashPassword: "123456"}); err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+
+			if store.registered.Verified != tt.wantVerified {
+				t.Errorf("user created with verified = %v, want %v", store.registered.Verified, tt.wantVerified)
+			}
+			if len(mail.sent) != tt.wantMails {
+				t.Fatalf("sent %d emails, want %d", len(mail.sent), tt.wantMails)
+			}
+			if tt.wantMails == 0 {
+				return
+			}
+
+			// the email carries the token, the database only its hash
+			body := mail.sent[0].Body
+			start := strings.Index(body, conf.LinkURL)
+			if start < 0 || mail.sent[0].To != "john@example.com" {
+				t.Fatalf("email %+v does not contain the link", mail.sent[0])
+			}
+			token := body[start+len(conf.LinkURL) : start+len(conf.LinkURL)+64]
+
+			if store.verifications[0].TokenHash == token || store.verifications[0].TokenHash != hashToken(token) {
+				t.Errorf("stored hash %q does not match the token", store.verifications[0].TokenHash)
+			}
+
+			if err := a.VerifyEmail(context.Background(), models.VerifyEmailRequest{Token: token}); err != nil {
+				t.Errorf("VerifyEmail() error = %v", err)
+			}
+			if err := a.VerifyEmail(context.Background(), models.VerifyEmailRequest{Token: token}); err != ErrInvalidToken {
+				t.Errorf("second VerifyEmail() error = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+const (
+	// TypeBalance is the type of the first notification with the current balance
+	TypeBalance = "balance"
+
+	// channelPrefix is the prefix of the Redis channels, the ID of the user follows it
+	channelPrefix = "balances:"
+)
+
+var (
+	ErrSlowConsumer = errors.New("client does not keep up with the balance notifications")
+	ErrStreamClosed = errors.New("balance stream is closed")
+)
+
+// Subscriber is a connected client of a user, the notifications are received from Updates.
+// The channel is closed when the subscriber is dropped, the reason is returned by Err after that.
+type Subscriber struct {
+	userId  uint
+	updates chan models.BalanceNotification
+	err     error
+}
+
+// Updates returns the channel of the notifications of the subscriber.
+func (s *Subscriber) Updates() <-chan models.BalanceNotification {
+	return s.updates
+}
+
+// Err returns the reason why the subscriber was dropped, it must be called after Updates is closed.
+func (s *Subscriber) Err() error {
+	return s.err
+}
+
+// Balances is a service that notifies the connected clients about the balance changes of their user.
+// The committed wallet events come from the outbox, the new balance is published to Redis,
+// every instance of the server listens to Redis and pushes the notifications to its own clients.
+type Balances struct {
+	log    *slog.Logger
+	db     storages.StoreBalances
+	pubsub storages.PubSub
+	conf   *config.Streaming
+	mu     sync.Mutex
+	subs   map[uint]map[*Subscriber]struct{}
+	closed bool
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a new instance of the Balances service.
+// It initializes the service with a logger, database storage for the balances,
+// Redis pub/sub between the instances and the streaming configuration.
+func New(log *slog.Logger, db storages.StoreBalances, pubsub storages.PubSub, conf *config.Streaming) *Balances {
+	log.Debug("service Balances: started creating")
+
+	log.Info("service Balances: successfully created")
+	return &Balances{
+		log:    log,
+		db:     db,
+		pubsub: pubsub,
+		conf:   conf,
+		subs:   make(map[uint]map[*Subscriber]struct{}),
+	}
+}
+
+// Stop gracefully shuts down the Balances service.
+// It stops the listener and closes all subscribers, so that the open streams are finished.
+func (b *Balances) Stop() error {
+	b.log.Debug("service Balances: stop started")
+
+	if b.cancel != nil {
+		b.cancel()
+	}
+	b.wg.Wait()
+
+	b.mu.Lock()
+	b.closed = true
+	for _, subs := range b.subs {
+		for sub := range subs {
+			b.drop(sub, ErrStreamClosed)
+		}
+	}
+	b.mu.Unlock()
+
+	b.log.Info("service Balances: stop successful")
+	return nil
+}
+
+// StartListener runs the listener of the Redis channels in the background.
+// If the subscription fails, it is created again after a pause. The listener is stopped by Stop.
+func (b *Balances) StartListener() {
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.log.Info("service Balances: listener started")
+
+		for {
+			if err := b.pubsub.Subscribe(ctx, channelPrefix+"*", b.deliver); err != nil {
+				b.log.Error("service Balances: listener failed", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				b.log.Info("service Balances: listener stopped")
+				return
+			case <-time.After(b.conf.HeartbeatInterval):
+			}
+		}
+	}()
+}
+
+// Notify publishes the new balance of the user after a committed wallet event, it is the handler of the outbox events.
+// The balance is read when the event is relayed, so the notification always has the latest balance.
+// If the notification can not be published, the event is relayed again.
+func (b *Balances) Notify(ctx context.Context, event models.OutboxEvent) error {
+	op := "service Balances: notification"
+	log := b.log.With(slog.String("operation", op))
+	log.Debug("Notify func call", slog.Any("requets data", event))
+
+	var operation models.WalletEvent
+	if err := json.Unmarshal(event.Payload, &operation); err != nil {
+		log.Error("failed to decode the wallet event", "event id", event.ID, "error", err)
+		return err
+	}
+
+	balance, err := b.db.AllAccountsBalance(ctx, event.UserID)
+	if err != nil {
+		log.Error("failed to get the balance", "user id", event.UserID, "error", err)
+		return err
+	}
+
+	message, err := json.Marshal(models.BalanceNotification{
+		Type:       event.Type,
+		EventID:    event.ID,
+		Operation:  &operation,
+		NewBalance: balance,
+		CreatedAt:  event.CreatedAt,
+	})
+	if err != nil {
+		log.Error("failed to encode the notification", "error", err)
+		return err
+	}
+
+	if err := b.pubsub.Publish(fmt.Sprintf("%s%d", channelPrefix, event.UserID), message); err != nil {
+		log.Error("failed to publish the notification", "user id", event.UserID, "error", err)
+		return err
+	}
+
+	log.Debug("notification published", "user id", event.UserID, "event id", event.ID)
+	return nil
+}
+
+// Subscribe connects a client of the user, the current balance is sent to it at once.
+func (b *Balances) Subscribe(ctx context.Context, userId uint) (*Subscriber, error) {
+	op := "service Balances: subscribe"
+	log := b.log.With(slog.String("operation", op))
+	log.Debug("Subscribe func call", slog.Any("requets data", userId))
+
+	balance, err := b.db.AllAccountsBalance(ctx, userId)
+	if err != nil {
+		log.Error("failed to get the balance", "error", err)
+		return nil, err
+	}
+
+	sub := &Subscriber{
+		userId:  userId,
+		updates: make(chan models.BalanceNotification, b.conf.ClientBuffer),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, ErrStreamClosed
+	}
+
+	if b.subs[userId] == nil {
+		b.subs[userId] = make(map[*Subscriber]struct{})
+	}
+	b.subs[userId][sub] = struct{}{}
+
+	b.send(sub, models.BalanceNotification{Type: TypeBalance, NewBalance: balance, CreatedAt: time.Now().UTC()})
+
+	log.Info("client subscribed to the balance", "user id", userId, "clients", len(b.subs[userId]))
+	return sub, nil
+}
+
+// Unsubscribe disconnects the client, it is safe to call it for an already dropped subscriber.
+func (b *Balances) Unsubscribe(sub *Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub.userId][sub]; ok {
+		b.drop(sub, ErrStreamClosed)
+		b.log.Debug("service Balances: client unsubscribed", "user id", sub.userId)
+	}
+}
+
+// deliver pushes a notification received from Redis to the clients of its user on this instance.
+func (b *Balances) deliver(channel string, message []byte) {
+	userId, err := strconv.ParseUint(strings.TrimPrefix(channel, channelPrefix), 10, 64)
+	if err != nil {
+		b.log.Warn("service Balances: message from an unknown channel", "channel", channel)
+		return
+	}
+
+	var notification models.BalanceNotification
+	if err := json.Unmarshal(message, &notification); err != nil {
+		b.log.Warn("service Balances: invalid notification", "channel", channel, "error", err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs[uint(userId)] {
+		b.send(sub, notification)
+	}
+}
+
+// send puts the notification into the queue of the subscriber without blocking the listener.
+// A client whose queue is full does not keep up with the notifications, it is dropped.
+// The caller must hold the mutex.
+func (b *Balances) send(sub *Subscriber, notification models.BalanceNotification) {
+	select {
+	case sub.updates <- notification:
+	default:
+		b.log.Warn("service Balances: slow client dropped", "user id", sub.userId, "queue", cap(sub.updates))
+		b.drop(sub, ErrSlowConsumer)
+	}
+}
+
+// drop removes the subscriber and closes its channel, the caller must hold the mutex.
+func (b *Balances) drop(sub *Subscriber, err error) {
+	delete(b.subs[sub.userId], sub)
+	if len(b.subs[sub.userId]) == 0 {
+		delete(b.subs, sub.userId)
+	}
+	sub.err = err
+	close(sub.updates)
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+type fakeStore struct {
+	balances map[uint]map[string]float32
+}
+
+func (f *fakeStore) AllAccountsBalance(ctx context.Context, userId uint) (map[string]float32, error) {
+	return f.balances[userId], nil
+}
+
+// fakePubSub delivers the published messages at once, like Redis does to every subscribed instance.
+type fakePubSub struct {
+	instances []func(channel string, message []byte)
+}
+
+func (f *fakePubSub) Publish(channel string, message []byte) error {
+	for _, handle := range f.instances {
+		handle(channel, message)
+	}
+	return nil
+}
+
+func (f *fakePubSub) Subscribe(ctx context.Context, pattern string, handle func(channel string, message []byte)) error {
+	return nil
+}
+
+func newTestBalances(store *fakeStore, pubsub *fakePubSub, buffer int) *Balances {
+	conf := &config.Streaming{HeartbeatInterval: time.Second, ClientBuffer: buffer}
+	b := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, pubsub, conf)
+	pubsub.instances = append(pubsub.instances, b.deliver)
+	return b
+}
+
+func walletEvent(t *testing.T, id uint64, userId uint, eventType string, operation models.WalletEvent) models.OutboxEvent {
+	payload, err := json.Marshal(operation)
+	if err != nil {
+		t.Fatalf("failed to encode the payload: %v", err)
+	}
+	return models.OutboxEvent{ID: id, UserID: userId, Type: eventType, Payload: payload}
+}
+
+// received drains the queued notifications of the subscriber.
+func received(sub *Subscriber) []models.BalanceNotification {
+	var got []models.BalanceNotification
+	for {
+		select {
+		case notification, ok := <-sub.Updates():
+			if !ok {
+				return got
+			}
+			got = append(got, notification)
+		default:
+			return got
+		}
+	}
+}
+
+func TestNotify(t *testing.T) {
+	store := &fakeStore{balances: map[uint]map[string]float32{
+		1: {"USD": 100, "EUR": 0},
+		2: {"USD": 50},
+	}}
+	pubsub := &fakePubSub{}
+
+	// two instances of the server, the user 1 is connected to both of them
+	first := newTestBalances(store, pubsub, 4)
+	second := newTestBalances(store, pubsub, 4)
+
+	subs := make(map[string]*Subscriber)
+	for name, conn := range map[string]struct {
+		service *Balances
+		userId  uint
+	}{
+		"user 1 on the first instance":  {first, 1},
+		"user 1 on the second instance": {second, 1},
+		"user 2 on the first instance":  {first, 2},
+	} {
+		sub, err := conn.service.Subscribe(context.Background(), conn.userId)
+		if err != nil {
+			t.Fatalf("Subscribe() error = %v", err)
+		}
+
+		snapshot := received(sub)
+		if len(snapshot) != 1 || snapshot[0].Type != TypeBalance || !reflect.DeepEqual(snapshot[0].NewBalance, store.balances[conn.userId]) {
+			t.Fatalf("%s: first notification = %+v, want the current balance", name, snapshot)
+		}
+		subs[name] = sub
+	}
+
+	store.balances[1] = map[string]float32{"USD": 150, "EUR": 0}
+	operation := models.WalletEvent{Operation: "deposit", Currency: "USD", Amount: 50, Balance: 150}
+	if err := first.Notify(context.Background(), walletEvent(t, 7, 1, "wallet.deposited", operation)); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		count int
+	}{
+		{"user 1 on the first instance", 1},
+		{"user 1 on the second instance", 1},
+		{"user 2 on the first instance", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := received(subs[tt.name])
+			if len(got) != tt.count {
+				t.Fatalf("received %d notifications, want %d", len(got), tt.count)
+			}
+			if tt.count == 0 {
+				return
+			}
+
+			if got[0].Type != "wallet.deposited" || got[0].EventID != 7 {
+				t.Errorf("notification = %+v, want the event 7", got[0])
+			}
+			if !reflect.DeepEqual(got[0].NewBalance, store.balances[1]) {
+				t.Errorf("new balance = %v, want %v", got[0].NewBalance, store.balances[1])
+			}
+			if got[0].Operation == nil || *got[0].Operation != operation {
+				t.Errorf("operation = %+v, want %+v", got[0].Operation, operation)
+			}
+		})
+	}
+}
+
+func TestSlowConsumer(t *testing.T) {
+	store := &fakeStore{balances: map[uint]map[string]float32{1: {"USD": 100}}}
+	b := newTestBalances(store, &fakePubSub{}, 1)
+
+	// the queue is already full with the current balance
+	sub, err := b.Subscribe(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	if err := b.Notify(context.Background(), walletEvent(t, 1, 1, "wallet.withdrawn", models.WalletEvent{Operation: "withdraw"})); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	received(sub)
+	if _, ok := <-sub.Updates(); ok {
+		t.Fatal("subscriber of a slow client is not closed")
+	}
+	if sub.Err() != ErrSlowConsumer {
+		t.Errorf("Err() = %v, want %v", sub.Err(), ErrSlowConsumer)
+	}
+
+	b.Unsubscribe(sub)
+	if err := b.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if _, err := b.Subscribe(context.Background(), 1); err != ErrStreamClosed {
+		t.Errorf("Subscribe() after Stop error = %v, want %v", err, ErrStreamClosed)
+	}
+}
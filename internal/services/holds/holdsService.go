@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+const (
+	StatusOpen     = "open"
+	StatusCaptured = "captured"
+	StatusReleased = "released"
+	StatusExpired  = "expired"
+)
+
+var (
+	ErrHoldNotFound       = errors.New("hold not found")
+	ErrHoldNotOpen        = errors.New("hold is not open")
+	ErrInvalidExpiry      = errors.New("expiry time must be in the future and within the maximum hold time")
+	ErrCaptureExceedsHold = errors.New("capture amount exceeds the held amount")
+)
+
+type stepUpVerifier interface {
+	VerifyStepUp(ctx context.Context, userId uint, code string) error
+}
+
+// Holds is a service that handles the holds of the funds.
+// A merchant holds an amount of the account, it stays on the account but is not available for withdrawals,
+// exchanges, transfers or orders. The hold is captured (the amount is withdrawn) or released,
+// a background expirer releases the holds whose expiry time has passed.
+type Holds struct {
+	log       *slog.Logger
+	db        storages.StoreHolds
+	stepUp    stepUpVerifier
+	conf      *config.Holds
+	twoFactor *config.TwoFactor
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// New creates a new instance of the Holds service.
+// It initializes the service with a logger, database storage, the verifier of the two-factor codes,
+// the settings of the holds and the thresholds of the operations that need a code.
+func New(log *slog.Logger, db storages.StoreHolds, stepUp stepUpVerifier, conf *config.Holds, twoFactor *config.TwoFactor) *Holds {
+	log.Debug("service Holds: started creating")
+
+	log.Info("service Holds: successfully created")
+	return &Holds{
+		log:       log,
+		db:        db,
+		stepUp:    stepUp,
+		conf:      conf,
+		twoFactor: twoFactor,
+	}
+}
+
+// Stop gracefully shuts down the Holds service.
+// It stops the expirer, waits for the current round to finish and cleans up resources.
+func (h *Holds) Stop() error {
+	h.log.Debug("service Holds: stop started")
+
+	if h.cancel != nil {
+		h.cancel()
+	}
+	h.wg.Wait()
+
+	h.db = nil
+	h.stepUp = nil
+
+	h.log.Info("service Holds: stop successful")
+	return nil
+}
+
+// StartExpirer runs the expirer in the background, every ExpireInterval it releases the expired holds.
+// The expirer is stopped by Stop.
+func (h *Holds) StartExpirer() {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		h.log.Info("service Holds: expirer started", "interval", h.conf.ExpireInterval.String())
+
+		ticker := time.NewTicker(h.conf.ExpireInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				h.log.Info("service Holds: expirer stopped")
+				return
+			case <-ticker.C:
+				h.expireHolds(ctx)
+			}
+		}
+	}()
+}
+
+// expireHolds is one round of the expirer.
+func (h *Holds) expireHolds(ctx context.Context) {
+	op := "service Holds: expiration round"
+	log := h.log.With(slog.String("operation", op))
+
+	ctx, cancel := context.WithTimeout(ctx, h.conf.ExpireInterval)
+	defer cancel()
+
+	expired, err := h.db.ExpireHolds(ctx)
+	if err != nil {
+		log.Error("failed to expire holds", "error", err)
+		return
+	}
+
+	if expired > 0 {
+		log.Info("outdated holds have been expired", "count", expired)
+	}
+}
+
+// CreateHold holds the amount on the account of the user until the expiry time, without it for DefaultTTL.
+// The hold authorizes a withdrawal, so a hold of at least the withdrawal threshold of its currency
+// needs the two-factor code of the user, if 2FA is enabled.
+// If the expiry time has passed or is later than MaxTTL, it returns ErrInvalidExpiry.
+func (h *Holds) CreateHold(ctx context.Context, req models.CreateHoldRequest) (*models.HoldResponse, error) {
+	op := "service Holds: hold creation"
+	log := h.log.With(slog.String("operation", op))
+	log.Debug("CreateHold func call", slog.Any("requets data", req))
+
+	now := time.Now()
+	if req.ExpiresAt == nil {
+		expiresAt := now.Add(h.conf.DefaultTTL)
+		req.ExpiresAt = &expiresAt
+	}
+
+	if !req.ExpiresAt.After(now) || req.ExpiresAt.After(now.Add(h.conf.MaxTTL)) {
+		log.Warn("invalid expiry time", "expires at", req.ExpiresAt)
+		return nil, ErrInvalidExpiry
+	}
+
+	// a large hold needs the two-factor code, if the user has enabled it
+	if threshold, ok := h.twoFactor.WithdrawThresholds[req.Currency]; ok && req.Amount >= threshold {
+		if err := h.stepUp.VerifyStepUp(ctx, req.UserID, req.TwoFactorCode); err != nil {
+			log.Warn("step-up verification failed", "amount", req.Amount, "currency", req.Currency, "error", err)
+			return nil, err
+		}
+	}
+
+	hold, err := h.db.CreateHold(ctx, &req)
+	if err != nil {
+		log.Warn("failed to save the hold in the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("hold successfully created", "hold id", hold.ID)
+	return &models.HoldResponse{Message: "hold successfully created", Hold: *hold}, nil
+}
+
+// UserHolds returns the holds of the user, optionally filtered by status.
+func (h *Holds) UserHolds(ctx context.Context, req models.HoldsRequest) (*models.HoldsResponse, error) {
+	op := "service Holds: list of holds"
+	log := h.log.With(slog.String("operation", op))
+	log.Debug("UserHolds func call", slog.Any("requets data", req))
+
+	holds, err := h.db.UserHolds(ctx, req.UserID, req.Status)
+	if err != nil {
+		log.Error("failed to get the holds from the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("holds successfully received", "count", len(holds))
+	return &models.HoldsResponse{Message: "holds successfully received", Holds: holds}, nil
+}
+
+// UserHold returns one hold of the user.
+func (h *Holds) UserHold(ctx context.Context, req models.HoldRequest) (*models.HoldResponse, error) {
+	op := "service Holds: hold details"
+	log := h.log.With(slog.String("operation", op))
+	log.Debug("UserHold func call", slog.Any("requets data", req))
+
+	hold, err := h.db.UserHold(ctx, req.UserID, req.HoldID)
+	if err != nil {
+		log.Warn("failed to get the hold from the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("hold successfully received")
+	return &models.HoldResponse{Message: "hold successfully received", Hold: *hold}, nil
+}
+
+// CaptureHold withdraws the captured amount of an open hold, without an amount the whole hold, the rest is released.
+func (h *Holds) CaptureHold(ctx context.Context, req models.CaptureHoldRequest) (*models.HoldResponse, error) {
+	op := "service Holds: hold capture"
+	log := h.log.With(slog.String("operation", op))
+	log.Debug("CaptureHold func call", slog.Any("requets data", req))
+
+	hold, err := h.db.CaptureHold(ctx, req.UserID, req.HoldID, req.Amount)
+	if err != nil {
+		log.Warn("failed to capture the hold", "error", err)
+		return nil, err
+	}
+
+	log.Info("hold successfully captured", "captured", *hold.Captured)
+	return &models.HoldResponse{Message: "hold successfully captured", Hold: *hold}, nil
+}
+
+// ReleaseHold releases an open hold of the user, its amount becomes available again.
+func (h *Holds) ReleaseHold(ctx context.Context, req models.HoldRequest) (*models.HoldResponse, error) {
+	op := "service Holds: hold release"
+	log := h.log.With(slog.String("operation", op))
+	log.Debug("ReleaseHold func call", slog.Any("requets data", req))
+
+	hold, err := h.db.ReleaseHold(ctx, req.UserID, req.HoldID)
+	if err != nil {
+		log.Warn("failed to release the hold", "error", err)
+		return nil, err
+	}
+
+	log.Info("hold successfully released")
+	return &models.HoldResponse{Message: "hold successfully released", Hold: *hold}, nil
+}
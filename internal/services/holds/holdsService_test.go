@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+var errStepUp = errors.New("two-factor code is required")
+
+type fakeStore struct {
+	storages.StoreHolds
+	created *models.CreateHoldRequest
+}
+
+func (f *fakeStore) CreateHold(ctx context.Context, req *models.CreateHoldRequest) (*models.Hold, error) {
+	f.created = req
+	return &models.Hold{ID: 1, Currency: req.Currency, Amount: req.Amount, Status: StatusOpen, ExpiresAt: *req.ExpiresAt}, nil
+}
+
+// fakeStepUp accepts only the code "123456".
+type fakeStepUp struct{}
+
+func (fakeStepUp) VerifyStepUp(ctx context.Context, userId uint, code string) error {
+	if code != "123456" {
+		return errStepUp
+	}
+	return nil
+}
+
+func TestCreateHold(t *testing.T) {
+	conf := &config.Holds{DefaultTTL: time.Hour, MaxTTL: 24 * time.Hour}
+	twoFactor := &config.TwoFactor{WithdrawThresholds: map[string]float32{"USD": 1000}}
+
+	past := time.Now().Add(-time.Minute)
+	tooLate := time.Now().Add(48 * time.Hour)
+	inTwoHours := time.Now().Add(2 * time.Hour)
+
+	tests := []struct {
+		name      string
+		req       models.CreateHoldRequest
+		err       error
+		expiresAt time.Duration
+	}{
+		{
+			name:      "default time to live",
+			req:       models.CreateHoldRequest{Currency: "USD", Amount: 100},
+			expiresAt: time.Hour,
+		},
+		{
+			name:      "own expiry time",
+			req:       models.CreateHoldRequest{Currency: "USD", Amount: 100, ExpiresAt: &inTwoHours},
+			expiresAt: 2 * time.Hour,
+		},
+		{
+			name: "expiry time has passed",
+			req:  models.CreateHoldRequest{Currency: "USD", Amount: 100, ExpiresAt: &past},
+			err:  ErrInvalidExpiry,
+		},
+		{
+			name: "expiry time after the maximum",
+			req:  models.CreateHoldRequest{Currency: "USD", Amount: 100, ExpiresAt: &tooLate},
+			err:  ErrInvalidExpiry,
+		},
+		{
+			name: "above the threshold without a code",
+			req:  models.CreateHoldRequest{Currency: "USD", Amount: 1000},
+			err:  errStepUp,
+		},
+		{
+			name:      "above the threshold with a code",
+			req:       models.CreateHoldRequest{Currency: "USD", Amount: 1000, TwoFactorCode: "123456"},
+			expiresAt: time.Hour,
+		},
+		{
+			name:      "currency without a threshold",
+			req:       models.CreateHoldRequest{Currency: "EUR", Amount: 5000},
+			expiresAt: time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{}
+			h := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, fakeStepUp{}, conf, twoFactor)
+
+			start := time.Now()
+			resp, err := h.CreateHold(context.Background(), tt.req)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+
+			if tt.err != nil {
+				if store.created != nil {
+					t.Errorf("hold is saved after an error")
+				}
+				return
+			}
+
+			ttl := resp.Hold.ExpiresAt.Sub(start)
+			if ttl < tt.expiresAt-time.Second || ttl > tt.expiresAt+time.Second {
+				t.Errorf("hold expires in %s, want %s", ttl, tt.expiresAt)
+			}
+		})
+	}
+}
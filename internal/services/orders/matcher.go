@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// StartMatcher runs the matcher in the background, every interval it expires the outdated orders
+// and executes the open orders whose target rate has been reached.
+// The matcher is stopped by Stop.
+func (o *Orders) StartMatcher() {
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel = cancel
+
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		o.log.Info("service Orders: matcher started", "interval", o.interval.String())
+
+		ticker := time.NewTicker(o.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				o.log.Info("service Orders: matcher stopped")
+				return
+			case <-ticker.C:
+				o.matchOrders(ctx)
+			}
+		}
+	}()
+}
+
+// matchOrders is one round of the matcher.
+// Every currency pair is requested from the rate service once, the cache is not used, so that the rate is fresh.
+// An order is executed through the exchange path: the fee of the user is applied and the database
+// executes the order only if it is still open, so the order can not be executed twice by several instances.
+func (o *Orders) matchOrders(ctx context.Context) {
+	op := "service Orders: matching round"
+	log := o.log.With(slog.String("operation", op))
+
+	ctx, cancel := context.WithTimeout(ctx, o.interval)
+	defer cancel()
+
+	expired, err := o.db.ExpireOrders(ctx)
+	if err != nil {
+		log.Error("failed to expire orders", "error", err)
+	} else if expired > 0 {
+		log.Info("outdated orders have been expired", "count", expired)
+	}
+
+	orders, err := o.db.OpenOrders(ctx)
+	if err != nil {
+		log.Error("failed to get open orders", "error", err)
+		return
+	}
+
+	if len(orders) == 0 {
+		log.Debug("no open orders")
+		return
+	}
+
+	rates := make(map[string]float32)
+	for _, order := range orders {
+		pair := order.FromCurrency + "/" + order.ToCurrency
+		if _, ok := rates[pair]; ok {
+			continue
+		}
+
+		rate := models.ExchangeRate{FromCurrency: order.FromCurrency, ToCurrency: order.ToCurrency}
+		if err := o.clientGRPC.ExchangeRate(ctx, &rate); err != nil {
+			log.Warn("failed to get the exchange rate", "pair", pair, "error", err)
+			rates[pair] = 0
+			continue
+		}
+		rates[pair] = rate.Rate
+	}
+
+	var filled int
+	for _, order := range orders {
+		midRate := rates[order.FromCurrency+"/"+order.ToCurrency]
+		if midRate == 0 {
+			continue
+		}
+
+		result, err := o.quoter.QuoteExchange(ctx, order.UserID, order.FromCurrency, order.ToCurrency, order.Amount, midRate)
+		if err != nil {
+			log.Warn("failed to quote the order", "order id", order.ID, "error", err)
+			continue
+		}
+
+		if result.AppliedRate < order.TargetRate {
+			continue
+		}
+
+		if err := o.db.ExecuteOrder(ctx, order.ID, result); err != nil {
+			log.Warn("failed to execute the order", "order id", order.ID, "error", err)
+			continue
+		}
+
+		filled++
+		log.Info("order executed", "order id", order.ID, "applied rate", result.AppliedRate, "target rate", order.TargetRate)
+	}
+
+	log.Debug("matching round completed", slog.Int("open orders", len(orders)), slog.Int("filled", filled))
+}
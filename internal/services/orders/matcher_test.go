@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	grpcclient "github.com/EvansTrein/RESTful_exchangerServer/pkg/gRPCclient"
+)
+
+type fakeStore struct {
+	storages.StoreOrders
+	open     []models.Order
+	executed []uint
+}
+
+func (f *fakeStore) ExpireOrders(ctx context.Context) (int, error) { return 0, nil }
+
+func (f *fakeStore) OpenOrders(ctx context.Context) ([]models.Order, error) { return f.open, nil }
+
+func (f *fakeStore) ExecuteOrder(ctx context.Context, orderId uint, result *models.CurrencyExchangeResult) error {
+	f.executed = append(f.executed, orderId)
+	return nil
+}
+
+type fakeRates struct {
+	grpcclient.ClientGRPC
+	rates map[string]float32
+}
+
+func (f *fakeRates) ExchangeRate(ctx context.Context, req *models.ExchangeRate) error {
+	req.Rate = f.rates[req.FromCurrency+"/"+req.ToCurrency]
+	return nil
+}
+
+// fakeQuoter charges a fee of 1% of the received amount.
+type fakeQuoter struct{}
+
+func (fakeQuoter) QuoteExchange(ctx context.Context, userId uint, from, to string, amount, midRate float32) (*models.CurrencyExchangeResult, error) {
+	received := amount * midRate * 0.99
+	return &models.CurrencyExchangeResult{Received: received, AppliedRate: received / amount}, nil
+}
+
+func TestMatchOrders(t *testing.T) {
+	tests := []struct {
+		name     string
+		rates    map[string]float32
+		orders   []models.Order
+		executed []uint
+	}{
+		{
+			name:  "target reached after the fee",
+			rates: map[string]float32{"USD/EUR": 0.96},
+			orders: []models.Order{
+				{ID: 1, FromCurrency: "USD", ToCurrency: "EUR", Amount: 1000, TargetRate: 0.95},
+			},
+			executed: []uint{1},
+		},
+		{
+			name:  "mid rate reaches the target but the applied rate does not",
+			rates: map[string]float32{"USD/EUR": 0.955},
+			orders: []models.Order{
+				{ID: 1, FromCurrency: "USD", ToCurrency: "EUR", Amount: 1000, TargetRate: 0.95},
+			},
+			executed: nil,
+		},
+		{
+			name:  "only the orders of the reached pairs are executed",
+			rates: map[string]float32{"USD/EUR": 0.9, "EUR/USD": 1.2},
+			orders: []models.Order{
+				{ID: 1, FromCurrency: "USD", ToCurrency: "EUR", Amount: 100, TargetRate: 0.95},
+				{ID: 2, FromCurrency: "EUR", ToCurrency: "USD", Amount: 100, TargetRate: 1.1},
+				{ID: 3, FromCurrency: "USD", ToCurrency: "EUR", Amount: 100, TargetRate: 0.85},
+			},
+			executed: []uint{2, 3},
+		},
+		{
+			name:  "rate is unavailable",
+			rates: map[string]float32{},
+			orders: []models.Order{
+				{ID: 1, FromCurrency: "USD", ToCurrency: "EUR", Amount: 100, TargetRate: 0.5},
+			},
+			executed: nil,
+		},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{open: tt.orders}
+			serv := New(log, store, &fakeRates{rates: tt.rates}, fakeQuoter{}, time.Second)
+
+			serv.matchOrders(context.Background())
+
+			if !reflect.DeepEqual(store.executed, tt.executed) {
+				t.Errorf("matchOrders() executed = %v, want %v", store.executed, tt.executed)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	grpcclient "github.com/EvansTrein/RESTful_exchangerServer/pkg/gRPCclient"
+)
+
+const (
+	StatusOpen      = "open"
+	StatusFilled    = "filled"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+
+	EventCreated   = "created"
+	EventFilled    = "filled"
+	EventCancelled = "cancelled"
+	EventExpired   = "expired"
+
+	// operation types of the reservation in the operations history
+	OperationReserve = "order_reserve"
+	OperationRelease = "order_release"
+)
+
+var (
+	ErrOrderNotFound = errors.New("order not found")
+	ErrOrderNotOpen  = errors.New("order is not open")
+	ErrSameCurrency  = errors.New("same currency is specified for buying and selling")
+	ErrInvalidExpiry = errors.New("expiry time must be in the future")
+)
+
+// exchangeQuoter calculates an exchange with the fee of the user, it is implemented by the Wallet service.
+type exchangeQuoter interface {
+	QuoteExchange(ctx context.Context, userId uint, fromCurrency, toCurrency string, amount, midRate float32) (*models.CurrencyExchangeResult, error)
+}
+
+// Orders is a service that handles limit orders.
+// Users create, list and cancel orders, the funds of an open order are reserved.
+// A background matcher executes the orders whose target rate has been reached and expires the outdated ones.
+type Orders struct {
+	log        *slog.Logger
+	db         storages.StoreOrders
+	clientGRPC grpcclient.ClientGRPC
+	quoter     exchangeQuoter
+	interval   time.Duration
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// New creates a new instance of the Orders service.
+// It initializes the service with a logger, database storage, gRPC client for fresh rates,
+// the exchange quoter and the interval of the matcher.
+func New(log *slog.Logger, db storages.StoreOrders, gRPC grpcclient.ClientGRPC, quoter exchangeQuoter, interval time.Duration) *Orders {
+	log.Debug("service Orders: started creating")
+
+	log.Info("service Orders: successfully created")
+	return &Orders{
+		log:        log,
+		db:         db,
+		clientGRPC: gRPC,
+		quoter:     quoter,
+		interval:   interval,
+	}
+}
+
+// Stop gracefully shuts down the Orders service.
+// It stops the matcher, waits for the current round to finish and cleans up resources.
+func (o *Orders) Stop() error {
+	o.log.Debug("service Orders: stop started")
+
+	if o.cancel != nil {
+		o.cancel()
+	}
+	o.wg.Wait()
+
+	o.db = nil
+	o.clientGRPC = nil
+	o.quoter = nil
+
+	o.log.Info("service Orders: stop successful")
+	return nil
+}
+
+// CreateOrder creates a limit order and reserves its amount on the account of the user.
+// If the currencies are the same or the expiry time has already passed, it returns an error.
+func (o *Orders) CreateOrder(ctx context.Context, req models.CreateOrderRequest) (*models.OrderResponse, error) {
+	op := "service Orders: order creation"
+	log := o.log.With(slog.String("operation", op))
+	log.Debug("CreateOrder func call", slog.Any("requets data", req))
+
+	if req.FromCurrency == req.ToCurrency {
+		log.Warn("same currencies")
+		return nil, ErrSameCurrency
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		log.Warn("expiry time has already passed", "expires at", req.ExpiresAt)
+		return nil, ErrInvalidExpiry
+	}
+
+	order, err := o.db.CreateOrder(ctx, &req)
+	if err != nil {
+		log.Error("failed to save the order in the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("order successfully created", "order id", order.ID)
+	return &models.OrderResponse{Message: "order successfully created", Order: *order}, nil
+}
+
+// UserOrders returns the orders of the user, optionally filtered by status.
+func (o *Orders) UserOrders(ctx context.Context, req models.OrdersRequest) (*models.OrdersResponse, error) {
+	op := "service Orders: list of orders"
+	log := o.log.With(slog.String("operation", op))
+	log.Debug("UserOrders func call", slog.Any("requets data", req))
+
+	orders, err := o.db.UserOrders(ctx, req.UserID, req.Status)
+	if err != nil {
+		log.Error("failed to get the orders from the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("orders successfully received", "count", len(orders))
+	return &models.OrdersResponse{Message: "orders successfully received", Orders: orders}, nil
+}
+
+// UserOrder returns one order of the user together with its audit trail.
+func (o *Orders) UserOrder(ctx context.Context, req models.OrderRequest) (*models.OrderResponse, error) {
+	op := "service Orders: order details"
+	log := o.log.With(slog.String("operation", op))
+	log.Debug("UserOrder func call", slog.Any("requets data", req))
+
+	order, events, err := o.db.UserOrder(ctx, req.UserID, req.OrderID)
+	if err != nil {
+		log.Warn("failed to get the order from the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("order successfully received")
+	return &models.OrderResponse{Message: "order successfully received", Order: *order, Events: events}, nil
+}
+
+// CancelOrder cancels an open order of the user and returns the reserved amount to the account.
+func (o *Orders) CancelOrder(ctx context.Context, req models.OrderRequest) (*models.OrderResponse, error) {
+	op := "service Orders: order cancellation"
+	log := o.log.With(slog.String("operation", op))
+	log.Debug("CancelOrder func call", slog.Any("requets data", req))
+
+	order, err := o.db.CancelOrder(ctx, req.UserID, req.OrderID)
+	if err != nil {
+		log.Warn("failed to cancel the order", "error", err)
+		return nil, err
+	}
+
+	log.Info("order successfully cancelled")
+	return &models.OrderResponse{Message: "order successfully cancelled", Order: *order}, nil
+}
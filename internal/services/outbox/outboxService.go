@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+const (
+	EventUserRegistered = "user.registered"
+	EventUserDeleted    = "user.deleted"
+	EventDeposited      = "wallet.deposited"
+	EventWithdrawn      = "wallet.withdrawn"
+	EventExchanged      = "wallet.exchanged"
+
+	SinkBus    = "bus"
+	SinkStdout = "stdout"
+	SinkFile   = "file"
+)
+
+// Outbox is a service that relays the domain events of the transactional outbox.
+// The events are written by the database in the same transaction as the change they describe,
+// the relay publishes them to the configured sinks at least once, in order for every user.
+// A failed event is retried with a doubling delay and dead-lettered after MaxAttempts attempts.
+type Outbox struct {
+	log       *slog.Logger
+	db        storages.StoreOutbox
+	bus       *Bus
+	publisher Publisher
+	closers   []io.Closer
+	conf      *config.Outbox
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// New creates a new instance of the Outbox service.
+// It initializes the service with a logger, database storage and the sinks from the configuration.
+// If a sink is unknown or can not be opened, it returns an error.
+func New(log *slog.Logger, db storages.StoreOutbox, conf *config.Outbox) (*Outbox, error) {
+	log.Debug("service Outbox: started creating")
+
+	outbox := &Outbox{
+		log:  log,
+		db:   db,
+		bus:  NewBus(),
+		conf: conf,
+	}
+
+	var publishers MultiPublisher
+	for _, sink := range conf.Sinks {
+		switch sink {
+		case SinkBus:
+			publishers = append(publishers, outbox.bus)
+		case SinkStdout:
+			publishers = append(publishers, NewWriterPublisher(os.Stdout))
+		case SinkFile:
+			file, err := NewFilePublisher(conf.FilePath)
+			if err != nil {
+				outbox.closeSinks()
+				return nil, err
+			}
+			publishers = append(publishers, file)
+			outbox.closers = append(outbox.closers, file)
+		default:
+			outbox.closeSinks()
+			return nil, fmt.Errorf("unknown outbox sink %q", sink)
+		}
+	}
+	outbox.publisher = publishers
+
+	log.Info("service Outbox: successfully created", "sinks", conf.Sinks)
+	return outbox, nil
+}
+
+// Stop gracefully shuts down the Outbox service.
+// It stops the relay, waits for the current batch to be published and closes the sinks.
+func (o *Outbox) Stop() error {
+	o.log.Debug("service Outbox: stop started")
+
+	if o.cancel != nil {
+		o.cancel()
+	}
+	o.wg.Wait()
+
+	if err := o.closeSinks(); err != nil {
+		o.log.Error("failed to close the outbox sinks", "error", err)
+		return err
+	}
+
+	o.db = nil
+
+	o.log.Info("service Outbox: stop successful")
+	return nil
+}
+
+// Subscribe adds an in-process subscriber for the event type, AllEvents subscribes it to every event type.
+// The subscribers are called only if the "bus" sink is configured.
+func (o *Outbox) Subscribe(eventType string, handler Handler) {
+	o.bus.Subscribe(eventType, handler)
+}
+
+// StartRelay runs the relay in the background, every poll interval the unpublished events are published.
+// The relay is stopped by Stop.
+func (o *Outbox) StartRelay() {
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel = cancel
+
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		o.log.Info("service Outbox: relay started", "poll interval", o.conf.PollInterval.String())
+
+		ticker := time.NewTicker(o.conf.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				o.log.Info("service Outbox: relay stopped")
+				return
+			case <-ticker.C:
+				o.relay(ctx)
+			}
+		}
+	}()
+}
+
+// relay publishes one batch of the unpublished events.
+// The batch is published to completion even if the relay is being stopped, so that no event is published twice because of the stop.
+func (o *Outbox) relay(ctx context.Context) {
+	op := "service Outbox: relaying events"
+	log := o.log.With(slog.String("operation", op))
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	published, err := o.db.RelayOutbox(ctx, o.conf.BatchSize, func(event models.OutboxEvent) error {
+		if err := o.publisher.Publish(ctx, event); err != nil {
+			log.Warn("failed to publish the event", "event id", event.ID, "type", event.Type, "user id", event.UserID, "error", err)
+			return err
+		}
+		return nil
+	}, func(attempts int) *time.Time {
+		return nextRetry(attempts, time.Now(), o.conf.MaxAttempts, o.conf.RetryDelay, o.conf.MaxRetryDelay)
+	})
+	if err != nil {
+		log.Error("failed to relay the outbox", "error", err)
+		return
+	}
+
+	if published > 0 {
+		log.Debug("events published", "count", published)
+	}
+}
+
+// nextRetry returns the time of the next attempt after the given number of failed attempts.
+// The delay doubles after every attempt, capped at maxDelay. After maxAttempts attempts it returns nil,
+// the event is dead-lettered.
+func nextRetry(attempts int, now time.Time, maxAttempts int, delay, maxDelay time.Duration) *time.Time {
+	if attempts >= maxAttempts {
+		return nil
+	}
+
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxDelay)
+
+	next := now.Add(delay)
+	return &next
+}
+
+func (o *Outbox) closeSinks() error {
+	var firstErr error
+	for _, closer := range o.closers {
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	o.closers = nil
+	return firstErr
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextRetry(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		attempts  int
+		wantDead  bool
+		wantDelay time.Duration
+	}{
+		{"first retry", 1, false, time.Second},
+		{"delay doubles", 4, false, 8 * time.Second},
+		{"delay is capped", 9, false, time.Minute},
+		{"last attempt", 10, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := nextRetry(tt.attempts, now, 10, time.Second, time.Minute)
+
+			if tt.wantDead {
+				if next != nil {
+					t.Errorf("nextRetry() = %v, want nil", next)
+				}
+				return
+			}
+
+			if next == nil || next.Sub(now) != tt.wantDelay {
+				t.Errorf("nextRetry() = %v, want %v", next, now.Add(tt.wantDelay))
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// AllEvents subscribes a handler of the Bus to every event type.
+const AllEvents = "*"
+
+// Publisher publishes the events of the outbox.
+// An event is published at least once: if Publish returns an error, the same event is published again later,
+// and the following events of the same user wait for it.
+type Publisher interface {
+	Publish(ctx context.Context, event models.OutboxEvent) error
+}
+
+// Handler is an in-process subscriber of the Bus.
+type Handler func(ctx context.Context, event models.OutboxEvent) error
+
+// Bus is an in-process publisher, it calls the handlers subscribed to the event type in the order of subscription.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe adds a handler for the event type, AllEvents subscribes it to every event type.
+func (b *Bus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Publish calls every handler of the event, the errors of the handlers are joined.
+func (b *Bus) Publish(ctx context.Context, event models.OutboxEvent) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler{}, b.handlers[event.Type]...), b.handlers[AllEvents]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WriterPublisher writes every event as a JSON line to the writer, e.g. os.Stdout.
+type WriterPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterPublisher(w io.Writer) *WriterPublisher {
+	return &WriterPublisher{w: w}
+}
+
+func (p *WriterPublisher) Publish(ctx context.Context, event models.OutboxEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err = p.w.Write(append(line, '\n'))
+	return err
+}
+
+// FilePublisher appends every event as a JSON line to a file, the file is synced after every event.
+type FilePublisher struct {
+	WriterPublisher
+	file *os.File
+}
+
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FilePublisher{WriterPublisher: WriterPublisher{w: file}, file: file}, nil
+}
+
+func (p *FilePublisher) Publish(ctx context.Context, event models.OutboxEvent) error {
+	if err := p.WriterPublisher.Publish(ctx, event); err != nil {
+		return err
+	}
+
+	return p.file.Sync()
+}
+
+func (p *FilePublisher) Close() error {
+	return p.file.Close()
+}
+
+// MultiPublisher publishes every event to all publishers in order and stops at the first error.
+// The event is published again to all of them, so the publishers before the failed one can receive it twice.
+type MultiPublisher []Publisher
+
+func (m MultiPublisher) Publish(ctx context.Context, event models.OutboxEvent) error {
+	for _, publisher := range m {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
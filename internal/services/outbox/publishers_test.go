@@ -0,0 +1,109 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+func TestBusPublish(t *testing.T) {
+	bus := NewBus()
+
+	var calls []string
+	record := func(name string, err error) Handler {
+		return func(ctx context.Context, event models.OutboxEvent) error {
+			calls = append(calls, name+":"+event.Type)
+			return err
+		}
+	}
+
+	errHandler := errors.New("handler failed")
+	bus.Subscribe(EventDeposited, record("first", nil))
+	bus.Subscribe(EventDeposited, record("second", errHandler))
+	bus.Subscribe(EventExchanged, record("exchange", nil))
+	bus.Subscribe(AllEvents, record("all", nil))
+
+	tests := []struct {
+		name      string
+		eventType string
+		wantCalls []string
+		wantErr   error
+	}{
+		{"handlers in the order of subscription", EventDeposited, []string{"first:" + EventDeposited, "second:" + EventDeposited, "all:" + EventDeposited}, errHandler},
+		{"only the subscribers of the type", EventExchanged, []string{"exchange:" + EventExchanged, "all:" + EventExchanged}, nil},
+		{"no typed subscribers", EventUserDeleted, []string{"all:" + EventUserDeleted}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+
+			err := bus.Publish(context.Background(), models.OutboxEvent{Type: tt.eventType})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Publish() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("Publish() calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+type failingPublisher struct{ err error }
+
+func (p failingPublisher) Publish(ctx context.Context, event models.OutboxEvent) error { return p.err }
+
+func TestMultiPublisherAndFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+
+	file, err := NewFilePublisher(path)
+	if err != nil {
+		t.Fatalf("NewFilePublisher() error = %v", err)
+	}
+
+	errSink := errors.New("sink failed")
+	events := []models.OutboxEvent{
+		{ID: 1, UserID: 1, Type: EventUserRegistered, Payload: json.RawMessage(`{"name":"Evans"}`)},
+		{ID: 2, UserID: 1, Type: EventDeposited, Payload: json.RawMessage(`{"amount":100}`)},
+	}
+
+	if err := (MultiPublisher{file, failingPublisher{errSink}}).Publish(context.Background(), events[0]); !errors.Is(err, errSink) {
+		t.Errorf("Publish() error = %v, want %v", err, errSink)
+	}
+
+	if err := (MultiPublisher{file}).Publish(context.Background(), events[1]); err != nil {
+		t.Errorf("Publish() error = %v", err)
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open error = %v", err)
+	}
+	defer f.Close()
+
+	var got []models.OutboxEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event models.OutboxEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		got = append(got, event)
+	}
+
+	if len(got) != 2 || got[0].ID != 1 || got[1].ID != 2 || string(got[1].Payload) != `{"amount":100}` {
+		t.Errorf("file contains %+v, want the events 1 and 2", got)
+	}
+}
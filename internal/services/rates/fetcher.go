@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// StartFetcher runs the shared fetcher in the background, every interval it requests the subscribed pairs
+// from the rate service and pushes the changed rates to the subscriptions.
+// The fetcher is stopped by Stop.
+func (r *Rates) StartFetcher() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.log.Info("service Rates: fetcher started", "interval", r.conf.FetchInterval.String())
+
+		ticker := time.NewTicker(r.conf.FetchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				r.log.Info("service Rates: fetcher stopped")
+				return
+			case <-ticker.C:
+				r.fetchRates(ctx)
+			}
+		}
+	}()
+}
+
+// fetchRates is one round of the fetcher.
+// Nothing is requested while there are no subscriptions. The rates of the pairs nobody is subscribed to
+// anymore are forgotten, so that a new subscriber never receives an outdated rate.
+// A pair that failed to be requested keeps its last rate and is requested again in the next round.
+func (r *Rates) fetchRates(ctx context.Context) {
+	op := "service Rates: fetching round"
+	log := r.log.With(slog.String("operation", op))
+
+	ctx, cancel := context.WithTimeout(ctx, r.conf.FetchInterval)
+	defer cancel()
+
+	pairs := r.subscribedPairs()
+	if len(pairs) == 0 {
+		return
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	fetched := make([]models.RateUpdate, 0, len(pairs))
+
+	for _, pair := range pairs {
+		wg.Add(1)
+		go func(pair string) {
+			defer wg.Done()
+
+			from, to, _ := strings.Cut(pair, "/")
+			rate := models.ExchangeRate{FromCurrency: from, ToCurrency: to}
+			if err := r.clientGRPC.ExchangeRate(ctx, &rate); err != nil {
+				log.Warn("failed to get the exchange rate", "pair", pair, "error", err)
+				return
+			}
+
+			mu.Lock()
+			fetched = append(fetched, models.RateUpdate{
+				Pair:         pair,
+				FromCurrency: from,
+				ToCurrency:   to,
+				Rate:         rate.Rate,
+				UpdatedAt:    time.Now().UTC(),
+			})
+			mu.Unlock()
+		}(pair)
+	}
+	wg.Wait()
+
+	changed := r.publish(fetched)
+
+	log.Debug("fetching round completed", slog.Int("pairs", len(pairs)), slog.Int("fetched", len(fetched)), slog.Int("changed", changed))
+}
+
+// subscribedPairs returns the pairs of all subscriptions and forgets the rates of the other pairs.
+func (r *Rates) subscribedPairs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	union := make(map[string]struct{})
+	for sub := range r.subs {
+		for pair := range sub.pairs {
+			union[pair] = struct{}{}
+		}
+	}
+
+	for pair := range r.last {
+		if _, ok := union[pair]; !ok {
+			delete(r.last, pair)
+		}
+	}
+
+	pairs := make([]string, 0, len(union))
+	for pair := range union {
+		pairs = append(pairs, pair)
+	}
+
+	return pairs
+}
+
+// publish remembers the fetched rates and sends the changed ones to the subscriptions of their pairs.
+// It returns the number of the changed rates.
+func (r *Rates) publish(fetched []models.RateUpdate) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := make(map[string]models.RateUpdate)
+	for _, update := range fetched {
+		if last, ok := r.last[update.Pair]; ok && last.Rate == update.Rate {
+			continue
+		}
+		r.last[update.Pair] = update
+		changed[update.Pair] = update
+	}
+
+	if len(changed) == 0 {
+		return 0
+	}
+
+	for sub := range r.subs {
+		var updates []models.RateUpdate
+		for pair := range sub.pairs {
+			if update, ok := changed[pair]; ok {
+				updates = append(updates, update)
+			}
+		}
+
+		if len(updates) > 0 {
+			sort.Slice(updates, func(i, j int) bool { return updates[i].Pair < updates[j].Pair })
+			r.send(sub, updates)
+		}
+	}
+
+	return len(changed)
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	grpcclient "github.com/EvansTrein/RESTful_exchangerServer/pkg/gRPCclient"
+)
+
+type fakeRates struct {
+	grpcclient.ClientGRPC
+	mu    sync.Mutex
+	rates map[string]float32
+	calls map[string]int
+}
+
+func (f *fakeRates) ExchangeRate(ctx context.Context, req *models.ExchangeRate) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pair := req.FromCurrency + "/" + req.ToCurrency
+	f.calls[pair]++
+	req.Rate = f.rates[pair]
+	return nil
+}
+
+func newTestRates(buffer int, rates map[string]float32) (*Rates, *fakeRates) {
+	gRPC := &fakeRates{rates: rates, calls: make(map[string]int)}
+	conf := &config.Streaming{FetchInterval: time.Second, ClientBuffer: buffer, MaxPairs: 3}
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), gRPC, conf), gRPC
+}
+
+// received drains the queued updates of the subscription and returns the pairs with their rates.
+func received(sub *Subscription) map[string]float32 {
+	got := make(map[string]float32)
+	for {
+		select {
+		case updates, ok := <-sub.Updates():
+			if !ok {
+				return got
+			}
+			for _, update := range updates {
+				got[update.Pair] = update.Rate
+			}
+		default:
+			return got
+		}
+	}
+}
+
+func TestParsePairs(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []string
+		want    []string
+		wantErr error
+	}{
+		{"normalized and deduplicated", []string{" usd/eur", "USD/EUR", "EUR/RUB"}, []string{"USD/EUR", "EUR/RUB"}, nil},
+		{"missing separator", []string{"USDEUR"}, nil, ErrInvalidPair},
+		{"same currency", []string{"USD/USD"}, nil, ErrInvalidPair},
+		{"short code", []string{"US/EUR"}, nil, ErrInvalidPair},
+		{"not a letter", []string{"USD/EU1"}, nil, ErrInvalidPair},
+		{"empty", []string{""}, nil, ErrInvalidPair},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePairs(tt.pairs)
+			if err != tt.wantErr {
+				t.Fatalf("parsePairs() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePairs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchRates(t *testing.T) {
+	r, gRPC := newTestRates(4, map[string]float32{"USD/EUR": 0.96, "EUR/RUB": 105})
+
+	first, err := r.Subscribe([]string{"USD/EUR"})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	second, err := r.Subscribe([]string{"usd/eur", "EUR/RUB"})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	rounds := []struct {
+		name       string
+		rates      map[string]float32
+		wantFirst  map[string]float32
+		wantSecond map[string]float32
+	}{
+		{
+			name:       "first round sends all pairs",
+			wantFirst:  map[string]float32{"USD/EUR": 0.96},
+			wantSecond: map[string]float32{"USD/EUR": 0.96, "EUR/RUB": 105},
+		},
+		{
+			name:       "unchanged rates are not sent",
+			wantFirst:  map[string]float32{},
+			wantSecond: map[string]float32{},
+		},
+		{
+			name:       "only the subscribers of the changed pair",
+			rates:      map[string]float32{"EUR/RUB": 106},
+			wantFirst:  map[string]float32{},
+			wantSecond: map[string]float32{"EUR/RUB": 106},
+		},
+	}
+
+	for i, round := range rounds {
+		t.Run(round.name, func(t *testing.T) {
+			gRPC.mu.Lock()
+			for pair, rate := range round.rates {
+				gRPC.rates[pair] = rate
+			}
+			gRPC.mu.Unlock()
+
+			r.fetchRates(context.Background())
+
+			if got := received(first); !reflect.DeepEqual(got, round.wantFirst) {
+				t.Errorf("first subscription received %v, want %v", got, round.wantFirst)
+			}
+			if got := received(second); !reflect.DeepEqual(got, round.wantSecond) {
+				t.Errorf("second subscription received %v, want %v", got, round.wantSecond)
+			}
+
+			// every pair is requested once per round, regardless of the number of subscriptions
+			for pair, calls := range gRPC.calls {
+				if calls != i+1 {
+					t.Errorf("pair %s requested %d times after %d rounds", pair, calls, i+1)
+				}
+			}
+		})
+	}
+
+	late, err := r.Subscribe([]string{"EUR/RUB"})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if got, want := received(late), map[string]float32{"EUR/RUB": 106}; !reflect.DeepEqual(got, want) {
+		t.Errorf("new subscription received %v, want the known rates %v", got, want)
+	}
+}
+
+func TestSlowConsumer(t *testing.T) {
+	r, gRPC := newTestRates(1, map[string]float32{"USD/EUR": 0.96})
+
+	sub, err := r.Subscribe([]string{"USD/EUR"})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	r.fetchRates(context.Background())
+
+	gRPC.mu.Lock()
+	gRPC.rates["USD/EUR"] = 0.97
+	gRPC.mu.Unlock()
+
+	r.fetchRates(context.Background())
+
+	received(sub)
+	if _, ok := <-sub.Updates(); ok {
+		t.Fatal("subscription of a slow client is not closed")
+	}
+	if sub.Err() != ErrSlowConsumer {
+		t.Errorf("Err() = %v, want %v", sub.Err(), ErrSlowConsumer)
+	}
+
+	if err := r.AddPairs(sub, []string{"EUR/RUB"}); err != ErrStreamClosed {
+		t.Errorf("AddPairs() on a dropped subscription error = %v, want %v", err, ErrStreamClosed)
+	}
+	r.Unsubscribe(sub)
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	grpcclient "github.com/EvansTrein/RESTful_exchangerServer/pkg/gRPCclient"
+)
+
+const (
+	// message types of the rate WebSocket
+	MessageRates       = "rates"
+	MessageSubscribe   = "subscribe"
+	MessageUnsubscribe = "unsubscribe"
+	MessageError       = "error"
+)
+
+var (
+	ErrInvalidPair  = errors.New("currency pair must be specified as FROM/TO, for example USD/EUR")
+	ErrTooManyPairs = errors.New("too many currency pairs in the subscription")
+	ErrSlowConsumer = errors.New("client does not keep up with the rate updates")
+	ErrStreamClosed = errors.New("rate stream is closed")
+)
+
+// Subscription is the subscription of one client to the rates of some currency pairs.
+// The changed rates are received from Updates, the channel is closed when the subscription is dropped,
+// the reason is returned by Err after that.
+type Subscription struct {
+	updates chan []models.RateUpdate
+	pairs   map[string]struct{}
+	err     error
+}
+
+// Updates returns the channel of the rate updates of the subscription.
+func (s *Subscription) Updates() <-chan []models.RateUpdate {
+	return s.updates
+}
+
+// Err returns the reason why the subscription was dropped, it must be called after Updates is closed.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// Rates is a service that streams the exchange rates to the connected clients.
+// A single fetcher requests every subscribed pair from the rate service once per interval,
+// regardless of the number of clients, and fans the changed rates out to the subscriptions.
+type Rates struct {
+	log        *slog.Logger
+	clientGRPC grpcclient.ClientGRPC
+	conf       *config.Streaming
+	mu         sync.Mutex
+	subs       map[*Subscription]struct{}
+	last       map[string]models.RateUpdate
+	closed     bool
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// New creates a new instance of the Rates service.
+// It initializes the service with a logger, gRPC client for the rates and the streaming configuration.
+func New(log *slog.Logger, gRPC grpcclient.ClientGRPC, conf *config.Streaming) *Rates {
+	log.Debug("service Rates: started creating")
+
+	log.Info("service Rates: successfully created")
+	return &Rates{
+		log:        log,
+		clientGRPC: gRPC,
+		conf:       conf,
+		subs:       make(map[*Subscription]struct{}),
+		last:       make(map[string]models.RateUpdate),
+	}
+}
+
+// Stop gracefully shuts down the Rates service.
+// It stops the fetcher and closes all subscriptions, so that the open streams are finished.
+func (r *Rates) Stop() error {
+	r.log.Debug("service Rates: stop started")
+
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+
+	r.mu.Lock()
+	r.closed = true
+	for sub := range r.subs {
+		r.drop(sub, ErrStreamClosed)
+	}
+	r.mu.Unlock()
+
+	r.log.Info("service Rates: stop successful")
+	return nil
+}
+
+// Subscribe creates a subscription to the given currency pairs.
+// The already known rates of the pairs are sent to the subscription at once, the others arrive with the next fetch.
+func (r *Rates) Subscribe(pairs []string) (*Subscription, error) {
+	op := "service Rates: subscribe"
+	log := r.log.With(slog.String("operation", op))
+	log.Debug("Subscribe func call", slog.Any("requets data", pairs))
+
+	parsed, err := parsePairs(pairs)
+	if err != nil {
+		log.Warn("invalid currency pairs", "error", err)
+		return nil, err
+	}
+
+	if len(parsed) > r.conf.MaxPairs {
+		log.Warn("too many currency pairs", "count", len(parsed), "max", r.conf.MaxPairs)
+		return nil, ErrTooManyPairs
+	}
+
+	sub := &Subscription{
+		updates: make(chan []models.RateUpdate, r.conf.ClientBuffer),
+		pairs:   make(map[string]struct{}, len(parsed)),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, ErrStreamClosed
+	}
+
+	r.subs[sub] = struct{}{}
+	r.addPairs(sub, parsed)
+
+	log.Info("client subscribed to the rates", "pairs", parsed, "subscriptions", len(r.subs))
+	return sub, nil
+}
+
+// AddPairs adds currency pairs to the subscription, the known rates of the new pairs are sent at once.
+func (r *Rates) AddPairs(sub *Subscription, pairs []string) error {
+	op := "service Rates: add pairs"
+	log := r.log.With(slog.String("operation", op))
+	log.Debug("AddPairs func call", slog.Any("requets data", pairs))
+
+	parsed, err := parsePairs(pairs)
+	if err != nil {
+		log.Warn("invalid currency pairs", "error", err)
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subs[sub]; !ok {
+		return ErrStreamClosed
+	}
+
+	count := len(sub.pairs)
+	for _, pair := range parsed {
+		if _, ok := sub.pairs[pair]; !ok {
+			count++
+		}
+	}
+
+	if count > r.conf.MaxPairs {
+		log.Warn("too many currency pairs", "count", count, "max", r.conf.MaxPairs)
+		return ErrTooManyPairs
+	}
+
+	r.addPairs(sub, parsed)
+
+	log.Debug("pairs added to the subscription", "pairs", parsed)
+	return nil
+}
+
+// RemovePairs removes currency pairs from the subscription.
+func (r *Rates) RemovePairs(sub *Subscription, pairs []string) error {
+	op := "service Rates: remove pairs"
+	log := r.log.With(slog.String("operation", op))
+	log.Debug("RemovePairs func call", slog.Any("requets data", pairs))
+
+	parsed, err := parsePairs(pairs)
+	if err != nil {
+		log.Warn("invalid currency pairs", "error", err)
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subs[sub]; !ok {
+		return ErrStreamClosed
+	}
+
+	for _, pair := range parsed {
+		delete(sub.pairs, pair)
+	}
+
+	log.Debug("pairs removed from the subscription", "pairs", parsed)
+	return nil
+}
+
+// Unsubscribe removes the subscription, it is safe to call it for an already dropped subscription.
+func (r *Rates) Unsubscribe(sub *Subscription) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subs[sub]; ok {
+		r.drop(sub, ErrStreamClosed)
+		r.log.Debug("service Rates: client unsubscribed", "subscriptions", len(r.subs))
+	}
+}
+
+// addPairs adds the pairs to the subscription and sends their known rates, the caller must hold the mutex.
+func (r *Rates) addPairs(sub *Subscription, pairs []string) {
+	var snapshot []models.RateUpdate
+	for _, pair := range pairs {
+		sub.pairs[pair] = struct{}{}
+		if rate, ok := r.last[pair]; ok {
+			snapshot = append(snapshot, rate)
+		}
+	}
+
+	if len(snapshot) > 0 {
+		r.send(sub, snapshot)
+	}
+}
+
+// send puts the updates into the queue of the subscription without blocking the fetcher.
+// A client whose queue is full does not keep up with the updates, its subscription is dropped.
+// The caller must hold the mutex.
+func (r *Rates) send(sub *Subscription, updates []models.RateUpdate) {
+	select {
+	case sub.updates <- updates:
+	default:
+		r.log.Warn("service Rates: slow client dropped", "queue", cap(sub.updates))
+		r.drop(sub, ErrSlowConsumer)
+	}
+}
+
+// drop removes the subscription and closes its channel, the caller must hold the mutex.
+func (r *Rates) drop(sub *Subscription, err error) {
+	delete(r.subs, sub)
+	sub.err = err
+	close(sub.updates)
+}
+
+// parsePairs normalizes the currency pairs to upper case FROM/TO and removes the duplicates.
+func parsePairs(pairs []string) ([]string, error) {
+	parsed := make([]string, 0, len(pairs))
+	seen := make(map[string]struct{}, len(pairs))
+
+	for _, pair := range pairs {
+		pair = strings.ToUpper(strings.TrimSpace(pair))
+
+		from, to, ok := strings.Cut(pair, "/")
+		if !ok || !validCurrency(from) || !validCurrency(to) || from == to {
+			return nil, ErrInvalidPair
+		}
+
+		if _, ok := seen[pair]; ok {
+			continue
+		}
+		seen[pair] = struct{}{}
+		parsed = append(parsed, pair)
+	}
+
+	if len(parsed) == 0 {
+		return nil, ErrInvalidPair
+	}
+
+	return parsed, nil
+}
+
+// validCurrency reports whether the code has 3 to 6 latin letters, like the currencies of the requests.
+func validCurrency(code string) bool {
+	if len(code) < 3 || len(code) > 6 {
+		return false
+	}
+
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// 2025-01-01 is a Wednesday
+	base := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"every minute", "* * * * *", base, time.Date(2025, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{"seconds are truncated", "* * * * *", base.Add(30 * time.Second), time.Date(2025, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{"every 15 minutes", "*/15 * * * *", base, time.Date(2025, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{"daily descriptor", "@daily", base, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"every monday by name", "0 9 * * MON", base, time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", base, time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"working days range", "0 8 * * 1-5", time.Date(2025, 1, 3, 9, 0, 0, 0, time.UTC), time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)},
+		{"list of hours", "0 6,18 * * *", base, time.Date(2025, 1, 1, 18, 0, 0, 0, time.UTC)},
+		{"first day of the month", "@monthly", base, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"day of month or day of week", "0 0 15 * FRI", base, time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", base, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"impossible date", "0 0 30 2 *", base, time.Time{}},
+		{"other time zone is converted to UTC", "0 12 * * *", time.Date(2025, 1, 1, 14, 0, 0, 0, time.FixedZone("UTC+3", 3*3600)), time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCron(%q) error = %v", tt.expr, err)
+			}
+
+			if got := cron.next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+		"@every",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := parseCron(expr); !errors.Is(err, ErrInvalidCron) {
+				t.Errorf("parseCron(%q) error = %v, want %v", expr, err, ErrInvalidCron)
+			}
+		})
+	}
+}
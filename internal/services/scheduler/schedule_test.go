@@ -0,0 +1,166 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+func TestPlanNextRun(t *testing.T) {
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	due := start.Add(7 * 24 * time.Hour)
+	end := due.Add(24 * time.Hour)
+
+	tests := []struct {
+		name       string
+		schedule   models.Schedule
+		failed     bool
+		now        time.Time
+		wantStatus string
+		wantDue    *time.Time
+		wantNext   *time.Time
+		wantRetry  int
+	}{
+		{
+			name:       "success moves to the next occurrence",
+			schedule:   models.Schedule{Interval: "168h", StartsAt: start, Status: StatusActive, DueAt: &due},
+			now:        due.Add(time.Minute),
+			wantStatus: StatusActive,
+			wantDue:    ptr(due.Add(7 * 24 * time.Hour)),
+			wantNext:   ptr(due.Add(7 * 24 * time.Hour)),
+		},
+		{
+			name:       "missed occurrences are skipped",
+			schedule:   models.Schedule{Cron: "0 9 * * MON", StartsAt: start, Status: StatusActive, DueAt: &start},
+			now:        start.Add(15 * 24 * time.Hour),
+			wantStatus: StatusActive,
+			wantDue:    ptr(start.Add(21 * 24 * time.Hour)),
+			wantNext:   ptr(start.Add(21 * 24 * time.Hour)),
+		},
+		{
+			name:       "failure is retried with a doubling delay",
+			schedule:   models.Schedule{Interval: "168h", StartsAt: start, Status: StatusActive, DueAt: &due, RetryCount: 1},
+			failed:     true,
+			now:        due.Add(time.Minute),
+			wantStatus: StatusActive,
+			wantDue:    &due,
+			wantNext:   ptr(due.Add(time.Minute + 2*time.Minute)),
+			wantRetry:  2,
+		},
+		{
+			name:       "last retry moves to the next occurrence",
+			schedule:   models.Schedule{Interval: "168h", StartsAt: start, Status: StatusActive, DueAt: &due, RetryCount: 3},
+			failed:     true,
+			now:        due.Add(time.Hour),
+			wantStatus: StatusActive,
+			wantDue:    ptr(due.Add(7 * 24 * time.Hour)),
+			wantNext:   ptr(due.Add(7 * 24 * time.Hour)),
+		},
+		{
+			name:       "schedule finishes after the end date",
+			schedule:   models.Schedule{Interval: "168h", StartsAt: start, EndsAt: &end, Status: StatusActive, DueAt: &due},
+			now:        due.Add(time.Minute),
+			wantStatus: StatusFinished,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := tt.schedule
+			if err := planNextRun(&schedule, tt.failed, tt.now, 3, time.Minute); err != nil {
+				t.Fatalf("planNextRun() error = %v", err)
+			}
+
+			if schedule.Status != tt.wantStatus {
+				t.Errorf("status = %v, want %v", schedule.Status, tt.wantStatus)
+			}
+			if !equalTime(schedule.DueAt, tt.wantDue) {
+				t.Errorf("due at = %v, want %v", schedule.DueAt, tt.wantDue)
+			}
+			if !equalTime(schedule.NextRunAt, tt.wantNext) {
+				t.Errorf("next run at = %v, want %v", schedule.NextRunAt, tt.wantNext)
+			}
+			if schedule.RetryCount != tt.wantRetry {
+				t.Errorf("retry count = %v, want %v", schedule.RetryCount, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestNewSchedule(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		req     models.CreateScheduleRequest
+		wantDue time.Time
+		wantErr error
+	}{
+		{
+			name:    "interval starts now",
+			req:     models.CreateScheduleRequest{Operation: "deposit", Currency: "USD", Amount: 10, Interval: "24h"},
+			wantDue: now,
+		},
+		{
+			name:    "interval aligned to a past start",
+			req:     models.CreateScheduleRequest{Operation: "deposit", Currency: "USD", Amount: 10, Interval: "1h", StartsAt: ptr(now.Add(-90 * time.Minute))},
+			wantDue: now.Add(30 * time.Minute),
+		},
+		{
+			name:    "cron",
+			req:     models.CreateScheduleRequest{Operation: "exchange", Currency: "USD", ToCurrency: "EUR", Amount: 100, Cron: "0 9 * * MON"},
+			wantDue: time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "both cron and interval",
+			req:     models.CreateScheduleRequest{Operation: "deposit", Currency: "USD", Amount: 10, Cron: "@daily", Interval: "24h"},
+			wantErr: ErrScheduleTiming,
+		},
+		{
+			name:    "interval is too short",
+			req:     models.CreateScheduleRequest{Operation: "deposit", Currency: "USD", Amount: 10, Interval: "30s"},
+			wantErr: ErrInvalidInterval,
+		},
+		{
+			name:    "exchange without target currency",
+			req:     models.CreateScheduleRequest{Operation: "exchange", Currency: "USD", Amount: 10, Interval: "24h"},
+			wantErr: ErrMissingToCurrency,
+		},
+		{
+			name:    "end date has passed",
+			req:     models.CreateScheduleRequest{Operation: "deposit", Currency: "USD", Amount: 10, Interval: "24h", StartsAt: ptr(past.Add(-time.Hour)), EndsAt: &past},
+			wantErr: ErrInvalidPeriod,
+		},
+		{
+			name:    "no run before the end date",
+			req:     models.CreateScheduleRequest{Operation: "deposit", Currency: "USD", Amount: 10, Cron: "0 0 1 1 *", EndsAt: ptr(now.Add(time.Hour))},
+			wantErr: ErrNoOccurrences,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := newSchedule(tt.req, now)
+			if err != tt.wantErr {
+				t.Fatalf("newSchedule() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err == nil && !schedule.DueAt.Equal(tt.wantDue) {
+				t.Errorf("due at = %v, want %v", schedule.DueAt, tt.wantDue)
+			}
+		})
+	}
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
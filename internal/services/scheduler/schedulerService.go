@@ -0,0 +1,347 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+const (
+	StatusActive   = "active"
+	StatusPaused   = "paused"
+	StatusFinished = "finished"
+
+	RunSuccess = "success"
+	RunFailed  = "failed"
+)
+
+var (
+	ErrScheduleNotFound  = errors.New("schedule not found")
+	ErrScheduleFinished  = errors.New("schedule is finished")
+	ErrScheduleTiming    = errors.New("exactly one of cron and interval must be specified")
+	ErrInvalidInterval   = errors.New("interval must be a duration of at least one minute")
+	ErrMissingToCurrency = errors.New("target currency is required for an exchange")
+	ErrSameCurrency      = errors.New("same currency is specified for buying and selling")
+	ErrInvalidPeriod     = errors.New("end date must be later than the start date and the current time")
+	ErrNoOccurrences     = errors.New("schedule has no runs before its end date")
+	ErrLeaseTooShort     = errors.New("lease of the schedules is too short for the claimed batch")
+)
+
+// walletOperations are the operations of the Wallet service run by the schedules.
+type walletOperations interface {
+	Deposit(ctx context.Context, req *models.AccountOperationRequest) (*models.AccountOperationResponse, error)
+	Exchange(ctx context.Context, req models.ExchangeRequest) (*models.ExchangeResponse, error)
+}
+
+// Scheduler is a service that handles scheduled and recurring deposits and exchanges.
+// Users manage their schedules, the workers run the due schedules through the Wallet service
+// on behalf of the owning user and record the result of every run.
+type Scheduler struct {
+	log    *slog.Logger
+	db     storages.StoreSchedules
+	wallet walletOperations
+	conf   *config.Scheduler
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a new instance of the Scheduler service.
+// It initializes the service with a logger, database storage, the Wallet service and the configuration of the workers.
+// If the lease is too short for the claimed batch, it returns an error.
+func New(log *slog.Logger, db storages.StoreSchedules, wallet walletOperations, conf *config.Scheduler) (*Scheduler, error) {
+	log.Debug("service Scheduler: started creating")
+
+	if err := checkLease(conf); err != nil {
+		log.Error("invalid configuration of the scheduler", "error", err)
+		return nil, err
+	}
+
+	log.Info("service Scheduler: successfully created")
+	return &Scheduler{
+		log:    log,
+		db:     db,
+		wallet: wallet,
+		conf:   conf,
+	}, nil
+}
+
+// checkLease verifies that the lease outlives the claimed batch. The batch is run by Workers goroutines,
+// every run and its record take up to RunTimeout each, so the last schedule of the batch may start
+// ceil(BatchSize/Workers) runs after the claim. A shorter lease would let another instance claim it while it waits.
+func checkLease(conf *config.Scheduler) error {
+	workers := max(conf.Workers, 1)
+	rounds := (max(conf.BatchSize, 1) + workers - 1) / workers
+	needed := time.Duration(rounds) * 2 * conf.RunTimeout
+
+	if conf.Lease <= needed {
+		return fmt.Errorf("%w: lease %s, the batch of %d schedules by %d workers needs more than %s",
+			ErrLeaseTooShort, conf.Lease, conf.BatchSize, workers, needed)
+	}
+
+	return nil
+}
+
+// Stop gracefully shuts down the Scheduler service.
+// It stops the workers, waits for the runs in progress to be recorded and cleans up resources.
+func (s *Scheduler) Stop() error {
+	s.log.Debug("service Scheduler: stop started")
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+
+	s.db = nil
+	s.wallet = nil
+
+	s.log.Info("service Scheduler: stop successful")
+	return nil
+}
+
+// CreateSchedule validates the request, calculates the first run and saves the schedule.
+func (s *Scheduler) CreateSchedule(ctx context.Context, req models.CreateScheduleRequest) (*models.ScheduleResponse, error) {
+	op := "service Scheduler: schedule creation"
+	log := s.log.With(slog.String("operation", op))
+	log.Debug("CreateSchedule func call", slog.Any("requets data", req))
+
+	schedule, err := newSchedule(req, time.Now())
+	if err != nil {
+		log.Warn("invalid schedule", "error", err)
+		return nil, err
+	}
+
+	if err := s.db.CreateSchedule(ctx, schedule); err != nil {
+		log.Error("failed to save the schedule in the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("schedule successfully created", "schedule id", schedule.ID)
+	return &models.ScheduleResponse{Message: "schedule successfully created", Schedule: *schedule}, nil
+}
+
+// Schedules returns all schedules of the user.
+func (s *Scheduler) Schedules(ctx context.Context, req models.SchedulesRequest) (*models.SchedulesResponse, error) {
+	op := "service Scheduler: list of schedules"
+	log := s.log.With(slog.String("operation", op))
+	log.Debug("Schedules func call", slog.Any("requets data", req))
+
+	schedules, err := s.db.UserSchedules(ctx, req.UserID)
+	if err != nil {
+		log.Error("failed to get the schedules from the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("schedules successfully received", "count", len(schedules))
+	return &models.SchedulesResponse{Message: "schedules successfully received", Schedules: schedules}, nil
+}
+
+// Schedule returns one schedule of the user together with the results of its latest runs.
+func (s *Scheduler) Schedule(ctx context.Context, req models.ScheduleRequest) (*models.ScheduleResponse, error) {
+	op := "service Scheduler: schedule details"
+	log := s.log.With(slog.String("operation", op))
+	log.Debug("Schedule func call", slog.Any("requets data", req))
+
+	schedule, runs, err := s.db.UserSchedule(ctx, req.UserID, req.ScheduleID)
+	if err != nil {
+		log.Warn("failed to get the schedule from the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("schedule successfully received")
+	return &models.ScheduleResponse{Message: "schedule successfully received", Schedule: *schedule, Runs: runs}, nil
+}
+
+// UpdateSchedule pauses or resumes the schedule, changes its amount or its end date.
+// A resumed schedule continues from the next occurrence, the missed ones are not run.
+func (s *Scheduler) UpdateSchedule(ctx context.Context, req models.UpdateScheduleRequest) (*models.ScheduleResponse, error) {
+	op := "service Scheduler: schedule update"
+	log := s.log.With(slog.String("operation", op))
+	log.Debug("UpdateSchedule func call", slog.Any("requets data", req))
+
+	schedule, _, err := s.db.UserSchedule(ctx, req.UserID, req.ScheduleID)
+	if err != nil {
+		log.Warn("failed to get the schedule from the database", "error", err)
+		return nil, err
+	}
+
+	if err := applyUpdate(schedule, req, time.Now()); err != nil {
+		log.Warn("invalid schedule update", "error", err)
+		return nil, err
+	}
+
+	if err := s.db.UpdateSchedule(ctx, schedule); err != nil {
+		log.Error("failed to update the schedule in the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("schedule successfully updated")
+	return &models.ScheduleResponse{Message: "schedule successfully updated", Schedule: *schedule}, nil
+}
+
+// DeleteSchedule deletes the schedule of the user together with the history of its runs.
+func (s *Scheduler) DeleteSchedule(ctx context.Context, req models.ScheduleRequest) error {
+	op := "service Scheduler: schedule removal"
+	log := s.log.With(slog.String("operation", op))
+	log.Debug("DeleteSchedule func call", slog.Any("requets data", req))
+
+	if err := s.db.DeleteSchedule(ctx, req.UserID, req.ScheduleID); err != nil {
+		log.Warn("failed to delete the schedule", "error", err)
+		return err
+	}
+
+	log.Info("schedule successfully deleted")
+	return nil
+}
+
+// newSchedule builds a schedule from the request and calculates its first run.
+// The schedule starts now if no start date is given.
+func newSchedule(req models.CreateScheduleRequest, now time.Time) (*models.Schedule, error) {
+	schedule := &models.Schedule{
+		UserID:    req.UserID,
+		Operation: req.Operation,
+		Currency:  req.Currency,
+		Amount:    req.Amount,
+		Cron:      req.Cron,
+		StartsAt:  now.UTC().Truncate(time.Second),
+		EndsAt:    req.EndsAt,
+		Status:    StatusActive,
+	}
+
+	switch req.Operation {
+	case servWallet.OperationExchange:
+		if req.ToCurrency == "" {
+			return nil, ErrMissingToCurrency
+		}
+		if req.ToCurrency == req.Currency {
+			return nil, ErrSameCurrency
+		}
+		schedule.ToCurrency = req.ToCurrency
+	case servWallet.OperationDeposit:
+	default:
+		return nil, servWallet.ErrInvalidOperationType
+	}
+
+	if (req.Cron == "") == (req.Interval == "") {
+		return nil, ErrScheduleTiming
+	}
+
+	if req.Cron != "" {
+		if _, err := parseCron(req.Cron); err != nil {
+			return nil, err
+		}
+	} else {
+		interval, err := time.ParseDuration(req.Interval)
+		if err != nil || interval < time.Minute {
+			return nil, ErrInvalidInterval
+		}
+		schedule.Interval = interval.String()
+	}
+
+	if req.StartsAt != nil {
+		schedule.StartsAt = req.StartsAt.UTC()
+	}
+
+	if req.EndsAt != nil && (!req.EndsAt.After(schedule.StartsAt) || !req.EndsAt.After(now)) {
+		return nil, ErrInvalidPeriod
+	}
+
+	due, err := nextOccurrence(schedule, now.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+
+	if due.IsZero() {
+		return nil, ErrNoOccurrences
+	}
+
+	schedule.DueAt = &due
+	schedule.NextRunAt = &due
+
+	return schedule, nil
+}
+
+// applyUpdate applies the requested changes to the schedule.
+func applyUpdate(schedule *models.Schedule, req models.UpdateScheduleRequest, now time.Time) error {
+	if schedule.Status == StatusFinished {
+		return ErrScheduleFinished
+	}
+
+	if req.Amount != nil {
+		schedule.Amount = *req.Amount
+	}
+
+	if req.EndsAt != nil {
+		if !req.EndsAt.After(schedule.StartsAt) || !req.EndsAt.After(now) {
+			return ErrInvalidPeriod
+		}
+		schedule.EndsAt = req.EndsAt
+	}
+
+	resumed := req.Status == StatusActive && schedule.Status == StatusPaused
+	if req.Status != "" {
+		schedule.Status = req.Status
+	}
+
+	if resumed && (schedule.DueAt == nil || schedule.DueAt.Before(now)) {
+		due, err := nextOccurrence(schedule, now)
+		if err != nil {
+			return err
+		}
+		schedule.DueAt = &due
+		schedule.NextRunAt = &due
+		schedule.RetryCount = 0
+	}
+
+	if schedule.DueAt != nil && (schedule.DueAt.IsZero() || (schedule.EndsAt != nil && schedule.DueAt.After(*schedule.EndsAt))) {
+		schedule.Status = StatusFinished
+		schedule.DueAt = nil
+		schedule.NextRunAt = nil
+	}
+
+	return nil
+}
+
+// nextOccurrence returns the first occurrence of the schedule after the given time.
+// Interval schedules are aligned to their start date, cron schedules are evaluated in UTC.
+// If the schedule has no more occurrences before its end date, it returns the zero time.
+func nextOccurrence(schedule *models.Schedule, after time.Time) (time.Time, error) {
+	var next time.Time
+
+	if schedule.Cron != "" {
+		cron, err := parseCron(schedule.Cron)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if schedule.StartsAt.After(after) {
+			after = schedule.StartsAt.Add(-time.Nanosecond)
+		}
+		next = cron.next(after)
+	} else {
+		interval, err := time.ParseDuration(schedule.Interval)
+		if err != nil || interval <= 0 {
+			return time.Time{}, ErrInvalidInterval
+		}
+
+		if schedule.StartsAt.After(after) {
+			next = schedule.StartsAt
+		} else {
+			periods := after.Sub(schedule.StartsAt)/interval + 1
+			next = schedule.StartsAt.Add(periods * interval)
+		}
+	}
+
+	if schedule.EndsAt != nil && next.After(*schedule.EndsAt) {
+		return time.Time{}, nil
+	}
+
+	return next.UTC(), nil
+}
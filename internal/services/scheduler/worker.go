@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// StartWorkers runs the workers in the background, every poll interval the due schedules are claimed and run.
+// A schedule is claimed in the database for the lease time, so several instances never run the same schedule at once.
+// The workers are stopped by Stop.
+func (s *Scheduler) StartWorkers() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.log.Info("service Scheduler: workers started", "workers", s.conf.Workers, "poll interval", s.conf.PollInterval.String())
+
+		ticker := time.NewTicker(s.conf.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.log.Info("service Scheduler: workers stopped")
+				return
+			case <-ticker.C:
+				s.runDueSchedules(ctx)
+			}
+		}
+	}()
+}
+
+// runDueSchedules claims a batch of due schedules and runs them concurrently, at most Workers at a time.
+// The runs are not interrupted by Stop, so that their result is always recorded.
+func (s *Scheduler) runDueSchedules(ctx context.Context) {
+	op := "service Scheduler: running due schedules"
+	log := s.log.With(slog.String("operation", op))
+
+	schedules, err := s.db.ClaimDueSchedules(ctx, s.conf.BatchSize, s.conf.Lease)
+	if err != nil {
+		log.Error("failed to claim due schedules", "error", err)
+		return
+	}
+
+	if len(schedules) == 0 {
+		log.Debug("no due schedules")
+		return
+	}
+
+	log.Debug("due schedules claimed", "count", len(schedules))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(s.conf.Workers, 1))
+
+	for i := range schedules {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(schedule *models.Schedule) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			s.runSchedule(schedule)
+		}(&schedules[i])
+	}
+
+	wg.Wait()
+}
+
+// runSchedule runs one occurrence of the schedule, records the result and plans the next run.
+func (s *Scheduler) runSchedule(schedule *models.Schedule) {
+	op := "service Scheduler: schedule run"
+	log := s.log.With(slog.String("operation", op), slog.Uint64("schedule id", uint64(schedule.ID)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.conf.RunTimeout)
+	defer cancel()
+
+	run := &models.ScheduleRun{
+		ScheduledFor: *schedule.DueAt,
+		Attempt:      schedule.RetryCount + 1,
+		Status:       RunSuccess,
+	}
+
+	details, err := s.execute(ctx, schedule)
+	if errors.Is(err, servWallet.ErrDuplicateOperation) {
+		// the operation was committed, but its run was not recorded, only the record is made now
+		log.Warn("scheduled operation has already been executed, the run is recorded", "scheduled for", run.ScheduledFor)
+		details, err = "already executed, the run is recorded late", nil
+	}
+
+	if err != nil {
+		log.Warn("scheduled operation failed", "attempt", run.Attempt, "error", err)
+		run.Status = RunFailed
+		run.Details = err.Error()
+	} else {
+		log.Info("scheduled operation completed", "details", details)
+		run.Details = details
+	}
+
+	if err := planNextRun(schedule, err != nil, time.Now(), s.conf.MaxRetries, s.conf.RetryDelay); err != nil {
+		log.Error("failed to plan the next run", "error", err)
+		return
+	}
+
+	finishCtx, finishCancel := context.WithTimeout(context.Background(), s.conf.RunTimeout)
+	defer finishCancel()
+
+	if err := s.db.FinishScheduleRun(finishCtx, schedule, run); err != nil {
+		log.Error("failed to record the schedule run", "error", err)
+	}
+}
+
+// execute runs the operation of the schedule through the Wallet service on behalf of the owning user.
+// The occurrence is the idempotency key of the operation, so an occurrence claimed again after its operation
+// was committed is not executed twice, the Wallet service returns ErrDuplicateOperation instead.
+// It returns a short description of the result.
+func (s *Scheduler) execute(ctx context.Context, schedule *models.Schedule) (string, error) {
+	key := occurrenceKey(schedule)
+
+	switch schedule.Operation {
+	case servWallet.OperationDeposit:
+		if _, err := s.wallet.Deposit(ctx, &models.AccountOperationRequest{
+			UserID:         schedule.UserID,
+			Amount:         schedule.Amount,
+			Currency:       schedule.Currency,
+			IdempotencyKey: key,
+		}); err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("deposited %.2f %s", schedule.Amount, schedule.Currency), nil
+	case servWallet.OperationExchange:
+		result, err := s.wallet.Exchange(ctx, models.ExchangeRequest{
+			UserID:         schedule.UserID,
+			FromCurrency:   schedule.Currency,
+			ToCurrency:     schedule.ToCurrency,
+			Amount:         schedule.Amount,
+			IdempotencyKey: key,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("exchanged %.2f %s to %.2f %s at %.6f, fee %.2f %s",
+			result.SpentAccoutn.Amount, result.SpentAccoutn.Currency,
+			result.ReceivedAccount.Amount, result.ReceivedAccount.Currency,
+			result.AppliedRate, result.Fee.Amount, result.Fee.Currency), nil
+	default:
+		return "", servWallet.ErrInvalidOperationType
+	}
+}
+
+// occurrenceKey is the idempotency key of the operation of the occurrence of the schedule that is due.
+func occurrenceKey(schedule *models.Schedule) string {
+	return fmt.Sprintf("schedule:%d:%d", schedule.ID, schedule.DueAt.UnixNano())
+}
+
+// planNextRun updates the schedule after a run.
+// A failed run is retried up to maxRetries times with a doubling delay, the occurrence stays the same.
+// After a successful run or the last retry, the schedule moves to the next occurrence after now,
+// the occurrences missed while the service was down are not run. If there are no more occurrences, the schedule is finished.
+func planNextRun(schedule *models.Schedule, failed bool, now time.Time, maxRetries int, retryDelay time.Duration) error {
+	if failed && schedule.RetryCount < maxRetries {
+		retry := now.Add(retryDelay << schedule.RetryCount).UTC()
+		schedule.RetryCount++
+		schedule.NextRunAt = &retry
+		return nil
+	}
+
+	schedule.RetryCount = 0
+
+	after := now
+	if schedule.DueAt != nil && schedule.DueAt.After(after) {
+		after = *schedule.DueAt
+	}
+
+	due, err := nextOccurrence(schedule, after)
+	if err != nil {
+		return err
+	}
+
+	if due.IsZero() {
+		schedule.Status = StatusFinished
+		schedule.DueAt = nil
+		schedule.NextRunAt = nil
+		return nil
+	}
+
+	schedule.DueAt = &due
+	schedule.NextRunAt = &due
+	return nil
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/logs"
+)
+
+// fakeScheduleStore records the finished runs.
+type fakeScheduleStore struct {
+	storages.StoreSchedules
+	runs []models.ScheduleRun
+}
+
+func (f *fakeScheduleStore) FinishScheduleRun(ctx context.Context, schedule *models.Schedule, run *models.ScheduleRun) error {
+	f.runs = append(f.runs, *run)
+	return nil
+}
+
+// fakeWallet records the idempotency keys of the deposits and returns err.
+type fakeWallet struct {
+	keys []string
+	err  error
+}
+
+func (f *fakeWallet) Deposit(ctx context.Context, req *models.AccountOperationRequest) (*models.AccountOperationResponse, error) {
+	f.keys = append(f.keys, req.IdempotencyKey)
+	return &models.AccountOperationResponse{}, f.err
+}
+
+func (f *fakeWallet) Exchange(ctx context.Context, req models.ExchangeRequest) (*models.ExchangeResponse, error) {
+	f.keys = append(f.keys, req.IdempotencyKey)
+	return nil, f.err
+}
+
+func TestRunScheduleIdempotency(t *testing.T) {
+	due := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		walletErr  error
+		wantStatus string
+		wantRetry  int
+	}{
+		{name: "executed", wantStatus: RunSuccess},
+		{name: "already executed", walletErr: servWallet.ErrDuplicateOperation, wantStatus: RunSuccess},
+		{name: "failed", walletErr: servWallet.ErrInsufficientFunds, wantStatus: RunFailed, wantRetry: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeScheduleStore{}
+			wallet := &fakeWallet{err: tt.walletErr}
+			s := &Scheduler{
+				log:    logs.NewDiscardLogger(),
+				db:     store,
+				wallet: wallet,
+				conf:   &config.Scheduler{RunTimeout: time.Second, MaxRetries: 3, RetryDelay: time.Minute},
+			}
+			schedule := &models.Schedule{ID: 7, Operation: servWallet.OperationDeposit, Currency: "USD", Amount: 10, Interval: "24h", DueAt: ptr(due)}
+
+			s.runSchedule(schedule)
+
+			if len(wallet.keys) != 1 || wallet.keys[0] != "schedule:7:"+strconv.FormatInt(due.UnixNano(), 10) {
+				t.Errorf("idempotency keys = %v", wallet.keys)
+			}
+			if len(store.runs) != 1 {
+				t.Fatalf("recorded runs = %d, want 1", len(store.runs))
+			}
+			if store.runs[0].Status != tt.wantStatus {
+				t.Errorf("status = %v, want %v", store.runs[0].Status, tt.wantStatus)
+			}
+			if schedule.RetryCount != tt.wantRetry {
+				t.Errorf("retry count = %v, want %v", schedule.RetryCount, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestCheckLease(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    config.Scheduler
+		wantErr error
+	}{
+		{name: "defaults", conf: config.Scheduler{BatchSize: 20, Workers: 4, RunTimeout: 30 * time.Second, Lease: 10 * time.Minute}},
+		{name: "equal to the batch", conf: config.Scheduler{BatchSize: 20, Workers: 4, RunTimeout: 30 * time.Second, Lease: 5 * time.Minute}, wantErr: ErrLeaseTooShort},
+		{name: "partial round", conf: config.Scheduler{BatchSize: 5, Workers: 4, RunTimeout: time.Minute, Lease: 4 * time.Minute}, wantErr: ErrLeaseTooShort},
+		{name: "no workers", conf: config.Scheduler{BatchSize: 2, RunTimeout: time.Minute, Lease: 5 * time.Minute}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkLease(&tt.conf); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkLease() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
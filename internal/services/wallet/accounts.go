@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// Accounts returns all accounts (pockets) of the user with their balances.
+func (w *Wallet) Accounts(ctx context.Context, req models.AccountsRequest) (*models.AccountsResponse, error) {
+	op := "service Wallet: list of accounts"
+	log := w.log.With(slog.String("operation", op))
+	log.Debug("Accounts func call", slog.Any("requets data", req))
+
+	accounts, err := w.db.UserAccounts(ctx, req.UserID)
+	if err != nil {
+		log.Error("failed to get the accounts from the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("accounts successfully received", "count", len(accounts))
+	return &models.AccountsResponse{Message: "accounts successfully received", Accounts: accounts}, nil
+}
+
+// CreateAccount opens a new named account of the user in the currency, the default account of the currency stays.
+// If the currency is unknown, it returns ErrCurrencyNotFound, if the name is taken in the currency, it returns ErrAccountNameTaken.
+func (w *Wallet) CreateAccount(ctx context.Context, req models.CreateAccountRequest) (*models.AccountResponse, error) {
+	op := "service Wallet: account creation"
+	log := w.log.With(slog.String("operation", op))
+	log.Debug("CreateAccount func call", slog.Any("requets data", req))
+
+	account, err := w.db.CreateAccount(ctx, &req)
+	if err != nil {
+		log.Warn("failed to create the account", "error", err)
+		return nil, err
+	}
+
+	log.Info("account successfully created", "account id", account.ID)
+	return &models.AccountResponse{Message: "account successfully created", Account: *account}, nil
+}
+
+// CloseAccount closes the empty named account of the user, the default accounts can not be closed.
+func (w *Wallet) CloseAccount(ctx context.Context, req models.AccountRequest) error {
+	op := "service Wallet: account closing"
+	log := w.log.With(slog.String("operation", op))
+	log.Debug("CloseAccount func call", slog.Any("requets data", req))
+
+	if err := w.db.CloseAccount(ctx, req.UserID, req.AccountID); err != nil {
+		log.Warn("failed to close the account", "error", err)
+		return err
+	}
+
+	log.Info("account successfully closed")
+	return nil
+}
+
+// Transfer moves funds between two accounts of the user in the same currency.
+// If both accounts are the same, it returns ErrSameAccount.
+func (w *Wallet) Transfer(ctx context.Context, req models.TransferRequest) (*models.TransferResponse, error) {
+	op := "service Wallet: transfer between accounts"
+	log := w.log.With(slog.String("operation", op))
+	log.Debug("Transfer func call", slog.Any("requets data", req))
+
+	if req.FromAccountID == req.ToAccountID {
+		log.Warn("same account is specified for the transfer", "account id", req.FromAccountID)
+		return nil, ErrSameAccount
+	}
+
+	from, to, err := w.db.Transfer(ctx, &req)
+	if err != nil {
+		log.Warn("failed to transfer between the accounts", "error", err)
+		return nil, err
+	}
+
+	log.Info("successfully transferred", "from", from.ID, "to", to.ID)
+	return &models.TransferResponse{Message: "successfully transferred", FromAccount: *from, ToAccount: *to}, nil
+}
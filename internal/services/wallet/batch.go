@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// ExchangeBatch executes several exchanges of the user in one database transaction, either all legs succeed or none.
+// If ConsolidateTo is set, the legs are built from every non-zero account that is not in the target currency,
+// the funds go to the default account of the target currency.
+// The rates of all pairs are fetched concurrently, then the legs are calculated one after another,
+// so that a leg can spend what a previous leg has received.
+func (w *Wallet) ExchangeBatch(ctx context.Context, req models.ExchangeBatchRequest) (*models.ExchangeBatchResponse, error) {
+	op := "service Wallet: batch currency exchange request"
+	log := w.log.With(slog.String("operation", op))
+	log.Debug("ExchangeBatch func call", slog.Any("requets data", req))
+
+	balanceUser, err := w.db.AllAccountsBalance(ctx, req.UserID)
+	if err != nil {
+		log.Error("failed to get current user balance", "error", err)
+		return nil, err
+	}
+
+	legs := req.Legs
+	if req.ConsolidateTo != "" {
+		if _, ok := balanceUser[req.ConsolidateTo]; !ok {
+			log.Warn("no account in the target currency", "currency", req.ConsolidateTo)
+			return nil, ErrCurrencyNotFound
+		}
+		accounts, err := w.db.UserAccounts(ctx, req.UserID)
+		if err != nil {
+			log.Error("failed to get the accounts of the user", "error", err)
+			return nil, err
+		}
+		legs = consolidationLegs(accounts, req.ConsolidateTo)
+	}
+
+	if len(legs) == 0 {
+		log.Warn("nothing to exchange")
+		return nil, ErrEmptyBatch
+	}
+
+	for _, leg := range legs {
+		if leg.FromCurrency == leg.ToCurrency {
+			log.Warn("same currencies in a leg", "currency", leg.FromCurrency)
+			return nil, ErrSameCurrency
+		}
+	}
+
+	// goroutines run, one per currency pair
+	rates := make(map[string]*models.ExchangeRate)
+	errChans := make(map[string]chan error)
+	for _, leg := range legs {
+		pair := leg.FromCurrency + "/" + leg.ToCurrency
+		if _, ok := rates[pair]; ok {
+			continue
+		}
+		rates[pair] = &models.ExchangeRate{FromCurrency: leg.FromCurrency, ToCurrency: leg.ToCurrency}
+		errChans[pair] = make(chan error, 1)
+		w.getExchangeRateAsync(ctx, rates[pair], errChans[pair])
+	}
+
+	segment, err := w.db.UserSegment(ctx, req.UserID)
+	if err != nil {
+		log.Error("failed to get the user segment", "error", err)
+		return nil, err
+	}
+
+	// waiting for all gorutines
+	for pair, errChan := range errChans {
+		select {
+		case err := <-errChan:
+			if err != nil {
+				log.Error("failed to get the exchange rate", "pair", pair, "error", err)
+				return nil, err
+			}
+		case <-ctx.Done():
+			log.Error("context canceled or timeout while waiting for exchange rates", "error", ctx.Err())
+			return nil, ctx.Err()
+		}
+	}
+
+	log.Debug("exchange rates successfully received", "pairs", len(rates))
+
+	// the legs are calculated on a copy of the balance, in the order in which they are executed
+	results := make([]*models.CurrencyExchangeResult, 0, len(legs))
+	for i, leg := range legs {
+		if _, ok := balanceUser[leg.FromCurrency]; !ok {
+			log.Warn("no base currency to exchange", "leg", i, "currency", leg.FromCurrency)
+			return nil, ErrAccountNotFound
+		}
+
+		if _, ok := balanceUser[leg.ToCurrency]; !ok {
+			log.Warn("not to currency exchange", "leg", i, "currency", leg.ToCurrency)
+			return nil, ErrCurrencyNotFound
+		}
+
+		if leg.Amount > balanceUser[leg.FromCurrency] {
+			log.Warn("insufficient funds for the leg", "leg", i, "current balance", balanceUser[leg.FromCurrency], "requested amount", leg.Amount)
+			return nil, ErrInsufficientFunds
+		}
+
+		rate := rates[leg.FromCurrency+"/"+leg.ToCurrency]
+		exchangeResult, err := w.CurrencyExchangeLogic(&models.CurrencyExchangeData{
+			BaseBalance:   balanceUser[leg.FromCurrency],
+			ToBalance:     balanceUser[leg.ToCurrency],
+			ExchangeRate:  rate.Rate,
+			Amount:        leg.Amount,
+			SpreadPercent: w.spreadPercent(segment, leg.FromCurrency, leg.ToCurrency),
+			MinFee:        w.minFee(leg.ToCurrency),
+		})
+		if err != nil {
+			log.Error("currency exchange failed", "leg", i, "error", err)
+			return nil, err
+		}
+
+		exchangeResult.UserID = req.UserID
+		exchangeResult.FromAccountID = leg.FromAccountID
+		exchangeResult.ToAccountID = leg.ToAccountID
+		exchangeResult.BaseCurrency = leg.FromCurrency
+		exchangeResult.ToCurrency = leg.ToCurrency
+		exchangeResult.Amount = leg.Amount
+		exchangeResult.MidRate = rate.Rate
+		exchangeResult.AppliedRate = exchangeResult.Received / leg.Amount
+		results = append(results, exchangeResult)
+
+		balanceUser[leg.FromCurrency] = exchangeResult.NewBaseBalance
+		balanceUser[leg.ToCurrency] = exchangeResult.NewToBalance
+	}
+
+	log.Debug("business logic check successfully completed")
+
+	if err := w.db.SaveExchangeBatch(ctx, results); err != nil {
+		log.Error("failed to save batch exchange changes in the database", "error", err)
+		return nil, err
+	}
+
+	// preparing response, the database has written the chosen accounts back to the results
+	var resp models.ExchangeBatchResponse
+	resp.Message = "batch currency exchange successfully"
+	resp.Legs = make([]models.ExchangeLegResult, 0, len(results))
+	for _, result := range results {
+		resp.Legs = append(resp.Legs, models.ExchangeLegResult{
+			MidRate:         result.MidRate,
+			AppliedRate:     result.AppliedRate,
+			Fee:             models.ExchangeFee{Currency: result.ToCurrency, Amount: result.Fee},
+			SpentAccoutn:    models.SpentAccoutn{AccountID: result.FromAccountID, Currency: result.BaseCurrency, Amount: result.Amount},
+			ReceivedAccount: models.ReceivedAccount{AccountID: result.ToAccountID, Currency: result.ToCurrency, Amount: result.Received},
+		})
+	}
+	resp.NewBalance = balanceUser
+
+	log.Info("successfully batch exchange", "legs", len(results))
+	return &resp, nil
+}
+
+// consolidationLegs builds the legs that convert the available balance of every account into the target currency,
+// the held amounts stay on the accounts and the accounts without an available balance are skipped. The legs are sorted by currency code and account ID, so that the order of execution does not depend on the order of the accounts.
+func consolidationLegs(accounts []models.Account, target string) []models.ExchangeLeg {
+	legs := make([]models.ExchangeLeg, 0, len(accounts))
+	for _, account := range accounts {
+		if account.Currency == target || account.Available <= 0 {
+			continue
+		}
+		legs = append(legs, models.ExchangeLeg{
+			FromCurrency:  account.Currency,
+			ToCurrency:    target,
+			Amount:        account.Available,
+			FromAccountID: account.ID,
+		})
+	}
+
+	sort.Slice(legs, func(i, j int) bool {
+		if legs[i].FromCurrency != legs[j].FromCurrency {
+			return legs[i].FromCurrency < legs[j].FromCurrency
+		}
+		return legs[i].FromAccountID < legs[j].FromAccountID
+	})
+	return legs
+}
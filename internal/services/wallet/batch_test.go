@@ -0,0 +1,72 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+func TestConsolidationLegs(t *testing.T) {
+	tests := []struct {
+		name     string
+		accounts []models.Account
+		target   string
+		want     []models.ExchangeLeg
+	}{
+		{
+			name: "Every non-zero account is converted",
+			accounts: []models.Account{
+				{ID: 1, Currency: "USD", Balance: 100, Available: 100},
+				{ID: 2, Currency: "EUR", Balance: 0, Available: 0},
+				{ID: 3, Currency: "RUB", Balance: 2500.5, Available: 2500.5},
+				{ID: 4, Currency: "CNY", Balance: 70, Available: 70},
+			},
+			target: "USD",
+			want: []models.ExchangeLeg{
+				{FromCurrency: "CNY", ToCurrency: "USD", Amount: 70, FromAccountID: 4},
+				{FromCurrency: "RUB", ToCurrency: "USD", Amount: 2500.5, FromAccountID: 3},
+			},
+		},
+		{
+			name: "Every pocket of a currency is a separate leg",
+			accounts: []models.Account{
+				{ID: 7, Currency: "EUR", Balance: 30, Available: 30},
+				{ID: 2, Currency: "EUR", Balance: 20, Available: 20},
+				{ID: 9, Currency: "USD", Balance: 5, Available: 5},
+			},
+			target: "USD",
+			want: []models.ExchangeLeg{
+				{FromCurrency: "EUR", ToCurrency: "USD", Amount: 20, FromAccountID: 2},
+				{FromCurrency: "EUR", ToCurrency: "USD", Amount: 30, FromAccountID: 7},
+			},
+		},
+		{
+			name: "Held amounts are not converted",
+			accounts: []models.Account{
+				{ID: 1, Currency: "EUR", Balance: 100, Held: 40, Available: 60},
+				{ID: 2, Currency: "RUB", Balance: 50, Held: 50, Available: 0},
+			},
+			target: "USD",
+			want: []models.ExchangeLeg{
+				{FromCurrency: "EUR", ToCurrency: "USD", Amount: 60, FromAccountID: 1},
+			},
+		},
+		{
+			name: "Only the target currency has funds",
+			accounts: []models.Account{
+				{ID: 1, Currency: "USD", Balance: 100, Available: 100},
+				{ID: 2, Currency: "EUR", Balance: 0, Available: 0},
+			},
+			target: "USD",
+			want:   []models.ExchangeLeg{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := consolidationLegs(tt.accounts, tt.target); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("consolidationLegs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
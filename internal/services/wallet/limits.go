@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+)
+
+// LimitExceededError is returned when an operation would exceed the daily or monthly limit of the user.
+// It carries the remaining allowance, so that the client can retry with a smaller amount.
+type LimitExceededError struct {
+	Operation string
+	Currency  string
+	Period    string
+	Limit     float32
+	Remaining float32
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s %s limit exceeded for %s, remaining %.2f of %.2f", e.Period, e.Operation, e.Currency, e.Remaining, e.Limit)
+}
+
+// Limits returns the daily and monthly limits of the user for every currency and operation
+// together with the amount already used in the current day and month.
+func (w *Wallet) Limits(ctx context.Context, req models.LimitsRequest) (*models.LimitsResponse, error) {
+	op := "service Wallet: getting the transaction limits"
+	log := w.log.With(slog.String("operation", op))
+	log.Debug("Limits func call", slog.Any("requets data", req))
+
+	segment, err := w.db.UserSegment(ctx, req.UserID)
+	if err != nil {
+		log.Error("failed to get the user segment", "error", err)
+		return nil, err
+	}
+
+	limits, err := w.db.LimitsUsage(ctx, req.UserID)
+	if err != nil {
+		log.Error("failed to get the limits usage from the database", "error", err)
+		return nil, err
+	}
+
+	for i := range limits {
+		limits[i].DailyRemaining = remainingAllowance(limits[i].DailyLimit, limits[i].DailyUsed)
+		limits[i].MonthlyRemaining = remainingAllowance(limits[i].MonthlyLimit, limits[i].MonthlyUsed)
+	}
+
+	var resp models.LimitsResponse
+	resp.Message = "limits successfully received"
+	resp.Segment = segment
+	resp.Limits = limits
+
+	log.Info("transaction limits successfully sent")
+	return &resp, nil
+}
+
+// remainingAllowance returns how much is left of the limit, nil means there is no limit.
+func remainingAllowance(limit *float32, used float32) *float32 {
+	if limit == nil {
+		return nil
+	}
+
+	remaining := float32(math.Max(0, math.Round(float64(*limit-used)*100)/100))
+	return &remaining
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestRemainingAllowance(t *testing.T) {
+	limit := func(v float32) *float32 { return &v }
+
+	tests := []struct {
+		name  string
+		limit *float32
+		used  float32
+		want  *float32
+	}{
+		{name: "No limit", limit: nil, used: 500, want: nil},
+		{name: "Nothing used", limit: limit(1000), used: 0, want: limit(1000)},
+		{name: "Partly used", limit: limit(1000), used: 250.5, want: limit(749.5)},
+		{name: "Fully used", limit: limit(1000), used: 1000, want: limit(0)},
+		{name: "Used above the limit", limit: limit(1000), used: 1200, want: limit(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := remainingAllowance(tt.limit, tt.used)
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("remainingAllowance() = %v, want %v", got, tt.want)
+			}
+			if got != nil && *got != *tt.want {
+				t.Errorf("remainingAllowance() = %v, want %v", *got, *tt.want)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 // CurrencyExchangeLogic handles the logic for currency exchange.
 // It calculates the new balances for the base and target currencies after the exchange.
+// The fee (spread percent with a minimum) is deducted from the amount received in the target currency.
 // It ensures that the exchange rate and amount are positive and that the resulting balances are not negative.
 // If any validation fails, it returns an error.
 func (w *Wallet) CurrencyExchangeLogic(data *models.CurrencyExchangeData) (*models.CurrencyExchangeResult, error) {
@@ -27,6 +28,23 @@ func (w *Wallet) CurrencyExchangeLogic(data *models.CurrencyExchangeData) (*mode
 
 	costInNewCurrency = float32(math.Round(float64(costInNewCurrency)*100) / 100)
 
+	// the fee is charged in the target currency, but not less than the minimum fee
+	var fee float32
+	if data.SpreadPercent > 0 || data.MinFee > 0 {
+		fee = float32(math.Round(float64(costInNewCurrency*data.SpreadPercent)) / 100)
+		if fee < data.MinFee {
+			fee = data.MinFee
+		}
+
+		if fee >= costInNewCurrency {
+			log.Warn("amount does not cover the exchange fee", "fee", fee, "amount in new currency", costInNewCurrency)
+			return nil, ErrAmountBelowFee
+		}
+
+		costInNewCurrency = float32(math.Round(float64(costInNewCurrency-fee)*100) / 100)
+		log.Debug("exchange fee has been charged", "fee", fee, "amount after fee", costInNewCurrency)
+	}
+
 	newBaseBalance := data.BaseBalance - data.Amount
 	newToBalance := data.ToBalance + costInNewCurrency
 
@@ -41,6 +59,7 @@ func (w *Wallet) CurrencyExchangeLogic(data *models.CurrencyExchangeData) (*mode
 	result.NewBaseBalance = newBaseBalance
 	result.NewToBalance = newToBalance
 	result.Received = costInNewCurrency
+	result.Fee = fee
 
 	log.Info("new account balances have been successfully calculated")
 	return &result, nil
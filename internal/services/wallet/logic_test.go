@@ -145,6 +145,64 @@ func TestWallet_CurrencyExchangeLogic(t *testing.T) {
             },
             want:    nil,
             wantErr: true,
+        },
+		{
+            name: "Valid exchange with spread fee",
+            w:    testWallet,
+            args: args{
+                data: &models.CurrencyExchangeData{
+                    BaseBalance:   1000.0,
+                    ToBalance:     500.0,
+                    ExchangeRate:  1.2,
+                    Amount:        100.0,
+                    SpreadPercent: 0.5,
+                    MinFee:        0.01,
+                },
+            },
+            want: &models.CurrencyExchangeResult{
+                NewBaseBalance: 900.0,
+                NewToBalance:  619.4,
+                Received:       119.4,
+                Fee:            0.6,
+            },
+            wantErr: false,
+        },
+		{
+            name: "Valid exchange with minimum fee",
+            w:    testWallet,
+            args: args{
+                data: &models.CurrencyExchangeData{
+                    BaseBalance:   1000.0,
+                    ToBalance:     500.0,
+                    ExchangeRate:  1.2,
+                    Amount:        10.0,
+                    SpreadPercent: 0.5,
+                    MinFee:        1,
+                },
+            },
+            want: &models.CurrencyExchangeResult{
+                NewBaseBalance: 990.0,
+                NewToBalance:  511.0,
+                Received:       11.0,
+                Fee:            1,
+            },
+            wantErr: false,
+        },
+		{
+            name: "Invalid amount below minimum fee",
+            w:    testWallet,
+            args: args{
+                data: &models.CurrencyExchangeData{
+                    BaseBalance:   1000.0,
+                    ToBalance:     500.0,
+                    ExchangeRate:  1.2,
+                    Amount:        0.5,
+                    SpreadPercent: 0.5,
+                    MinFee:        1,
+                },
+            },
+            want:    nil,
+            wantErr: true,
         },
 	}
 	for _, tt := range tests {
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+const (
+	StatementCSV  = "csv"
+	StatementJSON = "json"
+)
+
+// statementHeader is the header row of the CSV statement.
+var statementHeader = []string{
+	"operation_id", "created_at", "type", "currency", "account_id", "amount",
+	"opening_balance", "closing_balance", "counter_currency", "rate", "running_balance",
+}
+
+// statementEncoder writes a statement as the operations are read: begin gets the opening balances,
+// line gets every operation and end gets the closing balances.
+type statementEncoder interface {
+	begin(opening map[string]float32) error
+	line(line *models.StatementLine) error
+	end(closing map[string]float32) error
+}
+
+// Statement writes the statement of the operations of the user in [From, To) to out, as CSV or JSON.
+// The statement is built from the persisted history of the operations only, so the same period always gives the same statement.
+// The operations are written as they are read from the database, every line has the balances of its account before
+// and after the operation, the rate of an exchange and the running balance of the currency, which starts at the opening balance.
+// If the period is empty, it returns ErrInvalidPeriod before anything is written.
+func (w *Wallet) Statement(ctx context.Context, req models.StatementRequest, out io.Writer) error {
+	op := "service Wallet: statement of the operations"
+	log := w.log.With(slog.String("operation", op))
+	log.Debug("Statement func call", slog.Any("requets data", req))
+
+	if !req.From.Before(req.To) {
+		log.Warn("the beginning of the period is not earlier than its end", "from", req.From, "to", req.To)
+		return ErrInvalidPeriod
+	}
+
+	var encoder statementEncoder
+	if req.Format == StatementCSV {
+		encoder = &csvStatement{writer: csv.NewWriter(out)}
+	} else {
+		encoder = &jsonStatement{out: out, req: &req}
+	}
+
+	// the sums are kept in cents, so that a long statement does not drift
+	running := make(map[string]float64)
+
+	begin := func(opening map[string]float32) error {
+		if req.Currency != "" {
+			if _, ok := opening[req.Currency]; !ok {
+				opening[req.Currency] = 0
+			}
+		}
+		for currency, balance := range opening {
+			running[currency] = roundCents(float64(balance))
+		}
+		return encoder.begin(opening)
+	}
+
+	var count int
+	emit := func(operation models.OperationRecord) error {
+		running[operation.Currency] = roundCents(running[operation.Currency] + float64(operation.Amount))
+		count++
+
+		return encoder.line(&models.StatementLine{
+			OperationID:     operation.ID,
+			CreatedAt:       operation.CreatedAt,
+			Type:            operation.Type,
+			Currency:        operation.Currency,
+			AccountID:       operation.AccountID,
+			Amount:          operation.Amount,
+			OpeningBalance:  float32(roundCents(float64(operation.BalanceAfter) - float64(operation.Amount))),
+			ClosingBalance:  operation.BalanceAfter,
+			CounterCurrency: operation.CounterCurrency,
+			Rate:            operation.ExchangeRate,
+			RunningBalance:  float32(running[operation.Currency]),
+		})
+	}
+
+	if err := w.db.Statement(ctx, &req, begin, emit); err != nil {
+		log.Error("failed to read the statement from the database", "error", err)
+		return err
+	}
+
+	closing := make(map[string]float32, len(running))
+	for currency, balance := range running {
+		closing[currency] = float32(balance)
+	}
+
+	if err := encoder.end(closing); err != nil {
+		log.Error("failed to finish the statement", "error", err)
+		return err
+	}
+
+	log.Info("statement successfully written", "operations", count)
+	return nil
+}
+
+// roundCents rounds the amount to cents.
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// csvStatement writes the statement as CSV, one row per operation after the header.
+type csvStatement struct {
+	writer *csv.Writer
+}
+
+func (c *csvStatement) begin(opening map[string]float32) error {
+	return c.writer.Write(statementHeader)
+}
+
+func (c *csvStatement) line(line *models.StatementLine) error {
+	var accountId, rate string
+	if line.AccountID != 0 {
+		accountId = strconv.FormatUint(uint64(line.AccountID), 10)
+	}
+	if line.Rate != 0 {
+		rate = strconv.FormatFloat(float64(line.Rate), 'f', -1, 32)
+	}
+
+	return c.writer.Write([]string{
+		strconv.FormatUint(line.OperationID, 10),
+		line.CreatedAt.UTC().Format(time.RFC3339),
+		line.Type,
+		line.Currency,
+		accountId,
+		formatAmount(line.Amount),
+		formatAmount(line.OpeningBalance),
+		formatAmount(line.ClosingBalance),
+		line.CounterCurrency,
+		rate,
+		formatAmount(line.RunningBalance),
+	})
+}
+
+func (c *csvStatement) end(closing map[string]float32) error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// formatAmount formats the amount with two decimals.
+func formatAmount(amount float32) string {
+	return strconv.FormatFloat(float64(amount), 'f', 2, 32)
+}
+
+// jsonStatement writes the statement as a models.Statement, the operations are written one by one.
+type jsonStatement struct {
+	out   io.Writer
+	req   *models.StatementRequest
+	lines int
+}
+
+func (j *jsonStatement) begin(opening map[string]float32) error {
+	head, err := json.Marshal(struct {
+		From           time.Time          `json:"from"`
+		To             time.Time          `json:"to"`
+		Currency       string             `json:"currency,omitempty"`
+		OpeningBalance map[string]float32 `json:"opening_balance"`
+	}{j.req.From, j.req.To, j.req.Currency, opening})
+	if err != nil {
+		return err
+	}
+
+	// the object is left open to append the operations
+	head = append(head[:len(head)-1], []byte(`,"operations":[`)...)
+	_, err = j.out.Write(head)
+	return err
+}
+
+func (j *jsonStatement) line(line *models.StatementLine) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+
+	if j.lines > 0 {
+		data = append([]byte(","), data...)
+	}
+	j.lines++
+
+	_, err = j.out.Write(data)
+	return err
+}
+
+func (j *jsonStatement) end(closing map[string]float32) error {
+	data, err := json.Marshal(closing)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(j.out, `],"closing_balance":`+string(data)+"}\n")
+	return err
+}
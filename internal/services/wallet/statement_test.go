@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+type fakeStatementStore struct {
+	storages.StoreWallet
+	opening    map[string]float32
+	operations []models.OperationRecord
+}
+
+func (f *fakeStatementStore) Statement(ctx context.Context, req *models.StatementRequest, begin func(opening map[string]float32) error, emit func(operation models.OperationRecord) error) error {
+	if err := begin(f.opening); err != nil {
+		return err
+	}
+	for _, operation := range f.operations {
+		if err := emit(operation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func statementStore() *fakeStatementStore {
+	at := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	return &fakeStatementStore{
+		opening: map[string]float32{"USD": 1000},
+		operations: []models.OperationRecord{
+			{ID: 1, AccountID: 1, Type: OperationDeposit, Currency: "USD", Amount: 200.1, BalanceAfter: 1200.1, CreatedAt: at},
+			{ID: 2, AccountID: 1, Type: OperationExchange, Currency: "USD", Amount: -500, BalanceAfter: 700.1,
+				CounterCurrency: "EUR", ExchangeRate: 0.9164, CreatedAt: at.Add(time.Hour)},
+			{ID: 3, AccountID: 5, Type: OperationExchange, Currency: "EUR", Amount: 458.2, BalanceAfter: 458.2,
+				CounterCurrency: "USD", ExchangeRate: 0.9164, CreatedAt: at.Add(time.Hour)},
+			{ID: 4, AccountID: 2, Type: OperationTransfer, Currency: "USD", Amount: 50, BalanceAfter: 50, CreatedAt: at.Add(2 * time.Hour)},
+		},
+	}
+}
+
+func TestStatementCSV(t *testing.T) {
+	w := &Wallet{log: slog.New(slog.NewTextHandler(io.Discard, nil)), db: statementStore()}
+	req := models.StatementRequest{
+		From:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		Format: StatementCSV,
+	}
+
+	var out bytes.Buffer
+	if err := w.Statement(context.Background(), req, &out); err != nil {
+		t.Fatalf("Statement() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"operation_id,created_at,type,currency,account_id,amount,opening_balance,closing_balance,counter_currency,rate,running_balance",
+		"1,2025-01-10T12:00:00Z,deposit,USD,1,200.10,1000.00,1200.10,,,1200.10",
+		"2,2025-01-10T13:00:00Z,exchange,USD,1,-500.00,1200.10,700.10,EUR,0.9164,700.10",
+		"3,2025-01-10T13:00:00Z,exchange,EUR,5,458.20,0.00,458.20,USD,0.9164,458.20",
+		"4,2025-01-10T14:00:00Z,transfer,USD,2,50.00,0.00,50.00,,,750.10",
+	}, "\n") + "\n"
+
+	if out.String() != want {
+		t.Errorf("Statement() CSV =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestStatementJSON(t *testing.T) {
+	w := &Wallet{log: slog.New(slog.NewTextHandler(io.Discard, nil)), db: statementStore()}
+	req := models.StatementRequest{
+		From:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		Format: StatementJSON,
+	}
+
+	var out bytes.Buffer
+	if err := w.Statement(context.Background(), req, &out); err != nil {
+		t.Fatalf("Statement() error = %v", err)
+	}
+
+	var statement models.Statement
+	if err := json.Unmarshal(out.Bytes(), &statement); err != nil {
+		t.Fatalf("statement is not valid JSON: %v\n%s", err, out.String())
+	}
+
+	if !reflect.DeepEqual(statement.OpeningBalance, map[string]float32{"USD": 1000}) {
+		t.Errorf("opening balance = %v", statement.OpeningBalance)
+	}
+	if !reflect.DeepEqual(statement.ClosingBalance, map[string]float32{"USD": 750.1, "EUR": 458.2}) {
+		t.Errorf("closing balance = %v", statement.ClosingBalance)
+	}
+	if len(statement.Operations) != 4 {
+		t.Fatalf("operations = %d, want 4", len(statement.Operations))
+	}
+	if got := statement.Operations[1]; got.Rate != 0.9164 || got.RunningBalance != 700.1 || got.OpeningBalance != 1200.1 {
+		t.Errorf("exchange line = %+v", got)
+	}
+}
+
+func TestStatementInvalidPeriod(t *testing.T) {
+	w := &Wallet{log: slog.New(slog.NewTextHandler(io.Discard, nil)), db: statementStore()}
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var out bytes.Buffer
+	err := w.Statement(context.Background(), models.StatementRequest{From: day, To: day, Format: StatementCSV}, &out)
+	if !errors.Is(err, ErrInvalidPeriod) {
+		t.Fatalf("Statement() error = %v, want %v", err, ErrInvalidPeriod)
+	}
+	if out.Len() != 0 {
+		t.Errorf("statement is written for an invalid period: %q", out.String())
+	}
+}
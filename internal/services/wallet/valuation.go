@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sort"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// portfolioValuation converts every account into the target currency and sums the values.
+// The rates go through the same path as the exchange (cache first, then the gRPC server), all pairs are requested concurrently.
+func (w *Wallet) portfolioValuation(ctx context.Context, accounts map[string]float32, target string) (*models.PortfolioValuation, error) {
+	op := "service Wallet: portfolio valuation"
+	log := w.log.With(slog.String("operation", op))
+	log.Debug("portfolioValuation func call", "currency", target)
+
+	if _, ok := accounts[target]; !ok {
+		log.Warn("valuation currency is not supported", "currency", target)
+		return nil, ErrCurrencyNotFound
+	}
+
+	// goroutines run, one per account in another currency
+	rates := make(map[string]*models.ExchangeRate)
+	errChans := make(map[string]chan error)
+	for currency := range accounts {
+		if currency == target {
+			continue
+		}
+		rates[currency] = &models.ExchangeRate{FromCurrency: currency, ToCurrency: target}
+		errChans[currency] = make(chan error, 1)
+		w.getExchangeRateAsync(ctx, rates[currency], errChans[currency])
+	}
+
+	// waiting for all gorutines
+	for currency, errChan := range errChans {
+		select {
+		case err := <-errChan:
+			if err != nil {
+				log.Error("failed to get the exchange rate", "currency", currency, "error", err)
+				return nil, err
+			}
+		case <-ctx.Done():
+			log.Error("context canceled or timeout while waiting for exchange rates", "error", ctx.Err())
+			return nil, ctx.Err()
+		}
+	}
+
+	valuation := calculateValuation(accounts, target, rates)
+
+	log.Info("portfolio successfully valued", "currency", target, "total", valuation.Total)
+	return valuation, nil
+}
+
+// calculateValuation values every account with the given rates, the valuation currency itself has the rate 1.
+// Each value is rounded to cents before it is added to the total, so the total always equals the sum of the values.
+func calculateValuation(accounts map[string]float32, target string, rates map[string]*models.ExchangeRate) *models.PortfolioValuation {
+	valuation := &models.PortfolioValuation{
+		Currency: target,
+		Accounts: make([]models.AccountValuation, 0, len(accounts)),
+	}
+
+	var total float64
+	for currency, balance := range accounts {
+		item := models.AccountValuation{Currency: currency, Balance: balance, Rate: 1}
+		if rate, ok := rates[currency]; ok {
+			item.Rate = rate.Rate
+			if !rate.UpdatedAt.IsZero() {
+				updatedAt := rate.UpdatedAt
+				item.RateUpdatedAt = &updatedAt
+			}
+		}
+
+		value := math.Round(float64(balance)*float64(item.Rate)*100) / 100
+		item.Value = float32(value)
+		total += value
+
+		valuation.Accounts = append(valuation.Accounts, item)
+	}
+
+	sort.Slice(valuation.Accounts, func(i, j int) bool { return valuation.Accounts[i].Currency < valuation.Accounts[j].Currency })
+	valuation.Total = float32(math.Round(total*100) / 100)
+
+	return valuation
+}
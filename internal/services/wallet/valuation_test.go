@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+func TestCalculateValuation(t *testing.T) {
+	updatedAt := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	accounts := map[string]float32{"USD": 100, "EUR": 50, "RUB": 1000}
+	rates := map[string]*models.ExchangeRate{
+		"EUR": {FromCurrency: "EUR", ToCurrency: "USD", Rate: 1.0333, UpdatedAt: updatedAt},
+		"RUB": {FromCurrency: "RUB", ToCurrency: "USD", Rate: 0.010123},
+	}
+
+	got := calculateValuation(accounts, "USD", rates)
+
+	if got.Currency != "USD" {
+		t.Errorf("Currency = %s, want USD", got.Currency)
+	}
+
+	want := []models.AccountValuation{
+		{Currency: "EUR", Balance: 50, Rate: 1.0333, Value: 51.67, RateUpdatedAt: &updatedAt},
+		{Currency: "RUB", Balance: 1000, Rate: 0.010123, Value: 10.12},
+		{Currency: "USD", Balance: 100, Rate: 1, Value: 100},
+	}
+	if len(got.Accounts) != len(want) {
+		t.Fatalf("got %d accounts, want %d", len(got.Accounts), len(want))
+	}
+	for i := range want {
+		g, w := got.Accounts[i], want[i]
+		if g.Currency != w.Currency || g.Balance != w.Balance || g.Rate != w.Rate || g.Value != w.Value {
+			t.Errorf("account %d = %+v, want %+v", i, g, w)
+		}
+		if (g.RateUpdatedAt == nil) != (w.RateUpdatedAt == nil) || (g.RateUpdatedAt != nil && !g.RateUpdatedAt.Equal(*w.RateUpdatedAt)) {
+			t.Errorf("account %d rate time = %v, want %v", i, g.RateUpdatedAt, w.RateUpdatedAt)
+		}
+	}
+
+	// the total is the sum of the rounded values
+	if got.Total != 161.79 {
+		t.Errorf("Total = %v, want 161.79", got.Total)
+	}
+}
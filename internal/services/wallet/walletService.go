@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"time"
 
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
 	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages"
 	"github.com/EvansTrein/RESTful_exchangerServer/models"
 	grpcclient "github.com/EvansTrein/RESTful_exchangerServer/pkg/gRPCclient"
@@ -13,6 +15,8 @@ import (
 const (
 	OperationDeposit  = "deposit"
 	OperationWithdraw = "withdraw"
+	OperationExchange = "exchange"
+	OperationTransfer = "transfer"
 )
 
 var (
@@ -23,8 +27,23 @@ var (
 	ErrInvalidOperationType = errors.New("invalid operation type")
 	ErrNegativeBalance      = errors.New("negative balance")
 	ErrRateInCacheNotFound  = errors.New("exchange rate is not in the cache")
+	ErrAmountBelowFee       = errors.New("amount does not cover the exchange fee")
+	ErrInvalidPeriod        = errors.New("invalid period")
+	ErrSameCurrency         = errors.New("same currency is specified for buying and selling")
+	ErrEmptyBatch           = errors.New("no legs to exchange")
+	ErrEmailNotVerified     = errors.New("email is not verified")
+	ErrSameAccount          = errors.New("same account is specified for the transfer")
+	ErrCurrencyMismatch     = errors.New("accounts are in different currencies")
+	ErrAccountNameTaken     = errors.New("account with this name already exists in the currency")
+	ErrDefaultAccount       = errors.New("default account can not be closed")
+	ErrAccountNotEmpty      = errors.New("account still has funds")
+	ErrDuplicateOperation   = errors.New("operation with this idempotency key has already been executed")
 )
 
+type stepUpVerifier interface {
+	VerifyStepUp(ctx context.Context, userId uint, code string) error
+}
+
 // Wallet is a service that handles wallet-related operations such as balance retrieval, deposits, withdrawals, and currency exchange.
 // It interacts with the database, cache, and gRPC services to perform these operations.
 type Wallet struct {
@@ -32,11 +51,15 @@ type Wallet struct {
 	clientGRPC grpcclient.ClientGRPC
 	db         storages.StoreWallet
 	cacheDB    storages.CacheDB
+	fees       *config.Fees
+	stepUp     stepUpVerifier
+	twoFactor  *config.TwoFactor
 }
 
 // New creates a new instance of the Wallet service.
-// It initializes the service with a logger, gRPC client, database storage, and cache storage.
-func New(log *slog.Logger, gRPC grpcclient.ClientGRPC, db storages.StoreWallet, cacheDB storages.CacheDB) *Wallet {
+// It initializes the service with a logger, gRPC client, database storage, cache storage, the exchange fee settings,
+// the verifier of the two-factor codes and the thresholds of the operations that need a code.
+func New(log *slog.Logger, gRPC grpcclient.ClientGRPC, db storages.StoreWallet, cacheDB storages.CacheDB, fees *config.Fees, stepUp stepUpVerifier, twoFactor *config.TwoFactor) *Wallet {
 	log.Debug("service Wallet: started creating")
 
 	log.Info("service Wallet: successfully created")
@@ -45,6 +68,9 @@ func New(log *slog.Logger, gRPC grpcclient.ClientGRPC, db storages.StoreWallet,
 		clientGRPC: gRPC,
 		db:         db,
 		cacheDB:    cacheDB,
+		fees:       fees,
+		stepUp:     stepUp,
+		twoFactor:  twoFactor,
 	}
 }
 
@@ -56,13 +82,17 @@ func (w *Wallet) Stop() error {
 	w.clientGRPC = nil
 	w.db = nil
 	w.cacheDB = nil
+	w.fees = nil
 
 	w.log.Info("service Wallet: stop successful")
 	return nil
 }
 
 // Balance retrieves the balance of all accounts for the given user.
-// It fetches the account balances from the database and returns them in a response.
+// It fetches the total and the available account balances from the database and returns them in a response,
+// the amounts of the open holds are not available.
+// If a valuation currency is requested, the value of every account in that currency and the total are added,
+// without one the display currency of the user is used, if the user has chosen it.
 func (w *Wallet) Balance(ctx context.Context, req models.BalanceRequest) (*models.BalanceResponse, error) {
 	op := "service Wallet: getting the balance of all accounts"
 	log := w.log.With(slog.String("operation", op))
@@ -76,8 +106,32 @@ func (w *Wallet) Balance(ctx context.Context, req models.BalanceRequest) (*model
 
 	log.Debug("balance data for all accounts successfully obtained from the database", "accounts", accounts)
 
+	available, err := w.db.AvailableBalance(ctx, req.UserID)
+	if err != nil {
+		log.Error("failed to get the available balance from the database", "error", err)
+		return nil, err
+	}
+
 	var resp models.BalanceResponse
 	resp.Balance = accounts
+	resp.Available = available
+
+	if req.Valuation == "" {
+		req.Valuation, err = w.db.DisplayCurrency(ctx, req.UserID)
+		if err != nil {
+			log.Error("failed to get the display currency of the user", "error", err)
+			return nil, err
+		}
+	}
+
+	if req.Valuation != "" {
+		valuation, err := w.portfolioValuation(ctx, accounts, req.Valuation)
+		if err != nil {
+			log.Error("failed to value the accounts", "currency", req.Valuation, "error", err)
+			return nil, err
+		}
+		resp.Valuation = valuation
+	}
 
 	log.Info("balance data for all accounts successfully sent")
 	return &resp, nil
@@ -110,6 +164,7 @@ func (w *Wallet) Deposit(ctx context.Context, req *models.AccountOperationReques
 
 // Withdraw handles withdrawing funds from a user's account.
 // It updates the account balance in the database and returns the new balance.
+// A withdrawal of at least the threshold of its currency needs the two-factor code of the user, if 2FA is enabled.
 func (w *Wallet) Withdraw(ctx context.Context, req *models.AccountOperationRequest) (*models.AccountOperationResponse, error) {
 	op := "service Wallet: withdraw request received"
 	log := w.log.With(slog.String("operation", op))
@@ -117,6 +172,14 @@ func (w *Wallet) Withdraw(ctx context.Context, req *models.AccountOperationReque
 
 	req.Operation = OperationWithdraw
 
+	// a large withdrawal needs the two-factor code, if the user has enabled it
+	if threshold, ok := w.twoFactor.WithdrawThresholds[req.Currency]; ok && req.Amount >= threshold {
+		if err := w.stepUp.VerifyStepUp(ctx, req.UserID, req.TwoFactorCode); err != nil {
+			log.Warn("step-up verification failed", "amount", req.Amount, "currency", req.Currency, "error", err)
+			return nil, err
+		}
+	}
+
 	newBalance, err := w.db.AccountOperation(ctx, req)
 	if err != nil {
 		log.Error("failed to withdraw in the database", "error", err)
@@ -135,6 +198,7 @@ func (w *Wallet) Withdraw(ctx context.Context, req *models.AccountOperationReque
 
 // Exchange handles currency exchange for the user.
 // It retrieves the exchange rate, calculates the new balances, and updates the database.
+// The chosen accounts are used, without them the default accounts of the currencies.
 // If the exchange rate is not in the cache, it fetches it from the gRPC server.
 func (w *Wallet) Exchange(ctx context.Context, req models.ExchangeRequest) (*models.ExchangeResponse, error) {
 	op := "service Wallet: currency exchange request"
@@ -172,6 +236,12 @@ func (w *Wallet) Exchange(ctx context.Context, req models.ExchangeRequest) (*mod
 		return nil, ErrInsufficientFunds
 	}
 
+	segment, err := w.db.UserSegment(ctx, req.UserID)
+	if err != nil {
+		log.Error("failed to get the user segment", "error", err)
+		return nil, err
+	}
+
 	log.Debug("business logic check successfully completed")
 
 	// waiting for gorutina
@@ -190,10 +260,12 @@ func (w *Wallet) Exchange(ctx context.Context, req models.ExchangeRequest) (*mod
 
 	// collect data to calculate new account balances
 	exchangeData := models.CurrencyExchangeData{
-		BaseBalance:  currentBaseAccountBalance,
-		ToBalance:    currentToAccountBalance,
-		ExchangeRate: rate.Rate,
-		Amount:       req.Amount,
+		BaseBalance:   currentBaseAccountBalance,
+		ToBalance:     currentToAccountBalance,
+		ExchangeRate:  rate.Rate,
+		Amount:        req.Amount,
+		SpreadPercent: w.spreadPercent(segment, req.FromCurrency, req.ToCurrency),
+		MinFee:        w.minFee(req.ToCurrency),
 	}
 	exchangeResult, err := w.CurrencyExchangeLogic(&exchangeData)
 	if err != nil {
@@ -202,30 +274,38 @@ func (w *Wallet) Exchange(ctx context.Context, req models.ExchangeRequest) (*mod
 	}
 
 	exchangeResult.UserID = req.UserID
+	exchangeResult.FromAccountID = req.FromAccountID
+	exchangeResult.ToAccountID = req.ToAccountID
 	exchangeResult.BaseCurrency = req.FromCurrency
 	exchangeResult.ToCurrency = req.ToCurrency
+	exchangeResult.Amount = req.Amount
+	exchangeResult.MidRate = rate.Rate
+	exchangeResult.AppliedRate = exchangeResult.Received / req.Amount
+	exchangeResult.IdempotencyKey = req.IdempotencyKey
 	if err := w.db.SaveExchangeRateChanges(ctx, exchangeResult); err != nil {
 		log.Error("failed to save currency exchange changes in the database", "error", err)
 		return nil, err
 	}
 
-	// updating the current balance for the response
-	balanceUser[req.FromCurrency] = exchangeResult.NewBaseBalance
-	balanceUser[req.ToCurrency] = exchangeResult.NewToBalance
+	// updating the current balance for the response, the balance of a currency is the sum of its accounts
+	balanceUser[req.FromCurrency] -= req.Amount
+	balanceUser[req.ToCurrency] += exchangeResult.Received
 
 	// preparing response
 	var resp models.ExchangeResponse
 	resp.Message = "currency exchange successfully"
 	resp.ExchangeRate = rate.Rate
-	resp.SpentAccoutn = models.SpentAccoutn{Currency: req.FromCurrency, Amount: req.Amount}
-	resp.ReceivedAccount = models.ReceivedAccount{Currency: req.ToCurrency, Amount: exchangeResult.Received}
+	resp.MidRate = rate.Rate
+	resp.AppliedRate = exchangeResult.AppliedRate
+	resp.Fee = models.ExchangeFee{Currency: req.ToCurrency, Amount: exchangeResult.Fee}
+	resp.SpentAccoutn = models.SpentAccoutn{AccountID: exchangeResult.FromAccountID, Currency: req.FromCurrency, Amount: req.Amount}
+	resp.ReceivedAccount = models.ReceivedAccount{AccountID: exchangeResult.ToAccountID, Currency: req.ToCurrency, Amount: exchangeResult.Received}
 	resp.NewBalance = balanceUser
 
 	log.Info("successfully exchange")
 	return &resp, nil
 }
 
-
 // ExchangeRates retrieves all exchange rates from the gRPC server.
 // It returns the rates in a response.
 func (w *Wallet) ExchangeRates(ctx context.Context) (*models.ExchangeRatesResponse, error) {
@@ -246,8 +326,106 @@ func (w *Wallet) ExchangeRates(ctx context.Context) (*models.ExchangeRatesRespon
 	return &resp, nil
 }
 
+// QuoteExchange calculates the exchange of the amount at the given mid rate with the fee of the user segment.
+// The balances are not taken into account, they are checked when the exchange is saved.
+// The result is ready to be applied to the accounts by the database.
+func (w *Wallet) QuoteExchange(ctx context.Context, userId uint, fromCurrency, toCurrency string, amount, midRate float32) (*models.CurrencyExchangeResult, error) {
+	op := "service Wallet: exchange quote"
+	log := w.log.With(slog.String("operation", op))
+	log.Debug("QuoteExchange func call", "user id", userId, "from", fromCurrency, "to", toCurrency, "amount", amount, "rate", midRate)
+
+	segment, err := w.db.UserSegment(ctx, userId)
+	if err != nil {
+		log.Error("failed to get the user segment", "error", err)
+		return nil, err
+	}
+
+	exchangeResult, err := w.CurrencyExchangeLogic(&models.CurrencyExchangeData{
+		BaseBalance:   amount,
+		ExchangeRate:  midRate,
+		Amount:        amount,
+		SpreadPercent: w.spreadPercent(segment, fromCurrency, toCurrency),
+		MinFee:        w.minFee(toCurrency),
+	})
+	if err != nil {
+		log.Warn("failed to calculate the exchange", "error", err)
+		return nil, err
+	}
+
+	exchangeResult.UserID = userId
+	exchangeResult.BaseCurrency = fromCurrency
+	exchangeResult.ToCurrency = toCurrency
+	exchangeResult.Amount = amount
+	exchangeResult.MidRate = midRate
+	exchangeResult.AppliedRate = exchangeResult.Received / amount
+
+	log.Info("exchange quote successfully calculated")
+	return exchangeResult, nil
+}
+
+// FeeReport returns the fee revenue collected for each currency in the requested period.
+// The period includes the "from" day and excludes the "to" day.
+func (w *Wallet) FeeReport(ctx context.Context, req models.FeeReportRequest) (*models.FeeReportResponse, error) {
+	op := "service Wallet: fee revenue report"
+	log := w.log.With(slog.String("operation", op))
+	log.Debug("FeeReport func call", slog.Any("requets data", req))
+
+	if !req.From.Before(req.To) {
+		log.Warn("the beginning of the period is not earlier than its end", "from", req.From, "to", req.To)
+		return nil, ErrInvalidPeriod
+	}
+
+	revenue, err := w.db.FeeRevenue(ctx, req.From, req.To)
+	if err != nil {
+		log.Error("failed to get fee revenue from the database", "error", err)
+		return nil, err
+	}
+
+	var resp models.FeeReportResponse
+	resp.Message = "report successfully generated"
+	resp.From = req.From
+	resp.To = req.To
+	resp.Revenue = revenue
+
+	log.Info("fee revenue report successfully generated")
+	return &resp, nil
+}
+
+// spreadPercent returns the fee percent for the currency pair and the user segment.
+// The pair override takes precedence over the default spread, then the segment multiplier is applied.
+func (w *Wallet) spreadPercent(segment, fromCurrency, toCurrency string) float32 {
+	if w.fees == nil {
+		return 0
+	}
+
+	spread := w.fees.SpreadPercent
+	if pairSpread, ok := w.fees.PairSpreads[fromCurrency+"/"+toCurrency]; ok {
+		spread = pairSpread
+	}
+
+	if multiplier, ok := w.fees.SegmentMultipliers[segment]; ok {
+		spread *= multiplier
+	}
+
+	return spread
+}
+
+// minFee returns the minimum fee for the currency in which the fee is charged.
+func (w *Wallet) minFee(currency string) float32 {
+	if w.fees == nil {
+		return 0
+	}
+
+	if fee, ok := w.fees.MinFees[currency]; ok {
+		return fee
+	}
+
+	return w.fees.MinFee
+}
+
 // getExchangeRateAsync fetches the exchange rate asynchronously.
 // It first checks the cache for the rate, and if not found, it fetches it from the gRPC server.
+// The time when the rate was received from the gRPC server is kept in rate.UpdatedAt.
 // The result is sent back through a channel.
 func (w *Wallet) getExchangeRateAsync(ctx context.Context, rate *models.ExchangeRate, errChan chan<- error) {
 	go func() {
@@ -256,7 +434,7 @@ func (w *Wallet) getExchangeRateAsync(ctx context.Context, rate *models.Exchange
 		log := w.log.With(slog.String("operation", op))
 		log.Debug("getExchangeRateAsync func call")
 
-		value, err := w.cacheDB.GetExchange(rate.FromCurrency, rate.ToCurrency)
+		value, updatedAt, err := w.cacheDB.GetExchange(rate.FromCurrency, rate.ToCurrency)
 		if err != nil && err != ErrRateInCacheNotFound {
 			log.Error("failed to retrieve exchange rate from cache", "error", err)
 			errChan <- err
@@ -264,6 +442,7 @@ func (w *Wallet) getExchangeRateAsync(ctx context.Context, rate *models.Exchange
 		} else if value != 0 {
 			log.Info("GOROUTINE COMPLETED ==> the exchange rate was obtained from the cache")
 			rate.Rate = value
+			rate.UpdatedAt = updatedAt
 			errChan <- nil
 			return
 		}
@@ -275,11 +454,12 @@ func (w *Wallet) getExchangeRateAsync(ctx context.Context, rate *models.Exchange
 			errChan <- err
 			return
 		}
+		rate.UpdatedAt = time.Now().UTC()
 
 		log.Debug("exchange rate was received from the GRPC server, the rate was sent onward, saving of the rate to the cache was started")
 		errChan <- nil
 
-		if err := w.cacheDB.SetExchange(rate.FromCurrency, rate.ToCurrency, rate.Rate); err != nil {
+		if err := w.cacheDB.SetExchange(rate.FromCurrency, rate.ToCurrency, rate.Rate, rate.UpdatedAt); err != nil {
 			log.Error("failed to keep the exchange rate in the cache", "error", err)
 			return
 		}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// envelope is the JSON body of a webhook request.
+type envelope struct {
+	ID        uint64          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Sign returns the value of the signature header for the body sent at the given time:
+// "t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>" with the endpoint secret>".
+// The receiver recomputes the HMAC and should reject requests with an old timestamp to prevent replays.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// StartWorker runs the delivery worker in the background, every poll interval the pending deliveries are claimed and sent.
+// A delivery is claimed in the database for the lease time, so several instances never send it at once.
+// The worker is stopped by Stop.
+func (w *Webhooks) StartWorker() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.log.Info("service Webhooks: delivery worker started", "workers", w.conf.Workers, "poll interval", w.conf.PollInterval.String())
+
+		ticker := time.NewTicker(w.conf.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				w.log.Info("service Webhooks: delivery worker stopped")
+				return
+			case <-ticker.C:
+				w.deliverPending(ctx)
+			}
+		}
+	}()
+}
+
+// deliverPending claims a batch of pending deliveries and sends them concurrently, at most Workers at a time.
+func (w *Webhooks) deliverPending(ctx context.Context) {
+	op := "service Webhooks: delivering pending webhooks"
+	log := w.log.With(slog.String("operation", op))
+
+	jobs, err := w.db.ClaimWebhookDeliveries(ctx, w.conf.BatchSize, w.conf.Lease)
+	if err != nil {
+		log.Error("failed to claim pending deliveries", "error", err)
+		return
+	}
+
+	if len(jobs) == 0 {
+		log.Debug("no pending deliveries")
+		return
+	}
+
+	log.Debug("pending deliveries claimed", "count", len(jobs))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(w.conf.Workers, 1))
+
+	for i := range jobs {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(job *models.WebhookJob) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			w.deliver(job)
+		}(&jobs[i])
+	}
+
+	wg.Wait()
+}
+
+// deliver sends one delivery and records the result of the attempt.
+func (w *Webhooks) deliver(job *models.WebhookJob) {
+	op := "service Webhooks: delivery"
+	log := w.log.With(slog.String("operation", op), slog.Uint64("delivery id", job.DeliveryID))
+
+	attempt := &models.WebhookAttempt{DeliveryID: job.DeliveryID}
+
+	statusCode, err := w.send(job)
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+
+	if err == nil {
+		log.Info("webhook delivered", "url", job.URL, "status code", statusCode)
+		attempt.Status = DeliveryDelivered
+	} else {
+		attempt.Error = err.Error()
+		attempt.Status, attempt.NextAttemptAt = nextAttempt(job.Attempt, time.Now(), w.conf.MaxAttempts, w.conf.RetryDelay, w.conf.MaxRetryDelay)
+		log.Warn("webhook delivery failed", "url", job.URL, "attempt", job.Attempt, "status", attempt.Status, "error", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.conf.Timeout)
+	defer cancel()
+
+	if err := w.db.FinishWebhookDelivery(ctx, attempt); err != nil {
+		log.Error("failed to record the delivery attempt", "error", err)
+	}
+}
+
+// send posts the signed event to the endpoint and returns the response status code.
+// Any status code other than 2xx is an error.
+func (w *Webhooks) send(job *models.WebhookJob) (int, error) {
+	body, err := json.Marshal(envelope{
+		ID:        job.EventID,
+		Type:      job.EventType,
+		CreatedAt: job.EventCreatedAt,
+		Data:      job.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.conf.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "RESTful-exchanger-webhooks")
+	req.Header.Set(HeaderEvent, job.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(job.DeliveryID, 10))
+	req.Header.Set(HeaderSignature, Sign(job.Secret, time.Now(), body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// the body is drained so that the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// nextAttempt decides what happens after a failed attempt.
+// The delivery is retried with a doubling delay, capped at maxDelay, until maxAttempts attempts have been made.
+func nextAttempt(attempt int, now time.Time, maxAttempts int, delay, maxDelay time.Duration) (string, *time.Time) {
+	if attempt >= maxAttempts {
+		return DeliveryFailed, nil
+	}
+
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxDelay)
+
+	next := now.Add(delay)
+	return DeliveryPending, &next
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+func TestSend(t *testing.T) {
+	const secret = "whsec_test"
+
+	tests := []struct {
+		name       string
+		statusCode int
+		wantErr    bool
+	}{
+		{"delivered", http.StatusOK, false},
+		{"accepted", http.StatusAccepted, false},
+		{"server error", http.StatusInternalServerError, true},
+		{"redirect is not followed", http.StatusFound, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+
+				// the receiver side of the signature check
+				var ts, signature string
+				for _, part := range strings.Split(r.Header.Get(HeaderSignature), ",") {
+					key, value, _ := strings.Cut(part, "=")
+					switch key {
+					case "t":
+						ts = value
+					case "v1":
+						signature = value
+					}
+				}
+
+				mac := hmac.New(sha256.New, []byte(secret))
+				mac.Write([]byte(ts + "." + string(body)))
+				if !hmac.Equal([]byte(signature), []byte(hex.EncodeToString(mac.Sum(nil)))) {
+					t.Errorf("invalid signature %q", r.Header.Get(HeaderSignature))
+				}
+
+				var got envelope
+				if err := json.Unmarshal(body, &got); err != nil || got.ID != 7 || got.Type != EventDepositCompleted {
+					t.Errorf("unexpected body %s", body)
+				}
+
+				if r.Header.Get(HeaderEvent) != EventDepositCompleted || r.Header.Get(HeaderDelivery) != "42" {
+					t.Errorf("unexpected headers %v", r.Header)
+				}
+
+				if tt.statusCode == http.StatusFound {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			// the test server listens on the loopback address
+			serv := New(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, &config.Webhooks{Timeout: time.Second, AllowPrivate: true})
+
+			statusCode, err := serv.send(&models.WebhookJob{
+				DeliveryID: 42,
+				URL:        server.URL,
+				Secret:     secret,
+				EventID:    7,
+				EventType:  EventDepositCompleted,
+				Payload:    json.RawMessage(`{"operation":"deposit","currency":"USD","amount":100,"balance":100}`),
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if statusCode != tt.statusCode {
+				t.Errorf("send() status code = %v, want %v", statusCode, tt.statusCode)
+			}
+		})
+	}
+}
+
+func TestSendForbiddenAddress(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	serv := New(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, &config.Webhooks{Timeout: time.Second})
+
+	statusCode, err := serv.send(&models.WebhookJob{URL: server.URL, Secret: "whsec_test", Payload: json.RawMessage(`{}`)})
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("send() error = %v, want %v", err, ErrForbiddenAddress)
+	}
+	if statusCode != 0 || called {
+		t.Errorf("request reached the loopback server, status code %v", statusCode)
+	}
+}
+
+func TestForbiddenHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", false},
+		{"93.184.216.34", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+		{"localhost", true},
+		{"api.localhost.", true},
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.100.100.200", true},
+		{"fd00:ec2::254", true},
+		{"fe80::1", true},
+		{"0.0.0.0", true},
+		{"::", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := forbiddenHost(tt.host); got != tt.want {
+				t.Errorf("forbiddenHost(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextAttempt(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		attempt    int
+		wantStatus string
+		wantDelay  time.Duration
+	}{
+		{"first retry", 1, DeliveryPending, 30 * time.Second},
+		{"delay doubles", 3, DeliveryPending, 2 * time.Minute},
+		{"delay is capped", 7, DeliveryPending, 10 * time.Minute},
+		{"last attempt", 8, DeliveryFailed, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, next := nextAttempt(tt.attempt, now, 8, 30*time.Second, 10*time.Minute)
+
+			if status != tt.wantStatus {
+				t.Errorf("nextAttempt() status = %v, want %v", status, tt.wantStatus)
+			}
+
+			if tt.wantStatus == DeliveryFailed {
+				if next != nil {
+					t.Errorf("nextAttempt() next = %v, want nil", next)
+				}
+				return
+			}
+
+			if next == nil || next.Sub(now) != tt.wantDelay {
+				t.Errorf("nextAttempt() next = %v, want %v", next, now.Add(tt.wantDelay))
+			}
+		})
+	}
+}
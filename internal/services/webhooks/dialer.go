@@ -0,0 +1,94 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// forbiddenPrefixes are the ranges that are not covered by the checks of netip.Addr:
+// "this network", the shared address space of the carriers (also used by the metadata services of some clouds),
+// the IETF protocol assignments, the benchmarking networks and the reserved range.
+var forbiddenPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// forbiddenAddress reports whether the webhooks must not be sent to the address.
+// The loopback, private, link-local (with the metadata address 169.254.169.254), multicast and unspecified
+// addresses are forbidden, an IPv4 address mapped to IPv6 is checked as IPv4.
+func forbiddenAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return true
+	}
+
+	for _, prefix := range forbiddenPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forbiddenHost reports whether the host of a webhook url is a forbidden address or a local name.
+// The names are checked again at dial time, when they are resolved.
+func forbiddenHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(host)
+	return err == nil && forbiddenAddress(addr)
+}
+
+// newClient creates the HTTP client of the deliveries. Every connection is checked after the name is resolved,
+// right before it is made, so a name that resolves to a forbidden address (or starts to after the registration)
+// is refused with ErrForbiddenAddress. The proxy of the environment is not used, it would hide the real address.
+// Redirects are not followed, a redirect response is a failed attempt.
+// With allowPrivate the addresses are not checked, it is meant for local development only.
+func newClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	if !allowPrivate {
+		dialer.Control = func(network, address string, conn syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+
+			if forbiddenAddress(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+			}
+
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
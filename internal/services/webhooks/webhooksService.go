@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/config"
+	"github.com/EvansTrein/RESTful_exchangerServer/internal/storages"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+const (
+	EventDepositCompleted  = "deposit.completed"
+	EventWithdrawCompleted = "withdraw.completed"
+	EventExchangeCompleted = "exchange.completed"
+
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+
+	secretPrefix = "whsec_"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrInvalidURL       = errors.New("webhook url must be an absolute http or https url")
+	ErrForbiddenAddress = errors.New("webhook url must not point to a loopback, private, link-local or metadata address")
+)
+
+// Webhooks is a service that handles outgoing webhooks.
+// Users register endpoints for the wallet events, the events are written by the database in the same transaction
+// as the balance change, and the delivery worker sends them as signed JSON requests with retries.
+type Webhooks struct {
+	log    *slog.Logger
+	db     storages.StoreWebhooks
+	client *http.Client
+	conf   *config.Webhooks
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a new instance of the Webhooks service.
+// It initializes the service with a logger, database storage and the configuration of the delivery worker.
+// The endpoints on the local and private networks are refused unless AllowPrivate is set, redirects are not followed.
+func New(log *slog.Logger, db storages.StoreWebhooks, conf *config.Webhooks) *Webhooks {
+	log.Debug("service Webhooks: started creating")
+
+	if conf.AllowPrivate {
+		log.Warn("webhooks may be sent to the local and private networks, use it for development only")
+	}
+
+	log.Info("service Webhooks: successfully created")
+	return &Webhooks{
+		log:    log,
+		db:     db,
+		client: newClient(conf.Timeout, conf.AllowPrivate),
+		conf:   conf,
+	}
+}
+
+// Stop gracefully shuts down the Webhooks service.
+// It stops the delivery worker, waits for the requests in progress to be recorded and cleans up resources.
+func (w *Webhooks) Stop() error {
+	w.log.Debug("service Webhooks: stop started")
+
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+
+	w.client.CloseIdleConnections()
+	w.db = nil
+
+	w.log.Info("service Webhooks: stop successful")
+	return nil
+}
+
+// CreateWebhook registers an endpoint of the user for the chosen event types.
+// The signing secret is generated here and returned only in the response of this call.
+func (w *Webhooks) CreateWebhook(ctx context.Context, req models.CreateWebhookRequest) (*models.WebhookResponse, error) {
+	op := "service Webhooks: webhook creation"
+	log := w.log.With(slog.String("operation", op))
+	log.Debug("CreateWebhook func call", slog.Any("requets data", req))
+
+	endpoint, err := url.Parse(req.URL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		log.Warn("invalid webhook url", "url", req.URL)
+		return nil, ErrInvalidURL
+	}
+
+	// the names are resolved and checked again at every delivery
+	if !w.conf.AllowPrivate && forbiddenHost(endpoint.Hostname()) {
+		log.Warn("webhook url points to a forbidden address", "url", req.URL)
+		return nil, ErrForbiddenAddress
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		log.Error("failed to generate the webhook secret", "error", err)
+		return nil, err
+	}
+
+	webhook := &models.Webhook{
+		UserID:     req.UserID,
+		URL:        endpoint.String(),
+		EventTypes: uniqueEventTypes(req.EventTypes),
+		Secret:     secret,
+		Active:     true,
+	}
+
+	if err := w.db.CreateWebhook(ctx, webhook); err != nil {
+		log.Error("failed to save the webhook in the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("webhook successfully created", "webhook id", webhook.ID)
+	return &models.WebhookResponse{Message: "webhook successfully created, store the secret, it is not shown again", Webhook: *webhook}, nil
+}
+
+// Webhooks returns the endpoints of the user, the secrets are not returned.
+func (w *Webhooks) Webhooks(ctx context.Context, req models.WebhooksRequest) (*models.WebhooksResponse, error) {
+	op := "service Webhooks: list of webhooks"
+	log := w.log.With(slog.String("operation", op))
+	log.Debug("Webhooks func call", slog.Any("requets data", req))
+
+	webhooks, err := w.db.UserWebhooks(ctx, req.UserID)
+	if err != nil {
+		log.Error("failed to get the webhooks from the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("webhooks successfully received", "count", len(webhooks))
+	return &models.WebhooksResponse{Message: "webhooks successfully received", Webhooks: webhooks}, nil
+}
+
+// DeleteWebhook deletes the endpoint of the user together with its delivery log.
+func (w *Webhooks) DeleteWebhook(ctx context.Context, req models.WebhookRequest) error {
+	op := "service Webhooks: webhook removal"
+	log := w.log.With(slog.String("operation", op))
+	log.Debug("DeleteWebhook func call", slog.Any("requets data", req))
+
+	if err := w.db.DeleteWebhook(ctx, req.UserID, req.WebhookID); err != nil {
+		log.Warn("failed to delete the webhook", "error", err)
+		return err
+	}
+
+	log.Info("webhook successfully deleted")
+	return nil
+}
+
+// Deliveries returns the delivery log of the endpoint, optionally filtered by status.
+func (w *Webhooks) Deliveries(ctx context.Context, req models.WebhookDeliveriesRequest) (*models.WebhookDeliveriesResponse, error) {
+	op := "service Webhooks: delivery log"
+	log := w.log.With(slog.String("operation", op))
+	log.Debug("Deliveries func call", slog.Any("requets data", req))
+
+	deliveries, err := w.db.WebhookDeliveries(ctx, req.UserID, req.WebhookID, req.Status)
+	if err != nil {
+		log.Warn("failed to get the deliveries from the database", "error", err)
+		return nil, err
+	}
+
+	log.Info("deliveries successfully received", "count", len(deliveries))
+	return &models.WebhookDeliveriesResponse{Message: "deliveries successfully received", Deliveries: deliveries}, nil
+}
+
+// Redeliver schedules the delivery to be sent again as soon as possible, with a full set of attempts.
+func (w *Webhooks) Redeliver(ctx context.Context, req models.RedeliverRequest) (*models.WebhookDeliveryResponse, error) {
+	op := "service Webhooks: redelivery"
+	log := w.log.With(slog.String("operation", op))
+	log.Debug("Redeliver func call", slog.Any("requets data", req))
+
+	delivery, err := w.db.Redeliver(ctx, req.UserID, req.WebhookID, req.DeliveryID)
+	if err != nil {
+		log.Warn("failed to schedule the redelivery", "error", err)
+		return nil, err
+	}
+
+	log.Info("redelivery successfully scheduled")
+	return &models.WebhookDeliveryResponse{Message: "redelivery successfully scheduled", Delivery: *delivery}, nil
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(buf), nil
+}
+
+func uniqueEventTypes(eventTypes []string) []string {
+	seen := make(map[string]bool, len(eventTypes))
+	unique := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		if !seen[eventType] {
+			seen[eventType] = true
+			unique = append(unique, eventType)
+		}
+	}
+	return unique
+}
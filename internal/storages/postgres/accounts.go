@@ -0,0 +1,257 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+const accountColumns = `id, currency_code, name, is_default, balance, held, balance - held, created_at`
+
+// scanAccount scans a row selected with accountColumns.
+func scanAccount(row interface{ Scan(dest ...any) error }) (*models.Account, error) {
+	var account models.Account
+	err := row.Scan(&account.ID, &account.Currency, &account.Name, &account.Default, &account.Balance, &account.Held, &account.Available, &account.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// UserAccounts returns all accounts of the user, by currency, the default account of a currency goes first.
+func (db *PostgresDB) UserAccounts(ctx context.Context, userId uint) ([]models.Account, error) {
+	op := "Database: list of user accounts"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UserAccounts func call", "user id", userId)
+
+	query := `SELECT ` + accountColumns + `
+        FROM accounts
+        WHERE user_id = $1
+        ORDER BY currency_code, is_default DESC, id;`
+
+	rows, err := db.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := make([]models.Account, 0)
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			log.Error("failed to scan row", "error", err)
+			return nil, err
+		}
+		accounts = append(accounts, *account)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("error during rows iteration", "error", err)
+		return nil, err
+	}
+
+	log.Info("database successfully returned the accounts")
+	return accounts, nil
+}
+
+// CreateAccount opens a new named account of the user with a zero balance, it is never the default account.
+// If the currency is unknown, it returns ErrCurrencyNotFound,
+// if the user already has an account with this name in the currency, it returns ErrAccountNameTaken.
+func (db *PostgresDB) CreateAccount(ctx context.Context, req *models.CreateAccountRequest) (*models.Account, error) {
+	op := "Database: account creation"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("CreateAccount func call", slog.Any("requets data", req))
+
+	query := `
+        INSERT INTO accounts (user_id, currency_code, name)
+        VALUES ($1, $2, $3)
+        RETURNING ` + accountColumns + `;`
+
+	account, err := scanAccount(db.db.QueryRowContext(ctx, query, req.UserID, req.Currency, req.Name))
+	if err != nil {
+		if strings.Contains(err.Error(), "foreign key") {
+			log.Warn("currency not found", "currency", req.Currency)
+			return nil, servWallet.ErrCurrencyNotFound
+		}
+		if strings.Contains(err.Error(), "duplicate key value") {
+			log.Warn("account name is taken", "currency", req.Currency, "name", req.Name)
+			return nil, servWallet.ErrAccountNameTaken
+		}
+		log.Error("fail to execute SQL query", "error", err)
+		return nil, err
+	}
+
+	log.Info("account successfully created", "account id", account.ID)
+	return account, nil
+}
+
+// CloseAccount deletes the empty named account of the user, its operations stay in the history without the account.
+// If the account does not exist or belongs to another user, it returns ErrAccountNotFound.
+// The default account can not be closed, it returns ErrDefaultAccount, an account with funds returns ErrAccountNotEmpty.
+func (db *PostgresDB) CloseAccount(ctx context.Context, userId, accountId uint) error {
+	op := "Database: account closing"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("CloseAccount func call", "user id", userId, "account id", accountId)
+
+	lockQuery := `SELECT ` + accountColumns + ` FROM accounts WHERE id = $1 AND user_id = $2 FOR UPDATE;`
+
+	deleteQuery := `DELETE FROM accounts WHERE id = $1;`
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return err
+	}
+
+	account, err := scanAccount(tx.QueryRowContext(ctx, lockQuery, accountId, userId))
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("account not found", "account id", accountId, "transaction", "rollback")
+			return servWallet.ErrAccountNotFound
+		}
+		log.Error("failed to lock the account", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if account.Default {
+		tx.Rollback()
+		log.Warn("default account can not be closed", "account id", accountId, "transaction", "rollback")
+		return servWallet.ErrDefaultAccount
+	}
+
+	if account.Balance != 0 {
+		tx.Rollback()
+		log.Warn("account still has funds", "account id", accountId, "balance", account.Balance, "transaction", "rollback")
+		return servWallet.ErrAccountNotEmpty
+	}
+
+	if _, err = tx.ExecContext(ctx, deleteQuery, accountId); err != nil {
+		tx.Rollback()
+		log.Error("failed to delete the account", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return err
+	}
+
+	log.Info("transaction successfully completed")
+	return nil
+}
+
+// Transfer moves the amount between two accounts of the user in the same currency and records both sides in the history.
+// The funds stay with the user, so the transaction limits are not applied and no wallet events are written.
+// If an account does not exist or belongs to another user, it returns ErrAccountNotFound,
+// if the currencies differ, it returns ErrCurrencyMismatch, if the available balance is too low, it returns ErrInsufficientFunds.
+// It returns both accounts after the transfer.
+func (db *PostgresDB) Transfer(ctx context.Context, req *models.TransferRequest) (*models.Account, *models.Account, error) {
+	op := "Database: transfer between accounts"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("Transfer func call", slog.Any("requets data", req))
+
+	lockQuery := `SELECT ` + accountColumns + `
+        FROM accounts
+        WHERE user_id = $1 AND id IN ($2, $3)
+        ORDER BY id
+        FOR UPDATE;`
+
+	updateQuery := `
+        UPDATE accounts
+        SET balance = balance + $2
+        WHERE id = $1
+        RETURNING balance, balance - held;`
+
+	insertOperationQuery := `
+        INSERT INTO operations (user_id, operation_type, currency_code, amount, balance_after, account_id)
+        VALUES ($1, $2, $3, $4, $5, $6);`
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return nil, nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, lockQuery, req.UserID, req.FromAccountID, req.ToAccountID)
+	if err != nil {
+		tx.Rollback()
+		log.Error("failed to lock the accounts", "error", err, "transaction", "rollback")
+		return nil, nil, err
+	}
+
+	accounts := make(map[uint]*models.Account, 2)
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			rows.Close()
+			tx.Rollback()
+			log.Error("failed to scan row", "error", err, "transaction", "rollback")
+			return nil, nil, err
+		}
+		accounts[account.ID] = account
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		log.Error("error during rows iteration", "error", err, "transaction", "rollback")
+		return nil, nil, err
+	}
+
+	from, to := accounts[req.FromAccountID], accounts[req.ToAccountID]
+	if from == nil || to == nil {
+		tx.Rollback()
+		log.Warn("account not found", "from", req.FromAccountID, "to", req.ToAccountID, "transaction", "rollback")
+		return nil, nil, servWallet.ErrAccountNotFound
+	}
+
+	if from.Currency != to.Currency {
+		tx.Rollback()
+		log.Warn("accounts are in different currencies", "from", from.Currency, "to", to.Currency, "transaction", "rollback")
+		return nil, nil, servWallet.ErrCurrencyMismatch
+	}
+
+	if from.Available < req.Amount {
+		tx.Rollback()
+		log.Warn("insufficient funds", "available balance", from.Available, "requested amount", req.Amount, "transaction", "rollback")
+		return nil, nil, servWallet.ErrInsufficientFunds
+	}
+
+	for _, side := range []struct {
+		account *models.Account
+		amount  float32
+	}{
+		{from, -req.Amount},
+		{to, req.Amount},
+	} {
+		if err = tx.QueryRowContext(ctx, updateQuery, side.account.ID, side.amount).Scan(&side.account.Balance, &side.account.Available); err != nil {
+			tx.Rollback()
+			log.Error("failed to update account balance", "error", err, "transaction", "rollback")
+			return nil, nil, err
+		}
+
+		if _, err = tx.ExecContext(ctx, insertOperationQuery, req.UserID, servWallet.OperationTransfer, side.account.Currency,
+			side.amount, side.account.Balance, side.account.ID); err != nil {
+			tx.Rollback()
+			log.Error("failed to save the operation in the history", "error", err, "transaction", "rollback")
+			return nil, nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return nil, nil, err
+	}
+
+	log.Info("transaction successfully completed")
+	return from, to, nil
+}
@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+
+	servAuth "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/lib/pq"
+)
+
+// CreateAPIKey saves a new API key of the user, its ID and creation time are set in the passed key.
+// If the user does not exist, it returns ErrUserNotFound.
+func (db *PostgresDB) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	op := "Database: creating the API key"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("CreateAPIKey func call", "user id", key.UserID, "prefix", key.Prefix)
+
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at;`
+
+	err := db.db.QueryRowContext(ctx, query, key.UserID, key.Name, key.Prefix, key.KeyHash, pq.Array(key.Scopes), key.ExpiresAt).
+		Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "foreign key") {
+			log.Warn("user not found", "user id", key.UserID)
+			return servAuth.ErrUserNotFound
+		}
+		log.Error("fail to execute SQL query", "error", err)
+		return err
+	}
+
+	log.Info("API key successfully saved")
+	return nil
+}
+
+// UserAPIKeys returns the API keys of the user including the revoked ones, newest first.
+func (db *PostgresDB) UserAPIKeys(ctx context.Context, userId uint) ([]models.APIKey, error) {
+	op := "Database: list of user API keys"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UserAPIKeys func call", "user id", userId)
+
+	query := `
+		SELECT id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC;`
+
+	rows, err := db.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]models.APIKey, 0)
+	for rows.Next() {
+		key := models.APIKey{UserID: userId}
+		if err := rows.Scan(&key.ID, &key.Name, &key.Prefix, pq.Array(&key.Scopes), &key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt); err != nil {
+			log.Error("failed to scan row", "error", err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("error during rows iteration", "error", err)
+		return nil, err
+	}
+
+	log.Info("database successfully returned the API keys")
+	return keys, nil
+}
+
+// RevokeAPIKey revokes the API key of the user, the key stays in the list with its revocation time.
+// If the key does not exist, belongs to another user or is already revoked, it returns ErrAPIKeyNotFound.
+func (db *PostgresDB) RevokeAPIKey(ctx context.Context, userId, keyId uint) error {
+	op := "Database: API key revocation"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("RevokeAPIKey func call", "user id", userId, "key id", keyId)
+
+	query := `
+		UPDATE api_keys
+		SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;`
+
+	result, err := db.db.ExecContext(ctx, query, keyId, userId)
+	if err != nil {
+		log.Error("fail to execute SQL query", "error", err)
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		log.Warn("API key not found", "key id", keyId)
+		return servAuth.ErrAPIKeyNotFound
+	}
+
+	log.Info("API key successfully revoked")
+	return nil
+}
+
+// revokeUserAPIKeys revokes all active API keys of the user inside the transaction,
+// it is called when the password is changed or reset, the keys issued with the old credentials stop working.
+func revokeUserAPIKeys(ctx context.Context, tx *sql.Tx, userId uint) error {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL;`
+
+	_, err := tx.ExecContext(ctx, query, userId)
+	return err
+}
+
+// UseAPIKey returns the active API key with the hash together with the current role of its user.
+// The last use time is updated at most once a minute, so that every request does not write the row.
+// If the key is unknown, revoked or expired, it returns ErrInvalidAPIKey.
+func (db *PostgresDB) UseAPIKey(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	op := "Database: using the API key"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UseAPIKey func call")
+
+	selectQuery := `
+		SELECT k.id, k.user_id, k.name, k.prefix, k.scopes, k.expires_at, k.last_used_at, k.created_at, u.role
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id AND u.deleted_at IS NULL
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW());`
+
+	touchQuery := `
+		UPDATE api_keys
+		SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');`
+
+	var key models.APIKey
+	err := db.db.QueryRowContext(ctx, selectQuery, keyHash).
+		Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, pq.Array(&key.Scopes), &key.ExpiresAt, &key.LastUsedAt, &key.CreatedAt, &key.Role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("API key is unknown, revoked or expired")
+			return nil, servAuth.ErrInvalidAPIKey
+		}
+		log.Error("fail to execute SQL query", "error", err)
+		return nil, err
+	}
+
+	if _, err := db.db.ExecContext(ctx, touchQuery, key.ID); err != nil {
+		log.Warn("failed to update the last use time of the API key", "key id", key.ID, "error", err)
+	}
+
+	return &key, nil
+}
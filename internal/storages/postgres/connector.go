@@ -1,10 +1,12 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log/slog"
 
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/retry"
 	_ "github.com/lib/pq"
 )
 
@@ -16,9 +18,10 @@ type PostgresDB struct {
 }
 
 // New creates a new PostgresDB instance and establishes a connection to the PostgreSQL server.
-// It takes the database connection string and a logger as parameters.
+// It takes the database connection string, a logger and the retry policy of the connection as parameters,
+// the server is pinged until it answers or the deadline of the policy passes, every attempt is logged.
 // If the connection fails, it returns an error.
-func New(storagePath string, log *slog.Logger) (*PostgresDB, error) {
+func New(ctx context.Context, storagePath string, log *slog.Logger, policy retry.Policy) (*PostgresDB, error) {
 	log.Debug("database: connection to Postgres started")
 
 	db, err := sql.Open("postgres", storagePath)
@@ -26,7 +29,8 @@ func New(storagePath string, log *slog.Logger) (*PostgresDB, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
+	if err := retry.Do(ctx, log, policy, "postgres", db.PingContext); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
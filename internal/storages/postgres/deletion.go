@@ -0,0 +1,293 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	servAuth "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	servHolds "github.com/EvansTrein/RESTful_exchangerServer/internal/services/holds"
+	servOrders "github.com/EvansTrein/RESTful_exchangerServer/internal/services/orders"
+	servOutbox "github.com/EvansTrein/RESTful_exchangerServer/internal/services/outbox"
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	servWebhooks "github.com/EvansTrein/RESTful_exchangerServer/internal/services/webhooks"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// sweptAccount is an account of a deleted user with funds.
+type sweptAccount struct {
+	id       uint
+	currency string
+	balance  float32
+}
+
+// DeleteUser soft-deletes the user, the history of the operations is kept and the personal data is anonymized.
+// The open orders are cancelled and the open holds are released first, so that their amounts return to the accounts. If the accounts still
+// have funds, they are swept to req.SweepTo (see sweepAccounts), without a sweep target it returns ErrFundsRemain.
+// The verifications, the password resets, the two-factor authentication, the API keys, the webhooks and the schedules
+// of the user are deleted, the issued tokens are revoked. Everything is done in one transaction.
+// It returns the swept balances by currency. If the user is not found, it returns ErrUserNotFound.
+func (db *PostgresDB) DeleteUser(ctx context.Context, req *models.DeleteUserRequest) (map[string]float32, error) {
+	op := "Database: user removal"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("DeleteUser func call", slog.Any("requets data", req))
+
+	lockQuery := `SELECT id FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE;`
+
+	openOrdersQuery := `SELECT ` + orderColumns + ` FROM orders WHERE user_id = $1 AND status = 'open' FOR UPDATE;`
+
+	openHoldsQuery := `SELECT ` + holdColumns + ` FROM holds WHERE user_id = $1 AND status = 'open' FOR UPDATE;`
+
+	accountsQuery := `
+		SELECT id, currency_code, balance
+		FROM accounts
+		WHERE user_id = $1 AND balance > 0
+		ORDER BY id
+		FOR UPDATE;`
+
+	cleanupQueries := []string{
+		`DELETE FROM email_verifications WHERE user_id = $1;`,
+		`DELETE FROM password_resets WHERE user_id = $1;`,
+		`DELETE FROM two_factor WHERE user_id = $1;`,
+		`DELETE FROM api_keys WHERE user_id = $1;`,
+		`DELETE FROM webhook_endpoints WHERE user_id = $1;`,
+		`DELETE FROM schedules WHERE user_id = $1;`,
+	}
+
+	anonymizeQuery := `
+		UPDATE users
+		SET name = 'deleted user',
+			email = 'deleted-' || id || '@deleted.invalid',
+			password_hash = '',
+			email_verified_at = NULL,
+			display_currency = NULL,
+			token_version = token_version + 1,
+			deleted_at = NOW()
+		WHERE id = $1;`
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return nil, err
+	}
+
+	var id uint
+	if err = tx.QueryRowContext(ctx, lockQuery, req.UserID).Scan(&id); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("user not found", "user id", req.UserID, "transaction", "rollback")
+			return nil, servAuth.ErrUserNotFound
+		}
+		log.Error("failed to lock the user", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	orders, err := scanOrders(ctx, tx, openOrdersQuery, req.UserID)
+	if err != nil {
+		tx.Rollback()
+		log.Error("failed to get the open orders", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	for _, order := range orders {
+		if err = closeOrder(ctx, tx, order, servOrders.StatusCancelled, servOrders.EventCancelled, "cancelled by the deletion of the user"); err != nil {
+			tx.Rollback()
+			log.Error("failed to cancel the order", "order id", order.ID, "error", err, "transaction", "rollback")
+			return nil, err
+		}
+	}
+
+	holds, err := scanHolds(ctx, tx, openHoldsQuery, req.UserID)
+	if err != nil {
+		tx.Rollback()
+		log.Error("failed to get the open holds", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	for _, hold := range holds {
+		if err = closeHold(ctx, tx, hold, servHolds.StatusReleased, nil); err != nil {
+			tx.Rollback()
+			log.Error("failed to release the hold", "hold id", hold.ID, "error", err, "transaction", "rollback")
+			return nil, err
+		}
+	}
+
+	accounts, err := scanSweptAccounts(ctx, tx, accountsQuery, req.UserID)
+	if err != nil {
+		tx.Rollback()
+		log.Error("failed to lock the accounts", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	swept := make(map[string]float32, len(accounts))
+	if len(accounts) > 0 {
+		if req.SweepTo == "" {
+			tx.Rollback()
+			log.Warn("accounts still have funds", "accounts", len(accounts), "transaction", "rollback")
+			return nil, servAuth.ErrFundsRemain
+		}
+
+		if err = sweepAccounts(ctx, tx, req, accounts); err != nil {
+			tx.Rollback()
+			log.Warn("failed to sweep the funds", "error", err, "transaction", "rollback")
+			return nil, err
+		}
+
+		for _, account := range accounts {
+			swept[account.currency] += account.balance
+		}
+	}
+
+	for _, query := range cleanupQueries {
+		if _, err = tx.ExecContext(ctx, query, req.UserID); err != nil {
+			tx.Rollback()
+			log.Error("failed to delete the data of the user", "error", err, "transaction", "rollback")
+			return nil, err
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, anonymizeQuery, req.UserID); err != nil {
+		tx.Rollback()
+		log.Error("failed to anonymize the user", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if err = writeOutbox(ctx, tx, req.UserID, servOutbox.EventUserDeleted, &models.UserEvent{}); err != nil {
+		tx.Rollback()
+		log.Error("failed to write the outbox event", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return nil, err
+	}
+
+	log.Info("transaction successfully completed", "swept", swept)
+	return swept, nil
+}
+
+// sweepAccounts empties the locked accounts of the deleted user, every change is recorded in the history and the outbox.
+// With SweepWithdraw the funds are withdrawn, with SweepUser they are moved to the default accounts of the user with SweepEmail,
+// the missing accounts of the target are opened. The transaction limits are not applied, the funds can not stay.
+// The email of the deleted user must be verified, as for a withdrawal. If the target is not found or is the user itself,
+// it returns ErrSweepTarget.
+func sweepAccounts(ctx context.Context, tx *sql.Tx, req *models.DeleteUserRequest, accounts []sweptAccount) error {
+	targetQuery := `SELECT id FROM users WHERE email = $1 AND deleted_at IS NULL FOR UPDATE;`
+
+	emptyQuery := `UPDATE accounts SET balance = 0 WHERE id = $1;`
+
+	creditQuery := `
+		INSERT INTO accounts (user_id, currency_code, balance, is_default)
+		VALUES ($1, $2, $3, TRUE)
+		ON CONFLICT (user_id, currency_code) WHERE is_default DO UPDATE SET balance = accounts.balance + EXCLUDED.balance
+		RETURNING id, balance;`
+
+	insertOperationQuery := `
+		INSERT INTO operations (user_id, operation_type, currency_code, amount, balance_after, account_id)
+		VALUES ($1, $2, $3, $4, $5, $6);`
+
+	if err := checkVerified(ctx, tx, req.UserID); err != nil {
+		return err
+	}
+
+	var targetId uint
+	if req.SweepTo == servAuth.SweepUser {
+		if err := tx.QueryRowContext(ctx, targetQuery, req.SweepEmail).Scan(&targetId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return servAuth.ErrSweepTarget
+			}
+			return err
+		}
+
+		if targetId == req.UserID {
+			return servAuth.ErrSweepTarget
+		}
+	}
+
+	for _, account := range accounts {
+		if _, err := tx.ExecContext(ctx, emptyQuery, account.id); err != nil {
+			return err
+		}
+
+		operation := servWallet.OperationWithdraw
+		if req.SweepTo == servAuth.SweepUser {
+			operation = servAuth.OperationSweepOut
+		}
+
+		if _, err := tx.ExecContext(ctx, insertOperationQuery, req.UserID, operation, account.currency, -account.balance, 0, account.id); err != nil {
+			return err
+		}
+
+		event := &models.WalletEvent{Operation: operation, Currency: account.currency, Amount: account.balance}
+		if err := writeOutbox(ctx, tx, req.UserID, servOutbox.EventWithdrawn, event); err != nil {
+			return err
+		}
+
+		if req.SweepTo != servAuth.SweepUser {
+			continue
+		}
+
+		var targetAccountId uint
+		var balanceAfter float32
+		if err := tx.QueryRowContext(ctx, creditQuery, targetId, account.currency, account.balance).Scan(&targetAccountId, &balanceAfter); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, insertOperationQuery, targetId, servAuth.OperationSweepIn, account.currency, account.balance, balanceAfter, targetAccountId); err != nil {
+			return err
+		}
+
+		credit := &models.WalletEvent{Operation: servAuth.OperationSweepIn, Currency: account.currency, Amount: account.balance, Balance: balanceAfter}
+		if err := recordWebhookEvent(ctx, tx, targetId, servWebhooks.EventDepositCompleted, credit); err != nil {
+			return err
+		}
+
+		if err := writeOutbox(ctx, tx, targetId, servOutbox.EventDeposited, credit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scanOrders returns the orders selected by the query with orderColumns, the rows are read before they are changed.
+func scanOrders(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]*models.Order, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]*models.Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
+
+// scanSweptAccounts returns the accounts selected by the query with the ID, the currency and the balance.
+func scanSweptAccounts(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]sweptAccount, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := make([]sweptAccount, 0)
+	for rows.Next() {
+		var account sweptAccount
+		if err := rows.Scan(&account.id, &account.currency, &account.balance); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
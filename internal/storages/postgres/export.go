@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// ExportUser collects the data of the user for the export: the accounts, the whole history of the operations,
+// the orders, the holds, the schedules, the webhooks and the API keys. The profile is filled by the service.
+// If the user is not found, it returns ErrUserNotFound.
+func (db *PostgresDB) ExportUser(ctx context.Context, userId uint) (*models.UserExport, error) {
+	op := "Database: exporting the user data"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("ExportUser func call", slog.Any("user id", userId))
+
+	var export models.UserExport
+	var err error
+
+	if export.Accounts, err = db.UserAccounts(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	if export.Operations, err = db.userOperations(ctx, userId); err != nil {
+		log.Error("failed to get the operations", "error", err)
+		return nil, err
+	}
+
+	if export.Orders, err = db.UserOrders(ctx, userId, ""); err != nil {
+		return nil, err
+	}
+
+	if export.Holds, err = db.UserHolds(ctx, userId, ""); err != nil {
+		return nil, err
+	}
+
+	if export.Schedules, err = db.UserSchedules(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	if export.Webhooks, err = db.UserWebhooks(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	if export.APIKeys, err = db.UserAPIKeys(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	log.Info("database successfully returned the user data")
+	return &export, nil
+}
+
+const operationColumns = `id, account_id, operation_type, currency_code, amount, balance_after, counter_currency, exchange_rate, created_at`
+
+// scanOperation scans a row selected with operationColumns.
+func scanOperation(row interface{ Scan(dest ...any) error }) (*models.OperationRecord, error) {
+	var operation models.OperationRecord
+	var accountId sql.NullInt64
+	var counterCurrency sql.NullString
+	var exchangeRate sql.NullFloat64
+	if err := row.Scan(&operation.ID, &accountId, &operation.Type, &operation.Currency, &operation.Amount, &operation.BalanceAfter,
+		&counterCurrency, &exchangeRate, &operation.CreatedAt); err != nil {
+		return nil, err
+	}
+	operation.AccountID = uint(accountId.Int64)
+	operation.CounterCurrency = counterCurrency.String
+	operation.ExchangeRate = float32(exchangeRate.Float64)
+	return &operation, nil
+}
+
+// userOperations returns the history of the operations of the user, oldest first.
+func (db *PostgresDB) userOperations(ctx context.Context, userId uint) ([]models.OperationRecord, error) {
+	query := `
+		SELECT ` + operationColumns + `
+		FROM operations
+		WHERE user_id = $1
+		ORDER BY created_at, id;`
+
+	rows, err := db.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	operations := make([]models.OperationRecord, 0)
+	for rows.Next() {
+		operation, err := scanOperation(rows)
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, *operation)
+	}
+
+	return operations, rows.Err()
+}
@@ -0,0 +1,433 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	servHolds "github.com/EvansTrein/RESTful_exchangerServer/internal/services/holds"
+	servOutbox "github.com/EvansTrein/RESTful_exchangerServer/internal/services/outbox"
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	servWebhooks "github.com/EvansTrein/RESTful_exchangerServer/internal/services/webhooks"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+const holdColumns = `id, user_id, account_id, currency_code, amount, captured, reference, status, expires_at, created_at, closed_at`
+
+// scanHold scans a row selected with holdColumns.
+func scanHold(row interface{ Scan(dest ...any) error }) (*models.Hold, error) {
+	var hold models.Hold
+	var accountId sql.NullInt64
+	var captured sql.NullFloat64
+	var closedAt sql.NullTime
+
+	err := row.Scan(
+		&hold.ID,
+		&hold.UserID,
+		&accountId,
+		&hold.Currency,
+		&hold.Amount,
+		&captured,
+		&hold.Reference,
+		&hold.Status,
+		&hold.ExpiresAt,
+		&hold.CreatedAt,
+		&closedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	hold.AccountID = uint(accountId.Int64)
+	hold.Captured = nullFloat32(captured)
+	if closedAt.Valid {
+		hold.ClosedAt = &closedAt.Time
+	}
+
+	return &hold, nil
+}
+
+// scanHolds returns the holds selected by the query with holdColumns, the rows are read before they are changed.
+func scanHolds(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]*models.Hold, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := make([]*models.Hold, 0)
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, hold)
+	}
+
+	return holds, rows.Err()
+}
+
+// closeHold returns the amount of an open hold to the available balance of its account and sets the final status,
+// captured is the amount debited by the capture, it is nil for a release or an expiration.
+// The hold row must be locked by the caller.
+func closeHold(ctx context.Context, tx *sql.Tx, hold *models.Hold, status string, captured *float32) error {
+	releaseQuery := `UPDATE accounts SET held = held - $2 WHERE id = $1;`
+
+	closeQuery := `
+        UPDATE holds
+        SET status = $2, captured = $3, closed_at = NOW()
+        WHERE id = $1
+        RETURNING ` + holdColumns + `;`
+
+	if hold.AccountID != 0 {
+		if _, err := tx.ExecContext(ctx, releaseQuery, hold.AccountID, hold.Amount); err != nil {
+			return err
+		}
+	}
+
+	closed, err := scanHold(tx.QueryRowContext(ctx, closeQuery, hold.ID, status, captured))
+	if err != nil {
+		return err
+	}
+	*hold = *closed
+
+	return nil
+}
+
+// lockHold locks the hold of the user. If the hold is not found, it returns ErrHoldNotFound,
+// if it is not open or its expiry time has passed, it returns ErrHoldNotOpen.
+func lockHold(ctx context.Context, tx *sql.Tx, userId, holdId uint) (*models.Hold, error) {
+	lockQuery := `SELECT ` + holdColumns + ` FROM holds WHERE id = $1 AND user_id = $2 FOR UPDATE;`
+
+	hold, err := scanHold(tx.QueryRowContext(ctx, lockQuery, holdId, userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, servHolds.ErrHoldNotFound
+		}
+		return nil, err
+	}
+
+	// an expired hold may still be open until the next round of the expirer
+	if hold.Status != servHolds.StatusOpen || !hold.ExpiresAt.After(time.Now()) {
+		return nil, servHolds.ErrHoldNotOpen
+	}
+
+	return hold, nil
+}
+
+// CreateHold holds the amount on the account of the user, without req.AccountID on the default account of the currency.
+// It locks the account, checks the available balance, the verified email of the user and the withdrawal limit,
+// as the captured amount is withdrawn, and adds the amount to the held amount of the account.
+// If the account is not found, it returns ErrAccountNotFound, if the available balance is too low, it returns ErrInsufficientFunds.
+func (db *PostgresDB) CreateHold(ctx context.Context, req *models.CreateHoldRequest) (*models.Hold, error) {
+	op := "Database: hold creation"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("CreateHold func call", slog.Any("requets data", req))
+
+	getBalanceAndLockQuery := `
+        SELECT id, balance - held
+        FROM accounts
+        WHERE user_id = $1 AND currency_code = $2 AND (id = $3 OR ($3 = 0 AND is_default))
+        FOR UPDATE;`
+
+	holdQuery := `UPDATE accounts SET held = held + $2 WHERE id = $1;`
+
+	insertHoldQuery := `
+        INSERT INTO holds (user_id, account_id, currency_code, amount, reference, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING ` + holdColumns + `;`
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return nil, err
+	}
+
+	var accountId uint
+	var availableBalance float32
+	if err = tx.QueryRowContext(ctx, getBalanceAndLockQuery, req.UserID, req.Currency, req.AccountID).Scan(&accountId, &availableBalance); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("account not found", "user id", req.UserID, "currency", req.Currency, "account id", req.AccountID, "transaction", "rollback")
+			return nil, servWallet.ErrAccountNotFound
+		}
+		log.Error("failed to get current balance", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if availableBalance < req.Amount {
+		tx.Rollback()
+		log.Warn("insufficient funds", "available balance", availableBalance, "requested amount", req.Amount, "transaction", "rollback")
+		return nil, servWallet.ErrInsufficientFunds
+	}
+
+	if err = checkVerified(ctx, tx, req.UserID); err != nil {
+		tx.Rollback()
+		log.Warn("hold is not allowed", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	// the limit of the user and the currency is locked by the check until the hold is committed
+	if err = db.checkLimit(ctx, tx, req.UserID, req.Currency, servWallet.OperationWithdraw, req.Amount); err != nil {
+		tx.Rollback()
+		log.Warn("transaction limit check failed", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if _, err = tx.ExecContext(ctx, holdQuery, accountId, req.Amount); err != nil {
+		tx.Rollback()
+		log.Error("failed to hold the amount", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	hold, err := scanHold(tx.QueryRowContext(ctx, insertHoldQuery,
+		req.UserID, accountId, req.Currency, req.Amount, req.Reference, req.ExpiresAt))
+	if err != nil {
+		tx.Rollback()
+		log.Error("failed to save the hold", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return nil, err
+	}
+
+	log.Info("transaction successfully completed", "hold id", hold.ID)
+	return hold, nil
+}
+
+// UserHolds returns the holds of the user, newest first, optionally filtered by status.
+func (db *PostgresDB) UserHolds(ctx context.Context, userId uint, status string) ([]models.Hold, error) {
+	op := "Database: list of user holds"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UserHolds func call", "user id", userId, "status", status)
+
+	query := `SELECT ` + holdColumns + `
+        FROM holds
+        WHERE user_id = $1 AND ($2 = '' OR status = $2)
+        ORDER BY created_at DESC, id DESC;`
+
+	rows, err := db.db.QueryContext(ctx, query, userId, status)
+	if err != nil {
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := make([]models.Hold, 0)
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			log.Error("failed to scan row", "error", err)
+			return nil, err
+		}
+		holds = append(holds, *hold)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("error during rows iteration", "error", err)
+		return nil, err
+	}
+
+	log.Info("database successfully returned the holds")
+	return holds, nil
+}
+
+// UserHold returns one hold of the user. If the hold does not exist or belongs to another user, it returns ErrHoldNotFound.
+func (db *PostgresDB) UserHold(ctx context.Context, userId, holdId uint) (*models.Hold, error) {
+	op := "Database: user hold"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UserHold func call", "user id", userId, "hold id", holdId)
+
+	query := `SELECT ` + holdColumns + ` FROM holds WHERE id = $1 AND user_id = $2;`
+
+	hold, err := scanHold(db.db.QueryRowContext(ctx, query, holdId, userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("hold not found", "hold id", holdId)
+			return nil, servHolds.ErrHoldNotFound
+		}
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, err
+	}
+
+	log.Info("database successfully returned the hold")
+	return hold, nil
+}
+
+// CaptureHold withdraws the amount of an open hold from its account, a zero amount captures the whole hold,
+// the rest of the hold is released. The capture is recorded in the history as a withdrawal,
+// the webhook and outbox events of a withdrawal are written.
+// If the hold is not found, it returns ErrHoldNotFound, if it is not open, it returns ErrHoldNotOpen,
+// if the amount is larger than the hold, it returns ErrCaptureExceedsHold.
+func (db *PostgresDB) CaptureHold(ctx context.Context, userId, holdId uint, amount float32) (*models.Hold, error) {
+	op := "Database: hold capture"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("CaptureHold func call", "user id", userId, "hold id", holdId, "amount", amount)
+
+	debitQuery := `
+        UPDATE accounts
+        SET balance = balance - $2
+        WHERE id = $1
+        RETURNING balance;`
+
+	insertOperationQuery := `
+        INSERT INTO operations (user_id, operation_type, currency_code, amount, balance_after, account_id)
+        VALUES ($1, $2, $3, $4, $5, $6);`
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return nil, err
+	}
+
+	hold, err := lockHold(ctx, tx, userId, holdId)
+	if err != nil {
+		tx.Rollback()
+		log.Warn("hold can not be captured", "hold id", holdId, "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if amount == 0 {
+		amount = hold.Amount
+	}
+
+	if amount > hold.Amount {
+		tx.Rollback()
+		log.Warn("capture amount exceeds the hold", "held", hold.Amount, "requested amount", amount, "transaction", "rollback")
+		return nil, servHolds.ErrCaptureExceedsHold
+	}
+
+	// the held amount is released first, so that the debit never leaves the account below its held amount
+	if err = closeHold(ctx, tx, hold, servHolds.StatusCaptured, &amount); err != nil {
+		tx.Rollback()
+		log.Error("failed to close the hold", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	var balanceAfter float32
+	if err = tx.QueryRowContext(ctx, debitQuery, hold.AccountID, amount).Scan(&balanceAfter); err != nil {
+		tx.Rollback()
+		log.Error("failed to update account balance", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if _, err = tx.ExecContext(ctx, insertOperationQuery, userId, servWallet.OperationWithdraw, hold.Currency, -amount, balanceAfter, hold.AccountID); err != nil {
+		tx.Rollback()
+		log.Error("failed to save the operation in the history", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	event := &models.WalletEvent{
+		Operation: servWallet.OperationWithdraw,
+		Currency:  hold.Currency,
+		Amount:    amount,
+		Balance:   balanceAfter,
+	}
+
+	if err = recordWebhookEvent(ctx, tx, userId, servWebhooks.EventWithdrawCompleted, event); err != nil {
+		tx.Rollback()
+		log.Error("failed to save the webhook event", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if err = writeOutbox(ctx, tx, userId, servOutbox.EventWithdrawn, event); err != nil {
+		tx.Rollback()
+		log.Error("failed to write the outbox event", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return nil, err
+	}
+
+	log.Info("transaction successfully completed", "hold id", hold.ID)
+	return hold, nil
+}
+
+// ReleaseHold releases an open hold of the user, its amount becomes available again.
+// If the hold is not found, it returns ErrHoldNotFound, if it is not open, it returns ErrHoldNotOpen.
+func (db *PostgresDB) ReleaseHold(ctx context.Context, userId, holdId uint) (*models.Hold, error) {
+	op := "Database: hold release"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("ReleaseHold func call", "user id", userId, "hold id", holdId)
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return nil, err
+	}
+
+	hold, err := lockHold(ctx, tx, userId, holdId)
+	if err != nil {
+		tx.Rollback()
+		log.Warn("hold can not be released", "hold id", holdId, "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if err = closeHold(ctx, tx, hold, servHolds.StatusReleased, nil); err != nil {
+		tx.Rollback()
+		log.Error("failed to release the hold", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return nil, err
+	}
+
+	log.Info("transaction successfully completed", "hold id", hold.ID)
+	return hold, nil
+}
+
+// ExpireHolds releases the open holds whose expiry time has passed.
+// The holds locked by another instance are skipped, they are expired by that instance or on the next round.
+// It returns the number of expired holds.
+func (db *PostgresDB) ExpireHolds(ctx context.Context) (int, error) {
+	op := "Database: holds expiration"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("ExpireHolds func call")
+
+	lockQuery := `SELECT ` + holdColumns + `
+        FROM holds
+        WHERE status = 'open' AND expires_at <= NOW()
+        ORDER BY id
+        FOR UPDATE SKIP LOCKED;`
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return 0, err
+	}
+
+	holds, err := scanHolds(ctx, tx, lockQuery)
+	if err != nil {
+		tx.Rollback()
+		log.Error("failed to lock the expired holds", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	for _, hold := range holds {
+		if err := closeHold(ctx, tx, hold, servHolds.StatusExpired, nil); err != nil {
+			tx.Rollback()
+			log.Error("failed to expire the hold", "hold id", hold.ID, "error", err, "transaction", "rollback")
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return 0, err
+	}
+
+	log.Debug("transaction successfully completed", "expired", len(holds))
+	return len(holds), nil
+}
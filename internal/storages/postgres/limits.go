@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"math"
+
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// usageColumns sums the operations counted against a limit in the current day and month.
+// Deposits are counted by the credited amount, withdrawals and exchanges by the debited amount.
+// The open holds are withdrawals that are not captured yet, they are counted in both periods until they are closed.
+const usageColumns = `
+	COALESCE(SUM(ABS(o.amount)) FILTER (WHERE o.created_at >= date_trunc('day', NOW())), 0) + h.amount,
+	COALESCE(SUM(ABS(o.amount)), 0) + h.amount`
+
+const usageJoin = `
+	LEFT JOIN operations o
+		ON o.user_id = u.id
+		AND o.currency_code = l.currency_code
+		AND o.operation_type = l.operation_type
+		AND (o.amount > 0) = (l.operation_type = 'deposit')
+		AND o.created_at >= date_trunc('month', NOW())
+	LEFT JOIN LATERAL (
+		SELECT COALESCE(SUM(amount), 0) AS amount
+		FROM holds
+		WHERE user_id = u.id AND currency_code = l.currency_code AND status = 'open' AND l.operation_type = 'withdraw'
+	) h ON TRUE`
+
+// checkLimit verifies that the operation fits into the daily and monthly limits of the user segment.
+// It must be called inside the transaction of the operation. The usage of a limit is summed over all accounts
+// of the currency, so locking the account row is not enough: the check first takes a transaction advisory lock
+// on the user and the currency, the concurrent operations of the user in the currency are checked one by one
+// and every check sees the operations committed before it.
+// If a limit would be exceeded, it returns a *servWallet.LimitExceededError.
+func (db *PostgresDB) checkLimit(ctx context.Context, tx *sql.Tx, userId uint, currency, operation string, amount float32) error {
+	// the two-key form does not overlap the single-key locks of the migrations and the outbox
+	lockQuery := `SELECT pg_advisory_xact_lock($1, hashtext($2));`
+
+	query := `
+		SELECT l.daily_limit, l.monthly_limit,` + usageColumns + `
+		FROM users u
+		INNER JOIN transaction_limits l
+			ON l.segment = u.segment AND l.currency_code = $2 AND l.operation_type = $3` + usageJoin + `
+		WHERE u.id = $1
+		GROUP BY l.daily_limit, l.monthly_limit, h.amount;`
+
+	if _, err := tx.ExecContext(ctx, lockQuery, userId, currency); err != nil {
+		return err
+	}
+
+	var dailyLimit, monthlyLimit sql.NullFloat64
+	var dailyUsed, monthlyUsed float64
+	err := tx.QueryRowContext(ctx, query, userId, currency, operation).Scan(&dailyLimit, &monthlyLimit, &dailyUsed, &monthlyUsed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// no limits are configured for the segment, currency and operation
+			return nil
+		}
+		return err
+	}
+
+	periods := []struct {
+		name  string
+		limit sql.NullFloat64
+		used  float64
+	}{
+		{servWallet.PeriodDaily, dailyLimit, dailyUsed},
+		{servWallet.PeriodMonthly, monthlyLimit, monthlyUsed},
+	}
+
+	for _, period := range periods {
+		if !period.limit.Valid {
+			continue
+		}
+
+		if math.Round((period.used+float64(amount))*100) > math.Round(period.limit.Float64*100) {
+			return &servWallet.LimitExceededError{
+				Operation: operation,
+				Currency:  currency,
+				Period:    period.name,
+				Limit:     float32(period.limit.Float64),
+				Remaining: float32(math.Max(0, math.Round((period.limit.Float64-period.used)*100)/100)),
+			}
+		}
+	}
+
+	return nil
+}
+
+// LimitsUsage returns the limits of the user segment for every currency and operation
+// together with the amount used in the current day and month, the open holds are counted as used by the withdrawals.
+func (db *PostgresDB) LimitsUsage(ctx context.Context, userId uint) ([]models.LimitUsage, error) {
+	op := "Database: transaction limits usage"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("LimitsUsage func call", slog.Any("user id", userId))
+
+	query := `
+		SELECT l.operation_type, l.currency_code, l.daily_limit, l.monthly_limit,` + usageColumns + `
+		FROM users u
+		INNER JOIN transaction_limits l ON l.segment = u.segment` + usageJoin + `
+		WHERE u.id = $1
+		GROUP BY l.operation_type, l.currency_code, l.daily_limit, l.monthly_limit, h.amount
+		ORDER BY l.currency_code, l.operation_type;`
+
+	stmt, err := db.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Error("failed to prepare SQL query", "error", err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, userId)
+	if err != nil {
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	limits := make([]models.LimitUsage, 0)
+	for rows.Next() {
+		var item models.LimitUsage
+		var dailyLimit, monthlyLimit sql.NullFloat64
+		if err := rows.Scan(&item.Operation, &item.Currency, &dailyLimit, &monthlyLimit, &item.DailyUsed, &item.MonthlyUsed); err != nil {
+			log.Error("failed to scan row", "error", err)
+			return nil, err
+		}
+		item.DailyLimit = nullFloat32(dailyLimit)
+		item.MonthlyLimit = nullFloat32(monthlyLimit)
+		limits = append(limits, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("error during rows iteration", "error", err)
+		return nil, err
+	}
+
+	log.Info("database successfully returned the limits usage")
+	return limits, nil
+}
+
+func nullFloat32(value sql.NullFloat64) *float32 {
+	if !value.Valid {
+		return nil
+	}
+
+	result := float32(value.Float64)
+	return &result
+}
@@ -6,13 +6,17 @@ import (
 	"errors"
 	"log/slog"
 	"strings"
+	"time"
 
 	servAuth "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	servOutbox "github.com/EvansTrein/RESTful_exchangerServer/internal/services/outbox"
 	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	servWebhooks "github.com/EvansTrein/RESTful_exchangerServer/internal/services/webhooks"
 	"github.com/EvansTrein/RESTful_exchangerServer/models"
 )
 
-// CreateUser creates a new user in the database and initializes their accounts for all supported currencies.
+// CreateUser creates a new user in the database and initializes their default accounts for all supported currencies.
+// The registration event is written to the outbox in the same transaction.
 // It returns the user ID if successful, or an error if the operation fails.
 func (db *PostgresDB) CreateUser(ctx context.Context, req models.RegisterRequest) (uint, error) {
 	op := "Database: user registration"
@@ -20,12 +24,12 @@ func (db *PostgresDB) CreateUser(ctx context.Context, req models.RegisterRequest
 	log.Debug("Register func call", slog.Any("requets data", req))
 
 	query := `WITH new_user AS (
-		INSERT INTO users (name, email, password_hash)
-		VALUES ($1, $2, $3)
+		INSERT INTO users (name, email, password_hash, email_verified_at)
+		VALUES ($1, $2, $3, CASE WHEN $4::BOOLEAN THEN NOW() END)
 		RETURNING id)
 	
-	INSERT INTO accounts (user_id, currency_code)
-	SELECT new_user.id, code
+	INSERT INTO accounts (user_id, currency_code, is_default)
+	SELECT new_user.id, code, TRUE
 	FROM currencies
 	CROSS JOIN new_user
 	RETURNING user_id;`
@@ -37,13 +41,32 @@ func (db *PostgresDB) CreateUser(ctx context.Context, req models.RegisterRequest
 	}
 	defer stmt.Close()
 
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return 0, err
+	}
+
 	var id uint
-	err = stmt.QueryRowContext(ctx, req.Name, req.Email, req.HashPassword).Scan(&id)
+	err = tx.StmtContext(ctx, stmt).QueryRowContext(ctx, req.Name, req.Email, req.HashPassword, req.Verified).Scan(&id)
 	if err != nil {
+		tx.Rollback()
 		if strings.Contains(err.Error(), "duplicate key value") {
 			return 0, servAuth.ErrEmailAlreadyExists
 		}
-		log.Error("fail to execute SQL query", "error", err)
+		log.Error("fail to execute SQL query", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	if err = writeOutbox(ctx, tx, id, servOutbox.EventUserRegistered, &models.UserEvent{Name: req.Name, Email: req.Email}); err != nil {
+		tx.Rollback()
+		log.Error("failed to write the outbox event", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
 		return 0, err
 	}
 
@@ -58,9 +81,9 @@ func (db *PostgresDB) SearchUser(ctx context.Context, req models.LoginRequest) (
 	log := db.log.With(slog.String("operation", op))
 	log.Debug("Login func call", slog.Any("requets data", req))
 
-	query := `SELECT id, name, email, password_hash
+	query := `SELECT id, name, email, password_hash, role, segment, email_verified_at IS NOT NULL, COALESCE(display_currency, ''), token_version
 		FROM users
-		WHERE email = $1;`
+		WHERE email = $1 AND deleted_at IS NULL;`
 
 	stmt, err := db.db.PrepareContext(ctx, query)
 	if err != nil {
@@ -70,7 +93,7 @@ func (db *PostgresDB) SearchUser(ctx context.Context, req models.LoginRequest) (
 	defer stmt.Close()
 
 	var user models.User
-	err = stmt.QueryRowContext(ctx, req.Email).Scan(&user.ID, &user.Name, &user.Email, &user.HashPassword)
+	err = stmt.QueryRowContext(ctx, req.Email).Scan(&user.ID, &user.Name, &user.Email, &user.HashPassword, &user.Role, &user.Segment, &user.EmailVerified, &user.DisplayCurrency, &user.TokenVersion)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			log.Warn("user with this email is not in the database", "email", req.Email)
@@ -85,82 +108,17 @@ func (db *PostgresDB) SearchUser(ctx context.Context, req models.LoginRequest) (
 }
 
 
-// DeleteUser deletes a user from the database based on their ID.
-// It locks the user's accounts, deletes the user, and commits the transaction.
-// If the user is not found or the operation fails, it returns an error.
-func (db *PostgresDB) DeleteUser(ctx context.Context, userId uint) error {
-	op := "Database: user removal"
-	log := db.log.With(slog.String("operation", op))
-	log.Debug("DeleteUser func call", slog.Any("user id", userId))
-
-	querylock := `
-		SELECT u.name, a.id
-		FROM users u
-		INNER JOIN accounts a ON u.id = a.user_id
-		WHERE u.id = $1
-		FOR UPDATE;`
-
-	queryDelete := `DELETE FROM users WHERE id = $1;`
-
-	lockStmt, err := db.db.PrepareContext(ctx, querylock)
-	if err != nil {
-		log.Error("failed to prepare lock SQL query", "error", err)
-		return err
-	}
-	defer lockStmt.Close()
-
-	deleteStmt, err := db.db.PrepareContext(ctx, queryDelete)
-	if err != nil {
-		log.Error("failed to prepare delete SQL query", "error", err)
-		return err
-	}
-	defer deleteStmt.Close()
-
-	tx, err := db.db.BeginTx(ctx, nil)
-	if err != nil {
-		log.Error("failed to begin transaction", "error", err)
-		return err
-	}
-
-    var userName string
-    var accountID sql.NullInt64
-    err = tx.StmtContext(ctx, lockStmt).QueryRowContext(ctx, userId).Scan(&userName, &accountID)
-    if err != nil {
-		tx.Rollback()
-        if errors.Is(err, sql.ErrNoRows) {
-            log.Error("user not found", "user id", userId, "transaction", "rollback")
-            return servAuth.ErrUserNotFound
-        }
-        log.Error("failed to execute user lock query", "error", err, "transaction", "rollback")
-        return err
-    }
-
-	if _, err = tx.StmtContext(ctx, deleteStmt).ExecContext(ctx, userId); err != nil {
-		tx.Rollback()
-		log.Error("failed to execute user delete query", "error", err, "transaction", "rollback")
-		return err
-	}
-
-	if err = tx.Commit(); err != nil {
-		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
-		return err
-	}
-
-	log.Info("transaction successfully completed")
-	return nil
-}
-
-
-// AllAccountsBalance retrieves the balances of all accounts for a given user.
+// AllAccountsBalance retrieves the balances of all accounts for a given user, the accounts of one currency are summed.
 // It returns a map of currency codes to balances, or an error if the user is not found or the operation fails.
 func (db *PostgresDB) AllAccountsBalance(ctx context.Context, userId uint) (map[string]float32, error) {
 	op := "Database: balancing all accounts"
 	log := db.log.With(slog.String("operation", op))
 	log.Debug("AllAccountsBalance func call", slog.Any("requets data", userId))
 
-	query := `SELECT currency_code, balance
+	query := `SELECT currency_code, SUM(balance)
 		FROM accounts
-		WHERE user_id = $1;`
+		WHERE user_id = $1
+		GROUP BY currency_code;`
 
 	stmt, err := db.db.PrepareContext(ctx, query)
 	if err != nil {
@@ -201,8 +159,55 @@ func (db *PostgresDB) AllAccountsBalance(ctx context.Context, userId uint) (map[
 	return accounts, nil
 }
 
-// AccountOperation performs a deposit or withdrawal operation on a user's account.
-// It updates the account balance and returns the new balances of all accounts.
+// AvailableBalance retrieves the available balances of all accounts for a given user, the amounts of the open holds are not available.
+// It returns a map of currency codes to available balances, or an error if the user is not found or the operation fails.
+func (db *PostgresDB) AvailableBalance(ctx context.Context, userId uint) (map[string]float32, error) {
+	op := "Database: available balance of all accounts"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("AvailableBalance func call", slog.Any("requets data", userId))
+
+	query := `SELECT currency_code, SUM(balance - held)
+		FROM accounts
+		WHERE user_id = $1
+		GROUP BY currency_code;`
+
+	rows, err := db.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := make(map[string]float32)
+	for rows.Next() {
+		var currencyCode string
+		var available float32
+		if err := rows.Scan(&currencyCode, &available); err != nil {
+			log.Error("failed to scan row", "error", err)
+			return nil, err
+		}
+		accounts[currencyCode] = available
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("error during rows iteration", "error", err)
+		return nil, err
+	}
+
+	if len(accounts) == 0 {
+		log.Error("the balance of a non-existent user was requested", "user id", userId)
+		return nil, servAuth.ErrUserNotFound
+	}
+
+	log.Info("database successfully returned the available balances")
+	return accounts, nil
+}
+
+// AccountOperation performs a deposit or withdrawal operation on a user's account,
+// the account is chosen by req.AccountID, without it the default account of the currency is used.
+// It checks the transaction limits and, for a withdrawal, the verified email of the user and the available balance, updates the account balance, records the operation in the history,
+// writes the webhook and outbox events and returns the new balances of all accounts.
+// An operation with an idempotency key that has already been used returns ErrDuplicateOperation.
 // If the operation fails, it returns an error.
 func (db *PostgresDB) AccountOperation(ctx context.Context, req *models.AccountOperationRequest) (map[string]float32, error) {
 	op := "Database: account change"
@@ -218,21 +223,28 @@ func (db *PostgresDB) AccountOperation(ctx context.Context, req *models.AccountO
 
 	currencyCheckQuery := `SELECT EXISTS(SELECT 1 FROM currencies WHERE code = $1)`
 
+	// the amounts of the open holds are not available for a withdrawal
 	getBalanceAndLockQuery := `
-        SELECT balance
+        SELECT id, balance - held
         FROM accounts
-        WHERE user_id = $1 AND currency_code = $2
+        WHERE user_id = $1 AND currency_code = $2 AND (id = $3 OR ($3 = 0 AND is_default))
         FOR UPDATE`
 
 	updateQuery := `
         UPDATE accounts
         SET balance = balance + $1
-        WHERE user_id = $2 AND currency_code = $3`
+        WHERE id = $2
+        RETURNING balance`
+
+	insertOperationQuery := `
+        INSERT INTO operations (user_id, operation_type, currency_code, amount, balance_after, account_id)
+        VALUES ($1, $2, $3, $4, $5, $6)`
 
 	selectNewBalanceQuery := `
-        SELECT currency_code, balance
+        SELECT currency_code, SUM(balance)
         FROM accounts
-        WHERE user_id = $1`
+        WHERE user_id = $1
+        GROUP BY currency_code`
 
 	currencyCheckStmt, err := db.db.PrepareContext(ctx, currencyCheckQuery)
 	if err != nil {
@@ -255,6 +267,13 @@ func (db *PostgresDB) AccountOperation(ctx context.Context, req *models.AccountO
 	}
 	defer updateStmt.Close()
 
+	insertOperationStmt, err := db.db.PrepareContext(ctx, insertOperationQuery)
+	if err != nil {
+		log.Error("failed to prepare insert operation SQL query", "error", err)
+		return nil, err
+	}
+	defer insertOperationStmt.Close()
+
 	selectNewBalanceStmt, err := db.db.PrepareContext(ctx, selectNewBalanceQuery)
 	if err != nil {
 		log.Error("failed to prepare select balance SQL query", "error", err)
@@ -284,20 +303,35 @@ func (db *PostgresDB) AccountOperation(ctx context.Context, req *models.AccountO
 		return nil, servWallet.ErrCurrencyNotFound
 	}
 
-	var currentBalance float32
-	if err = tx.StmtContext(ctx, getBalanceAndLockStmt).QueryRowContext(ctx, req.UserID, req.Currency).Scan(&currentBalance); err != nil {
+	if err = claimOperationKey(ctx, tx, req.UserID, req.IdempotencyKey); err != nil {
+		tx.Rollback()
+		log.Warn("operation is not executed again", "idempotency key", req.IdempotencyKey, "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	var accountId uint
+	var availableBalance float32
+	if err = tx.StmtContext(ctx, getBalanceAndLockStmt).QueryRowContext(ctx, req.UserID, req.Currency, req.AccountID).Scan(&accountId, &availableBalance); err != nil {
 		tx.Rollback()
 		if errors.Is(err, sql.ErrNoRows) {
-			log.Error("account not found", "user id", req.UserID, "currency", req.Currency, "transaction", "rollback")
+			log.Error("account not found", "user id", req.UserID, "currency", req.Currency, "account id", req.AccountID, "transaction", "rollback")
 			return nil, servWallet.ErrAccountNotFound
 		}
 		log.Error("failed to get current balance", "error", err)
 		return nil, err
 	}
 
-	if req.Operation == servWallet.OperationWithdraw && currentBalance < req.Amount {
+	if req.Operation == servWallet.OperationWithdraw {
+		if err = checkVerified(ctx, tx, req.UserID); err != nil {
+			tx.Rollback()
+			log.Warn("withdrawal is not allowed", "error", err, "transaction", "rollback")
+			return nil, err
+		}
+	}
+
+	if req.Operation == servWallet.OperationWithdraw && availableBalance < req.Amount {
 		tx.Rollback()
-		log.Warn("insufficient funds", "current balance", currentBalance, "requested amount", req.Amount, "transaction", "rollback")
+		log.Warn("insufficient funds", "available balance", availableBalance, "requested amount", req.Amount, "transaction", "rollback")
 		return nil, servWallet.ErrInsufficientFunds
 	}
 
@@ -313,14 +347,52 @@ func (db *PostgresDB) AccountOperation(ctx context.Context, req *models.AccountO
 		return nil, servWallet.ErrInvalidOperationType
 	}
 
+	// the limit of the user and the currency is locked by the check until the balance change is committed
+	if err = db.checkLimit(ctx, tx, req.UserID, req.Currency, req.Operation, req.Amount); err != nil {
+		tx.Rollback()
+		log.Warn("transaction limit check failed", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
 	log.Debug("all business logic checks have been completed successfully")
 
-	if _, err = tx.StmtContext(ctx, updateStmt).ExecContext(ctx, amount, req.UserID, req.Currency); err != nil {
+	var balanceAfter float32
+	if err = tx.StmtContext(ctx, updateStmt).QueryRowContext(ctx, amount, accountId).Scan(&balanceAfter); err != nil {
 		tx.Rollback()
 		log.Error("failed to update account balance", "error", err, "transaction", "rollback")
 		return nil, err
 	}
 
+	if _, err = tx.StmtContext(ctx, insertOperationStmt).ExecContext(ctx, req.UserID, req.Operation, req.Currency, amount, balanceAfter, accountId); err != nil {
+		tx.Rollback()
+		log.Error("failed to save the operation in the history", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	event := &models.WalletEvent{
+		Operation: req.Operation,
+		Currency:  req.Currency,
+		Amount:    req.Amount,
+		Balance:   balanceAfter,
+	}
+
+	webhookEvent, outboxEvent := servWebhooks.EventDepositCompleted, servOutbox.EventDeposited
+	if req.Operation == servWallet.OperationWithdraw {
+		webhookEvent, outboxEvent = servWebhooks.EventWithdrawCompleted, servOutbox.EventWithdrawn
+	}
+
+	if err = recordWebhookEvent(ctx, tx, req.UserID, webhookEvent, event); err != nil {
+		tx.Rollback()
+		log.Error("failed to save the webhook event", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if err = writeOutbox(ctx, tx, req.UserID, outboxEvent, event); err != nil {
+		tx.Rollback()
+		log.Error("failed to write the outbox event", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
 	rows, err := tx.StmtContext(ctx, selectNewBalanceStmt).QueryContext(ctx, req.UserID)
 	if err != nil {
 		tx.Rollback()
@@ -362,66 +434,242 @@ func (db *PostgresDB) AccountOperation(ctx context.Context, req *models.AccountO
 	return accounts, nil
 }
 
-// SaveExchangeRateChanges updates the balances of a user's accounts after a currency exchange.
-// It locks the accounts, updates the balances, and commits the transaction.
-// If the operation fails, it returns an error.
-func (db *PostgresDB) SaveExchangeRateChanges(ctx context.Context, newData *models.CurrencyExchangeResult) error {
-	op := "Database: updating of accounts on exchange"
-	log := db.log.With(slog.String("operation", op))
-	log.Debug("SaveExchangeRateChanges func call", slog.Any("new data", newData))
+// exchangeStatements are the prepared statements used to apply one exchange inside a transaction.
+type exchangeStatements struct {
+	lock            *sql.Stmt
+	update          *sql.Stmt
+	insertOperation *sql.Stmt
+	revenue         *sql.Stmt
+	feeLedger       *sql.Stmt
+}
 
+// prepareExchangeStatements prepares the statements of an exchange.
+// The returned function closes all of them and must be called when the transaction is finished.
+func (db *PostgresDB) prepareExchangeStatements(ctx context.Context) (*exchangeStatements, func(), error) {
+	// an account is chosen by its ID, a zero ID chooses the default account of the currency,
+	// the available balance without the amounts of the open holds is selected
 	lockQuery := `
-        SELECT balance
+        SELECT id, currency_code, balance - held
         FROM accounts
-        WHERE user_id = $1 AND currency_code IN ($2, $3)
+        WHERE user_id = $1 AND (
+            (currency_code = $2 AND (id = $3 OR ($3 = 0 AND is_default))) OR
+            (currency_code = $4 AND (id = $5 OR ($5 = 0 AND is_default))))
+        ORDER BY id
         FOR UPDATE;`
 
 	updateQuery := `
         UPDATE accounts
-        SET balance = CASE
-            WHEN currency_code = $2 THEN $3
-            WHEN currency_code = $4 THEN $5
-            ELSE balance
-        END
-        WHERE user_id = $1 AND currency_code IN ($2, $4);`
-
-	lockStmt, err := db.db.PrepareContext(ctx, lockQuery)
-	if err != nil {
-		log.Error("failed to prepare lockQuery SQL", "error", err)
+        SET balance = balance + $2
+        WHERE id = $1
+        RETURNING balance;`
+
+	insertOperationQuery := `
+        INSERT INTO operations (user_id, operation_type, currency_code, amount, balance_after, counter_currency, exchange_rate, account_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8);`
+
+	revenueQuery := `
+        INSERT INTO revenue_accounts (currency_code, balance)
+        VALUES ($1, $2)
+        ON CONFLICT (currency_code) DO UPDATE
+        SET balance = revenue_accounts.balance + EXCLUDED.balance;`
+
+	feeLedgerQuery := `
+        INSERT INTO fee_ledger (user_id, from_currency, currency_code, amount, mid_rate, applied_rate)
+        VALUES ($1, $2, $3, $4, $5, $6);`
+
+	var stmts exchangeStatements
+	prepared := make([]*sql.Stmt, 0, 5)
+	closeAll := func() {
+		for _, stmt := range prepared {
+			stmt.Close()
+		}
+	}
+
+	for _, item := range []struct {
+		query string
+		stmt  **sql.Stmt
+	}{
+		{lockQuery, &stmts.lock},
+		{updateQuery, &stmts.update},
+		{insertOperationQuery, &stmts.insertOperation},
+		{revenueQuery, &stmts.revenue},
+		{feeLedgerQuery, &stmts.feeLedger},
+	} {
+		stmt, err := db.db.PrepareContext(ctx, item.query)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		prepared = append(prepared, stmt)
+		*item.stmt = stmt
+	}
+
+	return &stmts, closeAll, nil
+}
+
+// applyExchange applies one exchange inside the transaction.
+// It locks both accounts (the chosen ones or the default accounts of the currencies), checks the available balance, the verified email of the user and the exchange limit, updates the balances,
+// records both sides of the exchange in the history, credits the exchange fee to the revenue account
+// and writes the webhook and outbox events. The IDs and the balances of the accounts after the exchange are written back to newData.
+// An exchange with an idempotency key that has already been used returns ErrDuplicateOperation.
+func (db *PostgresDB) applyExchange(ctx context.Context, tx *sql.Tx, stmts *exchangeStatements, newData *models.CurrencyExchangeResult) error {
+	if err := claimOperationKey(ctx, tx, newData.UserID, newData.IdempotencyKey); err != nil {
 		return err
 	}
-	defer lockStmt.Close()
 
-	updateStmt, err := db.db.PrepareContext(ctx, updateQuery)
+	rows, err := tx.StmtContext(ctx, stmts.lock).QueryContext(ctx,
+		newData.UserID, newData.BaseCurrency, newData.FromAccountID, newData.ToCurrency, newData.ToAccountID)
 	if err != nil {
-		log.Error("failed to prepare updateQuery SQL", "error", err)
 		return err
 	}
-	defer updateStmt.Close()
 
-	// Start transaction
-	tx, err := db.db.BeginTx(ctx, nil)
-	if err != nil {
-		log.Error("failed to begin transaction", "error", err)
+	accounts := make(map[string]uint)
+	balances := make(map[string]float32)
+	for rows.Next() {
+		var accountId uint
+		var currencyCode string
+		var balance float32
+		if err := rows.Scan(&accountId, &currencyCode, &balance); err != nil {
+			rows.Close()
+			return err
+		}
+		accounts[currencyCode] = accountId
+		balances[currencyCode] = balance
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
 		return err
 	}
 
-	if _, err := tx.StmtContext(ctx, lockStmt).ExecContext(ctx, newData.UserID, newData.BaseCurrency, newData.ToCurrency); err != nil {
-		tx.Rollback()
-		log.Error("failed to lock accounts", "error", err, "transaction", "rollback")
+	baseBalance, ok := balances[newData.BaseCurrency]
+	if !ok {
+		return servWallet.ErrAccountNotFound
+	}
+
+	if _, ok := balances[newData.ToCurrency]; !ok {
+		if newData.ToAccountID != 0 {
+			return servWallet.ErrAccountNotFound
+		}
+		return servWallet.ErrCurrencyNotFound
+	}
+	newData.FromAccountID = accounts[newData.BaseCurrency]
+	newData.ToAccountID = accounts[newData.ToCurrency]
+
+	// the balance could have changed since the service read it, it is checked again under the lock,
+	// the amounts of the open holds can not be exchanged
+	if baseBalance < newData.Amount {
+		return servWallet.ErrInsufficientFunds
+	}
+
+	if err := checkVerified(ctx, tx, newData.UserID); err != nil {
+		return err
+	}
+
+	if err := db.checkLimit(ctx, tx, newData.UserID, newData.BaseCurrency, servWallet.OperationExchange, newData.Amount); err != nil {
+		return err
+	}
+
+	if err := tx.StmtContext(ctx, stmts.update).QueryRowContext(ctx, newData.FromAccountID, -newData.Amount).Scan(&newData.NewBaseBalance); err != nil {
+		return err
+	}
+
+	if err := tx.StmtContext(ctx, stmts.update).QueryRowContext(ctx, newData.ToAccountID, newData.Received).Scan(&newData.NewToBalance); err != nil {
 		return err
 	}
 
-	if _, err := tx.StmtContext(ctx, updateStmt).ExecContext(
+	if _, err := tx.StmtContext(ctx, stmts.insertOperation).ExecContext(
 		ctx,
 		newData.UserID,
+		servWallet.OperationExchange,
 		newData.BaseCurrency,
+		-newData.Amount,
 		newData.NewBaseBalance,
 		newData.ToCurrency,
+		newData.AppliedRate,
+		newData.FromAccountID,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.StmtContext(ctx, stmts.insertOperation).ExecContext(
+		ctx,
+		newData.UserID,
+		servWallet.OperationExchange,
+		newData.ToCurrency,
+		newData.Received,
 		newData.NewToBalance,
+		newData.BaseCurrency,
+		newData.AppliedRate,
+		newData.ToAccountID,
 	); err != nil {
+		return err
+	}
+
+	// the fee is credited to the revenue account in the same transaction as the exchange itself
+	if newData.Fee > 0 {
+		if _, err := tx.StmtContext(ctx, stmts.revenue).ExecContext(ctx, newData.ToCurrency, newData.Fee); err != nil {
+			return err
+		}
+
+		if _, err := tx.StmtContext(ctx, stmts.feeLedger).ExecContext(
+			ctx,
+			newData.UserID,
+			newData.BaseCurrency,
+			newData.ToCurrency,
+			newData.Fee,
+			newData.MidRate,
+			newData.AppliedRate,
+		); err != nil {
+			return err
+		}
+	}
+
+	event := &models.WalletEvent{
+		Operation:  servWallet.OperationExchange,
+		Currency:   newData.BaseCurrency,
+		Amount:     newData.Amount,
+		Balance:    newData.NewBaseBalance,
+		ToCurrency: newData.ToCurrency,
+		Received:   newData.Received,
+		ToBalance:  newData.NewToBalance,
+		Fee:        newData.Fee,
+		Rate:       newData.AppliedRate,
+	}
+
+	if err := recordWebhookEvent(ctx, tx, newData.UserID, servWebhooks.EventExchangeCompleted, event); err != nil {
+		return err
+	}
+
+	return writeOutbox(ctx, tx, newData.UserID, servOutbox.EventExchanged, event)
+}
+
+// SaveExchangeRateChanges debits the spent amount and credits the received amount of a currency exchange.
+// It locks both accounts, checks the balance, the verified email of the user and the exchange limit, updates the balances,
+// records both sides of the exchange in the history, credits the exchange fee to the revenue account and commits the transaction.
+// The balances after the exchange are written back to newData. If the operation fails, it returns an error.
+func (db *PostgresDB) SaveExchangeRateChanges(ctx context.Context, newData *models.CurrencyExchangeResult) error {
+	op := "Database: updating of accounts on exchange"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("SaveExchangeRateChanges func call", slog.Any("new data", newData))
+
+	stmts, closeStmts, err := db.prepareExchangeStatements(ctx)
+	if err != nil {
+		log.Error("failed to prepare exchange SQL queries", "error", err)
+		return err
+	}
+	defer closeStmts()
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return err
+	}
+
+	if err := db.applyExchange(ctx, tx, stmts, newData); err != nil {
 		tx.Rollback()
-		log.Error("failed to update balances", "error", err, "transaction", "rollback")
+		log.Warn("failed to apply the exchange", "error", err, "transaction", "rollback")
 		return err
 	}
 
@@ -433,3 +681,144 @@ func (db *PostgresDB) SaveExchangeRateChanges(ctx context.Context, newData *mode
 	log.Info("transaction successfully completed")
 	return nil
 }
+
+// SaveExchangeBatch applies several exchanges of one user in a single transaction, in the given order.
+// All accounts of the user are locked first, so that the legs can not interleave with other operations.
+// If any leg fails, the whole batch is rolled back. The balances after each leg are written back to the legs.
+func (db *PostgresDB) SaveExchangeBatch(ctx context.Context, legs []*models.CurrencyExchangeResult) error {
+	op := "Database: updating of accounts on batch exchange"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("SaveExchangeBatch func call", "legs", len(legs))
+
+	if len(legs) == 0 {
+		return servWallet.ErrEmptyBatch
+	}
+
+	lockAllQuery := `
+        SELECT id
+        FROM accounts
+        WHERE user_id = $1
+        ORDER BY id
+        FOR UPDATE;`
+
+	lockAllStmt, err := db.db.PrepareContext(ctx, lockAllQuery)
+	if err != nil {
+		log.Error("failed to prepare lockAllQuery SQL", "error", err)
+		return err
+	}
+	defer lockAllStmt.Close()
+
+	stmts, closeStmts, err := db.prepareExchangeStatements(ctx)
+	if err != nil {
+		log.Error("failed to prepare exchange SQL queries", "error", err)
+		return err
+	}
+	defer closeStmts()
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return err
+	}
+
+	if _, err := tx.StmtContext(ctx, lockAllStmt).ExecContext(ctx, legs[0].UserID); err != nil {
+		tx.Rollback()
+		log.Error("failed to lock accounts", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	for i, leg := range legs {
+		if err := db.applyExchange(ctx, tx, stmts, leg); err != nil {
+			tx.Rollback()
+			log.Warn("failed to apply the exchange leg", "leg", i, "error", err, "transaction", "rollback")
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return err
+	}
+
+	log.Info("transaction successfully completed", "legs", len(legs))
+	return nil
+}
+
+// UserSegment returns the segment of the user, it is used to choose the exchange fee tier.
+// If the user is not found, it returns an error.
+func (db *PostgresDB) UserSegment(ctx context.Context, userId uint) (string, error) {
+	op := "Database: getting the user segment"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UserSegment func call", slog.Any("user id", userId))
+
+	query := `SELECT segment FROM users WHERE id = $1;`
+
+	stmt, err := db.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Error("failed to prepare SQL query", "error", err)
+		return "", err
+	}
+	defer stmt.Close()
+
+	var segment string
+	if err := stmt.QueryRowContext(ctx, userId).Scan(&segment); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("user not found", "user id", userId)
+			return "", servAuth.ErrUserNotFound
+		}
+		log.Error("fail to execute SQL query", "error", err)
+		return "", err
+	}
+
+	log.Info("user segment successfully received")
+	return segment, nil
+}
+
+// FeeRevenue returns the fees collected in each currency for the period [from, to)
+// together with the current balance of the revenue account.
+func (db *PostgresDB) FeeRevenue(ctx context.Context, from, to time.Time) ([]models.FeeRevenue, error) {
+	op := "Database: fee revenue report"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("FeeRevenue func call", "from", from, "to", to)
+
+	query := `
+        SELECT r.currency_code, COALESCE(SUM(f.amount), 0), COUNT(f.id), r.balance
+        FROM revenue_accounts r
+        LEFT JOIN fee_ledger f
+            ON f.currency_code = r.currency_code AND f.created_at >= $1 AND f.created_at < $2
+        GROUP BY r.currency_code, r.balance
+        ORDER BY r.currency_code;`
+
+	stmt, err := db.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Error("failed to prepare SQL query", "error", err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, from, to)
+	if err != nil {
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	revenue := make([]models.FeeRevenue, 0)
+	for rows.Next() {
+		var item models.FeeRevenue
+		if err := rows.Scan(&item.Currency, &item.Total, &item.Exchanges, &item.Balance); err != nil {
+			log.Error("failed to scan row", "error", err)
+			return nil, err
+		}
+		revenue = append(revenue, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("error during rows iteration", "error", err)
+		return nil, err
+	}
+
+	log.Info("database successfully returned the fee revenue")
+	return revenue, nil
+}
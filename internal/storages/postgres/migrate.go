@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+
+	"github.com/golang-migrate/migrate/v4"
+	pgmigrate "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// migrationsLockID is the key of the advisory lock held while the migrations are applied,
+// the replicas started at the same time wait for each other instead of racing.
+const migrationsLockID int64 = 4_815_162_342
+
+var (
+	ErrSchemaTooNew = errors.New("the database schema is newer than the migrations of the binary")
+	ErrSchemaDirty  = errors.New("the last migration of the database has failed, the schema is dirty")
+)
+
+// Migrate applies the pending migrations of migrations, the up and down SQL files, under a Postgres advisory lock.
+// Before anything is applied the version of the database is checked: if it is newer than the latest migration,
+// the binary is older than the schema and it returns ErrSchemaTooNew, if the last migration failed, it returns ErrSchemaDirty.
+// If the database is up to date, nothing is changed.
+func (db *PostgresDB) Migrate(ctx context.Context, migrations fs.FS) error {
+	op := "Database: apply the migrations"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("Migrate func call")
+
+	src, err := iofs.New(migrations, ".")
+	if err != nil {
+		log.Error("failed to read the migrations", "error", err)
+		return err
+	}
+
+	latest, err := latestVersion(src)
+	if err != nil {
+		src.Close()
+		log.Error("failed to find the latest migration", "error", err)
+		return err
+	}
+
+	// the lock is held by the session, so one connection is used for the lock and the migrations
+	conn, err := db.db.Conn(ctx)
+	if err != nil {
+		src.Close()
+		log.Error("failed to get a connection", "error", err)
+		return err
+	}
+
+	log.Debug("waiting for the migrations lock")
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, migrationsLockID); err != nil {
+		conn.Close()
+		src.Close()
+		log.Error("failed to take the migrations lock", "error", err)
+		return err
+	}
+
+	driver, err := pgmigrate.WithConnection(ctx, conn, &pgmigrate.Config{})
+	if err != nil {
+		conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1);`, migrationsLockID)
+		conn.Close()
+		src.Close()
+		log.Error("failed to create the migration driver", "error", err)
+		return err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "postgres", driver)
+	if err != nil {
+		conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1);`, migrationsLockID)
+		conn.Close()
+		src.Close()
+		log.Error("failed to initialize the migrations", "error", err)
+		return err
+	}
+
+	applyErr := applyMigrations(m, latest, log)
+
+	// the connection goes back to the pool, it must not keep the lock
+	if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1);`, migrationsLockID); err != nil {
+		log.Error("failed to release the migrations lock", "error", err)
+	}
+
+	// closes the source and the connection
+	if srcErr, dbErr := m.Close(); srcErr != nil || dbErr != nil {
+		log.Warn("failed to close the migrations", "source error", srcErr, "database error", dbErr)
+	}
+
+	return applyErr
+}
+
+// applyMigrations checks the version of the database against latest and applies the pending migrations.
+func applyMigrations(m *migrate.Migrate, latest uint, log *slog.Logger) error {
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		log.Error("failed to read the version of the database", "error", err)
+		return err
+	}
+
+	if dirty {
+		log.Error("the database schema is dirty", "version", version)
+		return fmt.Errorf("%w: version %d, fix it and force the version with the migrator", ErrSchemaDirty, version)
+	}
+
+	if version > latest {
+		log.Error("the database schema is newer than the binary", "version", version, "latest migration", latest)
+		return fmt.Errorf("%w: database version %d, latest migration %d", ErrSchemaTooNew, version, latest)
+	}
+
+	if err := m.Up(); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			log.Info("database schema is up to date", "version", version)
+			return nil
+		}
+		log.Error("failed to apply the migrations", "error", err)
+		return err
+	}
+
+	log.Info("migrations have been successfully applied", "from version", version, "to version", latest)
+	return nil
+}
+
+// latestVersion returns the version of the last migration of the source.
+func latestVersion(src source.Driver) (uint, error) {
+	version, err := src.First()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+)
+
+// claimOperationKey saves the idempotency key of the operation inside its transaction, an empty key is not saved.
+// The key is committed or rolled back with the operation, so a failed operation can be retried with the same key.
+// A transaction with the same key in progress is waited for. If the key has already been used, it returns ErrDuplicateOperation.
+func claimOperationKey(ctx context.Context, tx *sql.Tx, userId uint, key string) error {
+	if key == "" {
+		return nil
+	}
+
+	result, err := tx.ExecContext(ctx, `INSERT INTO operation_keys (key, user_id) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING;`, key, userId)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return servWallet.ErrDuplicateOperation
+	}
+
+	return nil
+}
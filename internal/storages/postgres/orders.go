@@ -0,0 +1,573 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	servOrders "github.com/EvansTrein/RESTful_exchangerServer/internal/services/orders"
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/lib/pq"
+)
+
+const orderColumns = `id, user_id, from_currency, to_currency, amount, target_rate, status,
+	expires_at, created_at, executed_rate, received, fee, closed_at`
+
+// scanOrder scans a row selected with orderColumns.
+func scanOrder(row interface{ Scan(dest ...any) error }) (*models.Order, error) {
+	var order models.Order
+	var executedRate, received, fee sql.NullFloat64
+	var expiresAt, closedAt sql.NullTime
+
+	err := row.Scan(
+		&order.ID,
+		&order.UserID,
+		&order.FromCurrency,
+		&order.ToCurrency,
+		&order.Amount,
+		&order.TargetRate,
+		&order.Status,
+		&expiresAt,
+		&order.CreatedAt,
+		&executedRate,
+		&received,
+		&fee,
+		&closedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	order.ExecutedRate = nullFloat32(executedRate)
+	order.Received = nullFloat32(received)
+	order.Fee = nullFloat32(fee)
+	if expiresAt.Valid {
+		order.ExpiresAt = &expiresAt.Time
+	}
+	if closedAt.Valid {
+		order.ClosedAt = &closedAt.Time
+	}
+
+	return &order, nil
+}
+
+// insertOrderEvent adds an entry to the audit trail of the order.
+func insertOrderEvent(ctx context.Context, tx *sql.Tx, orderId uint, event string, rate *float32, details string) error {
+	query := `INSERT INTO order_events (order_id, event, rate, details) VALUES ($1, $2, $3, $4);`
+	_, err := tx.ExecContext(ctx, query, orderId, event, rate, details)
+	return err
+}
+
+// moveReserve changes the balance of the order account by amount and records the operation in the history.
+// The orders use the default account of the currency.
+// A negative amount reserves the funds of the order, a positive amount releases them.
+func moveReserve(ctx context.Context, tx *sql.Tx, order *models.Order, operation string, amount float32) error {
+	updateQuery := `
+        UPDATE accounts
+        SET balance = balance + $3
+        WHERE user_id = $1 AND currency_code = $2 AND is_default
+        RETURNING id, balance;`
+
+	insertOperationQuery := `
+        INSERT INTO operations (user_id, operation_type, currency_code, amount, balance_after, account_id)
+        VALUES ($1, $2, $3, $4, $5, $6);`
+
+	var accountId uint
+	var balanceAfter float32
+	if err := tx.QueryRowContext(ctx, updateQuery, order.UserID, order.FromCurrency, amount).Scan(&accountId, &balanceAfter); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return servWallet.ErrAccountNotFound
+		}
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, insertOperationQuery, order.UserID, operation, order.FromCurrency, amount, balanceAfter, accountId)
+	return err
+}
+
+// closeOrder releases the reserved funds of an open order and sets its final status.
+// The order row must be locked by the caller.
+func closeOrder(ctx context.Context, tx *sql.Tx, order *models.Order, status, event, details string) error {
+	if err := moveReserve(ctx, tx, order, servOrders.OperationRelease, order.Amount); err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE orders
+        SET status = $2, closed_at = NOW()
+        WHERE id = $1
+        RETURNING closed_at;`
+
+	if err := tx.QueryRowContext(ctx, query, order.ID, status).Scan(&order.ClosedAt); err != nil {
+		return err
+	}
+	order.Status = status
+
+	return insertOrderEvent(ctx, tx, order.ID, event, nil, details)
+}
+
+// CreateOrder saves a new limit order and reserves its amount on the default account of the currency.
+// It locks the account, checks the available balance and the exchange limit, debits the amount, records the reservation
+// in the history and adds the first entry to the audit trail of the order. If the operation fails, it returns an error.
+func (db *PostgresDB) CreateOrder(ctx context.Context, req *models.CreateOrderRequest) (*models.Order, error) {
+	op := "Database: order creation"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("CreateOrder func call", slog.Any("requets data", req))
+
+	currencyCheckQuery := `SELECT COUNT(*) FROM currencies WHERE code IN ($1, $2);`
+
+	// the amounts of the open holds can not be reserved
+	getBalanceAndLockQuery := `
+        SELECT balance - held
+        FROM accounts
+        WHERE user_id = $1 AND currency_code = $2 AND is_default
+        FOR UPDATE;`
+
+	insertOrderQuery := `
+        INSERT INTO orders (user_id, from_currency, to_currency, amount, target_rate, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING ` + orderColumns + `;`
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return nil, err
+	}
+
+	var currencies int
+	if err = tx.QueryRowContext(ctx, currencyCheckQuery, req.FromCurrency, req.ToCurrency).Scan(&currencies); err != nil {
+		tx.Rollback()
+		log.Error("failed to check currencies", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if currencies != 2 {
+		tx.Rollback()
+		log.Warn("currency not found", "from", req.FromCurrency, "to", req.ToCurrency, "transaction", "rollback")
+		return nil, servWallet.ErrCurrencyNotFound
+	}
+
+	var availableBalance float32
+	if err = tx.QueryRowContext(ctx, getBalanceAndLockQuery, req.UserID, req.FromCurrency).Scan(&availableBalance); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Error("account not found", "user id", req.UserID, "currency", req.FromCurrency, "transaction", "rollback")
+			return nil, servWallet.ErrAccountNotFound
+		}
+		log.Error("failed to get current balance", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if availableBalance < req.Amount {
+		tx.Rollback()
+		log.Warn("insufficient funds", "available balance", availableBalance, "requested amount", req.Amount, "transaction", "rollback")
+		return nil, servWallet.ErrInsufficientFunds
+	}
+
+	// an order of an unverified user could never be executed, so it is not created
+	if err = checkVerified(ctx, tx, req.UserID); err != nil {
+		tx.Rollback()
+		log.Warn("order is not allowed", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	// an order above the exchange limit would fail on every round of the matcher until it expires
+	if err = db.checkLimit(ctx, tx, req.UserID, req.FromCurrency, servWallet.OperationExchange, req.Amount); err != nil {
+		tx.Rollback()
+		log.Warn("transaction limit check failed", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	order, err := scanOrder(tx.QueryRowContext(ctx, insertOrderQuery,
+		req.UserID, req.FromCurrency, req.ToCurrency, req.Amount, req.TargetRate, req.ExpiresAt))
+	if err != nil {
+		tx.Rollback()
+		log.Error("failed to save the order", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if err = moveReserve(ctx, tx, order, servOrders.OperationReserve, -order.Amount); err != nil {
+		tx.Rollback()
+		log.Error("failed to reserve the order amount", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if err = insertOrderEvent(ctx, tx, order.ID, servOrders.EventCreated, &order.TargetRate, "funds reserved"); err != nil {
+		tx.Rollback()
+		log.Error("failed to save the order event", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return nil, err
+	}
+
+	log.Info("transaction successfully completed", "order id", order.ID)
+	return order, nil
+}
+
+// UserOrders returns the orders of the user, newest first.
+// If status is not empty, only the orders with this status are returned.
+func (db *PostgresDB) UserOrders(ctx context.Context, userId uint, status string) ([]models.Order, error) {
+	op := "Database: list of user orders"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UserOrders func call", "user id", userId, "status", status)
+
+	query := `SELECT ` + orderColumns + `
+        FROM orders
+        WHERE user_id = $1 AND ($2 = '' OR status = $2)
+        ORDER BY created_at DESC, id DESC;`
+
+	stmt, err := db.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Error("failed to prepare SQL query", "error", err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, userId, status)
+	if err != nil {
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]models.Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			log.Error("failed to scan row", "error", err)
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("error during rows iteration", "error", err)
+		return nil, err
+	}
+
+	log.Info("database successfully returned the orders")
+	return orders, nil
+}
+
+// UserOrder returns the order of the user together with its audit trail.
+// If the order does not exist or belongs to another user, it returns an error.
+func (db *PostgresDB) UserOrder(ctx context.Context, userId, orderId uint) (*models.Order, []models.OrderEvent, error) {
+	op := "Database: user order"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UserOrder func call", "user id", userId, "order id", orderId)
+
+	orderQuery := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1 AND user_id = $2;`
+
+	eventsQuery := `
+        SELECT event, rate, details, created_at
+        FROM order_events
+        WHERE order_id = $1
+        ORDER BY created_at, id;`
+
+	order, err := scanOrder(db.db.QueryRowContext(ctx, orderQuery, orderId, userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("order not found", "order id", orderId)
+			return nil, nil, servOrders.ErrOrderNotFound
+		}
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, nil, err
+	}
+
+	rows, err := db.db.QueryContext(ctx, eventsQuery, orderId)
+	if err != nil {
+		log.Error("failed to execute events SQL query", "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	events := make([]models.OrderEvent, 0)
+	for rows.Next() {
+		var event models.OrderEvent
+		var rate sql.NullFloat64
+		if err := rows.Scan(&event.Event, &rate, &event.Details, &event.CreatedAt); err != nil {
+			log.Error("failed to scan row", "error", err)
+			return nil, nil, err
+		}
+		event.Rate = nullFloat32(rate)
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("error during rows iteration", "error", err)
+		return nil, nil, err
+	}
+
+	log.Info("database successfully returned the order")
+	return order, events, nil
+}
+
+// CancelOrder cancels the open order of the user and releases its reserved amount.
+// If the order does not exist or is not open anymore, it returns an error.
+func (db *PostgresDB) CancelOrder(ctx context.Context, userId, orderId uint) (*models.Order, error) {
+	op := "Database: order cancellation"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("CancelOrder func call", "user id", userId, "order id", orderId)
+
+	lockQuery := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1 AND user_id = $2 FOR UPDATE;`
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return nil, err
+	}
+
+	order, err := scanOrder(tx.QueryRowContext(ctx, lockQuery, orderId, userId))
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("order not found", "order id", orderId, "transaction", "rollback")
+			return nil, servOrders.ErrOrderNotFound
+		}
+		log.Error("failed to lock the order", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if order.Status != servOrders.StatusOpen {
+		tx.Rollback()
+		log.Warn("order is not open", "status", order.Status, "transaction", "rollback")
+		return nil, servOrders.ErrOrderNotOpen
+	}
+
+	if err = closeOrder(ctx, tx, order, servOrders.StatusCancelled, servOrders.EventCancelled, "cancelled by the user"); err != nil {
+		tx.Rollback()
+		log.Error("failed to cancel the order", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return nil, err
+	}
+
+	log.Info("transaction successfully completed")
+	return order, nil
+}
+
+// OpenOrders returns all open orders that have not expired yet, oldest first.
+func (db *PostgresDB) OpenOrders(ctx context.Context) ([]models.Order, error) {
+	op := "Database: open orders"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("OpenOrders func call")
+
+	query := `SELECT ` + orderColumns + `
+        FROM orders
+        WHERE status = 'open' AND (expires_at IS NULL OR expires_at > NOW())
+        ORDER BY created_at, id;`
+
+	rows, err := db.db.QueryContext(ctx, query)
+	if err != nil {
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]models.Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			log.Error("failed to scan row", "error", err)
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("error during rows iteration", "error", err)
+		return nil, err
+	}
+
+	log.Debug("database successfully returned the open orders", "count", len(orders))
+	return orders, nil
+}
+
+// ExecuteOrder fills the open order with the calculated exchange.
+// The order is locked and checked to be still open and not expired, so that it is never executed twice
+// or after the expirer could have closed it. Both accounts of the exchange are locked in the order of their IDs
+// before the reserved amount is released, the same order as every other exchange, so that they can not deadlock.
+// The reserved amount is released and the exchange is applied in the same transaction,
+// the executed rate, the received amount and the fee are saved in the order and its audit trail.
+func (db *PostgresDB) ExecuteOrder(ctx context.Context, orderId uint, result *models.CurrencyExchangeResult) error {
+	op := "Database: order execution"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("ExecuteOrder func call", "order id", orderId, slog.Any("exchange", result))
+
+	lockQuery := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1 AND (expires_at IS NULL OR expires_at > NOW()) FOR UPDATE;`
+
+	fillQuery := `
+        UPDATE orders
+        SET status = 'filled', executed_rate = $2, received = $3, fee = $4, closed_at = NOW()
+        WHERE id = $1;`
+
+	stmts, closeStmts, err := db.prepareExchangeStatements(ctx)
+	if err != nil {
+		log.Error("failed to prepare exchange SQL queries", "error", err)
+		return err
+	}
+	defer closeStmts()
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return err
+	}
+
+	order, err := scanOrder(tx.QueryRowContext(ctx, lockQuery, orderId))
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			// the order has been deleted or has expired, an expired order is closed by the expirer
+			log.Warn("order not found or expired", "order id", orderId, "transaction", "rollback")
+			return servOrders.ErrOrderNotOpen
+		}
+		log.Error("failed to lock the order", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if order.Status != servOrders.StatusOpen {
+		tx.Rollback()
+		log.Warn("order is not open", "status", order.Status, "transaction", "rollback")
+		return servOrders.ErrOrderNotOpen
+	}
+
+	// the exchange locks the accounts again, the rows are already locked by this transaction
+	if _, err = tx.StmtContext(ctx, stmts.lock).ExecContext(ctx,
+		result.UserID, result.BaseCurrency, result.FromAccountID, result.ToCurrency, result.ToAccountID); err != nil {
+		tx.Rollback()
+		log.Error("failed to lock the accounts", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if err = moveReserve(ctx, tx, order, servOrders.OperationRelease, order.Amount); err != nil {
+		tx.Rollback()
+		log.Error("failed to release the order amount", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if err = db.applyExchange(ctx, tx, stmts, result); err != nil {
+		tx.Rollback()
+		log.Warn("failed to apply the exchange", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, fillQuery, order.ID, result.AppliedRate, result.Received, result.Fee); err != nil {
+		tx.Rollback()
+		log.Error("failed to mark the order as filled", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if err = insertOrderEvent(ctx, tx, order.ID, servOrders.EventFilled, &result.AppliedRate, "executed at the rate reaching the target"); err != nil {
+		tx.Rollback()
+		log.Error("failed to save the order event", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return err
+	}
+
+	log.Info("transaction successfully completed", "order id", order.ID)
+	return nil
+}
+
+// ExpireOrders closes the open orders whose expiry time has passed and releases their reserved amounts.
+// The orders locked by another instance are skipped, they are expired by that instance or on the next round.
+// It returns the number of expired orders.
+func (db *PostgresDB) ExpireOrders(ctx context.Context) (int, error) {
+	op := "Database: orders expiration"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("ExpireOrders func call")
+
+	lockQuery := `SELECT ` + orderColumns + `
+        FROM orders
+        WHERE status = 'open' AND expires_at <= NOW()
+        ORDER BY id
+        FOR UPDATE SKIP LOCKED;`
+
+	lockAccountsQuery := `
+        SELECT id
+        FROM accounts
+        WHERE is_default AND (user_id, currency_code) IN (SELECT * FROM unnest($1::int[], $2::varchar[]))
+        ORDER BY id
+        FOR UPDATE;`
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return 0, err
+	}
+
+	rows, err := tx.QueryContext(ctx, lockQuery)
+	if err != nil {
+		tx.Rollback()
+		log.Error("failed to lock the expired orders", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	orders := make([]*models.Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			rows.Close()
+			tx.Rollback()
+			log.Error("failed to scan row", "error", err, "transaction", "rollback")
+			return 0, err
+		}
+		orders = append(orders, order)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		log.Error("error during rows iteration", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	// the reserves are released to the default accounts, they are locked in the order of their IDs first,
+	// the same order as the exchanges and the execution of the orders, so that they can not deadlock
+	if len(orders) > 0 {
+		userIds := make([]int64, 0, len(orders))
+		currencies := make([]string, 0, len(orders))
+		for _, order := range orders {
+			userIds = append(userIds, int64(order.UserID))
+			currencies = append(currencies, order.FromCurrency)
+		}
+
+		if _, err := tx.ExecContext(ctx, lockAccountsQuery, pq.Array(userIds), pq.Array(currencies)); err != nil {
+			tx.Rollback()
+			log.Error("failed to lock the accounts of the expired orders", "error", err, "transaction", "rollback")
+			return 0, err
+		}
+	}
+
+	for _, order := range orders {
+		if err := closeOrder(ctx, tx, order, servOrders.StatusExpired, servOrders.EventExpired, "expiry time has passed"); err != nil {
+			tx.Rollback()
+			log.Error("failed to expire the order", "order id", order.ID, "error", err, "transaction", "rollback")
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return 0, err
+	}
+
+	log.Debug("transaction successfully completed", "expired", len(orders))
+	return len(orders), nil
+}
@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// outboxRelayLock is the key of the advisory lock held by the instance that relays the outbox,
+// only one relay runs at a time so that the events of every user are published in order.
+const outboxRelayLock = 730_033
+
+// writeOutbox writes a domain event to the outbox.
+// It must be called inside the transaction of the change, so that the event exists only if the change is committed.
+// The ID is taken when the event is written, not when it is committed: the events of the changes of a user that lock
+// the same account are in the order of the changes, but two concurrent transactions of the user on different accounts
+// may commit in the other order than their IDs and their events may be published in that other order.
+func writeOutbox(ctx context.Context, tx *sql.Tx, userId uint, eventType string, payload any) error {
+	query := `INSERT INTO outbox (user_id, event_type, payload) VALUES ($1, $2, $3);`
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, userId, eventType, data)
+	return err
+}
+
+// RelayOutbox publishes up to limit pending events in the order of their IDs and marks the published ones.
+// If the event of a user fails, retry returns the time of its next attempt, the following events of this user
+// are not selected until it is published, so they are published in order. If retry returns nil, the event
+// is dead-lettered: it is kept with its last error and is no longer published, the following events of the user go on.
+// If another instance is relaying right now, nothing is done. It returns the number of the published events.
+func (db *PostgresDB) RelayOutbox(ctx context.Context, limit int, publish func(event models.OutboxEvent) error, retry func(attempts int) *time.Time) (int, error) {
+	op := "Database: outbox relay"
+	log := db.log.With(slog.String("operation", op))
+
+	lockQuery := `SELECT pg_try_advisory_xact_lock($1);`
+
+	// an event is selected when it is due and no earlier event of its user waits for the next attempt
+	selectQuery := `
+        SELECT o.id, o.user_id, o.event_type, o.payload, o.created_at, o.attempts
+        FROM outbox o
+        WHERE o.published_at IS NULL AND o.dead_at IS NULL
+            AND (o.next_attempt_at IS NULL OR o.next_attempt_at <= NOW())
+            AND NOT EXISTS (
+                SELECT 1
+                FROM outbox e
+                WHERE e.user_id = o.user_id AND e.id < o.id
+                    AND e.published_at IS NULL AND e.dead_at IS NULL AND e.next_attempt_at > NOW())
+        ORDER BY o.id
+        LIMIT $1;`
+
+	publishedQuery := `UPDATE outbox SET published_at = NOW(), attempts = attempts + 1, next_attempt_at = NULL WHERE id = $1;`
+
+	// without the next attempt the event is dead-lettered
+	failedQuery := `
+        UPDATE outbox
+        SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3,
+            dead_at = CASE WHEN $3::timestamptz IS NULL THEN NOW() END
+        WHERE id = $1;`
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return 0, err
+	}
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, lockQuery, outboxRelayLock).Scan(&locked); err != nil {
+		tx.Rollback()
+		log.Error("failed to take the relay lock", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	if !locked {
+		tx.Rollback()
+		log.Debug("outbox is relayed by another instance")
+		return 0, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, selectQuery, limit)
+	if err != nil {
+		tx.Rollback()
+		log.Error("failed to select the unpublished events", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	events := make([]models.OutboxEvent, 0)
+	for rows.Next() {
+		var event models.OutboxEvent
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.UserID, &event.Type, &payload, &event.CreatedAt, &event.Attempts); err != nil {
+			rows.Close()
+			tx.Rollback()
+			log.Error("failed to scan row", "error", err, "transaction", "rollback")
+			return 0, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		log.Error("error during rows iteration", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	var published int
+	blocked := make(map[uint]bool)
+	for _, event := range events {
+		if blocked[event.UserID] {
+			continue
+		}
+
+		if err := publish(event); err != nil {
+			blocked[event.UserID] = true
+
+			next := retry(event.Attempts + 1)
+			if next == nil {
+				log.Error("event is dead-lettered after the last attempt", "event id", event.ID, "user id", event.UserID, "attempts", event.Attempts+1, "error", err)
+			}
+
+			if _, err := tx.ExecContext(ctx, failedQuery, event.ID, err.Error(), next); err != nil {
+				tx.Rollback()
+				log.Error("failed to record the publishing error", "error", err, "transaction", "rollback")
+				return 0, err
+			}
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, publishedQuery, event.ID); err != nil {
+			tx.Rollback()
+			log.Error("failed to mark the event as published", "error", err, "transaction", "rollback")
+			return 0, err
+		}
+		published++
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction, the published events will be published again", "error", err)
+		return 0, err
+	}
+
+	return published, nil
+}
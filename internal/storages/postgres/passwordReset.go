@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	servAuth "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// CreatePasswordReset saves a new password reset token of the user, the unused tokens of the user are deleted.
+// The user row is locked, so that two concurrent requests can not both pass the check of the resend interval.
+// If a token was created less than resendInterval ago, it returns ErrTooManyRequests.
+func (db *PostgresDB) CreatePasswordReset(ctx context.Context, reset *models.PasswordReset, resendInterval time.Duration) error {
+	op := "Database: creating the password reset"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("CreatePasswordReset func call", "user id", reset.UserID)
+
+	lockQuery := `SELECT id FROM users WHERE id = $1 FOR UPDATE;`
+
+	recentQuery := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM password_resets
+			WHERE user_id = $1 AND created_at > NOW() - make_interval(secs => $2)
+		);`
+
+	deleteQuery := `DELETE FROM password_resets WHERE user_id = $1 AND used_at IS NULL;`
+
+	insertQuery := `
+		INSERT INTO password_resets (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3);`
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return err
+	}
+
+	var id uint
+	if err = tx.QueryRowContext(ctx, lockQuery, reset.UserID).Scan(&id); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("user not found", "user id", reset.UserID, "transaction", "rollback")
+			return servAuth.ErrUserNotFound
+		}
+		log.Error("failed to lock the user", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	var recent bool
+	if err = tx.QueryRowContext(ctx, recentQuery, reset.UserID, resendInterval.Seconds()).Scan(&recent); err != nil {
+		tx.Rollback()
+		log.Error("failed to check the previous tokens", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if recent {
+		tx.Rollback()
+		log.Warn("reset token was sent recently", "user id", reset.UserID, "transaction", "rollback")
+		return servAuth.ErrTooManyRequests
+	}
+
+	if _, err = tx.ExecContext(ctx, deleteQuery, reset.UserID); err != nil {
+		tx.Rollback()
+		log.Error("failed to delete the unused tokens", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, insertQuery, reset.UserID, reset.TokenHash, reset.ExpiresAt); err != nil {
+		tx.Rollback()
+		log.Error("failed to save the token", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return err
+	}
+
+	log.Info("reset token successfully saved")
+	return nil
+}
+
+// ResetPassword marks the token as used and sets the new password hash of the user.
+// The token version of the user is increased, so that all issued access tokens stop working,
+// all API keys of the user are revoked and the other unused reset tokens of the user are deleted. It returns the ID of the user.
+// If the token is unknown, expired or already used, it returns ErrInvalidToken.
+func (db *PostgresDB) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (uint, error) {
+	op := "Database: password reset"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("ResetPassword func call")
+
+	useQuery := `
+		UPDATE password_resets
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id;`
+
+	updateQuery := `
+		UPDATE users
+		SET password_hash = $2, token_version = token_version + 1
+		WHERE id = $1;`
+
+	deleteQuery := `DELETE FROM password_resets WHERE user_id = $1 AND used_at IS NULL;`
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return 0, err
+	}
+
+	var userId uint
+	if err = tx.QueryRowContext(ctx, useQuery, tokenHash).Scan(&userId); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("token is invalid, expired or used", "transaction", "rollback")
+			return 0, servAuth.ErrInvalidToken
+		}
+		log.Error("failed to use the token", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	if _, err = tx.ExecContext(ctx, updateQuery, userId, passwordHash); err != nil {
+		tx.Rollback()
+		log.Error("failed to update the password", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	if _, err = tx.ExecContext(ctx, deleteQuery, userId); err != nil {
+		tx.Rollback()
+		log.Error("failed to delete the unused tokens", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	if err = revokeUserAPIKeys(ctx, tx, userId); err != nil {
+		tx.Rollback()
+		log.Error("failed to revoke the API keys", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return 0, err
+	}
+
+	log.Info("password successfully reset", "user id", userId)
+	return userId, nil
+}
+
+// TokenVersion returns the current token version of the user, the access tokens with another version are revoked.
+// If the user is not found, it returns ErrUserNotFound.
+func (db *PostgresDB) TokenVersion(ctx context.Context, userId uint) (int, error) {
+	op := "Database: getting the token version"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("TokenVersion func call", slog.Any("user id", userId))
+
+	var version int
+	err := db.db.QueryRowContext(ctx, `SELECT token_version FROM users WHERE id = $1 AND deleted_at IS NULL;`, userId).Scan(&version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("user is not in the database", "user id", userId)
+			return 0, servAuth.ErrUserNotFound
+		}
+		log.Error("fail to execute SQL query", "error", err)
+		return 0, err
+	}
+
+	return version, nil
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+
+	servAuth "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// UpdateProfile changes the passed fields of the user profile and returns the updated user.
+// An empty display currency removes it.
+// If the user is not found, it returns ErrUserNotFound, if the currency is unknown, it returns ErrCurrencyNotFound.
+func (db *PostgresDB) UpdateProfile(ctx context.Context, req *models.UpdateProfileRequest) (*models.User, error) {
+	op := "Database: updating the profile"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UpdateProfile func call", slog.Any("requets data", req))
+
+	query := `
+		UPDATE users
+		SET name = COALESCE($2, name),
+			display_currency = CASE WHEN $3::TEXT IS NULL THEN display_currency ELSE NULLIF($3, '') END
+		WHERE id = $1
+		RETURNING id, name, email, password_hash, role, segment, email_verified_at IS NOT NULL, COALESCE(display_currency, ''), token_version;`
+
+	var user models.User
+	err := db.db.QueryRowContext(ctx, query, req.UserID, req.Name, req.DisplayCurrency).Scan(
+		&user.ID, &user.Name, &user.Email, &user.HashPassword, &user.Role, &user.Segment, &user.EmailVerified, &user.DisplayCurrency, &user.TokenVersion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("user is not in the database", "user id", req.UserID)
+			return nil, servAuth.ErrUserNotFound
+		}
+		if strings.Contains(err.Error(), "foreign key") {
+			log.Warn("currency not found", "currency", *req.DisplayCurrency)
+			return nil, servWallet.ErrCurrencyNotFound
+		}
+		log.Error("fail to execute SQL query", "error", err)
+		return nil, err
+	}
+
+	log.Info("profile successfully updated")
+	return &user, nil
+}
+
+// ChangePassword sets the new password hash of the user and increases the token version,
+// so that all issued access tokens stop working, and revokes all API keys of the user.
+// The unused password reset tokens of the user are deleted. It returns the new token version. If the user is not found, it returns ErrUserNotFound.
+func (db *PostgresDB) ChangePassword(ctx context.Context, userId uint, passwordHash string) (int, error) {
+	op := "Database: changing the password"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("ChangePassword func call", slog.Any("user id", userId))
+
+	updateQuery := `
+		UPDATE users
+		SET password_hash = $2, token_version = token_version + 1
+		WHERE id = $1
+		RETURNING token_version;`
+
+	deleteQuery := `DELETE FROM password_resets WHERE user_id = $1 AND used_at IS NULL;`
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return 0, err
+	}
+
+	var version int
+	if err = tx.QueryRowContext(ctx, updateQuery, userId, passwordHash).Scan(&version); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("user not found", "user id", userId, "transaction", "rollback")
+			return 0, servAuth.ErrUserNotFound
+		}
+		log.Error("failed to update the password", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	if _, err = tx.ExecContext(ctx, deleteQuery, userId); err != nil {
+		tx.Rollback()
+		log.Error("failed to delete the unused reset tokens", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	if err = revokeUserAPIKeys(ctx, tx, userId); err != nil {
+		tx.Rollback()
+		log.Error("failed to revoke the API keys", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return 0, err
+	}
+
+	log.Info("password successfully changed", "user id", userId)
+	return version, nil
+}
+
+// DisplayCurrency returns the currency the user wants the balance to be valued in, it is empty if the user has not chosen one.
+// If the user is not found, it returns ErrUserNotFound.
+func (db *PostgresDB) DisplayCurrency(ctx context.Context, userId uint) (string, error) {
+	op := "Database: getting the display currency"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("DisplayCurrency func call", slog.Any("user id", userId))
+
+	var currency string
+	err := db.db.QueryRowContext(ctx, `SELECT COALESCE(display_currency, '') FROM users WHERE id = $1;`, userId).Scan(&currency)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("user not found", "user id", userId)
+			return "", servAuth.ErrUserNotFound
+		}
+		log.Error("fail to execute SQL query", "error", err)
+		return "", err
+	}
+
+	return currency, nil
+}
@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// Reconcile compares the stored balances with the persisted history of the operations.
+// For every user and currency the sum of the balances of the accounts must be the sum of the operations,
+// and for every account its balance must be the sum of the operations of the account.
+// The accounts with a negative balance and the orphaned accounts, without a user or with a non-zero balance
+// of a deleted user, are reported too. All checks are done in one read-only snapshot, so they always agree.
+func (db *PostgresDB) Reconcile(ctx context.Context) (*models.ReconcileReport, error) {
+	op := "Database: reconciliation of the balances"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("Reconcile func call")
+
+	countQuery := `SELECT COUNT(*) FROM accounts;`
+
+	// the operations of a closed account have no account ID, they are still counted for the user and the currency
+	mismatchesQuery := `
+		WITH balances AS (
+			SELECT user_id, currency_code, SUM(balance) AS balance
+			FROM accounts
+			WHERE user_id IS NOT NULL AND currency_code IS NOT NULL
+			GROUP BY user_id, currency_code
+		), history AS (
+			SELECT user_id, currency_code, SUM(amount) AS amount
+			FROM operations
+			WHERE user_id IS NOT NULL
+			GROUP BY user_id, currency_code
+		)
+		SELECT COALESCE(b.user_id, h.user_id), COALESCE(b.currency_code, h.currency_code),
+			COALESCE(b.balance, 0), COALESCE(h.amount, 0), COALESCE(b.balance, 0) - COALESCE(h.amount, 0)
+		FROM balances b
+		FULL JOIN history h ON h.user_id = b.user_id AND h.currency_code = b.currency_code
+		WHERE COALESCE(b.balance, 0) <> COALESCE(h.amount, 0)
+		ORDER BY 1, 2;`
+
+	accountMismatchesQuery := `
+		SELECT a.user_id, a.id, a.currency_code, a.balance, COALESCE(SUM(o.amount), 0), a.balance - COALESCE(SUM(o.amount), 0)
+		FROM accounts a
+		LEFT JOIN operations o ON o.account_id = a.id
+		WHERE a.user_id IS NOT NULL AND a.currency_code IS NOT NULL
+		GROUP BY a.id
+		HAVING a.balance <> COALESCE(SUM(o.amount), 0)
+		ORDER BY a.user_id, a.id;`
+
+	negativeQuery := `
+		SELECT id, COALESCE(user_id, 0), COALESCE(currency_code, ''), balance, held, 'negative balance'
+		FROM accounts
+		WHERE balance < 0
+		ORDER BY id;`
+
+	orphanedQuery := `
+		SELECT a.id, COALESCE(a.user_id, 0), COALESCE(a.currency_code, ''), a.balance, a.held,
+			CASE
+				WHEN a.user_id IS NULL THEN 'account has no user'
+				WHEN a.currency_code IS NULL THEN 'account has no currency'
+				ELSE 'funds are left on the account of a deleted user'
+			END
+		FROM accounts a
+		LEFT JOIN users u ON u.id = a.user_id
+		WHERE a.user_id IS NULL OR a.currency_code IS NULL
+			OR (u.deleted_at IS NOT NULL AND (a.balance <> 0 OR a.held <> 0))
+		ORDER BY a.id;`
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return nil, err
+	}
+
+	report := models.ReconcileReport{
+		CheckedAt:         time.Now().UTC(),
+		Mismatches:        []models.BalanceMismatch{},
+		AccountMismatches: []models.BalanceMismatch{},
+		NegativeBalances:  []models.ReconcileAccount{},
+		OrphanedAccounts:  []models.ReconcileAccount{},
+	}
+
+	if err := tx.QueryRowContext(ctx, countQuery).Scan(&report.Accounts); err != nil {
+		tx.Rollback()
+		log.Error("failed to count the accounts", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if report.Mismatches, err = scanMismatches(ctx, tx, mismatchesQuery, false); err != nil {
+		tx.Rollback()
+		log.Error("failed to compare the balances of the users with the history", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if report.AccountMismatches, err = scanMismatches(ctx, tx, accountMismatchesQuery, true); err != nil {
+		tx.Rollback()
+		log.Error("failed to compare the balances of the accounts with the history", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if report.NegativeBalances, err = scanReconcileAccounts(ctx, tx, negativeQuery); err != nil {
+		tx.Rollback()
+		log.Error("failed to find the negative balances", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	if report.OrphanedAccounts, err = scanReconcileAccounts(ctx, tx, orphanedQuery); err != nil {
+		tx.Rollback()
+		log.Error("failed to find the orphaned accounts", "error", err, "transaction", "rollback")
+		return nil, err
+	}
+
+	// nothing has been changed, the transaction only held the snapshot
+	if err = tx.Commit(); err != nil {
+		log.Error("failed to commit transaction", "error", err)
+		return nil, err
+	}
+
+	log.Info("reconciliation is finished", "accounts", report.Accounts, "discrepancies", report.Discrepancies())
+	return &report, nil
+}
+
+// scanMismatches reads the balances that differ from the history, with byAccount the rows have the account ID.
+func scanMismatches(ctx context.Context, tx *sql.Tx, query string, byAccount bool) ([]models.BalanceMismatch, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mismatches := []models.BalanceMismatch{}
+	for rows.Next() {
+		var mismatch models.BalanceMismatch
+		var err error
+		if byAccount {
+			err = rows.Scan(&mismatch.UserID, &mismatch.AccountID, &mismatch.Currency, &mismatch.Balance, &mismatch.Expected, &mismatch.Difference)
+		} else {
+			err = rows.Scan(&mismatch.UserID, &mismatch.Currency, &mismatch.Balance, &mismatch.Expected, &mismatch.Difference)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		mismatches = append(mismatches, mismatch)
+	}
+
+	return mismatches, rows.Err()
+}
+
+// scanReconcileAccounts reads the accounts with a problem.
+func scanReconcileAccounts(ctx context.Context, tx *sql.Tx, query string) ([]models.ReconcileAccount, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []models.ReconcileAccount{}
+	for rows.Next() {
+		var account models.ReconcileAccount
+		if err := rows.Scan(&account.AccountID, &account.UserID, &account.Currency, &account.Balance, &account.Held, &account.Reason); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
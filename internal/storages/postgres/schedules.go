@@ -0,0 +1,393 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	servScheduler "github.com/EvansTrein/RESTful_exchangerServer/internal/services/scheduler"
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+const scheduleColumns = `id, user_id, operation, currency_code, to_currency, amount, cron_expr, interval_seconds,
+	starts_at, ends_at, status, due_at, next_run_at, retry_count, created_at`
+
+// scheduleRunsLimit is the number of the latest runs returned with a schedule.
+const scheduleRunsLimit = 50
+
+// scanSchedule scans a row selected with scheduleColumns.
+func scanSchedule(row interface{ Scan(dest ...any) error }) (*models.Schedule, error) {
+	var schedule models.Schedule
+	var toCurrency, cron sql.NullString
+	var intervalSeconds sql.NullInt64
+
+	err := row.Scan(
+		&schedule.ID,
+		&schedule.UserID,
+		&schedule.Operation,
+		&schedule.Currency,
+		&toCurrency,
+		&schedule.Amount,
+		&cron,
+		&intervalSeconds,
+		&schedule.StartsAt,
+		&schedule.EndsAt,
+		&schedule.Status,
+		&schedule.DueAt,
+		&schedule.NextRunAt,
+		&schedule.RetryCount,
+		&schedule.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.ToCurrency = toCurrency.String
+	schedule.Cron = cron.String
+	if intervalSeconds.Valid {
+		schedule.Interval = (time.Duration(intervalSeconds.Int64) * time.Second).String()
+	}
+
+	return &schedule, nil
+}
+
+// scheduleTiming converts the timing of the schedule to the values of the cron_expr and interval_seconds columns.
+func scheduleTiming(schedule *models.Schedule) (sql.NullString, sql.NullInt64, error) {
+	if schedule.Cron != "" {
+		return sql.NullString{String: schedule.Cron, Valid: true}, sql.NullInt64{}, nil
+	}
+
+	interval, err := time.ParseDuration(schedule.Interval)
+	if err != nil {
+		return sql.NullString{}, sql.NullInt64{}, servScheduler.ErrInvalidInterval
+	}
+
+	return sql.NullString{}, sql.NullInt64{Int64: int64(interval / time.Second), Valid: true}, nil
+}
+
+// CreateSchedule saves a new schedule of the user, the ID and the creation time are written back to the schedule.
+// If a currency is not supported, it returns an error.
+func (db *PostgresDB) CreateSchedule(ctx context.Context, schedule *models.Schedule) error {
+	op := "Database: schedule creation"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("CreateSchedule func call", slog.Any("requets data", schedule))
+
+	query := `
+        INSERT INTO schedules (user_id, operation, currency_code, to_currency, amount, cron_expr, interval_seconds,
+            starts_at, ends_at, status, due_at, next_run_at)
+        VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11, $12)
+        RETURNING id, created_at;`
+
+	cron, intervalSeconds, err := scheduleTiming(schedule)
+	if err != nil {
+		log.Error("invalid schedule timing", "error", err)
+		return err
+	}
+
+	stmt, err := db.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Error("failed to prepare SQL query", "error", err)
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx,
+		schedule.UserID,
+		schedule.Operation,
+		schedule.Currency,
+		schedule.ToCurrency,
+		schedule.Amount,
+		cron,
+		intervalSeconds,
+		schedule.StartsAt,
+		schedule.EndsAt,
+		schedule.Status,
+		schedule.DueAt,
+		schedule.NextRunAt,
+	).Scan(&schedule.ID, &schedule.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "foreign key") {
+			log.Warn("currency not found", "currency", schedule.Currency, "to currency", schedule.ToCurrency)
+			return servWallet.ErrCurrencyNotFound
+		}
+		log.Error("fail to execute SQL query", "error", err)
+		return err
+	}
+
+	log.Info("data has been successfully saved in the database")
+	return nil
+}
+
+// UserSchedules returns all schedules of the user, newest first.
+func (db *PostgresDB) UserSchedules(ctx context.Context, userId uint) ([]models.Schedule, error) {
+	op := "Database: list of user schedules"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UserSchedules func call", "user id", userId)
+
+	query := `SELECT ` + scheduleColumns + `
+        FROM schedules
+        WHERE user_id = $1
+        ORDER BY created_at DESC, id DESC;`
+
+	stmt, err := db.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Error("failed to prepare SQL query", "error", err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, userId)
+	if err != nil {
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := make([]models.Schedule, 0)
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			log.Error("failed to scan row", "error", err)
+			return nil, err
+		}
+		schedules = append(schedules, *schedule)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("error during rows iteration", "error", err)
+		return nil, err
+	}
+
+	log.Info("database successfully returned the schedules")
+	return schedules, nil
+}
+
+// UserSchedule returns the schedule of the user together with its latest runs, newest first.
+// If the schedule does not exist or belongs to another user, it returns an error.
+func (db *PostgresDB) UserSchedule(ctx context.Context, userId, scheduleId uint) (*models.Schedule, []models.ScheduleRun, error) {
+	op := "Database: user schedule"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UserSchedule func call", "user id", userId, "schedule id", scheduleId)
+
+	scheduleQuery := `SELECT ` + scheduleColumns + ` FROM schedules WHERE id = $1 AND user_id = $2;`
+
+	runsQuery := `
+        SELECT id, scheduled_for, attempt, status, details, created_at
+        FROM schedule_runs
+        WHERE schedule_id = $1
+        ORDER BY created_at DESC, id DESC
+        LIMIT $2;`
+
+	schedule, err := scanSchedule(db.db.QueryRowContext(ctx, scheduleQuery, scheduleId, userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("schedule not found", "schedule id", scheduleId)
+			return nil, nil, servScheduler.ErrScheduleNotFound
+		}
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, nil, err
+	}
+
+	rows, err := db.db.QueryContext(ctx, runsQuery, scheduleId, scheduleRunsLimit)
+	if err != nil {
+		log.Error("failed to execute runs SQL query", "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	runs := make([]models.ScheduleRun, 0)
+	for rows.Next() {
+		var run models.ScheduleRun
+		if err := rows.Scan(&run.ID, &run.ScheduledFor, &run.Attempt, &run.Status, &run.Details, &run.CreatedAt); err != nil {
+			log.Error("failed to scan row", "error", err)
+			return nil, nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("error during rows iteration", "error", err)
+		return nil, nil, err
+	}
+
+	log.Info("database successfully returned the schedule")
+	return schedule, runs, nil
+}
+
+// UpdateSchedule saves the amount, the end date, the status and the next run of the schedule of the user.
+// If the schedule does not exist or belongs to another user, it returns an error.
+func (db *PostgresDB) UpdateSchedule(ctx context.Context, schedule *models.Schedule) error {
+	op := "Database: schedule update"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UpdateSchedule func call", slog.Any("requets data", schedule))
+
+	query := `
+        UPDATE schedules
+        SET amount = $3, ends_at = $4, status = $5, due_at = $6, next_run_at = $7, retry_count = $8
+        WHERE id = $1 AND user_id = $2;`
+
+	stmt, err := db.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Error("failed to prepare SQL query", "error", err)
+		return err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx,
+		schedule.ID,
+		schedule.UserID,
+		schedule.Amount,
+		schedule.EndsAt,
+		schedule.Status,
+		schedule.DueAt,
+		schedule.NextRunAt,
+		schedule.RetryCount,
+	)
+	if err != nil {
+		log.Error("fail to execute SQL query", "error", err)
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		log.Warn("schedule not found", "schedule id", schedule.ID)
+		return servScheduler.ErrScheduleNotFound
+	}
+
+	log.Info("schedule successfully updated")
+	return nil
+}
+
+// DeleteSchedule deletes the schedule of the user, the history of its runs is deleted with it.
+// If the schedule does not exist or belongs to another user, it returns an error.
+func (db *PostgresDB) DeleteSchedule(ctx context.Context, userId, scheduleId uint) error {
+	op := "Database: schedule removal"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("DeleteSchedule func call", "user id", userId, "schedule id", scheduleId)
+
+	query := `DELETE FROM schedules WHERE id = $1 AND user_id = $2;`
+
+	stmt, err := db.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Error("failed to prepare SQL query", "error", err)
+		return err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, scheduleId, userId)
+	if err != nil {
+		log.Error("fail to execute SQL query", "error", err)
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		log.Warn("schedule not found", "schedule id", scheduleId)
+		return servScheduler.ErrScheduleNotFound
+	}
+
+	log.Info("schedule successfully deleted")
+	return nil
+}
+
+// ClaimDueSchedules claims up to limit active schedules whose next run is due and leases them for the given time.
+// The rows locked by another instance are skipped and a leased schedule is not claimed again until the lease expires,
+// so several instances never run the same schedule at once.
+func (db *PostgresDB) ClaimDueSchedules(ctx context.Context, limit int, lease time.Duration) ([]models.Schedule, error) {
+	op := "Database: claiming due schedules"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("ClaimDueSchedules func call", "limit", limit, "lease", lease.String())
+
+	query := `
+        UPDATE schedules
+        SET locked_until = NOW() + make_interval(secs => $2)
+        WHERE id IN (
+            SELECT id
+            FROM schedules
+            WHERE status = 'active' AND next_run_at <= NOW() AND (locked_until IS NULL OR locked_until < NOW())
+            ORDER BY next_run_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED)
+        RETURNING ` + scheduleColumns + `;`
+
+	rows, err := db.db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := make([]models.Schedule, 0)
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			log.Error("failed to scan row", "error", err)
+			return nil, err
+		}
+		schedules = append(schedules, *schedule)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("error during rows iteration", "error", err)
+		return nil, err
+	}
+
+	log.Debug("database successfully claimed the due schedules", "count", len(schedules))
+	return schedules, nil
+}
+
+// FinishScheduleRun records the result of the run and saves the next run of the schedule in one transaction.
+// The lease is released. A schedule paused by the user while it was running stays paused.
+func (db *PostgresDB) FinishScheduleRun(ctx context.Context, schedule *models.Schedule, run *models.ScheduleRun) error {
+	op := "Database: recording of the schedule run"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("FinishScheduleRun func call", "schedule id", schedule.ID, slog.Any("run", run))
+
+	insertRunQuery := `
+        INSERT INTO schedule_runs (schedule_id, scheduled_for, attempt, status, details)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at;`
+
+	updateQuery := `
+        UPDATE schedules
+        SET due_at = $2,
+            next_run_at = $3,
+            retry_count = $4,
+            status = CASE WHEN $5 = 'finished' THEN 'finished' ELSE status END,
+            locked_until = NULL
+        WHERE id = $1;`
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, insertRunQuery, schedule.ID, run.ScheduledFor, run.Attempt, run.Status, run.Details).Scan(&run.ID, &run.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		if strings.Contains(err.Error(), "foreign key") {
+			log.Warn("schedule was deleted while it was running", "schedule id", schedule.ID, "transaction", "rollback")
+			return servScheduler.ErrScheduleNotFound
+		}
+		log.Error("failed to save the schedule run", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, updateQuery, schedule.ID, schedule.DueAt, schedule.NextRunAt, schedule.RetryCount, schedule.Status); err != nil {
+		tx.Rollback()
+		log.Error("failed to update the schedule", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return err
+	}
+
+	log.Info("transaction successfully completed", "schedule id", schedule.ID, "status", run.Status)
+	return nil
+}
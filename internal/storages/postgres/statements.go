@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// Statement reads the statement of the user from the persisted history of the operations, optionally of one currency.
+// The opening balances are the sums of the operations before req.From by currency, they are passed to begin,
+// then every operation in [req.From, req.To) is passed to emit, oldest first, as the rows are read.
+// Both reads are done in one read-only snapshot, so the opening balances and the operations always agree.
+// If begin or emit fails, the reading stops and the error is returned.
+func (db *PostgresDB) Statement(ctx context.Context, req *models.StatementRequest, begin func(opening map[string]float32) error, emit func(operation models.OperationRecord) error) error {
+	op := "Database: statement of the operations"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("Statement func call", slog.Any("requets data", req))
+
+	openingQuery := `
+		SELECT currency_code, SUM(amount)
+		FROM operations
+		WHERE user_id = $1 AND ($2 = '' OR currency_code = $2) AND created_at < $3
+		GROUP BY currency_code;`
+
+	operationsQuery := `
+		SELECT ` + operationColumns + `
+		FROM operations
+		WHERE user_id = $1 AND ($2 = '' OR currency_code = $2) AND created_at >= $3 AND created_at < $4
+		ORDER BY created_at, id;`
+
+	// Start transaction
+	tx, err := db.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, openingQuery, req.UserID, req.Currency, req.From)
+	if err != nil {
+		tx.Rollback()
+		log.Error("failed to execute SQL query", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	opening := make(map[string]float32)
+	for rows.Next() {
+		var currencyCode string
+		var balance float32
+		if err := rows.Scan(&currencyCode, &balance); err != nil {
+			rows.Close()
+			tx.Rollback()
+			log.Error("failed to scan row", "error", err, "transaction", "rollback")
+			return err
+		}
+		opening[currencyCode] = balance
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		log.Error("error during rows iteration", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if err := begin(opening); err != nil {
+		tx.Rollback()
+		log.Warn("failed to begin the statement", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	rows, err = tx.QueryContext(ctx, operationsQuery, req.UserID, req.Currency, req.From, req.To)
+	if err != nil {
+		tx.Rollback()
+		log.Error("failed to execute SQL query", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	var count int
+	for rows.Next() {
+		operation, err := scanOperation(rows)
+		if err != nil {
+			rows.Close()
+			tx.Rollback()
+			log.Error("failed to scan row", "error", err, "transaction", "rollback")
+			return err
+		}
+
+		if err := emit(*operation); err != nil {
+			rows.Close()
+			tx.Rollback()
+			log.Warn("failed to write the operation", "operation id", operation.ID, "error", err, "transaction", "rollback")
+			return err
+		}
+		count++
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		log.Error("error during rows iteration", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	// nothing has been changed, the transaction only held the snapshot
+	if err = tx.Commit(); err != nil {
+		log.Error("failed to commit transaction", "error", err)
+		return err
+	}
+
+	log.Info("database successfully returned the statement", "operations", count)
+	return nil
+}
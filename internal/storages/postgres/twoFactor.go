@@ -0,0 +1,282 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	servAuth "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// TwoFactor returns the two-factor authentication of the user, the secret stays encrypted.
+// If the user has never enrolled, it returns ErrTwoFactorDisabled.
+func (db *PostgresDB) TwoFactor(ctx context.Context, userId uint) (*models.TwoFactor, error) {
+	op := "Database: getting the two-factor authentication"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("TwoFactor func call", slog.Any("user id", userId))
+
+	query := `SELECT user_id, secret, enabled_at IS NOT NULL, last_used_step FROM two_factor WHERE user_id = $1;`
+
+	var twoFactor models.TwoFactor
+	err := db.db.QueryRowContext(ctx, query, userId).Scan(&twoFactor.UserID, &twoFactor.Secret, &twoFactor.Enabled, &twoFactor.LastUsedStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, servAuth.ErrTwoFactorDisabled
+		}
+		log.Error("fail to execute SQL query", "error", err)
+		return nil, err
+	}
+
+	return &twoFactor, nil
+}
+
+// SaveTwoFactorSecret saves a new secret of the user, the secret is not enabled until the first code is confirmed.
+// An unconfirmed secret from a previous enrollment is replaced.
+// If the two-factor authentication is already enabled, it returns ErrTwoFactorEnabled.
+func (db *PostgresDB) SaveTwoFactorSecret(ctx context.Context, userId uint, secret string) error {
+	op := "Database: saving the two-factor secret"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("SaveTwoFactorSecret func call", slog.Any("user id", userId))
+
+	query := `
+		INSERT INTO two_factor (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+		WHERE two_factor.enabled_at IS NULL;`
+
+	result, err := db.db.ExecContext(ctx, query, userId, secret)
+	if err != nil {
+		log.Error("fail to execute SQL query", "error", err)
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		log.Error("failed to get the affected rows", "error", err)
+		return err
+	}
+
+	if rows == 0 {
+		log.Warn("two-factor authentication is already enabled", "user id", userId)
+		return servAuth.ErrTwoFactorEnabled
+	}
+
+	log.Info("two-factor secret successfully saved")
+	return nil
+}
+
+// EnableTwoFactor enables the two-factor authentication of the user after the first code is confirmed
+// and saves the hashes of the recovery codes. step is the time step of the confirmed code, it can not be used again.
+// If there is no unconfirmed secret, because it has been confirmed in the meantime, it returns ErrTwoFactorEnabled.
+func (db *PostgresDB) EnableTwoFactor(ctx context.Context, userId uint, step int64, recoveryCodeHashes []string) error {
+	op := "Database: enabling the two-factor authentication"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("EnableTwoFactor func call", slog.Any("user id", userId))
+
+	enableQuery := `
+		UPDATE two_factor
+		SET enabled_at = NOW(), last_used_step = $2
+		WHERE user_id = $1 AND enabled_at IS NULL;`
+
+	deleteQuery := `DELETE FROM two_factor_recovery_codes WHERE user_id = $1;`
+
+	insertQuery := `INSERT INTO two_factor_recovery_codes (user_id, code_hash) VALUES ($1, $2);`
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, enableQuery, userId, step)
+	if err != nil {
+		tx.Rollback()
+		log.Error("failed to enable the two-factor authentication", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		log.Error("failed to get the affected rows", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if rows == 0 {
+		tx.Rollback()
+		log.Warn("no unconfirmed two-factor secret", "user id", userId, "transaction", "rollback")
+		return servAuth.ErrTwoFactorEnabled
+	}
+
+	if _, err = tx.ExecContext(ctx, deleteQuery, userId); err != nil {
+		tx.Rollback()
+		log.Error("failed to delete the old recovery codes", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		if _, err = tx.ExecContext(ctx, insertQuery, userId, hash); err != nil {
+			tx.Rollback()
+			log.Error("failed to save the recovery code", "error", err, "transaction", "rollback")
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return err
+	}
+
+	log.Info("two-factor authentication successfully enabled", "user id", userId)
+	return nil
+}
+
+// UseTwoFactorStep accepts a code of the time step, the step must be later than the step of the last accepted code,
+// so that a code can not be used twice. If the step is not later, it returns ErrInvalidTwoFactor.
+func (db *PostgresDB) UseTwoFactorStep(ctx context.Context, userId uint, step int64) error {
+	op := "Database: using the two-factor code"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UseTwoFactorStep func call", slog.Any("user id", userId))
+
+	query := `
+		UPDATE two_factor
+		SET last_used_step = $2
+		WHERE user_id = $1 AND enabled_at IS NOT NULL AND last_used_step < $2;`
+
+	result, err := db.db.ExecContext(ctx, query, userId, step)
+	if err != nil {
+		log.Error("fail to execute SQL query", "error", err)
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		log.Error("failed to get the affected rows", "error", err)
+		return err
+	}
+
+	if rows == 0 {
+		log.Warn("two-factor code is already used", "user id", userId)
+		return servAuth.ErrInvalidTwoFactor
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks the recovery code of the user as used.
+// If the code is unknown or already used, it returns ErrInvalidTwoFactor.
+func (db *PostgresDB) UseRecoveryCode(ctx context.Context, userId uint, codeHash string) error {
+	op := "Database: using the recovery code"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UseRecoveryCode func call", slog.Any("user id", userId))
+
+	query := `
+		UPDATE two_factor_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;`
+
+	result, err := db.db.ExecContext(ctx, query, userId, codeHash)
+	if err != nil {
+		log.Error("fail to execute SQL query", "error", err)
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		log.Error("failed to get the affected rows", "error", err)
+		return err
+	}
+
+	if rows == 0 {
+		log.Warn("recovery code is invalid or already used", "user id", userId)
+		return servAuth.ErrInvalidTwoFactor
+	}
+
+	log.Info("recovery code used", "user id", userId)
+	return nil
+}
+
+// CountTwoFactorAttempt counts an attempt to check a code of the user, it is called before the code is checked,
+// so the concurrent attempts are counted too. The attempt that reaches maxFailures locks the codes for lockout,
+// the count starts again after the lock. If the codes are locked, it returns ErrTwoFactorLocked.
+func (db *PostgresDB) CountTwoFactorAttempt(ctx context.Context, userId uint, maxFailures int, lockout time.Duration) error {
+	op := "Database: counting the two-factor attempt"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("CountTwoFactorAttempt func call", slog.Any("user id", userId))
+
+	query := `
+		UPDATE two_factor
+		SET failed_attempts = CASE WHEN locked_until IS NULL THEN failed_attempts + 1 ELSE 1 END,
+			locked_until = CASE
+				WHEN (CASE WHEN locked_until IS NULL THEN failed_attempts + 1 ELSE 1 END) >= $2
+				THEN NOW() + make_interval(secs => $3)
+			END
+		WHERE user_id = $1 AND (locked_until IS NULL OR locked_until <= NOW());`
+
+	result, err := db.db.ExecContext(ctx, query, userId, maxFailures, lockout.Seconds())
+	if err != nil {
+		log.Error("fail to execute SQL query", "error", err)
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		log.Error("failed to get the affected rows", "error", err)
+		return err
+	}
+
+	if rows == 0 {
+		log.Warn("two-factor codes are locked", "user id", userId)
+		return servAuth.ErrTwoFactorLocked
+	}
+
+	return nil
+}
+
+// ResetTwoFactorFailures resets the count of the attempts and the lock of the user after an accepted code.
+func (db *PostgresDB) ResetTwoFactorFailures(ctx context.Context, userId uint) error {
+	op := "Database: resetting the two-factor attempts"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("ResetTwoFactorFailures func call", slog.Any("user id", userId))
+
+	query := `UPDATE two_factor SET failed_attempts = 0, locked_until = NULL WHERE user_id = $1;`
+
+	if _, err := db.db.ExecContext(ctx, query, userId); err != nil {
+		log.Error("fail to execute SQL query", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// DisableTwoFactor deletes the secret and the recovery codes of the user.
+// If the two-factor authentication is not enabled, it returns ErrTwoFactorDisabled.
+func (db *PostgresDB) DisableTwoFactor(ctx context.Context, userId uint) error {
+	op := "Database: disabling the two-factor authentication"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("DisableTwoFactor func call", slog.Any("user id", userId))
+
+	result, err := db.db.ExecContext(ctx, `DELETE FROM two_factor WHERE user_id = $1;`, userId)
+	if err != nil {
+		log.Error("fail to execute SQL query", "error", err)
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		log.Error("failed to get the affected rows", "error", err)
+		return err
+	}
+
+	if rows == 0 {
+		log.Warn("two-factor authentication is not enabled", "user id", userId)
+		return servAuth.ErrTwoFactorDisabled
+	}
+
+	log.Info("two-factor authentication successfully disabled", "user id", userId)
+	return nil
+}
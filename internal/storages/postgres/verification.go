@@ -0,0 +1,190 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	servAuth "github.com/EvansTrein/RESTful_exchangerServer/internal/services/auth"
+	servWallet "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+)
+
+// checkVerified verifies that the email of the user is confirmed, the unverified users can not withdraw or exchange.
+// It is called inside the transaction of the operation.
+func checkVerified(ctx context.Context, tx *sql.Tx, userId uint) error {
+	var verified bool
+	err := tx.QueryRowContext(ctx, `SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1;`, userId).Scan(&verified)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return servAuth.ErrUserNotFound
+		}
+		return err
+	}
+
+	if !verified {
+		return servWallet.ErrEmailNotVerified
+	}
+
+	return nil
+}
+
+// UserByID retrieves a user from the database based on their ID.
+// It returns the user details if found, or an error if the user is not found or the operation fails.
+func (db *PostgresDB) UserByID(ctx context.Context, userId uint) (*models.User, error) {
+	op := "Database: getting the user"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UserByID func call", slog.Any("user id", userId))
+
+	query := `SELECT id, name, email, password_hash, role, segment, email_verified_at IS NOT NULL, COALESCE(display_currency, ''), token_version
+		FROM users
+		WHERE id = $1 AND deleted_at IS NULL;`
+
+	var user models.User
+	err := db.db.QueryRowContext(ctx, query, userId).Scan(&user.ID, &user.Name, &user.Email, &user.HashPassword, &user.Role, &user.Segment, &user.EmailVerified, &user.DisplayCurrency, &user.TokenVersion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("user is not in the database", "user id", userId)
+			return nil, servAuth.ErrUserNotFound
+		}
+		log.Error("fail to execute SQL query", "error", err)
+		return nil, err
+	}
+
+	log.Info("database successfully found the user")
+	return &user, nil
+}
+
+// CreateEmailVerification saves a new verification token of the user, the unused tokens of the user are deleted.
+// The user row is locked, so that two concurrent requests can not both pass the check of the resend interval.
+// If a token was created less than resendInterval ago, it returns ErrTooManyRequests.
+func (db *PostgresDB) CreateEmailVerification(ctx context.Context, verification *models.EmailVerification, resendInterval time.Duration) error {
+	op := "Database: creating the email verification"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("CreateEmailVerification func call", "user id", verification.UserID, "email", verification.Email)
+
+	lockQuery := `SELECT id FROM users WHERE id = $1 FOR UPDATE;`
+
+	recentQuery := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM email_verifications
+			WHERE user_id = $1 AND created_at > NOW() - make_interval(secs => $2)
+		);`
+
+	deleteQuery := `DELETE FROM email_verifications WHERE user_id = $1 AND used_at IS NULL;`
+
+	insertQuery := `
+		INSERT INTO email_verifications (user_id, email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4);`
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return err
+	}
+
+	var id uint
+	if err = tx.QueryRowContext(ctx, lockQuery, verification.UserID).Scan(&id); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("user not found", "user id", verification.UserID, "transaction", "rollback")
+			return servAuth.ErrUserNotFound
+		}
+		log.Error("failed to lock the user", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	var recent bool
+	if err = tx.QueryRowContext(ctx, recentQuery, verification.UserID, resendInterval.Seconds()).Scan(&recent); err != nil {
+		tx.Rollback()
+		log.Error("failed to check the previous tokens", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if recent {
+		tx.Rollback()
+		log.Warn("verification token was sent recently", "user id", verification.UserID, "transaction", "rollback")
+		return servAuth.ErrTooManyRequests
+	}
+
+	if _, err = tx.ExecContext(ctx, deleteQuery, verification.UserID); err != nil {
+		tx.Rollback()
+		log.Error("failed to delete the unused tokens", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, insertQuery, verification.UserID, verification.Email, verification.TokenHash, verification.ExpiresAt); err != nil {
+		tx.Rollback()
+		log.Error("failed to save the token", "error", err, "transaction", "rollback")
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return err
+	}
+
+	log.Info("verification token successfully saved")
+	return nil
+}
+
+// VerifyEmail marks the token as used and confirms the email it was issued for, the email becomes the email of the user.
+// It returns the ID of the user.
+// If the token is unknown, expired or already used, it returns ErrInvalidToken.
+// If the email has been taken by another user in the meantime, it returns ErrEmailAlreadyExists.
+func (db *PostgresDB) VerifyEmail(ctx context.Context, tokenHash string) (uint, error) {
+	op := "Database: email verification"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("VerifyEmail func call")
+
+	useQuery := `
+		UPDATE email_verifications
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id, email;`
+
+	verifyQuery := `
+		UPDATE users
+		SET email = $2, email_verified_at = NOW()
+		WHERE id = $1;`
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return 0, err
+	}
+
+	var userId uint
+	var email string
+	if err = tx.QueryRowContext(ctx, useQuery, tokenHash).Scan(&userId, &email); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("token is invalid, expired or used", "transaction", "rollback")
+			return 0, servAuth.ErrInvalidToken
+		}
+		log.Error("failed to use the token", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	if _, err = tx.ExecContext(ctx, verifyQuery, userId, email); err != nil {
+		tx.Rollback()
+		if strings.Contains(err.Error(), "duplicate key value") {
+			log.Warn("email is already taken", "transaction", "rollback")
+			return 0, servAuth.ErrEmailAlreadyExists
+		}
+		log.Error("failed to verify the email", "error", err, "transaction", "rollback")
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("!!!ATTENTION!!! failed to commit transaction", "error", err)
+		return 0, err
+	}
+
+	log.Info("email successfully verified", "user id", userId)
+	return userId, nil
+}
@@ -0,0 +1,366 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	servWebhooks "github.com/EvansTrein/RESTful_exchangerServer/internal/services/webhooks"
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/lib/pq"
+)
+
+const deliveryColumns = `d.id, d.event_id, ev.event_type, ev.payload, d.status, d.attempts,
+	d.next_attempt_at, d.last_status_code, d.last_error, d.delivered_at, d.created_at`
+
+// webhookDeliveriesLimit is the number of the latest deliveries returned in the delivery log.
+const webhookDeliveriesLimit = 100
+
+// recordWebhookEvent writes the wallet event for the endpoints of the user subscribed to the event type.
+// It must be called inside the transaction of the balance change, so that the event exists only if the change is committed.
+// If the user has no subscribed endpoints, nothing is written.
+func recordWebhookEvent(ctx context.Context, tx *sql.Tx, userId uint, eventType string, event *models.WalletEvent) error {
+	query := `
+        WITH endpoints AS (
+            SELECT id
+            FROM webhook_endpoints
+            WHERE user_id = $1 AND active AND $2 = ANY(event_types)
+        ), event AS (
+            INSERT INTO webhook_events (user_id, event_type, payload)
+            SELECT $1, $2, $3
+            WHERE EXISTS (SELECT 1 FROM endpoints)
+            RETURNING id
+        )
+        INSERT INTO webhook_deliveries (endpoint_id, event_id)
+        SELECT endpoints.id, event.id
+        FROM endpoints
+        CROSS JOIN event;`
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, userId, eventType, payload)
+	return err
+}
+
+func scanDelivery(row interface{ Scan(dest ...any) error }) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var statusCode sql.NullInt64
+	var payload []byte
+
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&statusCode,
+		&delivery.LastError,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Payload = payload
+	if statusCode.Valid {
+		code := int(statusCode.Int64)
+		delivery.LastStatusCode = &code
+	}
+	if delivery.Status != servWebhooks.DeliveryPending {
+		delivery.NextAttemptAt = nil
+	}
+
+	return &delivery, nil
+}
+
+// CreateWebhook saves a new endpoint of the user, the ID and the creation time are written back to the webhook.
+func (db *PostgresDB) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	op := "Database: webhook creation"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("CreateWebhook func call", "user id", webhook.UserID, "url", webhook.URL, "event types", webhook.EventTypes)
+
+	query := `
+        INSERT INTO webhook_endpoints (user_id, url, secret, event_types, active)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at;`
+
+	stmt, err := db.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Error("failed to prepare SQL query", "error", err)
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, webhook.UserID, webhook.URL, webhook.Secret, pq.Array(webhook.EventTypes), webhook.Active).
+		Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		log.Error("fail to execute SQL query", "error", err)
+		return err
+	}
+
+	log.Info("data has been successfully saved in the database")
+	return nil
+}
+
+// UserWebhooks returns the endpoints of the user without their secrets, newest first.
+func (db *PostgresDB) UserWebhooks(ctx context.Context, userId uint) ([]models.Webhook, error) {
+	op := "Database: list of user webhooks"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("UserWebhooks func call", "user id", userId)
+
+	query := `
+        SELECT id, url, event_types, active, created_at
+        FROM webhook_endpoints
+        WHERE user_id = $1
+        ORDER BY created_at DESC, id DESC;`
+
+	stmt, err := db.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Error("failed to prepare SQL query", "error", err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, userId)
+	if err != nil {
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]models.Webhook, 0)
+	for rows.Next() {
+		webhook := models.Webhook{UserID: userId}
+		if err := rows.Scan(&webhook.ID, &webhook.URL, pq.Array(&webhook.EventTypes), &webhook.Active, &webhook.CreatedAt); err != nil {
+			log.Error("failed to scan row", "error", err)
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("error during rows iteration", "error", err)
+		return nil, err
+	}
+
+	log.Info("database successfully returned the webhooks")
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes the endpoint of the user, its deliveries are deleted with it.
+// If the endpoint does not exist or belongs to another user, it returns an error.
+func (db *PostgresDB) DeleteWebhook(ctx context.Context, userId, webhookId uint) error {
+	op := "Database: webhook removal"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("DeleteWebhook func call", "user id", userId, "webhook id", webhookId)
+
+	query := `DELETE FROM webhook_endpoints WHERE id = $1 AND user_id = $2;`
+
+	stmt, err := db.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Error("failed to prepare SQL query", "error", err)
+		return err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, webhookId, userId)
+	if err != nil {
+		log.Error("fail to execute SQL query", "error", err)
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		log.Warn("webhook not found", "webhook id", webhookId)
+		return servWebhooks.ErrWebhookNotFound
+	}
+
+	log.Info("webhook successfully deleted")
+	return nil
+}
+
+// WebhookDeliveries returns the latest deliveries of the endpoint of the user, newest first.
+// If status is not empty, only the deliveries with this status are returned.
+// If the endpoint does not exist or belongs to another user, it returns an error.
+func (db *PostgresDB) WebhookDeliveries(ctx context.Context, userId, webhookId uint, status string) ([]models.WebhookDelivery, error) {
+	op := "Database: webhook delivery log"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("WebhookDeliveries func call", "user id", userId, "webhook id", webhookId, "status", status)
+
+	endpointQuery := `SELECT EXISTS(SELECT 1 FROM webhook_endpoints WHERE id = $1 AND user_id = $2);`
+
+	deliveriesQuery := `SELECT ` + deliveryColumns + `
+        FROM webhook_deliveries d
+        INNER JOIN webhook_events ev ON ev.id = d.event_id
+        WHERE d.endpoint_id = $1 AND ($2 = '' OR d.status = $2)
+        ORDER BY d.created_at DESC, d.id DESC
+        LIMIT $3;`
+
+	var exists bool
+	if err := db.db.QueryRowContext(ctx, endpointQuery, webhookId, userId).Scan(&exists); err != nil {
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, err
+	}
+
+	if !exists {
+		log.Warn("webhook not found", "webhook id", webhookId)
+		return nil, servWebhooks.ErrWebhookNotFound
+	}
+
+	rows, err := db.db.QueryContext(ctx, deliveriesQuery, webhookId, status, webhookDeliveriesLimit)
+	if err != nil {
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			log.Error("failed to scan row", "error", err)
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("error during rows iteration", "error", err)
+		return nil, err
+	}
+
+	log.Info("database successfully returned the deliveries")
+	return deliveries, nil
+}
+
+// Redeliver makes the delivery pending again with a new set of attempts, it is sent on the next poll of the worker.
+// A delivery that is being sent right now is not changed.
+// If the delivery does not exist or belongs to another user, it returns an error.
+func (db *PostgresDB) Redeliver(ctx context.Context, userId, webhookId uint, deliveryId uint64) (*models.WebhookDelivery, error) {
+	op := "Database: webhook redelivery"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("Redeliver func call", "user id", userId, "webhook id", webhookId, "delivery id", deliveryId)
+
+	query := `
+        WITH updated AS (
+            UPDATE webhook_deliveries d
+            SET status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+            FROM webhook_endpoints e
+            WHERE d.id = $1 AND d.endpoint_id = $2 AND e.id = d.endpoint_id AND e.user_id = $3
+                AND (d.locked_until IS NULL OR d.locked_until < NOW())
+            RETURNING d.*
+        )
+        SELECT ` + deliveryColumns + `
+        FROM updated d
+        INNER JOIN webhook_events ev ON ev.id = d.event_id;`
+
+	delivery, err := scanDelivery(db.db.QueryRowContext(ctx, query, deliveryId, webhookId, userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("delivery not found or is being sent", "delivery id", deliveryId)
+			return nil, servWebhooks.ErrDeliveryNotFound
+		}
+		log.Error("fail to execute SQL query", "error", err)
+		return nil, err
+	}
+
+	log.Info("delivery successfully scheduled")
+	return delivery, nil
+}
+
+// ClaimWebhookDeliveries claims up to limit pending deliveries of active endpoints that are due and leases them.
+// The attempt counter is increased on claim. The rows locked by another instance are skipped,
+// and a leased delivery is not claimed again until the lease expires.
+func (db *PostgresDB) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookJob, error) {
+	op := "Database: claiming webhook deliveries"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("ClaimWebhookDeliveries func call", "limit", limit, "lease", lease.String())
+
+	query := `
+        WITH claimed AS (
+            UPDATE webhook_deliveries
+            SET locked_until = NOW() + make_interval(secs => $2), attempts = attempts + 1
+            WHERE id IN (
+                SELECT d.id
+                FROM webhook_deliveries d
+                INNER JOIN webhook_endpoints e ON e.id = d.endpoint_id
+                WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND e.active
+                    AND (d.locked_until IS NULL OR d.locked_until < NOW())
+                ORDER BY d.next_attempt_at, d.id
+                LIMIT $1
+                FOR UPDATE OF d SKIP LOCKED)
+            RETURNING id, endpoint_id, event_id, attempts
+        )
+        SELECT c.id, c.attempts, e.url, e.secret, ev.id, ev.event_type, ev.payload, ev.created_at
+        FROM claimed c
+        INNER JOIN webhook_endpoints e ON e.id = c.endpoint_id
+        INNER JOIN webhook_events ev ON ev.id = c.event_id
+        ORDER BY ev.id;`
+
+	rows, err := db.db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		log.Error("failed to execute SQL query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]models.WebhookJob, 0)
+	for rows.Next() {
+		var job models.WebhookJob
+		var payload []byte
+		if err := rows.Scan(&job.DeliveryID, &job.Attempt, &job.URL, &job.Secret, &job.EventID, &job.EventType, &payload, &job.EventCreatedAt); err != nil {
+			log.Error("failed to scan row", "error", err)
+			return nil, err
+		}
+		job.Payload = payload
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("error during rows iteration", "error", err)
+		return nil, err
+	}
+
+	log.Debug("database successfully claimed the deliveries", "count", len(jobs))
+	return jobs, nil
+}
+
+// FinishWebhookDelivery records the result of a delivery attempt and releases the lease.
+func (db *PostgresDB) FinishWebhookDelivery(ctx context.Context, attempt *models.WebhookAttempt) error {
+	op := "Database: recording of the webhook attempt"
+	log := db.log.With(slog.String("operation", op))
+	log.Debug("FinishWebhookDelivery func call", slog.Any("attempt", attempt))
+
+	query := `
+        UPDATE webhook_deliveries
+        SET status = $2,
+            last_status_code = $3,
+            last_error = $4,
+            next_attempt_at = COALESCE($5, next_attempt_at),
+            delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() ELSE NULL END,
+            locked_until = NULL
+        WHERE id = $1;`
+
+	stmt, err := db.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Error("failed to prepare SQL query", "error", err)
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, attempt.DeliveryID, attempt.Status, attempt.StatusCode, attempt.Error, attempt.NextAttemptAt); err != nil {
+		log.Error("fail to execute SQL query", "error", err)
+		return err
+	}
+
+	log.Debug("delivery attempt successfully recorded")
+	return nil
+}
@@ -1,25 +1,39 @@
 package redis
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/retry"
 	"github.com/go-redis/redis"
 )
 
+// ErrUnavailable is returned without a request while Redis is unavailable after a degraded start.
+var ErrUnavailable = errors.New("redis is unavailable")
+
 // RedisDB represents a connection to a Redis database.
 // It includes a Redis client, a logger, and a TTL (Time-To-Live) for keys.
+// available is false while a degraded start is reconnecting in the background.
 type RedisDB struct {
-	client  *redis.Client
-	log     *slog.Logger
-	ttlKeys time.Duration
+	client    *redis.Client
+	log       *slog.Logger
+	ttlKeys   time.Duration
+	available atomic.Bool
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
 }
 
 // New creates a new RedisDB instance and establishes a connection to the Redis server.
-// It takes the host, port, password, and TTL for keys as parameters.
-// If the connection fails, it returns an error.
-func New(log *slog.Logger, host string, port string, password string, ttlKeys time.Duration) (*RedisDB, error) {
+// It takes the host, port, password, TTL for keys and the retry policy of the connection as parameters,
+// the server is pinged until it answers or the deadline of the policy passes, every attempt is logged.
+// If the connection fails and degraded is false, it returns an error. With degraded the instance is returned anyway,
+// the cache is skipped and the server is pinged in the background every MaxDelay of the policy until it answers.
+func New(ctx context.Context, log *slog.Logger, host string, port string, password string, ttlKeys time.Duration, policy retry.Policy, degraded bool) (*RedisDB, error) {
 	log.Debug("redis: connection to Redis started")
 
 	client := redis.NewClient(&redis.Options{
@@ -28,15 +42,65 @@ func New(log *slog.Logger, host string, port string, password string, ttlKeys ti
 		DB:       0,
 	})
 
-	if _, err := client.Ping().Result(); err != nil {
+	r := &RedisDB{client: client, ttlKeys: ttlKeys, log: log}
+
+	err := retry.Do(ctx, log, policy, "redis", r.ping)
+	if err == nil {
+		r.available.Store(true)
+		log.Info("redis: connect to Redis successfully")
+		return r, nil
+	}
+
+	if !degraded {
+		client.Close()
 		return nil, fmt.Errorf("failed to ping Redis: %w", err)
 	}
 
-	log.Info("redis: connect to Redis successfully")
-	return &RedisDB{client: client, ttlKeys: ttlKeys, log: log}, nil
+	log.Warn("redis: Redis is unavailable, started without it, the connection is retried in the background", "error", err)
+
+	reconnectCtx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wg.Add(1)
+	go r.reconnect(reconnectCtx, max(policy.MaxDelay, time.Second))
+
+	return r, nil
+}
+
+// ping checks the connection to the Redis server.
+func (r *RedisDB) ping(ctx context.Context) error {
+	return r.client.WithContext(ctx).Ping().Err()
+}
+
+// reconnect pings the Redis server every interval until it answers or the context is done.
+func (r *RedisDB) reconnect(ctx context.Context, interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.ping(ctx); err != nil {
+				r.log.Warn("redis: Redis is still unavailable", "error", err)
+				continue
+			}
+
+			r.available.Store(true)
+			r.log.Info("redis: connect to Redis successfully, the degraded mode is over")
+			return
+		}
+	}
+}
+
+// Available reports whether the connection to the Redis server has been established.
+func (r *RedisDB) Available() bool {
+	return r.available.Load()
 }
 
-// Close closes the connection to the Redis server.
+// Close closes the connection to the Redis server and stops the background reconnection.
 // If the connection is already closed, it returns an error.
 func (r *RedisDB) Close() error {
 	r.log.Debug("redis: stop started")
@@ -45,6 +109,11 @@ func (r *RedisDB) Close() error {
 		return fmt.Errorf("redis connection is already closed")
 	}
 
+	if r.cancel != nil {
+		r.cancel()
+		r.wg.Wait()
+	}
+
 	if err := r.client.Close(); err != nil {
 		return fmt.Errorf("failed to close Redis connection: %w", err)
 	}
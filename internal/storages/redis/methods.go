@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"strconv"
+	"time"
 
 	services "github.com/EvansTrein/RESTful_exchangerServer/internal/services/wallet"
 	"github.com/go-redis/redis"
@@ -11,15 +12,24 @@ import (
 
 // SetExchange stores the exchange rate for a given currency pair in the Redis cache.
 // The key is constructed from the currency pair, and the value is stored with a TTL.
-// If the operation fails, it returns an error.
-func (r *RedisDB) SetExchange(fromCurrency, toCurrency string, value float32) error {
+// The time when the rate was received is stored next to it under the same TTL.
+// If the operation fails, it returns an error, while Redis is unavailable it returns ErrUnavailable.
+func (r *RedisDB) SetExchange(fromCurrency, toCurrency string, value float32, updatedAt time.Time) error {
 	op := "Redis: saving the exchange rate in the cache"
 	log := r.log.With(slog.String("operation", op))
 	log.Debug("SetExchange func call", "fromCurrency", fromCurrency, "toCurrency", toCurrency, "value", value)
 
+	if !r.Available() {
+		return ErrUnavailable
+	}
+
 	key := fmt.Sprintf("%s/%s", fromCurrency, toCurrency)
 
-	err := r.client.Set(key, value, r.ttlKeys).Err()
+	_, err := r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Set(key, value, r.ttlKeys)
+		pipe.Set(updatedAtKey(key), updatedAt.Unix(), r.ttlKeys)
+		return nil
+	})
 	if err != nil {
 		r.log.Error("failed to save string to Redis", "error", err)
 		return err
@@ -29,24 +39,33 @@ func (r *RedisDB) SetExchange(fromCurrency, toCurrency string, value float32) er
 	return nil
 }
 
-// GetExchange retrieves the exchange rate for a given currency pair from the Redis cache.
+// GetExchange retrieves the exchange rate for a given currency pair and the time it was received from the Redis cache.
 // If the key is not found, it returns a specific error (ErrRateInCacheNotFound).
+// Rates cached without the time return the zero time, while Redis is unavailable every rate is not found.
 // If the operation fails, it returns an error.
-func (r *RedisDB) GetExchange(fromCurrency, toCurrency string) (float32, error) {
+func (r *RedisDB) GetExchange(fromCurrency, toCurrency string) (float32, time.Time, error) {
 	op := "Redis: getting exchange rate from cache"
 	log := r.log.With(slog.String("operation", op))
 	log.Debug("GetExchange func call", "fromCurrency", fromCurrency, "toCurrency", toCurrency)
 
+	// without Redis every rate is a cache miss, it is requested from the gRPC server
+	if !r.Available() {
+		log.Debug("Redis is unavailable, the cache is skipped")
+		return 0, time.Time{}, services.ErrRateInCacheNotFound
+	}
+
 	key := fmt.Sprintf("%s/%s", fromCurrency, toCurrency)
 
-	value, err := r.client.Get(key).Result()
+	values, err := r.client.MGet(key, updatedAtKey(key)).Result()
 	if err != nil {
-		if err == redis.Nil {
-			log.Warn("exchange rates are not in the cache")
-			return 0, services.ErrRateInCacheNotFound
-		}
 		r.log.Error("failed to get key from Redis", "key", key, "error", err)
-		return 0, err
+		return 0, time.Time{}, err
+	}
+
+	value, ok := values[0].(string)
+	if !ok {
+		log.Warn("exchange rates are not in the cache")
+		return 0, time.Time{}, services.ErrRateInCacheNotFound
 	}
 
 	log.Debug("cached data was retrieved", "value", value)
@@ -54,9 +73,20 @@ func (r *RedisDB) GetExchange(fromCurrency, toCurrency string) (float32, error)
 	floatValue, err := strconv.ParseFloat(value, 64)
 	if err != nil {
 		r.log.Error("failed to convert string to float", "value", value, "error", err)
-		return 0, err
+		return 0, time.Time{}, err
+	}
+
+	var updatedAt time.Time
+	if unixValue, ok := values[1].(string); ok {
+		if unix, err := strconv.ParseInt(unixValue, 10, 64); err == nil {
+			updatedAt = time.Unix(unix, 0).UTC()
+		}
 	}
 
 	log.Info("the exchange rate was successfully retrieved from the cache", "key", key, "vaule", floatValue)
-	return float32(floatValue), nil
+	return float32(floatValue), updatedAt, nil
+}
+
+func updatedAtKey(key string) string {
+	return key + ":updated_at"
 }
@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Publish sends the message to the channel, every instance subscribed to the channel receives it.
+// If the operation fails, it returns an error, while Redis is unavailable it returns ErrUnavailable.
+func (r *RedisDB) Publish(channel string, message []byte) error {
+	op := "Redis: publishing a message"
+	log := r.log.With(slog.String("operation", op))
+	log.Debug("Publish func call", "channel", channel)
+
+	if !r.Available() {
+		return ErrUnavailable
+	}
+
+	if err := r.client.Publish(channel, message).Err(); err != nil {
+		log.Error("failed to publish the message", "channel", channel, "error", err)
+		return err
+	}
+
+	log.Debug("message has been successfully published", "channel", channel)
+	return nil
+}
+
+// Subscribe receives the messages of the channels matching the pattern and passes them to handle
+// until the context is done. The client reconnects by itself if the connection to Redis is lost.
+// If the subscription can not be created, it returns an error, while Redis is unavailable it returns ErrUnavailable.
+func (r *RedisDB) Subscribe(ctx context.Context, pattern string, handle func(channel string, message []byte)) error {
+	op := "Redis: subscribing to channels"
+	log := r.log.With(slog.String("operation", op))
+	log.Debug("Subscribe func call", "pattern", pattern)
+
+	if !r.Available() {
+		return ErrUnavailable
+	}
+
+	pubsub := r.client.PSubscribe(pattern)
+	defer pubsub.Close()
+
+	// waiting for the confirmation, so that the messages published after the return are not lost
+	if _, err := pubsub.Receive(); err != nil {
+		log.Error("failed to subscribe", "pattern", pattern, "error", err)
+		return fmt.Errorf("failed to subscribe to %q: %w", pattern, err)
+	}
+
+	log.Info("subscribed to the channels", "pattern", pattern)
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("unsubscribed from the channels", "pattern", pattern)
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			handle(msg.Channel, []byte(msg.Payload))
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package redis
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// rateLimitPrefix is the prefix of the keys of the request counters
+const rateLimitPrefix = "ratelimit:"
+
+// Allow counts the request under the key in a fixed window and reports whether it fits into the limit.
+// The counter is shared by all instances of the server, it expires together with the window.
+// If the limit is exceeded, it also returns the time until the window ends.
+// If the operation fails, it returns an error, while Redis is unavailable it returns ErrUnavailable.
+func (r *RedisDB) Allow(key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	op := "Redis: counting the request"
+	log := r.log.With(slog.String("operation", op))
+	log.Debug("Allow func call", "key", key, "limit", limit)
+
+	if !r.Available() {
+		return false, 0, ErrUnavailable
+	}
+
+	key = rateLimitPrefix + key
+
+	var incr *redis.IntCmd
+	var ttl *redis.DurationCmd
+	_, err := r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(key)
+		ttl = pipe.PTTL(key)
+		return nil
+	})
+	if err != nil {
+		log.Error("failed to count the request", "key", key, "error", err)
+		return false, 0, err
+	}
+
+	// the first request of the window starts it, a counter left without the expiration is fixed the same way
+	retryAfter := ttl.Val()
+	if retryAfter < 0 {
+		if err := r.client.PExpire(key, window).Err(); err != nil {
+			log.Error("failed to set the window of the counter", "key", key, "error", err)
+			return false, 0, err
+		}
+		retryAfter = window
+	}
+
+	if incr.Val() > int64(limit) {
+		log.Warn("rate limit exceeded", "key", key, "requests", incr.Val())
+		return false, retryAfter, nil
+	}
+
+	return true, 0, nil
+}
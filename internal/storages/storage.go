@@ -2,29 +2,135 @@ package storages
 
 import (
 	"context"
+	"time"
 
 	"github.com/EvansTrein/RESTful_exchangerServer/models"
 )
 
 // StoreAuth defines the interface for authentication-related database operations.
-// It includes methods for creating, searching, and deleting users.
+// It includes methods for creating, searching, updating and deleting users, for the verification of their emails,
+// for the change and the reset of their passwords, for the two-factor authentication, for the API keys and for the export of their data.
 type StoreAuth interface {
 	CreateUser(ctx context.Context, req models.RegisterRequest) (uint, error)
 	SearchUser(ctx context.Context, req models.LoginRequest) (*models.User, error)
-	DeleteUser(ctx context.Context, userId uint) error
+	UserByID(ctx context.Context, userId uint) (*models.User, error)
+	DeleteUser(ctx context.Context, req *models.DeleteUserRequest) (map[string]float32, error)
+	ExportUser(ctx context.Context, userId uint) (*models.UserExport, error)
+	CreateEmailVerification(ctx context.Context, verification *models.EmailVerification, resendInterval time.Duration) error
+	VerifyEmail(ctx context.Context, tokenHash string) (uint, error)
+	CreatePasswordReset(ctx context.Context, reset *models.PasswordReset, resendInterval time.Duration) error
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (uint, error)
+	TokenVersion(ctx context.Context, userId uint) (int, error)
+	UpdateProfile(ctx context.Context, req *models.UpdateProfileRequest) (*models.User, error)
+	ChangePassword(ctx context.Context, userId uint, passwordHash string) (int, error)
+	TwoFactor(ctx context.Context, userId uint) (*models.TwoFactor, error)
+	SaveTwoFactorSecret(ctx context.Context, userId uint, secret string) error
+	EnableTwoFactor(ctx context.Context, userId uint, step int64, recoveryCodeHashes []string) error
+	UseTwoFactorStep(ctx context.Context, userId uint, step int64) error
+	UseRecoveryCode(ctx context.Context, userId uint, codeHash string) error
+	CountTwoFactorAttempt(ctx context.Context, userId uint, maxFailures int, lockout time.Duration) error
+	ResetTwoFactorFailures(ctx context.Context, userId uint) error
+	DisableTwoFactor(ctx context.Context, userId uint) error
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	UserAPIKeys(ctx context.Context, userId uint) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userId, keyId uint) error
+	UseAPIKey(ctx context.Context, keyHash string) (*models.APIKey, error)
 }
 
 // StoreWallet defines the interface for wallet-related database operations.
-// It includes methods for retrieving account balances, performing account operations, and saving exchange rate changes.
+// It includes methods for retrieving the total and the available account balances, performing account operations, saving exchange rate changes (single or batch),
+// reporting the collected exchange fees, the usage of the transaction limits, the display currency of the user,
+// for the named accounts (pockets) of the user with the transfers between them and for the statements of the operations.
 type StoreWallet interface {
 	AllAccountsBalance(ctx context.Context, userId uint) (map[string]float32, error)
+	AvailableBalance(ctx context.Context, userId uint) (map[string]float32, error)
 	AccountOperation(ctx context.Context, req *models.AccountOperationRequest) (map[string]float32, error)
 	SaveExchangeRateChanges(ctx context.Context, newData *models.CurrencyExchangeResult) error
+	SaveExchangeBatch(ctx context.Context, legs []*models.CurrencyExchangeResult) error
+	UserSegment(ctx context.Context, userId uint) (string, error)
+	FeeRevenue(ctx context.Context, from, to time.Time) ([]models.FeeRevenue, error)
+	LimitsUsage(ctx context.Context, userId uint) ([]models.LimitUsage, error)
+	DisplayCurrency(ctx context.Context, userId uint) (string, error)
+	UserAccounts(ctx context.Context, userId uint) ([]models.Account, error)
+	CreateAccount(ctx context.Context, req *models.CreateAccountRequest) (*models.Account, error)
+	CloseAccount(ctx context.Context, userId, accountId uint) error
+	Transfer(ctx context.Context, req *models.TransferRequest) (*models.Account, *models.Account, error)
+	Statement(ctx context.Context, req *models.StatementRequest, begin func(opening map[string]float32) error, emit func(operation models.OperationRecord) error) error
+}
+
+// StoreOrders defines the interface for limit order database operations.
+// It includes methods for creating, listing and cancelling orders of a user,
+// and the methods used by the matcher to execute and expire open orders.
+type StoreOrders interface {
+	CreateOrder(ctx context.Context, req *models.CreateOrderRequest) (*models.Order, error)
+	UserOrders(ctx context.Context, userId uint, status string) ([]models.Order, error)
+	UserOrder(ctx context.Context, userId, orderId uint) (*models.Order, []models.OrderEvent, error)
+	CancelOrder(ctx context.Context, userId, orderId uint) (*models.Order, error)
+	OpenOrders(ctx context.Context) ([]models.Order, error)
+	ExecuteOrder(ctx context.Context, orderId uint, result *models.CurrencyExchangeResult) error
+	ExpireOrders(ctx context.Context) (int, error)
+}
+
+// StoreHolds defines the interface for the database operations of the holds of the funds.
+// It includes methods for creating, listing, capturing and releasing the holds of a user,
+// and the method used by the expirer to release the expired holds.
+type StoreHolds interface {
+	CreateHold(ctx context.Context, req *models.CreateHoldRequest) (*models.Hold, error)
+	UserHolds(ctx context.Context, userId uint, status string) ([]models.Hold, error)
+	UserHold(ctx context.Context, userId, holdId uint) (*models.Hold, error)
+	CaptureHold(ctx context.Context, userId, holdId uint, amount float32) (*models.Hold, error)
+	ReleaseHold(ctx context.Context, userId, holdId uint) (*models.Hold, error)
+	ExpireHolds(ctx context.Context) (int, error)
+}
+
+// StoreSchedules defines the interface for scheduled operation database operations.
+// It includes methods for managing the schedules of a user,
+// and the methods used by the workers to claim the due schedules and record the result of a run.
+type StoreSchedules interface {
+	CreateSchedule(ctx context.Context, schedule *models.Schedule) error
+	UserSchedules(ctx context.Context, userId uint) ([]models.Schedule, error)
+	UserSchedule(ctx context.Context, userId, scheduleId uint) (*models.Schedule, []models.ScheduleRun, error)
+	UpdateSchedule(ctx context.Context, schedule *models.Schedule) error
+	DeleteSchedule(ctx context.Context, userId, scheduleId uint) error
+	ClaimDueSchedules(ctx context.Context, limit int, lease time.Duration) ([]models.Schedule, error)
+	FinishScheduleRun(ctx context.Context, schedule *models.Schedule, run *models.ScheduleRun) error
+}
+
+// StoreWebhooks defines the interface for webhook database operations.
+// It includes methods for managing the webhook endpoints of a user and their delivery logs,
+// and the methods used by the delivery worker to claim pending deliveries and record the result of an attempt.
+type StoreWebhooks interface {
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	UserWebhooks(ctx context.Context, userId uint) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, userId, webhookId uint) error
+	WebhookDeliveries(ctx context.Context, userId, webhookId uint, status string) ([]models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, userId, webhookId uint, deliveryId uint64) (*models.WebhookDelivery, error)
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookJob, error)
+	FinishWebhookDelivery(ctx context.Context, attempt *models.WebhookAttempt) error
+}
+
+// StoreOutbox defines the interface for the transactional outbox database operations.
+// RelayOutbox passes the pending events to publish in order and marks the published ones,
+// a failed event waits for the next attempt chosen by retry (or is dead-lettered without it)
+// and the following events of the same user wait for it.
+type StoreOutbox interface {
+	RelayOutbox(ctx context.Context, limit int, publish func(event models.OutboxEvent) error, retry func(attempts int) *time.Time) (int, error)
+}
+
+// StoreBalances defines the interface for the database operations of the balance notifications.
+type StoreBalances interface {
+	AllAccountsBalance(ctx context.Context, userId uint) (map[string]float32, error)
 }
 
 // CacheDB defines the interface for cache-related operations.
-// It includes methods for setting and retrieving exchange rates.
+// It includes methods for setting and retrieving exchange rates together with the time they were received.
 type CacheDB interface {
-	SetExchange(fromCurrency, toCurrency string, value float32) error
-	GetExchange(fromCurrency, toCurrency string) (float32, error)
-}
\ No newline at end of file
+	SetExchange(fromCurrency, toCurrency string, value float32, updatedAt time.Time) error
+	GetExchange(fromCurrency, toCurrency string) (float32, time.Time, error)
+}
+// PubSub defines the interface for the messages between the instances of the server.
+// Subscribe passes the messages of the channels matching the pattern to handle until the context is done.
+type PubSub interface {
+	Publish(channel string, message []byte) error
+	Subscribe(ctx context.Context, pattern string, handle func(channel string, message []byte)) error
+}
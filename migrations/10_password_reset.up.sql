@@ -0,0 +1,14 @@
+-- token_version is put into every access token, increasing it revokes all issued tokens of the user
+ALTER TABLE users ADD COLUMN token_version INT NOT NULL DEFAULT 0;
+
+-- only the SHA-256 hash of a token is stored, the token itself is sent by email
+CREATE TABLE password_resets (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX password_resets_user_idx ON password_resets (user_id, created_at);
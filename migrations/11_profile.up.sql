@@ -0,0 +1,2 @@
+-- the currency the balance is valued in when the client does not ask for another one
+ALTER TABLE users ADD COLUMN display_currency VARCHAR(5) REFERENCES currencies(code) ON DELETE SET NULL;
@@ -0,0 +1,3 @@
+DROP TABLE two_factor_recovery_codes;
+
+DROP TABLE two_factor;
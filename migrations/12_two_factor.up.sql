@@ -0,0 +1,18 @@
+-- secret is the TOTP secret encrypted with AES-GCM, it is enabled only after the first code is confirmed.
+-- last_used_step is the time step of the last accepted code, a code can not be used twice
+CREATE TABLE two_factor (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    enabled_at TIMESTAMPTZ,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- only the SHA-256 hash of a recovery code is stored, every code can be used once
+CREATE TABLE two_factor_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES two_factor(user_id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,2 @@
+-- a deleted user is kept for the history of the operations, the personal data is anonymized at the deletion
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
@@ -0,0 +1,20 @@
+-- the funds of the pockets return to the default accounts
+UPDATE accounts d
+SET balance = d.balance + p.balance
+FROM (
+    SELECT user_id, currency_code, SUM(balance) AS balance
+    FROM accounts
+    WHERE NOT is_default
+    GROUP BY user_id, currency_code
+) p
+WHERE d.is_default AND d.user_id = p.user_id AND d.currency_code = p.currency_code;
+
+ALTER TABLE operations DROP COLUMN account_id;
+DELETE FROM accounts WHERE NOT is_default;
+
+DROP INDEX accounts_user_currency_name_idx;
+DROP INDEX accounts_user_currency_default_idx;
+ALTER TABLE accounts DROP COLUMN created_at;
+ALTER TABLE accounts DROP COLUMN is_default;
+ALTER TABLE accounts DROP COLUMN name;
+ALTER TABLE accounts ADD CONSTRAINT accounts_user_id_currency_code_key UNIQUE (user_id, currency_code);
@@ -0,0 +1,17 @@
+-- a user may have several named accounts (pockets) in one currency, one of them is the default account of the currency.
+-- the existing accounts become the default accounts, the operations without an account ID use them.
+ALTER TABLE accounts ADD COLUMN name VARCHAR(50) NOT NULL DEFAULT 'main';
+ALTER TABLE accounts ADD COLUMN is_default BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE accounts ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+UPDATE accounts SET is_default = TRUE;
+
+ALTER TABLE accounts DROP CONSTRAINT accounts_user_id_currency_code_key;
+CREATE UNIQUE INDEX accounts_user_currency_default_idx ON accounts (user_id, currency_code) WHERE is_default;
+CREATE UNIQUE INDEX accounts_user_currency_name_idx ON accounts (user_id, currency_code, name);
+
+-- the account of the balance change, the history before the pockets belongs to the default accounts
+ALTER TABLE operations ADD COLUMN account_id INT REFERENCES accounts(id) ON DELETE SET NULL;
+UPDATE operations o
+SET account_id = a.id
+FROM accounts a
+WHERE a.user_id = o.user_id AND a.currency_code = o.currency_code AND a.is_default;
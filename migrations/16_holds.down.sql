@@ -0,0 +1,3 @@
+DROP TABLE holds;
+ALTER TABLE accounts DROP CONSTRAINT accounts_held_check;
+ALTER TABLE accounts DROP COLUMN held;
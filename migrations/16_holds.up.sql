@@ -0,0 +1,21 @@
+-- the amount of an open hold stays on the account but is not available, held is the sum of the open holds of the account
+ALTER TABLE accounts ADD COLUMN held DECIMAL(15, 2) NOT NULL DEFAULT 0.00;
+ALTER TABLE accounts ADD CONSTRAINT accounts_held_check CHECK (held >= 0 AND held <= balance);
+
+-- a hold reserves funds of an account until it is captured (in full or in part), released or expired
+CREATE TABLE holds (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id INT REFERENCES accounts(id) ON DELETE SET NULL,
+    currency_code VARCHAR(5) NOT NULL REFERENCES currencies(code) ON DELETE RESTRICT,
+    amount DECIMAL(15, 2) NOT NULL,
+    captured DECIMAL(15, 2), -- amount debited by the capture, the rest is released
+    reference VARCHAR(100) NOT NULL DEFAULT '', -- reference of the merchant, e.g. the order number
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- open, captured, released, expired
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    closed_at TIMESTAMPTZ
+);
+
+CREATE INDEX holds_user_id_idx ON holds (user_id);
+CREATE INDEX holds_open_idx ON holds (expires_at) WHERE status = 'open';
@@ -0,0 +1,7 @@
+-- idempotency keys of the wallet operations, a key is inserted in the transaction of its operation,
+-- so an operation with a key is executed at most once even if its caller retries it (the runs of the schedules)
+CREATE TABLE operation_keys (
+    key VARCHAR(100) PRIMARY KEY,
+    user_id INT REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
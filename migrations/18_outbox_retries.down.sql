@@ -0,0 +1,6 @@
+DROP INDEX outbox_pending_user_idx;
+DROP INDEX outbox_pending_idx;
+CREATE INDEX outbox_unpublished_idx ON outbox (id) WHERE published_at IS NULL;
+
+ALTER TABLE outbox DROP COLUMN dead_at;
+ALTER TABLE outbox DROP COLUMN next_attempt_at;
@@ -0,0 +1,8 @@
+-- a failed event waits until next_attempt_at, the later events of its user wait for it,
+-- after the last attempt the event is dead-lettered: it is kept with its last error and no longer published
+ALTER TABLE outbox ADD COLUMN next_attempt_at TIMESTAMPTZ;
+ALTER TABLE outbox ADD COLUMN dead_at TIMESTAMPTZ;
+
+DROP INDEX outbox_unpublished_idx;
+CREATE INDEX outbox_pending_idx ON outbox (id) WHERE published_at IS NULL AND dead_at IS NULL;
+CREATE INDEX outbox_pending_user_idx ON outbox (user_id, id) WHERE published_at IS NULL AND dead_at IS NULL;
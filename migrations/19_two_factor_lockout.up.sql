@@ -0,0 +1,4 @@
+-- failed_attempts counts the codes checked since the last accepted one,
+-- after too many of them the codes of the user are not checked until locked_until
+ALTER TABLE two_factor ADD COLUMN failed_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE two_factor ADD COLUMN locked_until TIMESTAMPTZ;
@@ -0,0 +1,5 @@
+DROP TABLE fee_ledger;
+DROP TABLE revenue_accounts;
+
+ALTER TABLE users DROP COLUMN role;
+ALTER TABLE users DROP COLUMN segment;
@@ -0,0 +1,25 @@
+ALTER TABLE users ADD COLUMN segment VARCHAR(20) NOT NULL DEFAULT 'standard'; -- fee tier of the user
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user'; -- 'admin' gets access to the reports
+
+-- system account where the exchange fees are collected, one per currency
+CREATE TABLE revenue_accounts (
+    currency_code VARCHAR(5) PRIMARY KEY REFERENCES currencies(code) ON DELETE RESTRICT,
+    balance DECIMAL(15, 2) NOT NULL DEFAULT 0.00
+);
+
+INSERT INTO revenue_accounts (currency_code)
+SELECT code FROM currencies;
+
+-- every fee charged, used for the revenue report
+CREATE TABLE fee_ledger (
+    id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    from_currency VARCHAR(5) NOT NULL REFERENCES currencies(code) ON DELETE RESTRICT,
+    currency_code VARCHAR(5) NOT NULL REFERENCES currencies(code) ON DELETE RESTRICT, -- currency in which the fee was charged
+    amount DECIMAL(15, 2) NOT NULL,
+    mid_rate DECIMAL(18, 8) NOT NULL,
+    applied_rate DECIMAL(18, 8) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX fee_ledger_created_at_idx ON fee_ledger (created_at);
@@ -0,0 +1,2 @@
+DROP TABLE transaction_limits;
+DROP TABLE operations;
@@ -0,0 +1,41 @@
+-- history of all balance changes, one row per changed account
+CREATE TABLE operations (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(id) ON DELETE CASCADE,
+    operation_type VARCHAR(20) NOT NULL, -- opening, deposit, withdraw, exchange
+    currency_code VARCHAR(5) NOT NULL REFERENCES currencies(code) ON DELETE RESTRICT,
+    amount DECIMAL(15, 2) NOT NULL, -- signed change of the balance
+    balance_after DECIMAL(15, 2) NOT NULL,
+    counter_currency VARCHAR(5) REFERENCES currencies(code) ON DELETE RESTRICT, -- the other currency of an exchange
+    exchange_rate DECIMAL(18, 8), -- applied rate of an exchange
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX operations_user_currency_created_at_idx ON operations (user_id, currency_code, created_at);
+
+-- balances that existed before the history was kept
+INSERT INTO operations (user_id, operation_type, currency_code, amount, balance_after)
+SELECT user_id, 'opening', currency_code, balance, balance
+FROM accounts
+WHERE balance <> 0;
+
+-- NULL limit means the operation is not limited for the period
+CREATE TABLE transaction_limits (
+    segment VARCHAR(20) NOT NULL,
+    currency_code VARCHAR(5) NOT NULL REFERENCES currencies(code) ON DELETE CASCADE,
+    operation_type VARCHAR(20) NOT NULL, -- deposit, withdraw, exchange
+    daily_limit DECIMAL(15, 2),
+    monthly_limit DECIMAL(15, 2),
+    PRIMARY KEY (segment, currency_code, operation_type)
+);
+
+INSERT INTO transaction_limits (segment, currency_code, operation_type, daily_limit, monthly_limit)
+SELECT s.segment, c.code, o.operation_type, c.daily_limit * s.multiplier, c.monthly_limit * s.multiplier
+FROM (VALUES
+        ('USD', 10000, 100000),
+        ('EUR', 10000, 100000),
+        ('CNY', 70000, 700000),
+        ('RUB', 1000000, 10000000)
+    ) AS c (code, daily_limit, monthly_limit)
+CROSS JOIN (VALUES ('standard', 1), ('vip', 10)) AS s (segment, multiplier)
+CROSS JOIN (VALUES ('deposit'), ('withdraw'), ('exchange')) AS o (operation_type);
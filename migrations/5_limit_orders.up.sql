@@ -0,0 +1,31 @@
+-- the amount of an open order is reserved, it is debited from the account when the order is created
+CREATE TABLE orders (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_currency VARCHAR(5) NOT NULL REFERENCES currencies(code) ON DELETE RESTRICT,
+    to_currency VARCHAR(5) NOT NULL REFERENCES currencies(code) ON DELETE RESTRICT,
+    amount DECIMAL(15, 2) NOT NULL,
+    target_rate DECIMAL(18, 8) NOT NULL, -- minimal applied rate at which the order is executed
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- open, filled, cancelled, expired
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    executed_rate DECIMAL(18, 8),
+    received DECIMAL(15, 2),
+    fee DECIMAL(15, 2),
+    closed_at TIMESTAMPTZ
+);
+
+CREATE INDEX orders_user_id_idx ON orders (user_id);
+CREATE INDEX orders_open_idx ON orders (from_currency, to_currency) WHERE status = 'open';
+
+-- audit trail of every order
+CREATE TABLE order_events (
+    id BIGSERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    event VARCHAR(20) NOT NULL, -- created, filled, cancelled, expired
+    rate DECIMAL(18, 8), -- rate at which the event happened
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX order_events_order_id_idx ON order_events (order_id);
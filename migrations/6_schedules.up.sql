@@ -0,0 +1,35 @@
+-- a schedule runs a deposit or an exchange by a cron expression (UTC) or at a fixed interval
+CREATE TABLE schedules (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    operation VARCHAR(20) NOT NULL, -- deposit, exchange
+    currency_code VARCHAR(5) NOT NULL REFERENCES currencies(code) ON DELETE RESTRICT,
+    to_currency VARCHAR(5) REFERENCES currencies(code) ON DELETE RESTRICT, -- target currency of an exchange
+    amount DECIMAL(15, 2) NOT NULL,
+    cron_expr VARCHAR(100),
+    interval_seconds BIGINT,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ,
+    status VARCHAR(20) NOT NULL DEFAULT 'active', -- active, paused, finished
+    due_at TIMESTAMPTZ, -- occurrence that is executed next
+    next_run_at TIMESTAMPTZ, -- time of the next attempt, later than due_at when a failed run is retried
+    retry_count INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMPTZ, -- lease of the instance that executes the schedule
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((cron_expr IS NULL) <> (interval_seconds IS NULL))
+);
+
+CREATE INDEX schedules_user_id_idx ON schedules (user_id);
+CREATE INDEX schedules_due_idx ON schedules (next_run_at) WHERE status = 'active';
+
+-- result of every run of a schedule
+CREATE TABLE schedule_runs (
+    id BIGSERIAL PRIMARY KEY,
+    schedule_id INT NOT NULL REFERENCES schedules(id) ON DELETE CASCADE,
+    scheduled_for TIMESTAMPTZ NOT NULL,
+    attempt INT NOT NULL,
+    status VARCHAR(20) NOT NULL, -- success, failed
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (schedule_id, scheduled_for, attempt)
+);
@@ -0,0 +1,14 @@
+-- domain events written in the same transaction as the change they describe,
+-- user_id has no foreign key so that the events of a deleted user are kept until they are published
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX outbox_unpublished_idx ON outbox (id) WHERE published_at IS NULL;
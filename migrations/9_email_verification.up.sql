@@ -0,0 +1,17 @@
+-- the users created before the verification are treated as verified
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+UPDATE users SET email_verified_at = NOW();
+
+-- only the SHA-256 hash of a token is stored, the token itself is sent by email.
+-- email is the address being confirmed, it becomes the email of the user when the token is used
+CREATE TABLE email_verifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(100) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX email_verifications_user_idx ON email_verifications (user_id, created_at);
@@ -0,0 +1,9 @@
+// Package migrations embeds the SQL migrations of the database, so that the server can apply them itself.
+package migrations
+
+import "embed"
+
+// FS holds the up and down files of all migrations.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -1,16 +1,27 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type User struct {
-	ID           uint   `json:"id"`
-	Name         string `json:"name"`
-	Email        string `json:"email"`
-	HashPassword string `json:"password"`
+	ID              uint   `json:"id"`
+	Name            string `json:"name"`
+	Email           string `json:"email"`
+	HashPassword    string `json:"password"`
+	Role            string `json:"role"`
+	Segment         string `json:"segment"`
+	EmailVerified   bool   `json:"email_verified"`
+	DisplayCurrency string `json:"display_currency"`
+	TokenVersion    int    `json:"-"`
 }
 
 type RegisterRequest struct {
 	Email        string `json:"email" binding:"required,email" example:"john.doe@example.com"`
 	Name         string `json:"username" binding:"required,min=3" example:"john"`
 	HashPassword string `json:"password" binding:"required,min=6" example:"123456"`
+	Verified     bool   `json:"-"`
 }
 
 type RegisterResponse struct {
@@ -18,32 +29,325 @@ type RegisterResponse struct {
 	Message string `json:"message" example:"user successfully created"`
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required,len=64,hexadecimal" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+}
+
+type ResendVerificationRequest struct {
+	UserID uint `json:"-"`
+}
+
+// EmailVerification is a verification token of an email address, only the hash of the token is stored.
+type EmailVerification struct {
+	UserID    uint
+	Email     string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email" example:"john.doe@example.com"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required,len=64,hexadecimal" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Password string `json:"password" binding:"required,min=6" example:"654321"`
+}
+
+// PasswordReset is a reset token of a password, only the hash of the token is stored.
+type PasswordReset struct {
+	UserID    uint
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+type ProfileResponse struct {
+	ID              uint   `json:"id" example:"1"`
+	Name            string `json:"name" example:"john"`
+	Email           string `json:"email" example:"john.doe@example.com"`
+	EmailVerified   bool   `json:"email_verified" example:"true"`
+	DisplayCurrency string `json:"display_currency,omitempty" example:"USD"`
+	Role            string `json:"role" example:"user"`
+	Segment         string `json:"segment" example:"standard"`
+}
+
+// UpdateProfileRequest changes only the passed fields, an empty display currency removes it.
+type UpdateProfileRequest struct {
+	UserID          uint    `json:"-"`
+	Name            *string `json:"name" binding:"omitempty,min=3,max=100" example:"john"`
+	DisplayCurrency *string `json:"display_currency" binding:"omitempty,min=3,max=5" example:"EUR"`
+}
+
+type ChangePasswordRequest struct {
+	UserID          uint   `json:"-"`
+	CurrentPassword string `json:"current_password" binding:"required" example:"123456"`
+	NewPassword     string `json:"new_password" binding:"required,min=6" example:"654321"`
+}
+
+type ChangeEmailRequest struct {
+	UserID   uint   `json:"-"`
+	Email    string `json:"email" binding:"required,email,max=100" example:"john.new@example.com"`
+	Password string `json:"password" binding:"required" example:"123456"`
+}
+
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email" example:"john.doe@example.com"`
 	Password string `json:"password" binding:"required" example:"123456"`
 }
 
+// LoginResponse has the token, or the challenge for the second step if the two-factor authentication is enabled.
 type LoginResponse struct {
-	Token string `json:"token" example:"JWT-token"`
+	Token             string `json:"token,omitempty" example:"JWT-token"`
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty" example:"false"`
+	Challenge         string `json:"challenge,omitempty" example:"JWT-challenge"`
+}
+
+type LoginTwoFactorRequest struct {
+	Challenge string `json:"challenge" binding:"required" example:"JWT-challenge"`
+	Code      string `json:"code" binding:"required,min=6,max=20" example:"123456"`
+}
+
+// TwoFactor is the two-factor authentication of a user, Secret is encrypted.
+type TwoFactor struct {
+	UserID       uint
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
 }
 
+type TwoFactorEnrollResponse struct {
+	Secret          string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	ProvisioningURI string `json:"provisioning_uri" example:"otpauth://totp/Exchanger:john.doe@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Exchanger"`
+}
+
+// TwoFactorCodeRequest has a TOTP code or a recovery code.
+type TwoFactorCodeRequest struct {
+	UserID uint   `json:"-"`
+	Code   string `json:"code" binding:"required,min=6,max=20" example:"123456"`
+}
+
+type TwoFactorConfirmResponse struct {
+	Message       string   `json:"message" example:"two-factor authentication enabled"`
+	RecoveryCodes []string `json:"recovery_codes" example:"k3x9q-7hb2m"`
+}
+
+// DeleteUserRequest is the deletion of the user, the remaining funds are swept to SweepTo:
+// "withdraw" withdraws them, "user" moves them to the accounts of the user with SweepEmail.
+type DeleteUserRequest struct {
+	UserID        uint   `json:"-"`
+	TwoFactorCode string `json:"-"`
+	SweepTo       string `json:"sweep_to" binding:"omitempty,oneof=withdraw user" example:"user"`
+	SweepEmail    string `json:"sweep_email" binding:"omitempty,email" example:"jane.doe@example.com"`
+}
+
+type DeleteUserResponse struct {
+	Message string             `json:"message" example:"user successfully deleted"`
+	Swept   map[string]float32 `json:"swept,omitempty"`
+}
+
+// OperationRecord is a balance change of the history of the user.
+type OperationRecord struct {
+	ID              uint64    `json:"id" example:"42"`
+	AccountID       uint      `json:"account_id,omitempty" example:"12"`
+	Type            string    `json:"type" example:"exchange"`
+	Currency        string    `json:"currency" example:"USD"`
+	Amount          float32   `json:"amount" example:"-500"`
+	BalanceAfter    float32   `json:"balance_after" example:"1000"`
+	CounterCurrency string    `json:"counter_currency,omitempty" example:"EUR"`
+	ExchangeRate    float32   `json:"exchange_rate,omitempty" example:"0.9164"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// StatementRequest asks for the statement of the operations from From (inclusive) to To (exclusive),
+// optionally of one currency, as CSV or JSON.
+type StatementRequest struct {
+	UserID   uint      `json:"-"`
+	From     time.Time `form:"from" binding:"required" time_format:"2006-01-02" example:"2025-01-01"`
+	To       time.Time `form:"to" binding:"required" time_format:"2006-01-02" example:"2025-02-01"`
+	Currency string    `form:"currency" binding:"omitempty,min=3,max=6" example:"USD"`
+	Format   string    `form:"format,default=json" binding:"oneof=csv json" example:"csv"`
+}
+
+// StatementLine is one operation of the statement. OpeningBalance and ClosingBalance are the balances
+// of the account of the operation before and after it, RunningBalance is the balance of the currency
+// (all accounts) after it. Rate is the rate of an exchange, CounterCurrency is its other currency.
+type StatementLine struct {
+	OperationID     uint64    `json:"operation_id" example:"42"`
+	CreatedAt       time.Time `json:"created_at"`
+	Type            string    `json:"type" example:"exchange"`
+	Currency        string    `json:"currency" example:"USD"`
+	AccountID       uint      `json:"account_id,omitempty" example:"12"`
+	Amount          float32   `json:"amount" example:"-500"`
+	OpeningBalance  float32   `json:"opening_balance" example:"1500"`
+	ClosingBalance  float32   `json:"closing_balance" example:"1000"`
+	CounterCurrency string    `json:"counter_currency,omitempty" example:"EUR"`
+	Rate            float32   `json:"rate,omitempty" example:"0.9164"`
+	RunningBalance  float32   `json:"running_balance" example:"1350"`
+}
+
+// Statement is the JSON statement of the operations, the balances of every currency at From and at To
+// are computed from the history of the operations.
+type Statement struct {
+	From           time.Time          `json:"from"`
+	To             time.Time          `json:"to"`
+	Currency       string             `json:"currency,omitempty" example:"USD"`
+	OpeningBalance map[string]float32 `json:"opening_balance"`
+	Operations     []StatementLine    `json:"operations"`
+	ClosingBalance map[string]float32 `json:"closing_balance"`
+}
+
+// UserExport is the archive of the data of the user for the data portability requests.
+type UserExport struct {
+	ExportedAt time.Time         `json:"exported_at"`
+	Profile    ProfileResponse   `json:"profile"`
+	Accounts   []Account         `json:"accounts"`
+	Operations []OperationRecord `json:"operations"`
+	Orders     []Order           `json:"orders"`
+	Holds      []Hold            `json:"holds"`
+	Schedules  []Schedule        `json:"schedules"`
+	Webhooks   []Webhook         `json:"webhooks"`
+	APIKeys    []APIKey          `json:"api_keys"`
+}
+
+// ReconcileReport is the result of the reconciliation of the balances with the history of the operations.
+// Balance is the stored balance, Expected is the sum of the recorded operations.
+type ReconcileReport struct {
+	CheckedAt         time.Time          `json:"checked_at"`
+	Accounts          int                `json:"accounts"`
+	Mismatches        []BalanceMismatch  `json:"mismatches"`
+	AccountMismatches []BalanceMismatch  `json:"account_mismatches"`
+	NegativeBalances  []ReconcileAccount `json:"negative_balances"`
+	OrphanedAccounts  []ReconcileAccount `json:"orphaned_accounts"`
+}
+
+// Discrepancies returns the number of the problems found by the reconciliation.
+func (r *ReconcileReport) Discrepancies() int {
+	return len(r.Mismatches) + len(r.AccountMismatches) + len(r.NegativeBalances) + len(r.OrphanedAccounts)
+}
+
+// BalanceMismatch is a balance that differs from the history, AccountID is set for a mismatch of one account,
+// otherwise the balances of all accounts of the user in the currency are summed.
+type BalanceMismatch struct {
+	UserID     uint    `json:"user_id"`
+	AccountID  uint    `json:"account_id,omitempty"`
+	Currency   string  `json:"currency"`
+	Balance    float64 `json:"balance"`
+	Expected   float64 `json:"expected"`
+	Difference float64 `json:"difference"`
+}
+
+// ReconcileAccount is an account with a problem, Reason explains it.
+type ReconcileAccount struct {
+	AccountID uint    `json:"account_id"`
+	UserID    uint    `json:"user_id,omitempty"`
+	Currency  string  `json:"currency"`
+	Balance   float64 `json:"balance"`
+	Held      float64 `json:"held"`
+	Reason    string  `json:"reason"`
+}
+
+// APIKey is a key of a machine client, only the hash of the key is stored, the prefix is its visible part.
+type APIKey struct {
+	ID         uint       `json:"id" example:"1"`
+	UserID     uint       `json:"-"`
+	Name       string     `json:"name" example:"reporting script"`
+	Prefix     string     `json:"prefix" example:"exk_3fa9c1d2"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes" example:"balance:read,exchange"`
+	Role       string     `json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	UserID    uint       `json:"-"`
+	Name      string     `json:"name" binding:"required,max=100" example:"reporting script"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=balance:read wallet:write exchange orders schedules webhooks admin" example:"balance:read,exchange"`
+	ExpiresAt *time.Time `json:"expires_at" example:"2027-01-01T00:00:00Z"`
+}
+
+type CreateAPIKeyResponse struct {
+	Message string `json:"message" example:"API key successfully created, store the key, it is not shown again"`
+	Key     string `json:"key" example:"exk_3fa9c1d2_9b1f..."`
+	APIKey  APIKey `json:"api_key"`
+}
+
+type APIKeysResponse struct {
+	Message string   `json:"message" example:"API keys successfully received"`
+	APIKeys []APIKey `json:"api_keys"`
+}
+
+type APIKeyRequest struct {
+	UserID uint `json:"-"`
+	KeyID  uint `uri:"id" binding:"required,min=1"`
+}
+
+// JWK is a public key of the access tokens in the JSON Web Key format (RFC 7517),
+// an RSA key has N and E, an Ed25519 key has Crv and X.
+type JWK struct {
+	Kty string `json:"kty" example:"OKP"`
+	Kid string `json:"kid" example:"2026-10"`
+	Use string `json:"use" example:"sig"`
+	Alg string `json:"alg" example:"EdDSA"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty" example:"AQAB"`
+	Crv string `json:"crv,omitempty" example:"Ed25519"`
+	X   string `json:"x,omitempty" example:"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PayloadToken is the authorized user of a request, Scopes are set only for the requests with an API key.
 type PayloadToken struct {
-	UserID uint `json:"id"`
+	UserID       uint     `json:"id"`
+	Role         string   `json:"role"`
+	TokenVersion int      `json:"token_version"`
+	Scopes       []string `json:"scopes,omitempty"`
 }
 
 type BalanceRequest struct {
-	UserID uint `json:"id"`
+	UserID    uint   `json:"id"`
+	Valuation string `json:"-" form:"valuation" binding:"omitempty,min=3,max=6" example:"USD"`
 }
 
+// BalanceResponse holds the total balance of every currency and the available part of it,
+// the amounts of the open holds are not available.
 type BalanceResponse struct {
-	Balance map[string]float32 `json:"balance"`
+	Balance   map[string]float32  `json:"balance"`
+	Available map[string]float32  `json:"available"`
+	Valuation *PortfolioValuation `json:"valuation,omitempty"`
 }
 
+type PortfolioValuation struct {
+	Currency string             `json:"currency" example:"USD"`
+	Total    float32            `json:"total" example:"1520.35"`
+	Accounts []AccountValuation `json:"accounts"`
+}
+
+// AccountValuation is the value of one account in the valuation currency.
+// RateUpdatedAt is the time when the rate was received from the rate service, it is empty for the valuation currency itself.
+type AccountValuation struct {
+	Currency      string     `json:"currency" example:"EUR"`
+	Balance       float32    `json:"balance" example:"500"`
+	Rate          float32    `json:"rate" example:"1.04"`
+	Value         float32    `json:"value" example:"520"`
+	RateUpdatedAt *time.Time `json:"rate_updated_at,omitempty"`
+}
+
+// AccountOperationRequest is a deposit or a withdrawal, without AccountID the default account of the currency is used.
 type AccountOperationRequest struct {
-	UserID    uint    `json:"-"`
-	Amount    float32 `json:"amount" binding:"required,gt=0" example:"2000"`
-	Currency  string  `json:"currency" binding:"required,min=3,max=6" example:"USD"`
-	Operation string  `json:"-"`
+	UserID        uint    `json:"-"`
+	Amount        float32 `json:"amount" binding:"required,gt=0" example:"2000"`
+	Currency      string  `json:"currency" binding:"required,min=3,max=6" example:"USD"`
+	AccountID     uint    `json:"account_id" binding:"omitempty,gt=0" example:"12"`
+	Operation     string  `json:"-"`
+	TwoFactorCode string  `json:"-"`
+	// IdempotencyKey makes the operation run at most once, it is set by the internal callers only
+	IdempotencyKey string `json:"-"`
 }
 
 type AccountOperationResponse struct {
@@ -51,27 +355,89 @@ type AccountOperationResponse struct {
 	NewBalance map[string]float32 `json:"new_balance"`
 }
 
+// Account is a named account (pocket) of the user, every currency has one default account.
+// Held is the sum of the open holds of the account, Available is the balance without it.
+type Account struct {
+	ID        uint      `json:"id" example:"12"`
+	Currency  string    `json:"currency" example:"EUR"`
+	Name      string    `json:"name" example:"travel"`
+	Default   bool      `json:"default" example:"false"`
+	Balance   float32   `json:"balance" example:"350"`
+	Held      float32   `json:"held" example:"50"`
+	Available float32   `json:"available" example:"300"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateAccountRequest struct {
+	UserID   uint   `json:"-"`
+	Currency string `json:"currency" binding:"required,min=3,max=6" example:"EUR"`
+	Name     string `json:"name" binding:"required,max=50" example:"travel"`
+}
+
+type AccountRequest struct {
+	UserID    uint `json:"-"`
+	AccountID uint `uri:"id" binding:"required,min=1"`
+}
+
+type AccountsRequest struct {
+	UserID uint `json:"-"`
+}
+
+type AccountResponse struct {
+	Message string  `json:"message" example:"text message"`
+	Account Account `json:"account"`
+}
+
+type AccountsResponse struct {
+	Message  string    `json:"message" example:"text message"`
+	Accounts []Account `json:"accounts"`
+}
+
+// TransferRequest is a move of funds between two accounts of the user in the same currency.
+type TransferRequest struct {
+	UserID        uint    `json:"-"`
+	FromAccountID uint    `json:"from_account_id" binding:"required,gt=0" example:"1"`
+	ToAccountID   uint    `json:"to_account_id" binding:"required,gt=0" example:"12"`
+	Amount        float32 `json:"amount" binding:"required,gt=0" example:"100"`
+}
+
+type TransferResponse struct {
+	Message     string  `json:"message" example:"text message"`
+	FromAccount Account `json:"from_account"`
+	ToAccount   Account `json:"to_account"`
+}
+
 type ExchangeRatesResponse struct {
 	Message string             `json:"message" example:"text message"`
 	Rates   map[string]float32 `json:"rates"`
 }
 
+// ExchangeRequest is an exchange between two accounts of the user,
+// without an account ID the default account of the currency is used.
 type ExchangeRequest struct {
-	UserID       uint    `json:"-"`
-	FromCurrency string  `json:"from_currency" binding:"required,min=3,max=6" example:"USD"`
-	ToCurrency   string  `json:"to_currency" binding:"required,min=3,max=6" example:"CNY"`
-	Amount       float32 `json:"amount" binding:"required,gt=0" example:"500"`
+	UserID        uint    `json:"-"`
+	FromCurrency  string  `json:"from_currency" binding:"required,min=3,max=6" example:"USD"`
+	ToCurrency    string  `json:"to_currency" binding:"required,min=3,max=6" example:"CNY"`
+	Amount        float32 `json:"amount" binding:"required,gt=0" example:"500"`
+	FromAccountID uint    `json:"from_account_id" binding:"omitempty,gt=0" example:"12"`
+	ToAccountID   uint    `json:"to_account_id" binding:"omitempty,gt=0" example:"14"`
+	// IdempotencyKey makes the exchange run at most once, it is set by the internal callers only
+	IdempotencyKey string `json:"-"`
 }
 
 type ExchangeRate struct {
-	FromCurrency string  `json:"from_currency" binding:"required"`
-	ToCurrency   string  `json:"to_currency" binding:"required"`
-	Rate         float32 `json:"rate" binding:"required"`
+	FromCurrency string    `json:"from_currency" binding:"required"`
+	ToCurrency   string    `json:"to_currency" binding:"required"`
+	Rate         float32   `json:"rate" binding:"required"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 type ExchangeResponse struct {
 	Message         string             `json:"message" example:"text message"`
 	ExchangeRate    float32            `json:"exchange_rate" example:"7.424683"`
+	MidRate         float32            `json:"mid_rate" example:"7.424683"`
+	AppliedRate     float32            `json:"applied_rate" example:"7.387560"`
+	Fee             ExchangeFee        `json:"fee"`
 	SpentAccoutn    SpentAccoutn       `json:"spent_accoutn"`
 	ReceivedAccount ReceivedAccount    `json:"received_account"`
 	NewBalance      map[string]float32 `json:"new_balance"`
@@ -84,27 +450,465 @@ type HandlerResponse struct {
 }
 
 type CurrencyExchangeData struct {
-	BaseBalance  float32
-	ToBalance    float32
-	ExchangeRate float32
-	Amount       float32
+	BaseBalance   float32
+	ToBalance     float32
+	ExchangeRate  float32
+	Amount        float32
+	SpreadPercent float32
+	MinFee        float32
 }
 
+// CurrencyExchangeResult is a calculated exchange, the accounts with zero IDs are the default accounts of the currencies.
+// The database writes the IDs and the balances of the changed accounts back.
 type CurrencyExchangeResult struct {
 	UserID         uint
+	FromAccountID  uint
+	ToAccountID    uint
 	BaseCurrency   string
 	NewBaseBalance float32
 	ToCurrency     string
 	NewToBalance   float32
 	Received       float32
+	Fee            float32
+	Amount         float32
+	MidRate        float32
+	AppliedRate    float32
+	IdempotencyKey string
 }
 
 type SpentAccoutn struct {
-	Currency string  `json:"currency" example:"USD"`
-	Amount   float32 `json:"amount" example:"500"`
+	AccountID uint    `json:"account_id,omitempty" example:"12"`
+	Currency  string  `json:"currency" example:"USD"`
+	Amount    float32 `json:"amount" example:"500"`
 }
 
 type ReceivedAccount struct {
+	AccountID uint    `json:"account_id,omitempty" example:"14"`
+	Currency  string  `json:"currency" example:"CNY"`
+	Amount    float32 `json:"amount" example:"3636.30"`
+}
+
+type ExchangeFee struct {
 	Currency string  `json:"currency" example:"CNY"`
-	Amount   float32 `json:"amount" example:"3636.30"`
+	Amount   float32 `json:"amount" example:"18.56"`
+}
+
+type FeeReportRequest struct {
+	From time.Time `form:"from" binding:"required" time_format:"2006-01-02" example:"2025-01-01"`
+	To   time.Time `form:"to" binding:"required" time_format:"2006-01-02" example:"2025-02-01"`
+}
+
+type FeeReportResponse struct {
+	Message string       `json:"message" example:"text message"`
+	From    time.Time    `json:"from"`
+	To      time.Time    `json:"to"`
+	Revenue []FeeRevenue `json:"revenue"`
+}
+
+type FeeRevenue struct {
+	Currency  string  `json:"currency" example:"CNY"`
+	Total     float32 `json:"total" example:"1250.40"`
+	Exchanges int     `json:"exchanges" example:"42"`
+	Balance   float32 `json:"revenue_account_balance" example:"5230.75"`
+}
+
+type LimitsRequest struct {
+	UserID uint `json:"id"`
+}
+
+type LimitsResponse struct {
+	Message string       `json:"message" example:"text message"`
+	Segment string       `json:"segment" example:"standard"`
+	Limits  []LimitUsage `json:"limits"`
+}
+
+// LimitUsage shows the limit of one operation in one currency and how much of it has been used.
+// A nil limit means that the operation is not limited for the period.
+type LimitUsage struct {
+	Operation        string   `json:"operation" example:"withdraw"`
+	Currency         string   `json:"currency" example:"USD"`
+	DailyLimit       *float32 `json:"daily_limit" example:"10000"`
+	DailyUsed        float32  `json:"daily_used" example:"2500"`
+	DailyRemaining   *float32 `json:"daily_remaining" example:"7500"`
+	MonthlyLimit     *float32 `json:"monthly_limit" example:"100000"`
+	MonthlyUsed      float32  `json:"monthly_used" example:"12500"`
+	MonthlyRemaining *float32 `json:"monthly_remaining" example:"87500"`
+}
+
+type LimitExceededResponse struct {
+	Status    int     `json:"status"`
+	Error     string  `json:"error" example:"text error"`
+	Message   string  `json:"message" example:"text message"`
+	Operation string  `json:"operation" example:"withdraw"`
+	Currency  string  `json:"currency" example:"USD"`
+	Period    string  `json:"period" example:"daily"`
+	Limit     float32 `json:"limit" example:"10000"`
+	Remaining float32 `json:"remaining" example:"750"`
+}
+
+// ExchangeBatchRequest is either a list of legs or a target currency into which all non-zero accounts are converted.
+type ExchangeBatchRequest struct {
+	UserID        uint          `json:"-"`
+	Legs          []ExchangeLeg `json:"legs" binding:"omitempty,max=20,dive"`
+	ConsolidateTo string        `json:"consolidate_to" binding:"omitempty,min=3,max=6" example:"USD"`
+}
+
+type ExchangeLeg struct {
+	FromCurrency  string  `json:"from_currency" binding:"required,min=3,max=6" example:"USD"`
+	ToCurrency    string  `json:"to_currency" binding:"required,min=3,max=6" example:"CNY"`
+	Amount        float32 `json:"amount" binding:"required,gt=0" example:"500"`
+	FromAccountID uint    `json:"from_account_id" binding:"omitempty,gt=0" example:"12"`
+	ToAccountID   uint    `json:"to_account_id" binding:"omitempty,gt=0" example:"14"`
+}
+
+type ExchangeBatchResponse struct {
+	Message    string              `json:"message" example:"text message"`
+	Legs       []ExchangeLegResult `json:"legs"`
+	NewBalance map[string]float32  `json:"new_balance"`
+}
+
+type ExchangeLegResult struct {
+	MidRate         float32         `json:"mid_rate" example:"7.424683"`
+	AppliedRate     float32         `json:"applied_rate" example:"7.387560"`
+	Fee             ExchangeFee     `json:"fee"`
+	SpentAccoutn    SpentAccoutn    `json:"spent_accoutn"`
+	ReceivedAccount ReceivedAccount `json:"received_account"`
+}
+
+// Order is a limit order, the amount is reserved while the order is open.
+// It is executed when the applied rate (after the fee) reaches the target rate.
+type Order struct {
+	ID           uint       `json:"id" example:"1"`
+	UserID       uint       `json:"-"`
+	FromCurrency string     `json:"from_currency" example:"USD"`
+	ToCurrency   string     `json:"to_currency" example:"EUR"`
+	Amount       float32    `json:"amount" example:"1000"`
+	TargetRate   float32    `json:"target_rate" example:"0.95"`
+	Status       string     `json:"status" example:"open"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExecutedRate *float32   `json:"executed_rate,omitempty" example:"0.9512"`
+	Received     *float32   `json:"received,omitempty" example:"951.2"`
+	Fee          *float32   `json:"fee,omitempty" example:"4.78"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+}
+
+type OrderEvent struct {
+	Event     string    `json:"event" example:"created"`
+	Rate      *float32  `json:"rate,omitempty" example:"0.9512"`
+	Details   string    `json:"details" example:"text details"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateOrderRequest struct {
+	UserID       uint       `json:"-"`
+	FromCurrency string     `json:"from_currency" binding:"required,min=3,max=6" example:"USD"`
+	ToCurrency   string     `json:"to_currency" binding:"required,min=3,max=6" example:"EUR"`
+	Amount       float32    `json:"amount" binding:"required,gt=0" example:"1000"`
+	TargetRate   float32    `json:"target_rate" binding:"required,gt=0" example:"0.95"`
+	ExpiresAt    *time.Time `json:"expires_at" example:"2025-12-31T23:59:59Z"`
+}
+
+type OrderRequest struct {
+	UserID  uint `json:"-"`
+	OrderID uint `uri:"id" binding:"required,min=1"`
+}
+
+type OrderResponse struct {
+	Message string       `json:"message" example:"text message"`
+	Order   Order        `json:"order"`
+	Events  []OrderEvent `json:"events,omitempty"`
+}
+
+type OrdersRequest struct {
+	UserID uint   `json:"-"`
+	Status string `form:"status" binding:"omitempty,oneof=open filled cancelled expired" example:"open"`
+}
+
+type OrdersResponse struct {
+	Message string  `json:"message" example:"text message"`
+	Orders  []Order `json:"orders"`
+}
+
+// Hold reserves the amount of an account for a merchant, the amount stays on the account but is not available.
+// An open hold is captured (in full or in part, the rest is released), released, or expires at ExpiresAt.
+type Hold struct {
+	ID        uint       `json:"id" example:"1"`
+	UserID    uint       `json:"-"`
+	AccountID uint       `json:"account_id,omitempty" example:"12"`
+	Currency  string     `json:"currency" example:"USD"`
+	Amount    float32    `json:"amount" example:"150"`
+	Captured  *float32   `json:"captured,omitempty" example:"120"`
+	Reference string     `json:"reference,omitempty" example:"order-1042"`
+	Status    string     `json:"status" example:"open"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+}
+
+// CreateHoldRequest holds the amount on the account, without AccountID on the default account of the currency.
+// Without ExpiresAt the hold expires after the default time to live.
+type CreateHoldRequest struct {
+	UserID        uint       `json:"-"`
+	Currency      string     `json:"currency" binding:"required,min=3,max=6" example:"USD"`
+	Amount        float32    `json:"amount" binding:"required,gt=0" example:"150"`
+	AccountID     uint       `json:"account_id" binding:"omitempty,gt=0" example:"12"`
+	Reference     string     `json:"reference" binding:"max=100" example:"order-1042"`
+	ExpiresAt     *time.Time `json:"expires_at" example:"2025-12-31T23:59:59Z"`
+	TwoFactorCode string     `json:"-"`
+}
+
+type HoldRequest struct {
+	UserID uint `json:"-"`
+	HoldID uint `json:"-" uri:"id" binding:"required,min=1"`
+}
+
+// CaptureHoldRequest debits the captured amount of the hold, without Amount the whole hold is captured.
+type CaptureHoldRequest struct {
+	UserID uint    `json:"-"`
+	HoldID uint    `json:"-" uri:"id" binding:"required,min=1"`
+	Amount float32 `json:"amount" binding:"omitempty,gt=0" example:"120"`
+}
+
+type HoldsRequest struct {
+	UserID uint   `json:"-"`
+	Status string `form:"status" binding:"omitempty,oneof=open captured released expired" example:"open"`
+}
+
+type HoldResponse struct {
+	Message string `json:"message" example:"text message"`
+	Hold    Hold   `json:"hold"`
+}
+
+type HoldsResponse struct {
+	Message string `json:"message" example:"text message"`
+	Holds   []Hold `json:"holds"`
+}
+
+// Schedule runs a deposit or an exchange for the user by a cron expression or at a fixed interval,
+// between the start and the optional end date.
+type Schedule struct {
+	ID         uint       `json:"id" example:"1"`
+	UserID     uint       `json:"-"`
+	Operation  string     `json:"operation" example:"exchange"`
+	Currency   string     `json:"currency" example:"USD"`
+	ToCurrency string     `json:"to_currency,omitempty" example:"EUR"`
+	Amount     float32    `json:"amount" example:"100"`
+	Cron       string     `json:"cron,omitempty" example:"0 9 * * MON"`
+	Interval   string     `json:"interval,omitempty" example:"168h"`
+	StartsAt   time.Time  `json:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at,omitempty"`
+	Status     string     `json:"status" example:"active"`
+	NextRunAt  *time.Time `json:"next_run_at,omitempty"`
+	RetryCount int        `json:"retry_count" example:"0"`
+	CreatedAt  time.Time  `json:"created_at"`
+	DueAt      *time.Time `json:"-"`
+}
+
+type ScheduleRun struct {
+	ID           uint      `json:"id" example:"1"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+	Attempt      int       `json:"attempt" example:"1"`
+	Status       string    `json:"status" example:"success"`
+	Details      string    `json:"details" example:"text details"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type CreateScheduleRequest struct {
+	UserID     uint       `json:"-"`
+	Operation  string     `json:"operation" binding:"required,oneof=deposit exchange" example:"exchange"`
+	Currency   string     `json:"currency" binding:"required,min=3,max=6" example:"USD"`
+	ToCurrency string     `json:"to_currency" binding:"omitempty,min=3,max=6" example:"EUR"`
+	Amount     float32    `json:"amount" binding:"required,gt=0" example:"100"`
+	Cron       string     `json:"cron" example:"0 9 * * MON"`
+	Interval   string     `json:"interval" example:"168h"`
+	StartsAt   *time.Time `json:"starts_at" example:"2025-01-06T00:00:00Z"`
+	EndsAt     *time.Time `json:"ends_at" example:"2025-12-31T23:59:59Z"`
+}
+
+type UpdateScheduleRequest struct {
+	UserID     uint       `json:"-"`
+	ScheduleID uint       `json:"-"`
+	Status     string     `json:"status" binding:"omitempty,oneof=active paused" example:"paused"`
+	Amount     *float32   `json:"amount" binding:"omitempty,gt=0" example:"150"`
+	EndsAt     *time.Time `json:"ends_at" example:"2025-12-31T23:59:59Z"`
+}
+
+type ScheduleRequest struct {
+	UserID     uint `json:"-"`
+	ScheduleID uint `uri:"id" binding:"required,min=1"`
+}
+
+type ScheduleResponse struct {
+	Message  string        `json:"message" example:"text message"`
+	Schedule Schedule      `json:"schedule"`
+	Runs     []ScheduleRun `json:"runs,omitempty"`
+}
+
+type SchedulesRequest struct {
+	UserID uint `json:"-"`
+}
+
+type SchedulesResponse struct {
+	Message   string     `json:"message" example:"text message"`
+	Schedules []Schedule `json:"schedules"`
+}
+
+// WalletEvent describes a completed balance change, it is the data of the webhook events.
+// For an exchange, Currency, Amount and Balance describe the spent account.
+type WalletEvent struct {
+	Operation  string  `json:"operation" example:"exchange"`
+	Currency   string  `json:"currency" example:"USD"`
+	Amount     float32 `json:"amount" example:"500"`
+	Balance    float32 `json:"balance" example:"1500"`
+	ToCurrency string  `json:"to_currency,omitempty" example:"EUR"`
+	Received   float32 `json:"received,omitempty" example:"458.2"`
+	ToBalance  float32 `json:"to_balance,omitempty" example:"958.2"`
+	Fee        float32 `json:"fee,omitempty" example:"2.3"`
+	Rate       float32 `json:"rate,omitempty" example:"0.9164"`
+}
+
+type Webhook struct {
+	ID         uint      `json:"id" example:"1"`
+	UserID     uint      `json:"-"`
+	URL        string    `json:"url" example:"https://example.com/hooks/wallet"`
+	EventTypes []string  `json:"event_types" example:"deposit.completed,exchange.completed"`
+	Secret     string    `json:"secret,omitempty" example:"whsec_5f2b..."`
+	Active     bool      `json:"active" example:"true"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type CreateWebhookRequest struct {
+	UserID     uint     `json:"-"`
+	URL        string   `json:"url" binding:"required,url,max=2000" example:"https://example.com/hooks/wallet"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=deposit.completed withdraw.completed exchange.completed" example:"deposit.completed,exchange.completed"`
+}
+
+type WebhookRequest struct {
+	UserID    uint `json:"-"`
+	WebhookID uint `uri:"id" binding:"required,min=1"`
+}
+
+type WebhooksRequest struct {
+	UserID uint `json:"-"`
+}
+
+type WebhookResponse struct {
+	Message string  `json:"message" example:"text message"`
+	Webhook Webhook `json:"webhook"`
+}
+
+type WebhooksResponse struct {
+	Message  string    `json:"message" example:"text message"`
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+type WebhookDelivery struct {
+	ID             uint64          `json:"id" example:"1"`
+	EventID        uint64          `json:"event_id" example:"1"`
+	EventType      string          `json:"event_type" example:"deposit.completed"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         string          `json:"status" example:"delivered"`
+	Attempts       int             `json:"attempts" example:"1"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int            `json:"last_status_code,omitempty" example:"200"`
+	LastError      string          `json:"last_error,omitempty" example:"text error"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type WebhookDeliveriesRequest struct {
+	UserID    uint   `json:"-"`
+	WebhookID uint   `uri:"id" binding:"required,min=1"`
+	Status    string `form:"status" binding:"omitempty,oneof=pending delivered failed" example:"failed"`
+}
+
+type WebhookDeliveriesResponse struct {
+	Message    string            `json:"message" example:"text message"`
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+type RedeliverRequest struct {
+	UserID     uint   `json:"-"`
+	WebhookID  uint   `uri:"id" binding:"required,min=1"`
+	DeliveryID uint64 `uri:"delivery_id" binding:"required,min=1"`
+}
+
+type WebhookDeliveryResponse struct {
+	Message  string          `json:"message" example:"text message"`
+	Delivery WebhookDelivery `json:"delivery"`
+}
+
+// WebhookJob is a claimed delivery together with the endpoint and the event, it is sent by the delivery worker.
+type WebhookJob struct {
+	DeliveryID     uint64
+	Attempt        int
+	URL            string
+	Secret         string
+	EventID        uint64
+	EventType      string
+	Payload        json.RawMessage
+	EventCreatedAt time.Time
+}
+
+// WebhookAttempt is the result of one attempt to deliver a webhook.
+// NextAttemptAt is set when the delivery is retried.
+type WebhookAttempt struct {
+	DeliveryID    uint64
+	Status        string
+	StatusCode    *int
+	Error         string
+	NextAttemptAt *time.Time
+}
+
+// OutboxEvent is a domain event from the transactional outbox.
+// The events of one user are published in the order of their IDs.
+type OutboxEvent struct {
+	ID        uint64          `json:"id"`
+	UserID    uint            `json:"user_id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+	// Attempts is the number of the failed attempts to publish the event, it is not published
+	Attempts int `json:"-"`
+}
+
+// UserEvent is the payload of the user events of the outbox.
+type UserEvent struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// RatesStreamRequest is the subscription of a rate stream, Pairs is a comma separated list like "USD/EUR,EUR/RUB".
+type RatesStreamRequest struct {
+	Pairs string `form:"pairs" binding:"required" example:"USD/EUR,EUR/RUB"`
+}
+
+// RateUpdate is a changed rate of a currency pair pushed to the rate streams.
+type RateUpdate struct {
+	Pair         string    `json:"pair" example:"USD/EUR"`
+	FromCurrency string    `json:"from_currency" example:"USD"`
+	ToCurrency   string    `json:"to_currency" example:"EUR"`
+	Rate         float32   `json:"rate" example:"0.96"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// RatesStreamMessage is a message of the rate WebSocket, it is sent by the server with the updates or an error
+// and by the client to change the subscription ("subscribe" or "unsubscribe" with the pairs).
+type RatesStreamMessage struct {
+	Type  string       `json:"type" example:"rates"`
+	Rates []RateUpdate `json:"rates,omitempty"`
+	Pairs []string     `json:"pairs,omitempty" example:"USD/EUR"`
+	Error string       `json:"error,omitempty"`
+}
+
+// BalanceNotification is pushed to the balance WebSocket of the user.
+// The first message after the connection has the type "balance" and only the current balance,
+// the following ones are sent after a committed balance change and carry the operation.
+type BalanceNotification struct {
+	Type       string             `json:"type" example:"wallet.deposited"`
+	EventID    uint64             `json:"event_id,omitempty" example:"42"`
+	Operation  *WalletEvent       `json:"operation,omitempty"`
+	NewBalance map[string]float32 `json:"new_balance"`
+	CreatedAt  time.Time          `json:"created_at"`
 }
@@ -0,0 +1,68 @@
+package encryption
+
+import (
+	"strings"
+	"testing"
+)
+
+const testKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func TestEncryptDecrypt(t *testing.T) {
+	c, err := New(testKey)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	first, err := c.Encrypt([]byte("JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	second, err := c.Encrypt([]byte("JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	if first == second {
+		t.Error("the same plaintext is encrypted to the same ciphertext")
+	}
+	if strings.Contains(first, "JBSWY3DPEHPK3PXP") {
+		t.Error("the ciphertext contains the plaintext")
+	}
+
+	plaintext, err := c.Decrypt(first)
+	if err != nil || string(plaintext) != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("Decrypt() = %q, %v, want the plaintext", plaintext, err)
+	}
+
+	other, err := New(strings.Repeat("ff", KeySize))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		cipher     *Cipher
+		ciphertext string
+	}{
+		{"another key", other, first},
+		{"changed ciphertext", c, first[:len(first)-4] + "AAAA"},
+		{"not base64", c, "not base64!"},
+		{"too short", c, "AAAA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.cipher.Decrypt(tt.ciphertext); err != ErrInvalidCiphertext {
+				t.Errorf("Decrypt() error = %v, want %v", err, ErrInvalidCiphertext)
+			}
+		})
+	}
+}
+
+func TestNewInvalidKey(t *testing.T) {
+	for _, key := range []string{"", "zz", strings.Repeat("00", 16)} {
+		if _, err := New(key); err == nil {
+			t.Errorf("New(%q) accepted an invalid key", key)
+		}
+	}
+}
@@ -5,15 +5,23 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/EvansTrein/RESTful_exchangerServer/pkg/retry"
 	pb "github.com/EvansTrein/proto-exchange/exchange"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
 )
 
+// minConnectTimeout is the shortest time given to one connection attempt of the client.
+const minConnectTimeout = 20 * time.Second
+
 var (
 	ErrServerUnavailable = errors.New("gRPC server is unavailable")
 	ErrServerTimeOut     = errors.New("gRPC method call execution timeout expired")
@@ -28,34 +36,117 @@ type ClientGRPC interface {
 }
 
 // ServerGRPC represents a gRPC client connection.
-// It includes a logger and a gRPC connection.
+// It includes a logger and a gRPC connection, cancel stops the background reconnection of a degraded start.
 type ServerGRPC struct {
-	log  *slog.Logger
-	conn *grpc.ClientConn
+	log    *slog.Logger
+	conn   *grpc.ClientConn
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
 // New creates a new instance of the ServerGRPC and establishes a connection to the gRPC server.
-// It takes the server address, port, and a logger as parameters.
-// If the connection fails, it returns an error.
-func New(log *slog.Logger, address, port string) (*ServerGRPC, error) {
+// It takes the server address, port, a logger and the retry policy of the connection as parameters,
+// the connection is attempted until it is ready or the deadline of the policy passes, every attempt is logged.
+// If the connection fails and degraded is false, it returns an error. With degraded the instance is returned anyway,
+// the calls fail until the server is reachable and the connection is retried in the background.
+func New(ctx context.Context, log *slog.Logger, address, port string, policy retry.Policy, degraded bool) (*ServerGRPC, error) {
 	grpcAddr := fmt.Sprintf("%s:%s", address, port)
 	log.Debug("gRPC server: started creating", "address", grpcAddr)
 
-	conn, err := grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	options := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if policy.MaxDelay > 0 {
+		// the reconnection of the client follows the policy, the default backoff grows up to two minutes
+		options = append(options, grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff: backoff.Config{
+				BaseDelay:  max(policy.InitialDelay, 100*time.Millisecond),
+				Multiplier: backoff.DefaultConfig.Multiplier,
+				Jitter:     backoff.DefaultConfig.Jitter,
+				MaxDelay:   policy.MaxDelay,
+			},
+			MinConnectTimeout: minConnectTimeout,
+		}))
+	}
+
+	conn, err := grpc.NewClient(grpcAddr, options...)
 	if err != nil {
 		log.Error("failed to create a client for gRPC server", "error", err)
 		return nil, err
 	}
 
-	log.Info("gRPC server: successfully created")
-	return &ServerGRPC{log: log, conn: conn}, nil
+	s := &ServerGRPC{log: log, conn: conn}
+
+	// the client connects lazily, the connection is requested to find out whether the server is reachable
+	err = retry.Do(ctx, log, policy, "gRPC server", s.waitReady)
+	if err == nil {
+		log.Info("gRPC server: successfully created")
+		return s, nil
+	}
+
+	if !degraded {
+		conn.Close()
+		return nil, err
+	}
+
+	log.Warn("gRPC server: unavailable, started without it, the connection is retried in the background", "error", err)
+
+	reconnectCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.wg.Add(1)
+	go s.reconnect(reconnectCtx, max(policy.MaxDelay, time.Second))
+
+	return s, nil
 }
 
-// Close closes the connection to the gRPC server.
+// waitReady connects the client and waits until the connection is ready.
+// If the connection attempt fails, it returns ErrServerUnavailable.
+func (s *ServerGRPC) waitReady(ctx context.Context) error {
+	s.conn.Connect()
+
+	state := s.conn.GetState()
+	for state != connectivity.Ready {
+		if !s.conn.WaitForStateChange(ctx, state) {
+			return ctx.Err()
+		}
+
+		state = s.conn.GetState()
+		if state == connectivity.TransientFailure || state == connectivity.Shutdown {
+			return fmt.Errorf("%w: connection is in state %s", ErrServerUnavailable, state)
+		}
+	}
+
+	return nil
+}
+
+// reconnect tries to connect the client every interval until the connection is ready or the context is done.
+func (s *ServerGRPC) reconnect(ctx context.Context, interval time.Duration) {
+	defer s.wg.Done()
+
+	for {
+		if err := s.waitReady(ctx); err == nil {
+			s.log.Info("gRPC server: connection is ready, the degraded mode is over")
+			return
+		} else if ctx.Err() == nil {
+			s.log.Warn("gRPC server: still unavailable", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Close closes the connection to the gRPC server and stops the background reconnection.
 // If the connection is already closed, it returns an error.
 func (s *ServerGRPC) Close() error {
 	s.log.Debug("gRPC server: stop started")
 
+	if s.cancel != nil {
+		s.cancel()
+		s.wg.Wait()
+	}
+
 	if err := s.conn.Close(); err != nil {
 		s.log.Error("failed to close connection with gRPC server")
 		return err
@@ -0,0 +1,201 @@
+// Package jwtkeys holds the keys that sign and verify the access tokens and publishes their public parts as a JWKS.
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/EvansTrein/RESTful_exchangerServer/models"
+	"github.com/golang-jwt/jwt"
+)
+
+// minRSABits is the smallest accepted RSA key
+const minRSABits = 2048
+
+var (
+	ErrUnknownKey          = errors.New("token is signed with an unknown key")
+	ErrUnexpectedAlgorithm = errors.New("token is signed with an unexpected algorithm")
+)
+
+// key is a key of the keyring, a key without the private part only verifies.
+type key struct {
+	id      string
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// Keyring signs the tokens with the active key and verifies them with any of its keys, the key is chosen by the kid header.
+// A keyring without the asymmetric keys uses HS256 with the secret, its tokens have no kid.
+// With the asymmetric keys the secret, if it is still set, only verifies the tokens without a kid issued before the switch.
+type Keyring struct {
+	active *key
+	keys   map[string]*key
+}
+
+// Load reads the PEM files of the keys by their kid and creates the keyring, see New.
+func Load(files map[string]string, active, secret string) (*Keyring, error) {
+	pems := make(map[string][]byte, len(files))
+	for kid, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the key %q: %w", kid, err)
+		}
+		pems[kid] = data
+	}
+
+	return New(pems, active, secret)
+}
+
+// New creates the keyring from the PEM keys by their kid, the tokens are signed with the key active.
+// A key is a PKCS#8 or PKCS#1 private key or a PKIX public key, RSA keys of at least 2048 bits and Ed25519 keys are accepted.
+// Without the keys the keyring uses HS256 with the secret. With the keys the secret never signs,
+// it is kept to verify the HS256 tokens without a kid, so that the switch to the keys does not log anybody out,
+// and can be removed after the lifetime of the tokens.
+// If a key is malformed or the active key is missing or has no private part, it returns an error.
+func New(pems map[string][]byte, active, secret string) (*Keyring, error) {
+	if len(pems) == 0 {
+		if secret == "" {
+			return nil, errors.New("neither the keys nor the secret are set")
+		}
+		hmac := &key{method: jwt.SigningMethodHS256, private: []byte(secret), public: []byte(secret)}
+		return &Keyring{active: hmac, keys: map[string]*key{"": hmac}}, nil
+	}
+
+	keys := make(map[string]*key, len(pems))
+	for kid, data := range pems {
+		if kid == "" {
+			return nil, errors.New("key without kid")
+		}
+
+		k, err := parseKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", kid, err)
+		}
+		k.id = kid
+		keys[kid] = k
+	}
+
+	if secret != "" {
+		keys[""] = &key{method: jwt.SigningMethodHS256, public: []byte(secret)}
+	}
+
+	activeKey, ok := keys[active]
+	if !ok {
+		return nil, fmt.Errorf("active key %q is not among the keys", active)
+	}
+	if activeKey.private == nil {
+		return nil, fmt.Errorf("active key %q has no private key", active)
+	}
+
+	return &Keyring{active: activeKey, keys: keys}, nil
+}
+
+// Sign signs the claims with the active key and sets its kid in the header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.method, claims)
+	if k.active.id != "" {
+		token.Header["kid"] = k.active.id
+	}
+
+	return token.SignedString(k.active.private)
+}
+
+// Keyfunc returns the verification key of the token by its kid, it is passed to jwt.Parse.
+// The algorithm of the token must be the algorithm of the key, so that a public key can not be used as an HMAC secret.
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	verifier, ok := k.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if token.Method.Alg() != verifier.method.Alg() {
+		return nil, ErrUnexpectedAlgorithm
+	}
+
+	return verifier.public, nil
+}
+
+// JWKS returns the public keys sorted by kid, the HMAC secret is never published.
+func (k *Keyring) JWKS() models.JWKS {
+	jwks := models.JWKS{Keys: make([]models.JWK, 0, len(k.keys))}
+
+	for _, verifier := range k.keys {
+		switch public := verifier.public.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, models.JWK{
+				Kty: "RSA",
+				Kid: verifier.id,
+				Use: "sig",
+				Alg: verifier.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, models.JWK{
+				Kty: "OKP",
+				Kid: verifier.id,
+				Use: "sig",
+				Alg: verifier.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
+
+// parseKey parses a PEM private or public key.
+func parseKey(data []byte) (*key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch parsed := parsed.(type) {
+	case *rsa.PrivateKey:
+		if parsed.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key of %d bits, at least %d are required", parsed.N.BitLen(), minRSABits)
+		}
+		return &key{method: jwt.SigningMethodRS256, private: parsed, public: &parsed.PublicKey}, nil
+	case *rsa.PublicKey:
+		if parsed.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key of %d bits, at least %d are required", parsed.N.BitLen(), minRSABits)
+		}
+		return &key{method: jwt.SigningMethodRS256, public: parsed}, nil
+	case ed25519.PrivateKey:
+		return &key{method: jwt.SigningMethodEdDSA, private: parsed, public: parsed.Public()}, nil
+	case ed25519.PublicKey:
+		return &key{method: jwt.SigningMethodEdDSA, public: parsed}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+}
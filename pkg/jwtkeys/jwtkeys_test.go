@@ -0,0 +1,187 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func rsaPEM(t *testing.T) ([]byte, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey() error = %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})
+}
+
+func ed25519PEM(t *testing.T) []byte {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() error = %v", err)
+	}
+	private, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey() error = %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private})
+}
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{"userID": 1, "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func verify(k *Keyring, token string) error {
+	_, err := jwt.Parse(token, k.Keyfunc)
+	return err
+}
+
+func TestRotation(t *testing.T) {
+	rsaKey, rsaPublic := rsaPEM(t)
+	edKey := ed25519PEM(t)
+
+	old, err := New(map[string][]byte{"old": rsaKey}, "old", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	oldToken, err := old.Sign(claims())
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// the new key signs, the old one only verifies with its public part
+	rotated, err := New(map[string][]byte{"old": rsaPublic, "new": edKey}, "new", "")
+	if err != nil {
+		t.Fatalf("New() after the rotation error = %v", err)
+	}
+
+	newToken, err := rotated.Sign(claims())
+	if err != nil {
+		t.Fatalf("Sign() after the rotation error = %v", err)
+	}
+
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if err := verify(rotated, token); err != nil {
+			t.Errorf("token of the %s key is rejected after the rotation: %v", name, err)
+		}
+	}
+
+	if err := verify(old, newToken); err == nil {
+		t.Error("token of an unknown key is accepted")
+	}
+
+	jwks := rotated.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != "new" || jwks.Keys[0].Kty != "OKP" || jwks.Keys[1].Kty != "RSA" {
+		t.Fatalf("JWKS() = %+v, want the Ed25519 and the RSA key", jwks)
+	}
+}
+
+func TestKeyfunc(t *testing.T) {
+	rsaKey, rsaPublic := rsaPEM(t)
+
+	keys, err := New(map[string][]byte{"rsa": rsaKey}, "rsa", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// the secret is still set after the switch to the keys
+	switched, err := New(map[string][]byte{"rsa": rsaKey}, "rsa", "secret")
+	if err != nil {
+		t.Fatalf("New() with the keys and the secret error = %v", err)
+	}
+
+	hmac, err := New(nil, "", "secret")
+	if err != nil {
+		t.Fatalf("New() with the secret error = %v", err)
+	}
+	hmacToken, err := hmac.Sign(claims())
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// the public key used as an HMAC secret must not pass
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+	confused.Header["kid"] = "rsa"
+	confusedToken, err := confused.SignedString(rsaPublic)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	// the secret with the kid of the asymmetric key must not pass
+	secretWithKid := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+	secretWithKid.Header["kid"] = "rsa"
+	secretWithKidToken, err := secretWithKid.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		keys   *Keyring
+		token  string
+		wantOK bool
+	}{
+		{"HS256 token after the secret is removed", keys, hmacToken, false},
+		{"HS256 token while the secret is set", switched, hmacToken, true},
+		{"HS256 token of the public key", switched, confusedToken, false},
+		{"HS256 token of the secret with a kid", switched, secretWithKidToken, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verify(tt.keys, tt.token); (err == nil) != tt.wantOK {
+				t.Fatalf("verify() error = %v, want accepted %v", err, tt.wantOK)
+			}
+		})
+	}
+
+	// the secret only verifies, the new tokens are signed with the active key
+	token, err := switched.Sign(claims())
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	parsed, err := jwt.Parse(token, switched.Keyfunc)
+	if err != nil || parsed.Method.Alg() != "RS256" || parsed.Header["kid"] != "rsa" {
+		t.Fatalf("token is not signed with the active key: %v", err)
+	}
+
+	if len(hmac.JWKS().Keys) != 0 || len(switched.JWKS().Keys) != 1 {
+		t.Fatal("the HMAC secret is published")
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	rsaKey, rsaPublic := rsaPEM(t)
+
+	tests := []struct {
+		name   string
+		pems   map[string][]byte
+		active string
+	}{
+		{"no keys and no secret", nil, ""},
+		{"missing active key", map[string][]byte{"rsa": rsaKey}, "other"},
+		{"public active key", map[string][]byte{"rsa": rsaPublic}, "rsa"},
+		{"malformed key", map[string][]byte{"bad": []byte("not a key")}, "bad"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.pems, tt.active, ""); err == nil {
+				t.Fatal("New() error = nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+	DriverLog  = "log"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails. The SMTP implementation delivers them, the file and log implementations
+// only record them, so that the flows that send emails can be used and tested offline.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// FileMailer appends every message to a file.
+type FileMailer struct {
+	mu   sync.Mutex
+	from string
+	path string
+}
+
+// NewFile creates a mailer that appends the messages to the file, the file is created if it does not exist.
+func NewFile(from, path string) (*FileMailer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the mail file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	return &FileMailer{from: from, path: path}, nil
+}
+
+// Send appends the message with its headers to the file, messages are separated by an empty line.
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(buildMessage(m.from, msg, time.Now()), '\n')); err != nil {
+		return err
+	}
+
+	return file.Sync()
+}
+
+// LogMailer writes every message to the log.
+type LogMailer struct {
+	log  *slog.Logger
+	from string
+}
+
+// NewLog creates a mailer that writes the messages to the log.
+func NewLog(log *slog.Logger, from string) *LogMailer {
+	return &LogMailer{log: log, from: from}
+}
+
+// Send writes the message to the log at the info level.
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.log.Info("mailer: message", "from", m.from, "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// buildMessage formats the message with the headers of a plain text email.
+func buildMessage(from string, msg Message, date time.Time) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMessage(t *testing.T) {
+	date := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	got := string(buildMessage("no-reply@exchanger.local", Message{To: "john@example.com", Subject: "Hello", Body: "line 1\nline 2"}, date))
+
+	want := "From: no-reply@exchanger.local\r\n" +
+		"To: john@example.com\r\n" +
+		"Subject: Hello\r\n" +
+		"Date: Thu, 02 Jan 2025 03:04:05 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" +
+		"line 1\r\nline 2\r\n"
+
+	if got != want {
+		t.Errorf("buildMessage() = %q, want %q", got, want)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+
+	m, err := NewFile("no-reply@exchanger.local", path)
+	if err != nil {
+		t.Fatalf("NewFile() error = %v", err)
+	}
+
+	for _, to := range []string{"first@example.com", "second@example.com"} {
+		if err := m.Send(context.Background(), Message{To: to, Subject: "Confirm your email", Body: "token"}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read the mail file: %v", err)
+	}
+
+	for _, want := range []string{"To: first@example.com\r\n", "To: second@example.com\r\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("mail file does not contain %q", want)
+		}
+	}
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	m := NewSMTP("localhost", "25", "", "", "no-reply@exchanger.local")
+
+	err := m.Send(context.Background(), Message{To: "john@example.com\r\nBcc: all@example.com", Subject: "Hello"})
+	if err != ErrInvalidHeader {
+		t.Errorf("Send() error = %v, want %v", err, ErrInvalidHeader)
+	}
+}
@@ -0,0 +1,60 @@
+// Package ratelimit counts the requests in the memory of the process with fixed windows.
+// It is the fallback of the shared limiter, the counts are not shared between the instances of the server.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the counters of the ended windows are removed.
+const sweepInterval = time.Minute
+
+type counter struct {
+	requests int
+	end      time.Time
+}
+
+// Local is a rate limiter in the memory of the process, it is safe for concurrent use.
+type Local struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewLocal creates an empty in-process rate limiter.
+func NewLocal() *Local {
+	return &Local{counters: make(map[string]*counter), now: time.Now}
+}
+
+// Allow counts a request of the key, the first request of the key starts a window.
+// It reports whether the request is within limit requests per window, and if not, the time until the window ends.
+// The error is always nil, it matches the signature of the shared limiter.
+func (l *Local) Allow(key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		for k, c := range l.counters {
+			if !now.Before(c.end) {
+				delete(l.counters, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.counters[key]
+	if !ok || !now.Before(c.end) {
+		c = &counter{end: now.Add(window)}
+		l.counters[key] = c
+	}
+
+	c.requests++
+	if c.requests > limit {
+		return false, c.end.Sub(now), nil
+	}
+
+	return true, 0, nil
+}
@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	now := start
+	l := NewLocal()
+	l.now = func() time.Time { return now }
+
+	tests := []struct {
+		name      string
+		key       string
+		after     time.Duration
+		allowed   bool
+		wantRetry time.Duration
+	}{
+		{name: "first request", key: "a", allowed: true},
+		{name: "second request", key: "a", after: time.Second, allowed: true},
+		{name: "over the limit", key: "a", after: 2 * time.Second, wantRetry: 8 * time.Second},
+		{name: "other key", key: "b", after: 2 * time.Second, allowed: true},
+		{name: "next window", key: "a", after: 10 * time.Second, allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = start.Add(tt.after)
+			allowed, retryAfter, err := l.Allow(tt.key, 2, 10*time.Second)
+			if err != nil {
+				t.Fatalf("Allow() error = %v", err)
+			}
+			if allowed != tt.allowed || retryAfter != tt.wantRetry {
+				t.Errorf("Allow() = %v, %v, want %v, %v", allowed, retryAfter, tt.allowed, tt.wantRetry)
+			}
+		})
+	}
+
+	// the ended windows are swept
+	now = start.Add(time.Hour)
+	l.Allow("c", 2, time.Second)
+	if len(l.counters) != 1 {
+		t.Errorf("counters after the sweep = %d, want 1", len(l.counters))
+	}
+}
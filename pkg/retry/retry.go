@@ -0,0 +1,62 @@
+// Package retry retries the connections to the dependencies with an exponential backoff up to a deadline.
+package retry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// minDelay keeps a policy without the initial delay from retrying in a busy loop.
+const minDelay = 10 * time.Millisecond
+
+// Policy describes how an operation is retried. The first retry is made after InitialDelay, the delay doubles
+// after every failed attempt up to MaxDelay, and no retry is started after Deadline from the first attempt.
+// With a zero Deadline the operation is tried once.
+type Policy struct {
+	Deadline     time.Duration
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// Do calls attempt until it succeeds, the deadline of the policy passes or ctx is done, every attempt is logged.
+// The context of an attempt is done at the deadline, so a slow attempt can not outlive it.
+// It returns nil after the first successful attempt, otherwise the error of the last attempt.
+func Do(ctx context.Context, log *slog.Logger, policy Policy, name string, attempt func(ctx context.Context) error) error {
+	if policy.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.Deadline)
+		defer cancel()
+	}
+
+	delay := max(policy.InitialDelay, minDelay)
+	for number := 1; ; number++ {
+		log.Debug("connection attempt", "dependency", name, "attempt", number)
+
+		err := attempt(ctx)
+		if err == nil {
+			log.Debug("connection attempt succeeded", "dependency", name, "attempt", number)
+			return nil
+		}
+
+		deadline, _ := ctx.Deadline()
+		if policy.Deadline <= 0 || ctx.Err() != nil || time.Now().Add(delay).After(deadline) {
+			log.Error("connection attempt failed, no more retries", "dependency", name, "attempt", number, "error", err)
+			return fmt.Errorf("%s is unavailable after %d attempt(s): %w", name, number, err)
+		}
+
+		log.Warn("connection attempt failed", "dependency", name, "attempt", number, "retry in", delay, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s is unavailable after %d attempt(s): %w", name, number, err)
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+}
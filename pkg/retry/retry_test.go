@@ -0,0 +1,89 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("connection refused")
+
+func TestDo(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		failures int
+		attempts int
+		err      bool
+	}{
+		{
+			name:     "first attempt succeeds",
+			policy:   Policy{Deadline: time.Second, InitialDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond},
+			attempts: 1,
+		},
+		{
+			name:     "succeeds after retries",
+			policy:   Policy{Deadline: time.Second, InitialDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond},
+			failures: 3,
+			attempts: 4,
+		},
+		{
+			name:     "deadline passes",
+			policy:   Policy{Deadline: 150 * time.Millisecond, InitialDelay: 30 * time.Millisecond, MaxDelay: time.Second},
+			failures: 100,
+			attempts: 3,
+			err:      true,
+		},
+		{
+			name:     "without a deadline it is tried once",
+			policy:   Policy{InitialDelay: time.Millisecond},
+			failures: 100,
+			attempts: 1,
+			err:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			err := Do(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)), tt.policy, "test", func(ctx context.Context) error {
+				attempts++
+				if attempts <= tt.failures {
+					return errDown
+				}
+				return nil
+			})
+
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v, want error %t", err, tt.err)
+			}
+			if tt.err && !errors.Is(err, errDown) {
+				t.Errorf("error = %v, want the error of the last attempt", err)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestDoStopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := Policy{Deadline: time.Minute, InitialDelay: time.Second, MaxDelay: time.Second}
+
+	start := time.Now()
+	err := Do(ctx, slog.New(slog.NewTextHandler(io.Discard, nil)), policy, "test", func(ctx context.Context) error {
+		cancel()
+		return errDown
+	})
+
+	if !errors.Is(err, errDown) {
+		t.Fatalf("error = %v, want %v", err, errDown)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("retries went on after the context was done")
+	}
+}
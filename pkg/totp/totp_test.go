@@ -0,0 +1,85 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the SHA-1 test vectors of RFC 6238, the codes are truncated to 6 digits
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(secret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Code() at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	step := Step(now)
+
+	tests := []struct {
+		name   string
+		step   int64
+		wantOk bool
+	}{
+		{"current step", step, true},
+		{"previous step", step - 1, true},
+		{"next step", step + 1, true},
+		{"too old", step - 2, false},
+		{"too new", step + 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Code(secret, tt.step)
+			if err != nil {
+				t.Fatalf("Code() error = %v", err)
+			}
+
+			got, ok := Validate(secret, code, now, 1)
+			if ok != tt.wantOk || (ok && got != tt.step) {
+				t.Errorf("Validate() = %d, %v, want %d, %v", got, ok, tt.step, tt.wantOk)
+			}
+		})
+	}
+
+	if _, ok := Validate(secret, "12345", now, 1); ok {
+		t.Error("Validate() accepted a code of a wrong length")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Exchanger", "john@example.com", "JBSWY3DPEHPK3PXP")
+
+	for _, want := range []string{"otpauth://totp/Exchanger:john@example.com?", "secret=JBSWY3DPEHPK3PXP", "issuer=Exchanger", "digits=6", "period=30"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("ProvisioningURI() = %s, does not contain %s", uri, want)
+		}
+	}
+}
@@ -572,6 +572,7 @@ func TestDeleteUserAfter(t *testing.T) {
 
 	t.Run("successful delete for tests", func(t *testing.T) {
 		testCase := testHTTP.DELETE(apiVersion+urlPathDel).WithHeader("Authorization", "Bearer "+token).
+			WithJSON(map[string]string{"sweep_to": "withdraw"}).
 			Expect().
 			Status(http.StatusOK).
 			JSON().Object().NotEmpty()